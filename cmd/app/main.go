@@ -1,12 +1,23 @@
 package main
 
 import (
-	"log"
+	"expvar"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
 	"travel-agent/internal/config"
 	"travel-agent/internal/handlers"
+	"travel-agent/internal/logging"
 	"travel-agent/internal/models"
 	"travel-agent/internal/service"
 	"travel-agent/internal/service/ai"
+	"travel-agent/internal/service/airports"
+	"travel-agent/internal/service/currency"
+	"travel-agent/internal/service/flights"
+	"travel-agent/internal/service/transport"
+	"travel-agent/internal/store"
+	"travel-agent/pkg/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -15,25 +26,118 @@ func main() {
 	// Load configuration
 	cfg, err := config.Load("config.json")
 	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+		fatal("Failed to load configuration", err)
 	}
 
+	// Structured logs, tagged with the request, booking and stage they belong to
+	logger, err := logging.New(os.Stdout, cfg.LogLevel, cfg.Logging.Format)
+	if err != nil {
+		fatal("Invalid logging configuration", err)
+	}
+	slog.SetDefault(logger)
+
+	// Provider payloads carry travelers' personal data: they are only logged at the debug
+	// level, for a sample of calls, and redacted
+	payloadLogging := ai.WithPayloadLogging(&utils.PayloadLogger{
+		SampleRate: *cfg.Logging.PayloadSampleRate,
+	})
+
 	// Initialize AI inference module
-	extractionInference, err := ai.NewInferenceEngine[models.TravelParameters, models.BookingRequest](cfg.AIProvider.APIKey)
+	extractionInference, err := ai.NewInferenceEngine[models.TravelParameters, models.BookingRequest](cfg.AIProvider.APIKey, payloadLogging)
+	if err != nil {
+		fatal("Failed to initialize AI processor", err)
+	}
+	recommendationInference, err := ai.NewInferenceEngine[models.FlightRecommendation, models.FlightRecommendationRequest](cfg.AIProvider.APIKey, payloadLogging)
+	if err != nil {
+		fatal("Failed to initialize AI processor", err)
+	}
+	hotelInference, err := ai.NewInferenceEngine[models.HotelRecommendation, models.HotelRecommendationRequest](cfg.AIProvider.APIKey, payloadLogging)
+	if err != nil {
+		fatal("Failed to initialize AI processor", err)
+	}
+	suggestionInference, err := ai.NewInferenceEngine[models.SuggestionPlan, models.SuggestionRequest](cfg.AIProvider.APIKey, payloadLogging)
+	if err != nil {
+		fatal("Failed to initialize AI processor", err)
+	}
+	planInference, err := ai.NewInferenceEngine[models.TripPlanDraft, models.TripPlanRequest](cfg.AIProvider.APIKey, payloadLogging)
+	if err != nil {
+		fatal("Failed to initialize AI processor", err)
+	}
+	classifierInference, err := ai.NewInferenceEngine[models.InjectionAssessment, models.BookingRequest](cfg.AIProvider.APIKey, payloadLogging)
+	if err != nil {
+		fatal("Failed to initialize AI processor", err)
+	}
+	intentInference, err := ai.NewInferenceEngine[models.IntentClassification, models.BookingRequest](cfg.AIProvider.APIKey, payloadLogging)
+	if err != nil {
+		fatal("Failed to initialize AI processor", err)
+	}
+
+	// Load few-shot examples for parameter extraction
+	examples, err := loadExampleLibrary(cfg.Extraction)
+	if err != nil {
+		fatal("Failed to load extraction examples", err)
+	}
+
+	// Load exchange rates for converting prices into the traveler's currency
+	rates, err := loadRates(cfg.Currency)
 	if err != nil {
-		log.Fatalf("Failed to initialize AI processor: %v", err)
+		fatal("Failed to load exchange rates", err)
 	}
-	recommendationInference, err := ai.NewInferenceEngine[models.FlightRecommendation, models.FlightRecommendationRequest](cfg.AIProvider.APIKey)
+
+	// Load airport transfer routes for getting from the arrival airport into town
+	transport, err := loadTransportFixture(cfg.GroundTransport)
 	if err != nil {
-		log.Fatalf("Failed to initialize AI processor: %v", err)
+		fatal("Failed to load ground transport routes", err)
 	}
 
 	// Initialize services
-	bookingService := service.NewBookingService(extractionInference, recommendationInference)
+	bookingStore := store.NewMemoryBookingStore()
+	options := []service.BookingOption{
+		service.WithExampleLibrary(examples, cfg.Extraction.MaxExamples, cfg.Extraction.ExampleTokenBudget),
+		service.WithRecommendationDefaults(service.RecommendationDefaults{
+			TravelClass: cfg.Recommendation.DefaultTravelClass,
+			MaxBudget:   cfg.Recommendation.DefaultMaxBudget,
+		}),
+		service.WithRateProvider(rates),
+		service.WithDefaultCurrency(cfg.Currency.Default),
+		service.WithRankingWeights(service.RankingWeights{
+			Price:         cfg.Ranking.PriceWeight,
+			Stops:         cfg.Ranking.StopsWeight,
+			Duration:      cfg.Ranking.DurationWeight,
+			DepartureTime: cfg.Ranking.DepartureTimeWeight,
+		}),
+		service.WithRankingNarrative(cfg.Ranking.Narrative),
+		service.WithHotelRecommender(hotelInference),
+		service.WithGroundTransport(transport),
+		service.WithBookingStore(bookingStore),
+		service.WithScreening(service.ScreeningPolicy{
+			Action:        cfg.Screening.Action,
+			MinConfidence: cfg.Screening.MinConfidence,
+		}, store.NewMemoryAuditStore()),
+		service.WithIntentClassifier(intentInference),
+	}
+	if cfg.Screening.Classifier {
+		options = append(options, service.WithInjectionClassifier(classifierInference))
+	}
+	if cfg.FlightSearch.BaseURL != "" {
+		// Real offers come from the search API instead of the model
+		timeout := time.Duration(cfg.FlightSearch.TimeoutSeconds) * time.Second
+		options = append(options, service.WithFlightSearch(
+			flights.NewClient(cfg.FlightSearch.BaseURL, cfg.FlightSearch.APIKey, timeout),
+		))
+	}
+	bookingService := service.NewBookingService(extractionInference, recommendationInference, options...)
 	bookingHandler := handlers.NewBookingHandler(bookingService)
+	conversationService := service.NewConversationService(bookingService, store.NewMemoryConversationStore())
+	conversationHandler := handlers.NewConversationHandler(conversationService)
+	suggestionService := service.NewSuggestionService(bookingStore, store.NewMemorySuggestionStore(), suggestionInference)
+	suggestionHandler := handlers.NewSuggestionHandler(suggestionService)
+	planService := service.NewPlanService(bookingStore, planInference)
+	planHandler := handlers.NewPlanHandler(planService)
 
-	// Create Gin router
-	router := gin.Default()
+	// Create Gin router; requests are logged by handlers.RequestLogging instead of gin
+	router := gin.New()
+	router.Use(gin.Recovery())
 
 	// Setup routes
 	router.POST("/api/v1/bookings", func(c *gin.Context) {
@@ -42,10 +146,82 @@ func main() {
 	router.GET("/api/v1/bookings/status", func(c *gin.Context) {
 		bookingHandler.GetBooking(c.Writer, c.Request)
 	})
+	router.GET("/api/v1/bookings/:id/suggestions", func(c *gin.Context) {
+		c.Request.SetPathValue("id", c.Param("id"))
+		suggestionHandler.GetSuggestions(c.Writer, c.Request)
+	})
+	router.POST("/api/v1/bookings/:id/plan", func(c *gin.Context) {
+		c.Request.SetPathValue("id", c.Param("id"))
+		planHandler.CreatePlan(c.Writer, c.Request)
+	})
+	router.GET("/api/v1/bookings/:id/plan", func(c *gin.Context) {
+		c.Request.SetPathValue("id", c.Param("id"))
+		planHandler.GetPlan(c.Writer, c.Request)
+	})
+	router.PUT("/api/v1/bookings/:id/plan/days/:day", func(c *gin.Context) {
+		c.Request.SetPathValue("id", c.Param("id"))
+		c.Request.SetPathValue("day", c.Param("day"))
+		planHandler.UpdatePlanDay(c.Writer, c.Request)
+	})
+	router.POST("/api/v1/conversations", func(c *gin.Context) {
+		conversationHandler.CreateConversation(c.Writer, c.Request)
+	})
+	router.GET("/api/v1/conversations/:id", func(c *gin.Context) {
+		c.Request.SetPathValue("id", c.Param("id"))
+		conversationHandler.GetConversation(c.Writer, c.Request)
+	})
+	router.POST("/api/v1/conversations/:id/messages", func(c *gin.Context) {
+		c.Request.SetPathValue("id", c.Param("id"))
+		conversationHandler.AddMessage(c.Writer, c.Request)
+	})
+	// Counters such as booking_intents are only served on the admin listener, which
+	// listens on localhost unless configured otherwise
+	admin := http.NewServeMux()
+	admin.Handle("GET /debug/vars", expvar.Handler())
+	go func() {
+		slog.Info("Admin server starting", "addr", cfg.Admin.Addr)
+		if err := http.ListenAndServe(cfg.Admin.Addr, admin); err != nil {
+			fatal("Admin server failed to start", err)
+		}
+	}()
 
 	// Start server
-	log.Printf("Server starting on port %s", cfg.ServerPort)
-	if err := router.Run(cfg.ServerPort); err != nil {
-		log.Fatalf("Server failed to start: %v", err)
+	slog.Info("Server starting", "port", cfg.ServerPort)
+	if err := http.ListenAndServe(cfg.ServerPort, handlers.RequestLogging(router)); err != nil {
+		fatal("Server failed to start", err)
+	}
+}
+
+// fatal logs an error that keeps the server from running and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// loadExampleLibrary reads the configured examples file, falling back to the bundled library
+func loadExampleLibrary(cfg config.ExtractionConfig) (*ai.ExampleLibrary, error) {
+	if cfg.ExamplesFile != "" {
+		return ai.LoadExampleLibrary(cfg.ExamplesFile)
+	}
+	return ai.DefaultExampleLibrary()
+}
+
+// loadTransportFixture reads the configured routes file, falling back to the bundled fixture
+func loadTransportFixture(cfg config.GroundTransportConfig) (*transport.Fixture, error) {
+	db, err := airports.Default()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.FixtureFile != "" {
+		return transport.LoadFixture(cfg.FixtureFile, db)
+	}
+	return transport.DefaultFixture(db)
+}
+
+// loadRates reads the configured rates file, falling back to the bundled table
+func loadRates(cfg config.CurrencyConfig) (*currency.Table, error) {
+	if cfg.RatesFile != "" {
+		return currency.LoadTable(cfg.RatesFile)
 	}
+	return currency.DefaultTable()
 }
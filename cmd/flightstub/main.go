@@ -0,0 +1,37 @@
+// Command flightstub serves a fixture flight inventory through the same REST API the
+// booking service searches, so the full pipeline runs locally without an airline API.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"travel-agent/internal/service/airports"
+	"travel-agent/internal/service/flights"
+)
+
+func main() {
+	addr := flag.String("addr", ":8090", "address to listen on")
+	inventoryFile := flag.String("inventory", "", "schedules JSON; the bundled inventory is used when empty")
+	flag.Parse()
+
+	db, err := airports.Default()
+	if err != nil {
+		log.Fatalf("Failed to load airports: %v", err)
+	}
+
+	var inventory *flights.Inventory
+	if *inventoryFile != "" {
+		inventory, err = flights.LoadInventory(*inventoryFile, db)
+	} else {
+		inventory, err = flights.DefaultInventory(db)
+	}
+	if err != nil {
+		log.Fatalf("Failed to load inventory: %v", err)
+	}
+
+	log.Printf("Flight stub listening on %s", *addr)
+	if err := http.ListenAndServe(*addr, flights.NewStubServer(inventory)); err != nil {
+		log.Fatalf("Server failed to start: %v", err)
+	}
+}
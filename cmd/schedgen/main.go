@@ -0,0 +1,73 @@
+// Command schedgen writes synthetic flight schedules in the flight stub's inventory
+// format, e.g. go run cmd/schedgen/main.go -seed 7 -days 90 -out inventory.json
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"time"
+	"travel-agent/internal/service/airports"
+	"travel-agent/internal/service/schedgen"
+)
+
+func main() {
+	seed := flag.Uint64("seed", 1, "random seed; the same seed gives the same schedules")
+	from := flag.String("from", "", "first operating date, YYYY-MM-DD; today when empty")
+	days := flag.Int("days", 90, "number of days the schedules operate")
+	routes := flag.Int("routes", 4, "round-trip routes out of each hub")
+	connections := flag.Int("connections", 2, "one-stop routes through each hub")
+	airportsFile := flag.String("airports", "", "airports JSON; the bundled database is used when empty")
+	out := flag.String("out", "", "output file; standard output when empty")
+	flag.Parse()
+
+	db, err := loadAirports(*airportsFile)
+	if err != nil {
+		log.Fatalf("Failed to load airports: %v", err)
+	}
+
+	start := time.Now().UTC().Truncate(24 * time.Hour)
+	if *from != "" {
+		if start, err = time.Parse("2006-01-02", *from); err != nil {
+			log.Fatalf("Invalid -from date: %v", err)
+		}
+	}
+	if *days < 1 {
+		log.Fatalf("-days must be at least 1")
+	}
+
+	schedules, err := schedgen.Generate(db, schedgen.Options{
+		Seed:        *seed,
+		From:        start,
+		To:          start.AddDate(0, 0, *days-1),
+		Routes:      *routes,
+		Connections: *connections,
+	})
+	if err != nil {
+		log.Fatalf("Failed to generate schedules: %v", err)
+	}
+
+	data, err := json.MarshalIndent(schedules, "", "  ")
+	if err != nil {
+		log.Fatalf("Failed to encode schedules: %v", err)
+	}
+	data = append(data, '\n')
+
+	if *out == "" {
+		os.Stdout.Write(data)
+		return
+	}
+	if err := os.WriteFile(*out, data, 0o644); err != nil {
+		log.Fatalf("Failed to write schedules: %v", err)
+	}
+	log.Printf("Wrote %d schedules to %s", len(schedules), *out)
+}
+
+// loadAirports reads the given airports file, falling back to the bundled database
+func loadAirports(filename string) (*airports.Database, error) {
+	if filename != "" {
+		return airports.Load(filename)
+	}
+	return airports.Default()
+}
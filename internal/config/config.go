@@ -7,15 +7,93 @@ import (
 )
 
 type Config struct {
-	ServerPort string
-	LogLevel   string
-	AIProvider AIProviderConfig
+	ServerPort      string
+	LogLevel        string
+	AIProvider      AIProviderConfig
+	Extraction      ExtractionConfig
+	Recommendation  RecommendationConfig
+	Currency        CurrencyConfig
+	FlightSearch    FlightSearchConfig
+	Ranking         RankingConfig
+	GroundTransport GroundTransportConfig
+	Screening       ScreeningConfig
+	Logging         LoggingConfig
+	Admin           AdminConfig
 }
 
 type AIProviderConfig struct {
 	APIKey string `json:"api_key" required:"true"`
 }
 
+// ExtractionConfig controls the few-shot examples sent with extraction prompts
+type ExtractionConfig struct {
+	ExamplesFile       string `json:"examples_file"`
+	MaxExamples        int    `json:"max_examples"`
+	ExampleTokenBudget int    `json:"example_token_budget"`
+}
+
+// RecommendationConfig holds the preferences used when neither the traveler
+// nor their profile gives one
+type RecommendationConfig struct {
+	DefaultTravelClass string  `json:"default_travel_class"`
+	DefaultMaxBudget   float64 `json:"default_max_budget"`
+}
+
+// CurrencyConfig controls the exchange rates used to convert prices into the
+// traveler's currency
+type CurrencyConfig struct {
+	Default   string `json:"default"`    // ISO 4217 code used when the query and profile name none
+	RatesFile string `json:"rates_file"` // Rates table JSON; the bundled table is used when empty
+}
+
+// FlightSearchConfig points at a flight offer search API. Without a base URL the
+// model recommends flights on its own.
+type FlightSearchConfig struct {
+	BaseURL        string `json:"base_url"`        // e.g. http://localhost:8090 for cmd/flightstub
+	APIKey         string `json:"api_key"`         // Sent as a bearer token when set
+	TimeoutSeconds int    `json:"timeout_seconds"` // Per search request
+}
+
+// RankingConfig controls how flights are scored. Weights left at zero keep the
+// built-in ones; only their ratios matter.
+type RankingConfig struct {
+	PriceWeight         float64 `json:"price_weight"`
+	StopsWeight         float64 `json:"stops_weight"`
+	DurationWeight      float64 `json:"duration_weight"`
+	DepartureTimeWeight float64 `json:"departure_time_weight"`
+	Narrative           bool    `json:"narrative"` // Have the model explain the ranking of searched flights
+}
+
+// GroundTransportConfig controls the airport transfer options added to bookings
+type GroundTransportConfig struct {
+	FixtureFile string `json:"fixture_file"` // Routes JSON; the bundled fixture is used when empty
+}
+
+// ScreeningConfig controls the prompt-injection checks run on travelers' queries
+type ScreeningConfig struct {
+	Action        string  `json:"action"`         // reject or quarantine flagged queries
+	Classifier    bool    `json:"classifier"`     // Also have the model check queries the heuristics pass
+	MinConfidence float64 `json:"min_confidence"` // Classifier confidence needed to flag a query
+}
+
+// LoggingConfig controls how logs are written; LogLevel sets which are. Provider
+// payloads are only logged at the debug level, and always redacted.
+type LoggingConfig struct {
+	Format string `json:"format"` // text or json
+	// PayloadSampleRate is the share of provider calls whose payloads are logged, 0 to 1.
+	// It is a pointer so that an explicit 0, turning payload logging off, differs from unset.
+	PayloadSampleRate *float64 `json:"payload_sample_rate"`
+}
+
+// defaultPayloadSampleRate logs the payloads of every call when debug logging is on
+const defaultPayloadSampleRate = 1.0
+
+// AdminConfig controls the listener serving operational endpoints such as
+// /debug/vars, kept apart from the public API
+type AdminConfig struct {
+	Addr string `json:"addr"` // e.g. localhost:8081; keep it off public interfaces
+}
+
 func Load(filename string) (*Config, error) {
 	// Check if API key is set in environment
 	apiKey := os.Getenv("AI_PROVIDER_API_KEY")
@@ -31,6 +109,31 @@ func Load(filename string) (*Config, error) {
 				AIProvider: AIProviderConfig{
 					APIKey: apiKey,
 				},
+				Extraction: ExtractionConfig{
+					MaxExamples:        3,
+					ExampleTokenBudget: 1200,
+				},
+				Recommendation: RecommendationConfig{
+					DefaultTravelClass: "economy",
+					DefaultMaxBudget:   2000,
+				},
+				Currency: CurrencyConfig{
+					Default: "USD",
+				},
+				FlightSearch: FlightSearchConfig{
+					TimeoutSeconds: 30,
+				},
+				Screening: ScreeningConfig{
+					Action:        "reject",
+					MinConfidence: 0.8,
+				},
+				Logging: LoggingConfig{
+					Format:            "text",
+					PayloadSampleRate: ptr(defaultPayloadSampleRate),
+				},
+				Admin: AdminConfig{
+					Addr: "localhost:8081",
+				},
 			}
 			return cfg, nil
 		}
@@ -53,17 +156,97 @@ func Load(filename string) (*Config, error) {
 	if cfg.AIProvider.APIKey == "" {
 		cfg.AIProvider.APIKey = apiKey
 	}
+	if cfg.Extraction.MaxExamples == 0 {
+		cfg.Extraction.MaxExamples = 3
+	}
+	if cfg.Extraction.ExampleTokenBudget == 0 {
+		cfg.Extraction.ExampleTokenBudget = 1200
+	}
+	if cfg.Recommendation.DefaultTravelClass == "" {
+		cfg.Recommendation.DefaultTravelClass = "economy"
+	}
+	if cfg.Recommendation.DefaultMaxBudget == 0 {
+		cfg.Recommendation.DefaultMaxBudget = 2000
+	}
+	if cfg.Currency.Default == "" {
+		cfg.Currency.Default = "USD"
+	}
+	if cfg.FlightSearch.TimeoutSeconds == 0 {
+		cfg.FlightSearch.TimeoutSeconds = 30
+	}
+	if cfg.Screening.Action == "" {
+		cfg.Screening.Action = "reject"
+	}
+	if cfg.Screening.MinConfidence == 0 {
+		cfg.Screening.MinConfidence = 0.8
+	}
+	if cfg.Logging.Format == "" {
+		cfg.Logging.Format = "text"
+	}
+	if cfg.Logging.PayloadSampleRate == nil {
+		cfg.Logging.PayloadSampleRate = ptr(defaultPayloadSampleRate)
+	}
+	if rate := *cfg.Logging.PayloadSampleRate; rate < 0 || rate > 1 {
+		return nil, fmt.Errorf("logging payload_sample_rate must be between 0 and 1, got %g", rate)
+	}
+	if cfg.Admin.Addr == "" {
+		cfg.Admin.Addr = "localhost:8081"
+	}
 
 	return &cfg, nil
 }
 
+func ptr[T any](v T) *T {
+	return &v
+}
+
 // Example usage of config.json:
 /*
 {
     "ServerPort": ":8080",           // The port the server will listen on
-    "LogLevel": "info",              // Logging level (debug, info, warn, error)
+    "LogLevel": "info",              // Logging level (debug, info, warn, error); debug also logs provider payloads
     "AIProvider": {
         "api_key": ""                // AI Provider API key
+    },
+    "Extraction": {
+        "examples_file": "",         // Few-shot examples JSON; the bundled library is used when empty
+        "max_examples": 3,           // Maximum number of examples per extraction request
+        "example_token_budget": 1200 // Estimated token cap for all examples in a request
+    },
+    "Recommendation": {
+        "default_travel_class": "economy", // Cabin requested when the traveler and profile don't say
+        "default_max_budget": 2000         // Per-passenger budget sent when none is given; not enforced
+    },
+    "Currency": {
+        "default": "USD",            // Currency used when the query and profile name none; the default budget is in it
+        "rates_file": ""             // Exchange rates JSON; the bundled table is used when empty
+    },
+    "FlightSearch": {
+        "base_url": "",              // Flight offer API; the model invents flights when empty
+        "api_key": "",               // Bearer token for the API, if it needs one
+        "timeout_seconds": 30        // Timeout for each search request
+    },
+    "Ranking": {
+        "price_weight": 0.4,         // How much each factor counts in a flight's score
+        "stops_weight": 0.25,
+        "duration_weight": 0.2,
+        "departure_time_weight": 0.15,
+        "narrative": false           // Ask the model to explain the ranking; a plain summary is used otherwise
+    },
+    "GroundTransport": {
+        "fixture_file": ""           // Airport transfer routes JSON; the bundled fixture is used when empty
+    },
+    "Screening": {
+        "action": "reject",          // What to do with queries flagged as prompt injection: reject or quarantine
+        "classifier": false,         // Also ask the model about queries the heuristics pass; one extra call per query
+        "min_confidence": 0.8        // Classifier confidence needed to flag a query
+    },
+    "Logging": {
+        "format": "text",            // Log output: text, or json for log collectors
+        "payload_sample_rate": 1     // Share of provider calls whose redacted payloads are logged at the debug level; 0 turns them off
+    },
+    "Admin": {
+        "addr": "localhost:8081"     // Listener for /debug/vars, apart from the public API; keep it on a private interface
     }
 }
 
@@ -76,4 +259,16 @@ Default values:
 - ServerPort: ":8080"
 - LogLevel: "info"
 - AIProvider.api_key: Must be provided either in config.json or via environment variable
+- Extraction.max_examples: 3
+- Extraction.example_token_budget: 1200
+- Recommendation.default_travel_class: "economy"
+- Recommendation.default_max_budget: 2000
+- Currency.default: "USD"
+- FlightSearch.timeout_seconds: 30
+- Ranking weights: price 0.4, stops 0.25, duration 0.2, departure time 0.15
+- Screening.action: "reject"
+- Screening.min_confidence: 0.8
+- Logging.format: "text"
+- Logging.payload_sample_rate: 1
+- Admin.addr: "localhost:8081"
 */
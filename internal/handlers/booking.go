@@ -3,10 +3,14 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"time"
 	"travel-agent/internal/models"
+	"travel-agent/internal/service"
+	"travel-agent/internal/service/currency"
 )
 
 type BookingServiceInterface interface {
@@ -40,14 +44,31 @@ func (h *BookingHandler) CreateBooking(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Process the booking request
-	response, err := h.bookingService.ProcessBooking(context.Background(), req)
+	response, err := h.bookingService.ProcessBooking(r.Context(), req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		var rejected *service.RejectedFlightsError
+		switch {
+		case errors.As(err, &rejected):
+			respondWithJSON(w, http.StatusUnprocessableEntity, map[string]any{
+				"error":            err.Error(),
+				"rejected_flights": rejected.Rejected,
+			})
+		case errors.Is(err, service.ErrRequestRejected), errors.Is(err, service.ErrIncompleteStay):
+			respondWithError(w, http.StatusUnprocessableEntity, err.Error())
+		case errors.Is(err, service.ErrBookingNotFound):
+			respondWithError(w, http.StatusNotFound, err.Error())
+		default:
+			slog.ErrorContext(r.Context(), "request failed", "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
 		return
 	}
 
 	// Return response
 	w.Header().Set("Content-Type", "application/json")
+	if response.Status == models.StatusQuarantined {
+		w.WriteHeader(http.StatusAccepted)
+	}
 	// If writing fails for any reason(network issues, closed connection), respond with an error
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
@@ -91,10 +112,26 @@ func respondWithError(w http.ResponseWriter, code int, message string) {
 	}); err != nil {
 		// If encoding fails, log the error but don't attempt to send another response
 		// since headers have already been written
-		fmt.Printf("Failed to encode error response: %v\n", err)
+		slog.Error("failed to encode error response", "error", err)
+	}
+}
+
+func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
+		// Headers have already been written, so the error can only be logged
+		slog.Error("failed to encode response", "error", err)
 	}
 }
 
+// respondWithInternalError logs an unexpected error with the request's context before
+// reporting it
+func respondWithInternalError(w http.ResponseWriter, r *http.Request, err error) {
+	slog.ErrorContext(r.Context(), "request failed", "error", err)
+	respondWithError(w, http.StatusInternalServerError, err.Error())
+}
+
 func validateBookingRequest(req models.BookingRequest) error {
 	if req.Query == "" {
 		return fmt.Errorf("query cannot be empty")
@@ -106,6 +143,22 @@ func validateBookingRequest(req models.BookingRequest) error {
 	if req.Deadline.Before(time.Now()) {
 		return fmt.Errorf("deadline cannot be in the past")
 	}
+	if req.TimeZone != "" {
+		if _, err := time.LoadLocation(req.TimeZone); err != nil {
+			return fmt.Errorf("invalid time zone: %s", req.TimeZone)
+		}
+	}
+	if req.Profile != nil {
+		if req.Profile.MaxBudget != nil && req.Profile.MaxBudget.IsNegative() {
+			return fmt.Errorf("profile budget cannot be negative")
+		}
+		if req.Profile.MaxLayovers != nil && *req.Profile.MaxLayovers < 0 {
+			return fmt.Errorf("profile layover limit cannot be negative")
+		}
+		if req.Profile.Currency != "" && currency.Normalize(req.Profile.Currency) == "" {
+			return fmt.Errorf("invalid profile currency: %s", req.Profile.Currency)
+		}
+	}
 
 	return nil
 }
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service"
+)
+
+type ConversationServiceInterface interface {
+	StartConversation(ctx context.Context, req models.BookingRequest) (*models.Conversation, error)
+	AddMessage(ctx context.Context, id string, content string) (*models.Conversation, error)
+	GetConversation(ctx context.Context, id string) (*models.Conversation, error)
+}
+
+type ConversationHandler struct {
+	conversationService ConversationServiceInterface
+}
+
+func NewConversationHandler(conversationService ConversationServiceInterface) *ConversationHandler {
+	return &ConversationHandler{conversationService: conversationService}
+}
+
+// CreateConversation starts a conversation from an initial booking query
+func (h *ConversationHandler) CreateConversation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.BookingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := validateBookingRequest(req); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	conversation, err := h.conversationService.StartConversation(r.Context(), req)
+	if err != nil && !conversationFailed(r, conversation, err) {
+		respondWithConversationError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, conversation)
+}
+
+// AddMessage answers the agent's follow-up questions. The conversation ID is read
+// from the "id" path value.
+func (h *ConversationHandler) AddMessage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	conversationID := r.PathValue("id")
+	if conversationID == "" {
+		respondWithError(w, http.StatusBadRequest, "Conversation ID is required")
+		return
+	}
+
+	var req models.ConversationMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Content == "" {
+		respondWithError(w, http.StatusBadRequest, "content cannot be empty")
+		return
+	}
+
+	conversation, err := h.conversationService.AddMessage(r.Context(), conversationID, req.Content)
+	if err != nil && !conversationFailed(r, conversation, err) {
+		respondWithConversationError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, conversation)
+}
+
+// GetConversation returns the conversation identified by the "id" path value
+func (h *ConversationHandler) GetConversation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	conversationID := r.PathValue("id")
+	if conversationID == "" {
+		respondWithError(w, http.StatusBadRequest, "Conversation ID is required")
+		return
+	}
+
+	conversation, err := h.conversationService.GetConversation(r.Context(), conversationID)
+	if err != nil {
+		respondWithConversationError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, conversation)
+}
+
+// conversationFailed reports whether err is a failed booking recorded in the returned
+// conversation, which is then sent as is so the traveler can read the outcome
+func conversationFailed(r *http.Request, conversation *models.Conversation, err error) bool {
+	if conversation == nil || !errors.Is(err, service.ErrConversationFailed) {
+		return false
+	}
+	slog.WarnContext(r.Context(), "conversation failed", "conversation_id", conversation.ID, "error", err)
+	return true
+}
+
+func respondWithConversationError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, service.ErrConversationNotFound):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrConversationClosed):
+		respondWithError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrRequestRejected):
+		respondWithError(w, http.StatusUnprocessableEntity, err.Error())
+	default:
+		respondWithInternalError(w, r, err)
+	}
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service"
+)
+
+type PlanServiceInterface interface {
+	CreatePlan(ctx context.Context, bookingID string) (*models.TripPlan, error)
+	GetPlan(ctx context.Context, bookingID string) (*models.TripPlan, error)
+	UpdatePlanDay(ctx context.Context, bookingID string, day int, update models.PlanDayUpdate) (*models.TripPlan, error)
+}
+
+type PlanHandler struct {
+	planService PlanServiceInterface
+}
+
+func NewPlanHandler(planService PlanServiceInterface) *PlanHandler {
+	return &PlanHandler{planService: planService}
+}
+
+// CreatePlan plans the stay of the booking identified by the "id" path value,
+// replacing any earlier plan
+func (h *PlanHandler) CreatePlan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	bookingID := r.PathValue("id")
+	if bookingID == "" {
+		respondWithError(w, http.StatusBadRequest, "Booking ID is required")
+		return
+	}
+
+	plan, err := h.planService.CreatePlan(r.Context(), bookingID)
+	if err != nil {
+		respondWithPlanError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, plan)
+}
+
+// GetPlan returns the stored plan of the booking identified by the "id" path value
+func (h *PlanHandler) GetPlan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	bookingID := r.PathValue("id")
+	if bookingID == "" {
+		respondWithError(w, http.StatusBadRequest, "Booking ID is required")
+		return
+	}
+
+	plan, err := h.planService.GetPlan(r.Context(), bookingID)
+	if err != nil {
+		respondWithPlanError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, plan)
+}
+
+// UpdatePlanDay replaces the blocks of the day given by the "day" path value in the
+// plan of the booking identified by the "id" path value
+func (h *PlanHandler) UpdatePlanDay(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	bookingID := r.PathValue("id")
+	if bookingID == "" {
+		respondWithError(w, http.StatusBadRequest, "Booking ID is required")
+		return
+	}
+	day, err := strconv.Atoi(r.PathValue("day"))
+	if err != nil || day < 1 {
+		respondWithError(w, http.StatusBadRequest, "day must be a positive number")
+		return
+	}
+
+	var update models.PlanDayUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	plan, err := h.planService.UpdatePlanDay(r.Context(), bookingID, day, update)
+	if err != nil {
+		respondWithPlanError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, plan)
+}
+
+func respondWithPlanError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, service.ErrBookingNotFound),
+		errors.Is(err, service.ErrPlanNotFound),
+		errors.Is(err, service.ErrPlanDayNotFound):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrInvalidPlan):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrNoStay):
+		respondWithError(w, http.StatusUnprocessableEntity, err.Error())
+	default:
+		respondWithInternalError(w, r, err)
+	}
+}
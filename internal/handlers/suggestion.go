@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service"
+)
+
+type SuggestionServiceInterface interface {
+	Suggest(ctx context.Context, bookingID string) (*models.Suggestions, error)
+}
+
+type SuggestionHandler struct {
+	suggestionService SuggestionServiceInterface
+}
+
+func NewSuggestionHandler(suggestionService SuggestionServiceInterface) *SuggestionHandler {
+	return &SuggestionHandler{suggestionService: suggestionService}
+}
+
+// GetSuggestions returns day-by-day activity and dining suggestions for the booking
+// identified by the "id" path value
+func (h *SuggestionHandler) GetSuggestions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	bookingID := r.PathValue("id")
+	if bookingID == "" {
+		respondWithError(w, http.StatusBadRequest, "Booking ID is required")
+		return
+	}
+
+	suggestions, err := h.suggestionService.Suggest(r.Context(), bookingID)
+	if err != nil {
+		respondWithSuggestionError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, suggestions)
+}
+
+func respondWithSuggestionError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, service.ErrBookingNotFound):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrNoStay):
+		respondWithError(w, http.StatusUnprocessableEntity, err.Error())
+	default:
+		respondWithInternalError(w, r, err)
+	}
+}
@@ -0,0 +1,140 @@
+// Package logging sets up structured logging and carries the attributes that correlate
+// log lines, such as the request and booking IDs, in the context
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Attributes added to every line logged with a context carrying them
+const (
+	KeyRequestID      = "request_id"
+	KeyBookingID      = "booking_id"
+	KeyConversationID = "conversation_id"
+	KeyStage          = "stage"
+)
+
+// Output formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// New builds a logger writing lines at level and above to w as text or JSON. Lines
+// logged with a context get the attributes it carries.
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	options := &slog.HandlerOptions{Level: lvl}
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "", FormatText:
+		handler = slog.NewTextHandler(w, options)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, options)
+	default:
+		return nil, fmt.Errorf("unknown log format %q, want text or json", format)
+	}
+	return slog.New(contextHandler{handler}), nil
+}
+
+// ParseLevel reads a level name: debug, info, warn or error
+func ParseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q, want debug, info, warn or error", level)
+}
+
+type attrsKey struct{}
+
+// With returns a context whose log lines carry the given key-value pairs. A key set
+// again replaces the earlier value, so stages nest without piling up.
+func With(ctx context.Context, args ...any) context.Context {
+	attrs := slices.Clone(attrsFrom(ctx))
+	// A record parses the key-value pairs the way the logger would
+	record := slog.NewRecord(time.Time{}, 0, "", 0)
+	record.Add(args...)
+	record.Attrs(func(attr slog.Attr) bool {
+		i := slices.IndexFunc(attrs, func(a slog.Attr) bool { return a.Key == attr.Key })
+		if i >= 0 {
+			attrs[i] = attr
+		} else {
+			attrs = append(attrs, attr)
+		}
+		return true
+	})
+	return context.WithValue(ctx, attrsKey{}, attrs)
+}
+
+// WithRequestID tags the context's log lines with the HTTP request they belong to
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return With(ctx, KeyRequestID, id)
+}
+
+// WithBookingID tags the context's log lines with the booking being processed
+func WithBookingID(ctx context.Context, id string) context.Context {
+	return With(ctx, KeyBookingID, id)
+}
+
+// WithConversationID tags the context's log lines with the conversation being held
+func WithConversationID(ctx context.Context, id string) context.Context {
+	return With(ctx, KeyConversationID, id)
+}
+
+// WithStage tags the context's log lines with the step of the booking flow
+func WithStage(ctx context.Context, stage string) context.Context {
+	return With(ctx, KeyStage, stage)
+}
+
+// RequestID gives the ID of the request the context belongs to, if any
+func RequestID(ctx context.Context) string {
+	for _, attr := range attrsFrom(ctx) {
+		if attr.Key == KeyRequestID {
+			return attr.Value.String()
+		}
+	}
+	return ""
+}
+
+func attrsFrom(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	return attrs
+}
+
+// contextHandler adds the attributes carried by the context to each record
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if attrs := attrsFrom(ctx); len(attrs) > 0 {
+		record = record.Clone()
+		record.AddAttrs(attrs...)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
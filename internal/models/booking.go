@@ -7,9 +7,21 @@ import (
 type BookingStatus string
 
 const (
-	StatusProcessing BookingStatus = "processing"
-	StatusConfirmed  BookingStatus = "confirmed"
-	StatusFailed     BookingStatus = "failed"
+	StatusProcessing  BookingStatus = "processing"
+	StatusConfirmed   BookingStatus = "confirmed"
+	StatusFailed      BookingStatus = "failed"
+	StatusQuarantined BookingStatus = "quarantined" // Held for review as a suspected prompt injection
+	StatusDeclined    BookingStatus = "declined"    // The query asked for something the agent doesn't do
+)
+
+// TripType describes the shape of the trip
+type TripType string
+
+const (
+	TripOneWay    TripType = "one_way"
+	TripRoundTrip TripType = "round_trip"
+	TripOpenJaw   TripType = "open_jaw" // Returns from or to a different city than the outbound
+	TripMultiCity TripType = "multi_city"
 )
 
 // Input structure for the extraction
@@ -17,69 +29,314 @@ type BookingRequest struct {
 	Query    string    `json:"query"`    // Natural language query for the booking
 	Deadline time.Time `json:"deadline"` // When to stop looking for deals
 	// Deadline string `json:"deadline"`
+	TimeZone      string           `json:"time_zone,omitempty"`      // Traveler's IANA time zone, UTC if empty
+	ReferenceTime *time.Time       `json:"reference_time,omitempty"` // "Now" for relative dates, the server time if empty
+	Profile       *TravelerProfile `json:"profile,omitempty"`        // Caller's standing preferences
+}
+
+// TravelerProfile holds the caller's standing preferences. They apply whenever
+// the query doesn't say otherwise and are enforced like the traveler's own.
+type TravelerProfile struct {
+	TravelClass         string   `json:"travel_class,omitempty"`
+	MaxBudget           *Money   `json:"max_budget,omitempty"`   // Per-passenger fare limit, in Currency when it has none
+	Currency            string   `json:"currency,omitempty"`     // ISO 4217 code prices are shown in
+	MaxLayovers         *int     `json:"max_layovers,omitempty"` // Most connections the traveler accepts
+	DietaryRestrictions []string `json:"dietary_restrictions,omitempty"`
 }
 
 type BookingResponse struct {
-	ID            string        `json:"id"`               // Unique booking request ID
-	Status        BookingStatus `json:"status"`           // Status of the booking (pending, completed, failed)
-	Query         string        `json:"query"`            // Original query
-	Deadline      time.Time     `json:"deadline"`         // Original deadline
-	FlightDetails *Flight       `json:"flight,omitempty"` // Flight details if found
-	Message       string        `json:"message"`          // Additional information or error message
-	CreatedAt     time.Time     `json:"created_at"`
-	UpdatedAt     time.Time     `json:"updated_at"`
+	ID            string              `json:"id"`               // Unique booking request ID
+	Status        BookingStatus       `json:"status"`           // Status of the booking (pending, completed, failed)
+	Intent        Intent              `json:"intent,omitempty"` // What the query asked for
+	Query         string              `json:"query"`            // Original query
+	Deadline      time.Time           `json:"deadline"`         // Original deadline
+	TripType      TripType            `json:"trip_type,omitempty"`
+	Passengers    *Passengers         `json:"passengers,omitempty"`
+	Currency      string              `json:"currency,omitempty"`       // Traveler's currency, budgets are compared in it
+	TimeZone      string              `json:"time_zone,omitempty"`      // Traveler's time zone used to resolve dates
+	ResolvedDates []DateResolution    `json:"resolved_dates,omitempty"` // How each travel date was determined
+	FlightDetails *Flight             `json:"flight,omitempty"`         // Flight details if found
+	Itinerary     *MultiCityItinerary `json:"itinerary,omitempty"`      // Set for multi-city trips
+	// RoundTrip is the selected outbound and return pairing of round-trip and open-jaw
+	// trips; FlightDetails is its outbound flight
+	RoundTrip *RoundTripItinerary `json:"round_trip,omitempty"`
+	// RoundTripOptions are the other pairings, best first
+	RoundTripOptions []RoundTripItinerary `json:"round_trip_options,omitempty"`
+	Hotels           *HotelStay           `json:"hotels,omitempty"` // Hotel options for the stay at the destination
+	// GroundTransport gives the ways from the arrival airport to the hotel or city center
+	GroundTransport *GroundTransport `json:"ground_transport,omitempty"`
+	Reasoning       string           `json:"reasoning,omitempty"` // Why the flights were ranked as they were
+	// RejectedFlights lists the recommendations discarded by the sanity checks and why
+	RejectedFlights []FlightRejection `json:"rejected_flights,omitempty"`
+	Message         string            `json:"message"` // Additional information or error message
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+}
+
+// BookingRecord is a processed booking stored with the parameters it was made from
+type BookingRecord struct {
+	Booking    BookingResponse  `json:"booking"`
+	Parameters TravelParameters `json:"parameters"`
+	Plan       *TripPlan        `json:"plan,omitempty"` // Set once the stay has been planned
 }
 
 // Define the expected output structure
 type TravelParameters struct {
-	DepartureCity string      `json:"departure_city"`
-	Destination   string      `json:"destination"`
-	DepartureDate *time.Time  `json:"departure_date"`
-	ReturnDate    *time.Time  `json:"return_date"`
-	Preferences   Preferences `json:"preferences"`
+	TripType      TripType   `json:"trip_type"`
+	DepartureCity string     `json:"departure_city"`
+	Destination   string     `json:"destination"`
+	DepartureDate *time.Time `json:"departure_date"`
+	ReturnDate    *time.Time `json:"return_date"`
+	// ReturnOrigin and ReturnDestination are only set for open-jaw trips
+	ReturnOrigin      string `json:"return_origin,omitempty"`
+	ReturnDestination string `json:"return_destination,omitempty"`
+	// Legs lists the ordered hops of a multi-city trip
+	Legs        []TripLeg   `json:"legs,omitempty"`
+	Passengers  Passengers  `json:"passengers"`
+	Preferences Preferences `json:"preferences"`
+	// DepartureDateText and ReturnDateText are the date phrases as written by the traveler
+	DepartureDateText string `json:"departure_date_text,omitempty"`
+	ReturnDateText    string `json:"return_date_text,omitempty"`
+	// AmbiguousFields lists fields whose value the model had to guess
+	AmbiguousFields []string `json:"ambiguous_fields,omitempty"`
+	// ResolvedDates records how each date was determined; it is not part of the model output
+	ResolvedDates []DateResolution `json:"resolved_dates,omitempty"`
+}
+
+// Sources of a resolved date
+const (
+	DateSourceResolver = "resolver" // Resolved deterministically from the traveler's phrase
+	DateSourceModel    = "model"    // Taken from the model output
+)
+
+// DateResolution explains how a travel date was determined
+type DateResolution struct {
+	Field    string    `json:"field"`
+	Text     string    `json:"text,omitempty"`
+	Resolved time.Time `json:"resolved"`
+	Source   string    `json:"source"`
+}
+
+// Passengers describes who is traveling. Children are 2 to 11 years old and
+// infants under 2 travel on an adult's lap without a seat of their own.
+type Passengers struct {
+	Adults    int   `json:"adults"`
+	Children  int   `json:"children"`
+	ChildAges []int `json:"child_ages,omitempty"` // Ages of the children, when given
+	Infants   int   `json:"infants"`
+}
+
+// TripLeg is one hop of a multi-city trip
+type TripLeg struct {
+	Origin       string     `json:"origin"`
+	Destination  string     `json:"destination"`
+	EarliestDate *time.Time `json:"earliest_date"`         // First acceptable departure date
+	LatestDate   *time.Time `json:"latest_date,omitempty"` // Last acceptable departure date, if flexible
+	StayDays     *int       `json:"stay_days,omitempty"`   // Days spent at the destination before the next leg
+	// EarliestDateText and LatestDateText are the leg's date phrases as written by the traveler
+	EarliestDateText string `json:"earliest_date_text,omitempty"`
+	LatestDateText   string `json:"latest_date_text,omitempty"`
 }
 
 type Preferences struct {
 	BudgetRange struct {
-		Min *float64 `json:"min"`
-		Max *float64 `json:"max"`
+		Min   *Money      `json:"min"`
+		Max   *Money      `json:"max"`
+		Scope BudgetScope `json:"scope,omitempty"` // Per person when empty
 	} `json:"budget_range"`
 	TravelClass         string   `json:"travel_class"`
+	DepartureTime       string   `json:"departure_time,omitempty"` // morning, afternoon, evening or night
 	Activities          []string `json:"activities"`
 	DietaryRestrictions []string `json:"dietary_restrictions"`
+	Baggage             Baggage  `json:"baggage"`
+}
+
+// BudgetScope tells whether a budget is for each traveler or for the whole party
+type BudgetScope string
+
+const (
+	BudgetPerPerson BudgetScope = "per_person"
+	BudgetTotal     BudgetScope = "total"
+)
+
+// Baggage is the number of bags the whole party is bringing
+type Baggage struct {
+	CheckedBags int `json:"checked_bags"`
+	CabinBags   int `json:"cabin_bags"`
 }
 
 // FlightRecommendationRequest represents the input for flight recommendations
 type FlightRecommendationRequest struct {
-	DepartureCity  string    `json:"departure_city"`
-	Destination    string    `json:"destination"`
-	DepartureDate  time.Time `json:"departure_date"`
-	ReturnDate     time.Time `json:"return_date"`
-	Passengers     int       `json:"passengers"`
-	MaxBudget      float64   `json:"max_budget,omitempty"`
-	PreferredClass string    `json:"preferred_class,omitempty"`
+	TripType          TripType   `json:"trip_type"`
+	DepartureCity     string     `json:"departure_city"`
+	Destination       string     `json:"destination"`
+	DepartureDate     time.Time  `json:"departure_date"`
+	ReturnDate        *time.Time `json:"return_date,omitempty"` // Nil for one-way trips
+	ReturnOrigin      string     `json:"return_origin,omitempty"`
+	ReturnDestination string     `json:"return_destination,omitempty"`
+	// DepartureWindowEnd is the last acceptable departure date, if the traveler is flexible
+	DepartureWindowEnd *time.Time `json:"departure_window_end,omitempty"`
+	// DepartureAirports and DestinationAirports are the IATA codes serving each city, when known
+	DepartureAirports   []string `json:"departure_airports,omitempty"`
+	DestinationAirports []string `json:"destination_airports,omitempty"`
+	// Legs and LegIndex give the full multi-city trip and the leg being recommended
+	Legs           []TripLeg  `json:"legs,omitempty"`
+	LegIndex       int        `json:"leg_index,omitempty"`
+	Passengers     Passengers `json:"passengers"`
+	MinBudget      Money      `json:"min_budget"`         // Per passenger, the traveler wants at least this level of service
+	MaxBudget      Money      `json:"max_budget"`         // Per passenger
+	Currency       string     `json:"currency,omitempty"` // Traveler's currency, the budgets are in it
+	PreferredClass string     `json:"preferred_class,omitempty"`
+	MaxLayovers    *int       `json:"max_layovers,omitempty"`
+	// PreferredDepartureTime is the part of the day the traveler wants to leave in, if any
+	PreferredDepartureTime string `json:"preferred_departure_time,omitempty"`
+	// DietaryRestrictions are passed on so the model can favor airlines serving suitable meals
+	DietaryRestrictions []string `json:"dietary_restrictions,omitempty"`
+	// Baggage is what the party is bringing; bags beyond a fare's allowance are priced in
+	Baggage Baggage `json:"baggage"`
+	// Offers are the ranked flights the model explains when writing the ranking narrative
+	Offers []Flight `json:"offers,omitempty"`
+}
+
+// FlightSearchRequest asks a flight search provider for offers on one route
+type FlightSearchRequest struct {
+	Origin        string     `json:"origin"`      // IATA city or airport code, or the city name when unknown
+	Destination   string     `json:"destination"` // IATA city or airport code, or the city name when unknown
+	DepartureDate time.Time  `json:"departure_date"`
+	LatestDate    *time.Time `json:"latest_date,omitempty"` // Last acceptable departure date, if flexible
+	Passengers    Passengers `json:"passengers"`
+	TravelClass   string     `json:"travel_class,omitempty"`
+	MaxLayovers   *int       `json:"max_layovers,omitempty"`
+	Currency      string     `json:"currency,omitempty"` // Currency the offers should be priced in, if the source supports it
 }
 
 // FlightRecommendation represents the structured output
 type FlightRecommendation struct {
 	Recommendations []Flight `json:"recommendations"`
-	Reasoning       string   `json:"reasoning"`
+	// ReturnRecommendations are the flights back, for round-trip and open-jaw trips
+	ReturnRecommendations []Flight `json:"return_recommendations,omitempty"`
+	Reasoning             string   `json:"reasoning"`
+	// Itineraries pair outbound and return flights, best first; they are filled in by
+	// the booking service
+	Itineraries []RoundTripItinerary `json:"itineraries,omitempty"`
+}
+
+// RoundTripItinerary pairs an outbound flight with a return flight, priced together
+type RoundTripItinerary struct {
+	Outbound   Flight `json:"outbound"`
+	Return     Flight `json:"return"`
+	TotalPrice Money  `json:"total_price"` // Both directions for the whole party, in the traveler's currency
+	// RecommendationScore is the mean of the two flights' scores
+	RecommendationScore float64 `json:"recommendation_score"`
+}
+
+// MultiCityItinerary combines the flights chosen for each leg of a multi-city trip
+type MultiCityItinerary struct {
+	Legs       []LegRecommendation `json:"legs"`
+	TotalPrice Money               `json:"total_price"`      // In the traveler's currency
+	Feasible   bool                `json:"feasible"`         // False when a connection check failed
+	Issues     []string            `json:"issues,omitempty"` // Why the itinerary is not feasible
+}
+
+type LegRecommendation struct {
+	Leg          TripLeg `json:"leg"`
+	Flight       Flight  `json:"flight"`
+	Alternatives int     `json:"alternatives"` // Other options returned for this leg
 }
 
 type Flight struct {
-	Airline             string    `json:"airline"`
-	FlightNumber        string    `json:"flight_number"`
-	DepartureCity       string    `json:"departure_city"`
-	DepartureTime       time.Time `json:"departure_time"`
-	ArrivalCity         string    `json:"arrival_city"`
-	ArrivalTime         time.Time `json:"arrival_time"`
-	Class               string    `json:"class"`
-	LayoverCount        int       `json:"layover_count"`
-	TotalDuration       string    `json:"total_duration"`
+	OfferID       string    `json:"offer_id,omitempty"` // Set for flights from a search provider
+	Airline       string    `json:"airline"`
+	FlightNumber  string    `json:"flight_number"`
+	DepartureCity string    `json:"departure_city"`
+	DepartureTime time.Time `json:"departure_time"`
+	ArrivalCity   string    `json:"arrival_city"`
+	ArrivalTime   time.Time `json:"arrival_time"`
+	Class         string    `json:"class"`
+	LayoverCount  int       `json:"layover_count"`
+	// Segments are the flight's hops in order; connecting flights have one per takeoff
+	Segments            []Segment `json:"segments,omitempty"`
+	TotalDuration       Duration  `json:"total_duration"` // Departure to arrival, including layovers
 	AvailableSeats      int       `json:"available_seats"`
 	RecommendationScore float64   `json:"recommendation_score"`
-	Price               float64   `json:"price"`
-	Currency            string    `json:"currency"`
+	// ScoreBreakdown shows how each ranking factor contributed to the recommendation score
+	ScoreBreakdown []FactorScore `json:"score_breakdown,omitempty"`
+	Price          Money         `json:"price"` // Fare for one adult, in the currency the airline sells it in
+	// Fare prices the flight for the whole party; it is filled in by the booking service
+	Fare *FareBreakdown `json:"fare,omitempty"`
+	// Converted gives the prices in the traveler's currency when the flight is priced in another
+	Converted *ConvertedPrice `json:"converted,omitempty"`
+	// Conditions are the fare's baggage allowance and rules, when the source gives them
+	Conditions *FareConditions `json:"conditions,omitempty"`
+	// Warnings are problems found by the sanity checks that didn't warrant rejecting the flight
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Segment is one takeoff-to-landing hop of a flight. Times are local to each airport.
+type Segment struct {
+	Airline       string    `json:"airline,omitempty"`
+	Carrier       string    `json:"carrier,omitempty"` // IATA airline code
+	FlightNumber  string    `json:"flight_number"`
+	Origin        string    `json:"origin"`      // Airport IATA code
+	Destination   string    `json:"destination"` // Airport IATA code
+	DepartureTime time.Time `json:"departure_time"`
+	ArrivalTime   time.Time `json:"arrival_time"`
+	Aircraft      string    `json:"aircraft,omitempty"`
+	Duration      Duration  `json:"duration"`
+	// Layover is the connection time at Origin before this segment; zero for the first
+	Layover Duration `json:"layover,omitempty"`
+}
+
+// FactorScore is one ranking factor's part in a flight's recommendation score
+type FactorScore struct {
+	Factor       string  `json:"factor"`       // price, stops, duration or departure_time
+	Score        float64 `json:"score"`        // 0 to 1, higher is better
+	Weight       float64 `json:"weight"`       // Share of the total score; the weights add up to 1
+	Contribution float64 `json:"contribution"` // Score times weight; the contributions add up to the total
+	Detail       string  `json:"detail"`
+}
+
+// FlightRejection explains why a recommended flight was discarded
+type FlightRejection struct {
+	Airline      string   `json:"airline"`
+	FlightNumber string   `json:"flight_number"`
+	Leg          int      `json:"leg,omitempty"`       // 1-based leg of a multi-city trip
+	Direction    string   `json:"direction,omitempty"` // "return" for flights back on a round trip
+	Reasons      []string `json:"reasons"`
+}
+
+// FareConditions are what a fare includes and what changing it costs. Allowances are
+// per passenger with a seat; fees are in the flight's currency and nil when unknown.
+type FareConditions struct {
+	FareFamily  string `json:"fare_family,omitempty"` // e.g. Basic, Standard, Flex
+	CheckedBags int    `json:"checked_bags"`
+	CabinBags   int    `json:"cabin_bags"`
+	// CheckedBagFee and CabinBagFee are charged per bag beyond the allowance, each way
+	CheckedBagFee    *Money `json:"checked_bag_fee,omitempty"`
+	CabinBagFee      *Money `json:"cabin_bag_fee,omitempty"`
+	Refundable       bool   `json:"refundable"`
+	RefundFee        *Money `json:"refund_fee,omitempty"`
+	Changeable       bool   `json:"changeable"`
+	ChangeFee        *Money `json:"change_fee,omitempty"`
+	SeatSelectionFee *Money `json:"seat_selection_fee,omitempty"` // Per passenger, zero when seats are free
+}
+
+// FareBreakdown gives the fare per passenger type and the total for the party
+type FareBreakdown struct {
+	Adult  Money  `json:"adult"`
+	Child  *Money `json:"child,omitempty"`
+	Infant *Money `json:"infant,omitempty"`
+	// ExtraBags and BaggageFees are the bags beyond the fare's allowance and what they cost
+	ExtraBags   int    `json:"extra_bags,omitempty"`
+	BaggageFees *Money `json:"baggage_fees,omitempty"`
+	Total       Money  `json:"total"` // Fares and baggage fees
+}
+
+// ConvertedPrice is a flight's price in the traveler's currency
+type ConvertedPrice struct {
+	Price Money   `json:"price"` // Fare for one adult
+	Total Money   `json:"total"` // Fare for the party
+	Rate  float64 `json:"rate"`  // Units of the traveler's currency per unit of the flight's
 }
 
 // Define mock response and request types
@@ -88,10 +345,10 @@ type MockTravelRequest struct{}
 
 // Define a single type for all travel-related requests
 type TravelInput interface {
-	BookingRequest | FlightRecommendationRequest | MockTravelRequest
+	BookingRequest | FlightRecommendationRequest | HotelRecommendationRequest | SuggestionRequest | TripPlanRequest | MockTravelRequest
 }
 
 // Define a single type for all travel-related responses
 type TravelOutput interface {
-	TravelParameters | FlightRecommendation | MockTravelResponse
+	TravelParameters | FlightRecommendation | HotelRecommendation | SuggestionPlan | TripPlanDraft | InjectionAssessment | IntentClassification | MockTravelResponse
 }
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+)
+
+type ConversationStatus string
+
+const (
+	ConversationAwaitingInput ConversationStatus = "awaiting_input"
+	ConversationCompleted     ConversationStatus = "completed"
+	ConversationFailed        ConversationStatus = "failed"
+	ConversationQuarantined   ConversationStatus = "quarantined" // Held for review as a suspected prompt injection
+)
+
+// Roles of the participants in a conversation
+const (
+	RoleTraveler = "traveler"
+	RoleAgent    = "agent"
+)
+
+// Conversation tracks a booking request that needs follow-up questions before it can be processed
+type Conversation struct {
+	ID            string                `json:"id"`
+	Status        ConversationStatus    `json:"status"`
+	Deadline      time.Time             `json:"deadline"`
+	TimeZone      string                `json:"time_zone,omitempty"`
+	ReferenceTime *time.Time            `json:"reference_time,omitempty"` // "Now" for relative dates in every turn
+	Profile       *TravelerProfile      `json:"profile,omitempty"`
+	Messages      []ConversationMessage `json:"messages"`
+	Parameters    *TravelParameters     `json:"parameters,omitempty"`     // Parameters gathered so far
+	MissingFields []string              `json:"missing_fields,omitempty"` // Fields the agent is asking about
+	Booking       *BookingResponse      `json:"booking,omitempty"`        // Set once the parameters are complete
+	AskedFields   []string              `json:"-"`                        // Fields already asked about, to avoid asking twice
+	CreatedAt     time.Time             `json:"created_at"`
+	UpdatedAt     time.Time             `json:"updated_at"`
+}
+
+type ConversationMessage struct {
+	Role      string    `json:"role"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ConversationMessageRequest is a traveler's answer to the agent's questions
+type ConversationMessageRequest struct {
+	Content string `json:"content"`
+}
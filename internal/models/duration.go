@@ -0,0 +1,97 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Duration is a flight or layover duration. It is encoded as a string like "7h05m" and
+// decoded from the formats models and flight APIs produce: "7h 30m", "7 hours 30
+// minutes", "PT7H30M", "07:30", or a number of minutes. Text that can't be read
+// decodes to zero rather than failing the whole response.
+type Duration time.Duration
+
+// Std returns the duration as a time.Duration
+func (d Duration) Std() time.Duration {
+	return time.Duration(d)
+}
+
+// String formats the duration in hours and minutes, e.g. "7h05m"
+func (d Duration) String() string {
+	rounded := time.Duration(d).Round(time.Minute)
+	sign := ""
+	if rounded < 0 {
+		sign, rounded = "-", -rounded
+	}
+	return fmt.Sprintf("%s%dh%02dm", sign, int(rounded.Hours()), int(rounded.Minutes())%60)
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var minutes float64
+	if err := json.Unmarshal(data, &minutes); err == nil {
+		*d = Duration(math.Round(minutes * float64(time.Minute)))
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("duration must be a string or a number of minutes: %w", err)
+	}
+	parsed, _ := ParseDuration(text)
+	*d = parsed
+	return nil
+}
+
+var (
+	clockDurationPattern = regexp.MustCompile(`^(\d{1,2}):(\d{2})$`)
+	hoursPattern         = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*h`)
+	minutesPattern       = regexp.MustCompile(`(\d+)\s*m`)
+)
+
+// ParseDuration reads "7h 30m", "7 hours 30 minutes", "PT7H30M", "07:30" and
+// "450 minutes"
+func ParseDuration(text string) (Duration, error) {
+	original := text
+	text = strings.ToLower(strings.TrimSpace(text))
+	if text == "" {
+		return 0, fmt.Errorf("empty duration")
+	}
+
+	if m := clockDurationPattern.FindStringSubmatch(text); m != nil {
+		hours, _ := strconv.Atoi(m[1])
+		minutes, _ := strconv.Atoi(m[2])
+		return Duration(time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute), nil
+	}
+
+	text = strings.TrimPrefix(text, "pt")
+	var total time.Duration
+	found := false
+	if m := hoursPattern.FindStringSubmatch(text); m != nil {
+		hours, err := strconv.ParseFloat(m[1], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", original)
+		}
+		total += time.Duration(math.Round(hours * float64(time.Hour)))
+		found = true
+		// Minutes come after the hours
+		text = text[strings.Index(text, m[0])+len(m[0]):]
+	}
+	if m := minutesPattern.FindStringSubmatch(text); m != nil {
+		minutes, _ := strconv.Atoi(m[1])
+		total += time.Duration(minutes) * time.Minute
+		found = true
+	}
+	if !found {
+		return 0, fmt.Errorf("invalid duration %q", original)
+	}
+	return Duration(total), nil
+}
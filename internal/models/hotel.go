@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+)
+
+// HotelRecommendationRequest represents the input for hotel recommendations
+type HotelRecommendationRequest struct {
+	City     string     `json:"city"`
+	CheckIn  time.Time  `json:"check_in"`
+	CheckOut time.Time  `json:"check_out"`
+	Guests   Passengers `json:"guests"`
+	Rooms    int        `json:"rooms"`
+	Currency string     `json:"currency,omitempty"` // Traveler's currency, rates should be in it
+	// Activities are what the traveler plans to do; the neighborhood should suit them
+	Activities []string `json:"activities,omitempty"`
+	// DietaryRestrictions are passed on so the model can favor hotels with suitable dining
+	DietaryRestrictions []string `json:"dietary_restrictions,omitempty"`
+}
+
+// HotelRecommendation represents the structured hotel output
+type HotelRecommendation struct {
+	Hotels    []Hotel `json:"hotels"`
+	Reasoning string  `json:"reasoning"`
+}
+
+type Hotel struct {
+	Name         string  `json:"name"`
+	Neighborhood string  `json:"neighborhood"`
+	Address      string  `json:"address,omitempty"`
+	Stars        float64 `json:"stars,omitempty"`
+	NightlyRate  Money   `json:"nightly_rate"` // Per room
+	// TotalPrice covers every room for the whole stay; it is filled in by the booking service
+	TotalPrice Money `json:"total_price"`
+	// Converted gives the prices in the traveler's currency when the hotel is priced in another
+	Converted *ConvertedPrice `json:"converted,omitempty"`
+	Amenities []string        `json:"amenities,omitempty"`
+	// NeighborhoodTags say what the neighborhood is known for, e.g. "museums" or "nightlife"
+	NeighborhoodTags []string `json:"neighborhood_tags,omitempty"`
+	// NeighborhoodFit is the share of the traveler's activities the neighborhood suits,
+	// from 0 to 1; it is zero when the traveler named none
+	NeighborhoodFit float64 `json:"neighborhood_fit"`
+	FitDetail       string  `json:"fit_detail,omitempty"`
+}
+
+// HotelStay gives the hotel options for the stay at the destination
+type HotelStay struct {
+	City     string    `json:"city"`
+	CheckIn  time.Time `json:"check_in"`
+	CheckOut time.Time `json:"check_out"`
+	Nights   int       `json:"nights"`
+	Rooms    int       `json:"rooms"`
+	Hotels   []Hotel   `json:"hotels"` // Best neighborhood fit first
+	// Reasoning is the model's explanation of its choices
+	Reasoning string `json:"reasoning,omitempty"`
+}
@@ -0,0 +1,22 @@
+package models
+
+// Intent is what a traveler's query asks for
+type Intent string
+
+const (
+	IntentFlightSearch   Intent = "flight_search"    // Find flights for a new trip
+	IntentHotelOnly      Intent = "hotel_only"       // Find a place to stay, no flights
+	IntentBookingStatus  Intent = "booking_status"   // Check on an existing booking
+	IntentChangeOrCancel Intent = "change_or_cancel" // Change or cancel an existing booking
+	IntentOffTopic       Intent = "off_topic"        // Not about travel
+)
+
+// Intents lists every intent a query can be classified as
+var Intents = []Intent{IntentFlightSearch, IntentHotelOnly, IntentBookingStatus, IntentChangeOrCancel, IntentOffTopic}
+
+// IntentClassification is the model's reading of what a query asks for
+type IntentClassification struct {
+	Intent     Intent  `json:"intent"`
+	BookingID  string  `json:"booking_id"` // Booking the query refers to, if it names one
+	Confidence float64 `json:"confidence"` // 0 to 1
+}
@@ -0,0 +1,327 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// Money is an exact amount in a currency's minor units, e.g. cents. Currency is the
+// ISO 4217 code and is empty for amounts decoded without one, such as the bare numbers
+// models produce; Assume attaches it once it is known.
+//
+// It is encoded as {"amount": 750.00, "currency": "USD"} and decoded from that form,
+// a number, or a string like "750.00" or "750.00 EUR".
+type Money struct {
+	Minor    int64
+	Currency string
+}
+
+var (
+	// ErrCurrencyMismatch is returned when adding amounts in different currencies
+	ErrCurrencyMismatch = errors.New("money: amounts are in different currencies")
+	// ErrOverflow is returned when a sum doesn't fit in the minor units
+	ErrOverflow = errors.New("money: amount out of range")
+)
+
+// minorUnitExceptions lists the currencies that don't have two decimal places
+var minorUnitExceptions = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// MinorUnits returns the number of decimal places of a currency, 2 when unknown
+func MinorUnits(currency string) int {
+	if units, ok := minorUnitExceptions[currency]; ok {
+		return units
+	}
+	return 2
+}
+
+// NewMoney rounds an amount in major units, e.g. dollars, to the currency's minor units.
+// Amounts too large for them are clamped to the largest one.
+func NewMoney(amount float64, currency string) Money {
+	currency = NormalizeCurrency(currency)
+	return Money{
+		Minor:    clampMinor(amount * math.Pow10(MinorUnits(currency))),
+		Currency: currency,
+	}
+}
+
+// ParseMoney reads a decimal amount exactly, rounding half away from zero to the
+// currency's minor units. Exponents such as "7.5e2" are accepted.
+func ParseMoney(amount, currency string) (Money, error) {
+	currency = NormalizeCurrency(currency)
+	value, ok := new(big.Rat).SetString(strings.TrimSpace(amount))
+	if !ok {
+		return Money{}, fmt.Errorf("invalid amount %q", amount)
+	}
+	minor, err := roundRat(value.Mul(value, new(big.Rat).SetInt(pow10(MinorUnits(currency)))))
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid amount %q: %w", amount, err)
+	}
+	return Money{Minor: minor, Currency: currency}, nil
+}
+
+// Assume attaches a currency to an amount decoded without one. Amounts that already
+// have a currency are returned unchanged; converting between currencies needs a rate.
+func (m Money) Assume(currency string) Money {
+	currency = NormalizeCurrency(currency)
+	if m.Currency != "" || currency == "" {
+		return m
+	}
+	return m.rescale(currency)
+}
+
+// Exchange converts the amount at rate units of to per unit of m's currency
+func (m Money) Exchange(rate float64, to string) Money {
+	to = NormalizeCurrency(to)
+	shift := math.Pow10(MinorUnits(to) - MinorUnits(m.Currency))
+	return Money{Minor: clampMinor(float64(m.Minor) * rate * shift), Currency: to}
+}
+
+// Add sums two amounts in the same currency. An amount without a currency takes the
+// other's. Adding different currencies fails with ErrCurrencyMismatch.
+func (m Money) Add(other Money) (Money, error) {
+	currency, err := m.sameCurrency(other)
+	if err != nil {
+		return Money{}, err
+	}
+	a, b := m.Assume(currency).Minor, other.Assume(currency).Minor
+	sum := a + b
+	if (b > 0 && sum < a) || (b < 0 && sum > a) || sum == math.MinInt64 {
+		return Money{}, fmt.Errorf("adding %s and %s: %w", m, other, ErrOverflow)
+	}
+	return Money{Minor: sum, Currency: currency}, nil
+}
+
+// Mul multiplies the amount by a count, e.g. the number of passengers, clamping
+// products too large for the minor units
+func (m Money) Mul(n int) Money {
+	product := m.Minor * int64(n)
+	if n != 0 && (product/int64(n) != m.Minor || product == math.MinInt64) {
+		product = clampMinor(float64(m.Minor) * float64(n))
+	}
+	return Money{Minor: product, Currency: m.Currency}
+}
+
+// Scale multiplies the amount by a ratio, rounding to the nearest minor unit
+func (m Money) Scale(ratio float64) Money {
+	return Money{Minor: clampMinor(float64(m.Minor) * ratio), Currency: m.Currency}
+}
+
+// Cmp compares two amounts, returning -1, 0 or +1. Amounts in different currencies
+// can't be compared by value; they are ordered by currency code so sorts stay
+// consistent, and callers comparing prices check the currency first.
+func (m Money) Cmp(other Money) int {
+	currency, err := m.sameCurrency(other)
+	if err != nil {
+		return strings.Compare(m.Currency, other.Currency)
+	}
+	a, b := m.Assume(currency).Minor, other.Assume(currency).Minor
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Less orders prices for sorting: amounts in the preferred currency come first, the
+// others grouped by currency code, and the smaller amount first within a currency.
+// Only comparing amounts in the same currency would not be transitive.
+func (m Money) Less(other Money, preferred string) bool {
+	if (m.Currency == preferred) != (other.Currency == preferred) {
+		return m.Currency == preferred
+	}
+	return m.Cmp(other) < 0
+}
+
+func (m Money) IsZero() bool     { return m.Minor == 0 }
+func (m Money) IsPositive() bool { return m.Minor > 0 }
+func (m Money) IsNegative() bool { return m.Minor < 0 }
+
+// Float returns the amount in major units. It is meant for display and rate
+// arithmetic, never for sums.
+func (m Money) Float() float64 {
+	return float64(m.Minor) / math.Pow10(MinorUnits(m.Currency))
+}
+
+// Amount formats the amount in major units with the currency's decimals, e.g. "750.00"
+func (m Money) Amount() string {
+	units := MinorUnits(m.Currency)
+	// The magnitude is unsigned so that the most negative amount can't overflow
+	minor := uint64(m.Minor)
+	sign := ""
+	if m.Minor < 0 {
+		sign, minor = "-", -minor
+	}
+	if units == 0 {
+		return fmt.Sprintf("%s%d", sign, minor)
+	}
+	scale := uint64(math.Pow10(units))
+	return fmt.Sprintf("%s%d.%0*d", sign, minor/scale, units, minor%scale)
+}
+
+// String formats the amount with its currency, e.g. "750.00 EUR"
+func (m Money) String() string {
+	if m.Currency == "" {
+		return m.Amount()
+	}
+	return m.Amount() + " " + m.Currency
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	currency, err := json.Marshal(m.Currency)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf(`{"amount":%s,"currency":%s}`, m.Amount(), currency)), nil
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		return nil
+	case len(data) > 0 && data[0] == '{':
+		var object struct {
+			Amount   json.RawMessage `json:"amount"`
+			Currency string          `json:"currency"`
+		}
+		if err := json.Unmarshal(data, &object); err != nil {
+			return fmt.Errorf("invalid money: %w", err)
+		}
+		amount, err := amountText(object.Amount)
+		if err != nil {
+			return err
+		}
+		parsed, err := ParseMoney(amount, object.Currency)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	case len(data) > 0 && data[0] == '"':
+		var text string
+		if err := json.Unmarshal(data, &text); err != nil {
+			return fmt.Errorf("invalid money: %w", err)
+		}
+		parsed, err := parseMoneyText(text)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	}
+
+	parsed, err := ParseMoney(string(data), "")
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// amountText accepts the amount of the object form as a number or a string
+func amountText(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return "0", nil
+	}
+	if raw[0] == '"' {
+		var text string
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return "", fmt.Errorf("invalid amount: %w", err)
+		}
+		return text, nil
+	}
+	return string(raw), nil
+}
+
+// parseMoneyText reads "750.00", "750.00 EUR" and "EUR 750.00"; thousands separators
+// are not accepted since "1.500" is ambiguous
+func parseMoneyText(text string) (Money, error) {
+	fields := strings.Fields(text)
+	switch len(fields) {
+	case 1:
+		return ParseMoney(fields[0], "")
+	case 2:
+		if code := NormalizeCurrency(fields[1]); code != "" {
+			return ParseMoney(fields[0], code)
+		}
+		if code := NormalizeCurrency(fields[0]); code != "" {
+			return ParseMoney(fields[1], code)
+		}
+	}
+	return Money{}, fmt.Errorf("invalid amount %q", text)
+}
+
+func (m Money) rescale(currency string) Money {
+	shift := MinorUnits(currency) - MinorUnits(m.Currency)
+	minor := m.Minor
+	if shift > 0 {
+		minor *= int64(math.Pow10(shift))
+	} else if shift < 0 {
+		minor = clampMinor(float64(minor) / math.Pow10(-shift))
+	}
+	return Money{Minor: minor, Currency: currency}
+}
+
+func (m Money) sameCurrency(other Money) (string, error) {
+	switch {
+	case m.Currency == other.Currency || other.Currency == "":
+		return m.Currency, nil
+	case m.Currency == "":
+		return other.Currency, nil
+	}
+	return "", fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+}
+
+// clampMinor rounds to whole minor units, saturating at the largest amount either way
+// rather than overflowing; NaN is zero
+func clampMinor(minor float64) int64 {
+	switch {
+	case math.IsNaN(minor):
+		return 0
+	case minor >= math.MaxInt64:
+		return math.MaxInt64
+	case minor <= -math.MaxInt64:
+		return -math.MaxInt64
+	}
+	return int64(math.Round(minor))
+}
+
+// NormalizeCurrency uppercases an ISO 4217 code, returning "" when it isn't three letters
+func NormalizeCurrency(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 3 || strings.Trim(code, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return ""
+	}
+	return code
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// roundRat rounds half away from zero to an int64
+func roundRat(value *big.Rat) (int64, error) {
+	num, den := new(big.Int).Abs(value.Num()), value.Denom()
+	quotient, remainder := new(big.Int).QuoRem(num, den, new(big.Int))
+	if remainder.Mul(remainder, big.NewInt(2)).Cmp(den) >= 0 {
+		quotient.Add(quotient, big.NewInt(1))
+	}
+	if value.Sign() < 0 {
+		quotient.Neg(quotient)
+	}
+	if !quotient.IsInt64() {
+		return 0, fmt.Errorf("out of range")
+	}
+	return quotient.Int64(), nil
+}
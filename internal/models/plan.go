@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+)
+
+// TripPlanRequest represents the input for planning the stay hour by hour
+type TripPlanRequest struct {
+	City         string `json:"city"`
+	Hotel        string `json:"hotel,omitempty"`        // Where the traveler stays, when known
+	Neighborhood string `json:"neighborhood,omitempty"` // The hotel's neighborhood
+	TimeZone     string `json:"time_zone"`              // Destination time zone; block times are local to it
+	// Days are the empty days of the plan, each giving the hours free for activities
+	Days   []PlanDay  `json:"days"`
+	Guests Passengers `json:"guests"`
+	// Activities are what the traveler wants to do; the plan should include them
+	Activities []string `json:"activities,omitempty"`
+	// DietaryRestrictions must be respected by every meal in the plan
+	DietaryRestrictions []string `json:"dietary_restrictions,omitempty"`
+}
+
+// TripPlanDraft represents the structured plan output, with local wall-clock times
+type TripPlanDraft struct {
+	Days      []DraftDay `json:"days"`
+	Reasoning string     `json:"reasoning"`
+}
+
+type DraftDay struct {
+	Day    int          `json:"day"` // 1-based day of the stay
+	Blocks []DraftBlock `json:"blocks"`
+}
+
+type DraftBlock struct {
+	Start       string `json:"start"` // 24-hour "HH:MM", local to the destination
+	End         string `json:"end"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Location    string `json:"location,omitempty"`
+	Kind        string `json:"kind,omitempty"` // activity, meal, rest or transfer
+}
+
+// TripPlan is the time-blocked day-by-day plan for a booking's stay. It is stored
+// with the booking and can be edited a day at a time.
+type TripPlan struct {
+	BookingID string    `json:"booking_id"`
+	City      string    `json:"city"`
+	TimeZone  string    `json:"time_zone"` // Times are given in the destination's zone
+	Days      []PlanDay `json:"days"`
+	Reasoning string    `json:"reasoning,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PlanDay is one day of the stay. Start and End bound the time free for the plan:
+// the arrival day starts once the traveler has landed and reached the hotel, and the
+// departure day ends in time to get to the airport.
+type PlanDay struct {
+	Day    int         `json:"day"`
+	Date   time.Time   `json:"date"` // Local midnight
+	Start  time.Time   `json:"start"`
+	End    time.Time   `json:"end"`
+	Note   string      `json:"note,omitempty"` // Why the day is shortened, e.g. the arrival flight
+	Blocks []PlanBlock `json:"blocks"`         // In order, never overlapping
+}
+
+type PlanBlock struct {
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	Location    string    `json:"location,omitempty"`
+	Kind        string    `json:"kind,omitempty"`
+}
+
+// PlanDayUpdate replaces the blocks of one day of a plan
+type PlanDayUpdate struct {
+	Blocks []PlanBlock `json:"blocks"`
+}
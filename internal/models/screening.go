@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+// What happens to a query flagged as a prompt-injection attempt
+const (
+	ScreeningReject     = "reject"     // The request fails and nothing is stored
+	ScreeningQuarantine = "quarantine" // The request is held for review without reaching the model
+)
+
+// Where a screened query came from
+const (
+	ScreeningSourceBooking      = "booking"
+	ScreeningSourceConversation = "conversation"
+)
+
+// InjectionAssessment is the classifier's verdict on a traveler's query
+type InjectionAssessment struct {
+	Injection  bool    `json:"injection"`
+	Confidence float64 `json:"confidence"` // 0 to 1
+	Reason     string  `json:"reason"`
+}
+
+// ScreeningAudit records a query flagged as a prompt-injection attempt and what was
+// done with it
+type ScreeningAudit struct {
+	ID          string               `json:"id"`
+	Source      string               `json:"source"`                 // booking or conversation
+	ReferenceID string               `json:"reference_id,omitempty"` // Booking or conversation the query was for
+	Query       string               `json:"query"`
+	Score       float64              `json:"score"` // Heuristic score, flagged from 1
+	Signals     []string             `json:"signals,omitempty"`
+	Classifier  *InjectionAssessment `json:"classifier,omitempty"` // Set when the classifier ran
+	Action      string               `json:"action"`               // reject or quarantine
+	CreatedAt   time.Time            `json:"created_at"`
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+)
+
+// SuggestionRequest represents the input for activity and dining suggestions
+type SuggestionRequest struct {
+	City         string     `json:"city"`
+	Neighborhood string     `json:"neighborhood,omitempty"` // Where the traveler stays, when known
+	StartDate    time.Time  `json:"start_date"`
+	Days         int        `json:"days"`
+	Guests       Passengers `json:"guests"`
+	// Activities are what the traveler wants to do; each day should include some of them
+	Activities []string `json:"activities,omitempty"`
+	// DietaryRestrictions must be catered to by every dining suggestion
+	DietaryRestrictions []string `json:"dietary_restrictions,omitempty"`
+}
+
+// SuggestionPlan represents the structured suggestions output, one entry per day
+type SuggestionPlan struct {
+	Days      []DaySuggestions `json:"days"`
+	Reasoning string           `json:"reasoning"`
+}
+
+type DaySuggestions struct {
+	Day        int          `json:"day"`  // 1-based day of the stay
+	Date       time.Time    `json:"date"` // Filled in by the suggestion service
+	Activities []Suggestion `json:"activities"`
+	Dining     []Suggestion `json:"dining"`
+}
+
+type Suggestion struct {
+	Name         string `json:"name"`
+	Description  string `json:"description,omitempty"`
+	Neighborhood string `json:"neighborhood,omitempty"`
+	TimeOfDay    string `json:"time_of_day,omitempty"` // morning, afternoon, evening or night
+	// Interest is the traveler's activity an activity suggestion answers
+	Interest string `json:"interest,omitempty"`
+	Cuisine  string `json:"cuisine,omitempty"` // For dining suggestions
+	// DietaryOptions lists the restrictions a dining suggestion caters to, e.g. "vegetarian"
+	DietaryOptions []string `json:"dietary_options,omitempty"`
+}
+
+// Suggestions are the day-by-day activity and dining ideas for a booking's stay
+type Suggestions struct {
+	BookingID string           `json:"booking_id"`
+	City      string           `json:"city"`
+	StartDate time.Time        `json:"start_date"`
+	Days      []DaySuggestions `json:"days"`
+	Reasoning string           `json:"reasoning,omitempty"`
+	// Excluded lists the dining suggestions dropped for not catering to a dietary restriction
+	Excluded []string `json:"excluded,omitempty"`
+}
+
+// SuggestionRecord is a booking's suggestions stored with the request they were made
+// from, so they are only made again when the booking changes what would be asked
+type SuggestionRecord struct {
+	Request     SuggestionRequest `json:"request"`
+	Suggestions Suggestions       `json:"suggestions"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+)
+
+// Ground transport modes
+const (
+	TransportTrain     = "train"
+	TransportBus       = "bus"
+	TransportTaxi      = "taxi"
+	TransportTransfer  = "transfer" // Pre-booked private car or shuttle
+	TransportCarRental = "car_rental"
+)
+
+// GroundTransportRequest asks for ways from the arrival airport into town
+type GroundTransportRequest struct {
+	Airport     string     `json:"airport"`      // IATA code of the airport the flight lands at
+	City        string     `json:"city"`         // Destination city
+	ArrivalTime time.Time  `json:"arrival_time"` // When the flight lands
+	Destination string     `json:"destination"`  // The hotel, or the city center when there is none
+	Passengers  Passengers `json:"passengers"`
+	// ReturnTime is when the return flight leaves; car rentals are priced until then
+	ReturnTime *time.Time `json:"return_time,omitempty"`
+}
+
+// TransportOption is one way from the airport to the destination, priced for the party
+type TransportOption struct {
+	Mode     string    `json:"mode"` // train, bus, taxi, transfer or car_rental
+	Provider string    `json:"provider"`
+	From     string    `json:"from"` // Airport IATA code
+	To       string    `json:"to"`
+	Departs  time.Time `json:"departs"` // Local to the airport
+	Arrives  time.Time `json:"arrives"`
+	Duration Duration  `json:"duration"` // Time on board, excluding the wait
+	// Price covers the whole party: every seat on trains and buses, every vehicle
+	// needed for taxis and transfers, and every rental day for cars
+	Price      Money           `json:"price"`
+	Converted  *ConvertedPrice `json:"converted,omitempty"`
+	Vehicles   int             `json:"vehicles,omitempty"`    // Taxis, transfers and rental cars needed
+	RentalDays int             `json:"rental_days,omitempty"` // Set for car rentals
+	Notes      string          `json:"notes,omitempty"`
+}
+
+// GroundTransport gives the options from the arrival airport to the hotel or city center
+type GroundTransport struct {
+	Airport     string            `json:"airport"`
+	Destination string            `json:"destination"`
+	ArrivalTime time.Time         `json:"arrival_time"`
+	Options     []TransportOption `json:"options"` // Earliest arrival first
+}
@@ -0,0 +1,170 @@
+package ai
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"travel-agent/internal/models"
+	"unicode"
+)
+
+//go:embed examples/extraction.json
+var defaultExtractionExamples []byte
+
+const (
+	// DefaultExampleTokenBudget caps the estimated tokens spent on few-shot examples
+	DefaultExampleTokenBudget = 1200
+	// DefaultMaxExamples caps the number of few-shot examples per request
+	DefaultMaxExamples = 3
+
+	// charsPerToken is a rough heuristic used to estimate token usage without a tokenizer
+	charsPerToken = 4
+)
+
+// ExtractionExample is a curated query and the parameters we expect the model to extract from it
+type ExtractionExample struct {
+	Name     string    `json:"name"`
+	Query    string    `json:"query"`
+	Deadline time.Time `json:"deadline"`
+	// ReferenceTime is "now" for the example's relative dates; defaults to the deadline
+	ReferenceTime *time.Time              `json:"reference_time,omitempty"`
+	TimeZone      string                  `json:"time_zone,omitempty"`
+	Tags          []string                `json:"tags"`
+	Output        models.TravelParameters `json:"output"`
+}
+
+// ExampleLibrary holds the few-shot examples available to the extraction prompt
+type ExampleLibrary struct {
+	examples []ExtractionExample
+}
+
+// DefaultExampleLibrary returns the library bundled with the binary
+func DefaultExampleLibrary() (*ExampleLibrary, error) {
+	return ParseExampleLibrary(defaultExtractionExamples)
+}
+
+// LoadExampleLibrary reads a JSON array of examples from disk
+func LoadExampleLibrary(filename string) (*ExampleLibrary, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading examples file: %w", err)
+	}
+	return ParseExampleLibrary(data)
+}
+
+// ParseExampleLibrary builds a library from a JSON array of examples
+func ParseExampleLibrary(data []byte) (*ExampleLibrary, error) {
+	var examples []ExtractionExample
+	if err := json.Unmarshal(data, &examples); err != nil {
+		return nil, fmt.Errorf("parsing examples: %w", err)
+	}
+
+	for i, example := range examples {
+		if example.Query == "" {
+			return nil, fmt.Errorf("example %d: query is required", i+1)
+		}
+		if example.Deadline.IsZero() {
+			return nil, fmt.Errorf("example %d: deadline is required", i+1)
+		}
+	}
+
+	return &ExampleLibrary{examples: examples}, nil
+}
+
+// Len returns the number of examples in the library
+func (l *ExampleLibrary) Len() int {
+	return len(l.examples)
+}
+
+// Rank orders the examples by relevance to the query. Examples whose tags appear
+// in the query rank first, followed by lexical similarity. Examples sharing
+// nothing with the query are left out.
+func (l *ExampleLibrary) Rank(query string) []ExtractionExample {
+	if l == nil {
+		return nil
+	}
+
+	normalizedQuery := " " + strings.Join(tokenize(query), " ") + " "
+	queryTokens := tokenSet(query)
+
+	type candidate struct {
+		example ExtractionExample
+		score   float64
+	}
+
+	candidates := make([]candidate, 0, len(l.examples))
+	for _, example := range l.examples {
+		score := similarity(queryTokens, tokenSet(example.Query))
+		for _, tag := range example.Tags {
+			phrase := strings.Join(tokenize(tag), " ")
+			if phrase != "" && strings.Contains(normalizedQuery, " "+phrase+" ") {
+				score++
+			}
+		}
+		if score == 0 {
+			continue
+		}
+		candidates = append(candidates, candidate{example: example, score: score})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+
+	ranked := make([]ExtractionExample, len(candidates))
+	for i, c := range candidates {
+		ranked[i] = c.example
+	}
+	return ranked
+}
+
+// EstimateTokens approximates the number of tokens a piece of prompt text costs
+func EstimateTokens(text string) int {
+	return (len(text) + charsPerToken - 1) / charsPerToken
+}
+
+// tokenize lowercases the text and splits it into words, dropping punctuation
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// stopWords are too common in travel requests to say anything about similarity
+var stopWords = map[string]struct{}{
+	"a": {}, "an": {}, "and": {}, "the": {}, "to": {}, "from": {}, "in": {},
+	"on": {}, "for": {}, "me": {}, "i": {}, "my": {}, "of": {}, "flight": {},
+	"fly": {}, "book": {}, "want": {}, "trip": {},
+}
+
+// tokenSet returns the distinct, meaningful words of the text
+func tokenSet(text string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, token := range tokenize(text) {
+		if _, ok := stopWords[token]; ok {
+			continue
+		}
+		set[token] = struct{}{}
+	}
+	return set
+}
+
+// similarity returns the Jaccard index of two token sets
+func similarity(a, b map[string]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for token := range a {
+		if _, ok := b[token]; ok {
+			shared++
+		}
+	}
+
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
@@ -1,11 +1,13 @@
 package ai
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 	"travel-agent/internal/models"
+	"travel-agent/pkg/utils"
 )
 
 // FlightRecommendationStrategy implements the PromptStrategy interface
@@ -21,18 +23,46 @@ Output must be a valid JSON object with this exact structure:
             "airline": "string",
             "flight_number": "string",
             "departure_city": "string",
-            "departure_time": "YYYY-MM-DDTHH:MM:SSZ",
+            "departure_time": "YYYY-MM-DDTHH:MM:SS+HH:MM",
             "arrival_city": "string",
-            "arrival_time": "YYYY-MM-DDTHH:MM:SSZ",
+            "arrival_time": "YYYY-MM-DDTHH:MM:SS+HH:MM",
             "class": "string",
-            "estimated_price": number,
+            "price": number,
+            "currency": "ISO 4217 code",
             "layover_count": number,
-            "total_duration": "string",
+            "total_duration": "e.g. 7h15m",
+            "segments": [
+                {
+                    "airline": "string",
+                    "carrier": "IATA airline code",
+                    "flight_number": "string",
+                    "origin": "IATA airport code",
+                    "destination": "IATA airport code",
+                    "departure_time": "YYYY-MM-DDTHH:MM:SS+HH:MM",
+                    "arrival_time": "YYYY-MM-DDTHH:MM:SS+HH:MM",
+                    "aircraft": "string"
+                }
+            ],
             "available_seats": number,
-            "recommendation_score": number
+            "recommendation_score": number,
+            "conditions": {
+                "fare_family": "e.g. Basic, Standard, Flex",
+                "checked_bags": number,
+                "cabin_bags": number,
+                "checked_bag_fee": number,
+                "cabin_bag_fee": number,
+                "refundable": boolean,
+                "refund_fee": number,
+                "changeable": boolean,
+                "change_fee": number,
+                "seat_selection_fee": number
+            }
         }
     ],
-    "reasoning": "string explaining why these flights were recommended",
+    "return_recommendations": [
+        { same structure as recommendations }
+    ],
+    "reasoning": "string explaining why these flights were recommended"
 }
 
 Recommendation Rules:
@@ -43,6 +73,16 @@ Recommendation Rules:
 5. Consider time of day and arrival/departure convenience
 6. Account for seasonal factors and typical delays
 7. Consider airport-specific factors
+8. For one-way trips, recommend outbound flights only and never invent a return flight
+9. For round-trip and open-jaw trips, put the outbound flights in recommendations and the flights back, departing on the return date, in return_recommendations; open-jaw returns leave from and arrive at the cities given in the return route
+10. For multi-city trips, recommend flights for the current leg only, departing within its window
+11. Give price as the fare for one adult, in the currency the airline sells it in, with its ISO 4217 code in currency; child and infant fares are derived from it
+12. Only recommend flights with enough available seats for every adult and child; infants sit on a lap
+13. Depart from and arrive at the listed airports, and use the requested city names in departure_city and arrival_city
+14. Respect the preferred class, the maximum budget and any layover limit; flights breaking them are discarded
+15. Give departure_time and arrival_time in the local time of the departure and arrival airports with their UTC offset, and list every segment in flying order, with local times and their UTC offset; each segment leaves from the airport the previous one landed at, and connections shorter than the minimum connection time are flagged
+16. Give the fare's conditions: the bags included per passenger, the fee per extra bag each way, whether it can be refunded or changed and at what fee, and the fee to pick a seat; fees are in the fare's currency, and use null for fees you don't know
+17. ` + fenceRule + `
 
 Return only the JSON object, no additional text or explanation.`
 }
@@ -51,44 +91,172 @@ func (s *FlightRecommendationStrategy) GetUserPrompt(req models.FlightRecommenda
 	return fmt.Sprintf(`Analyze this flight request and provide recommendations:
 
 TRAVEL DETAILS:
+- Trip Type: %s
 - Departure: %s
 - Destination: %s
 - Departure Date: %s
-- Return Date: %s
+%s
 - Preferred Class: %s
-- Maximum Budget: %.2f
-- Passengers: %d
+- Budget per Passenger: %s
+- Passengers: %s
 
 Additional Context:
 %s
 
 Please recommend optimal flights considering:
-1. Price within budget (%%..2f per passenger)
+1. Price within the budget per passenger
 2. Convenient departure/arrival times
 3. Airline reliability
 4. Connection efficiency
 5. Overall value
 
 Format recommendations according to the specified JSON structure.`,
-		req.DepartureCity,
-		req.Destination,
+		req.TripType,
+		cityDetails(req.DepartureCity, req.DepartureAirports),
+		cityDetails(req.Destination, req.DestinationAirports),
 		req.DepartureDate.Format(time.RFC3339),
-		req.ReturnDate.Format(time.RFC3339),
+		tripDetails(req),
 		req.PreferredClass,
-		req.MaxBudget,
-		req.Passengers,
-		"No additional context provided",
+		budgetDetails(req),
+		passengerDetails(req.Passengers),
+		additionalContext(req),
 	)
 }
 
+// tripDetails describes the return leg or the multi-city context for the user prompt
+func tripDetails(req models.FlightRecommendationRequest) string {
+	if req.TripType == models.TripMultiCity {
+		return multiCityDetails(req)
+	}
+	if req.ReturnDate == nil {
+		return "- Return Date: none (one-way trip)"
+	}
+
+	details := fmt.Sprintf("- Return Date: %s", req.ReturnDate.Format(time.RFC3339))
+	if req.TripType == models.TripOpenJaw {
+		details += fmt.Sprintf("\n- Return Route: %s to %s", req.ReturnOrigin, req.ReturnDestination)
+	}
+	return details
+}
+
+// cityDetails adds the airports serving a city, e.g. "NYC (JFK, LGA, EWR)"
+func cityDetails(city string, airports []string) string {
+	if len(airports) == 0 {
+		return city
+	}
+	return fmt.Sprintf("%s (%s)", city, strings.Join(airports, ", "))
+}
+
+// budgetDetails describes the per-passenger budget range, e.g. "up to 800.00 EUR"
+func budgetDetails(req models.FlightRecommendationRequest) string {
+	switch {
+	case req.MinBudget.IsPositive() && req.MaxBudget.IsPositive():
+		return fmt.Sprintf("%s to %s", req.MinBudget.Amount(), req.MaxBudget)
+	case req.MinBudget.IsPositive():
+		return fmt.Sprintf("at least %s", req.MinBudget)
+	case req.MaxBudget.IsPositive():
+		return fmt.Sprintf("up to %s", req.MaxBudget)
+	}
+	return "no limit"
+}
+
+// additionalContext lists the preferences that don't have a field of their own
+func additionalContext(req models.FlightRecommendationRequest) string {
+	var lines []string
+	if req.MaxLayovers != nil {
+		lines = append(lines, fmt.Sprintf("- At most %d layovers", *req.MaxLayovers))
+	}
+	if req.PreferredDepartureTime != "" {
+		lines = append(lines, fmt.Sprintf("- Prefers departing in the %s", req.PreferredDepartureTime))
+	}
+	if len(req.DietaryRestrictions) > 0 {
+		lines = append(lines, "- Dietary restrictions:\n"+fenceList(req.DietaryRestrictions))
+	}
+	if bags := baggageDetails(req.Baggage); bags != "" {
+		lines = append(lines, fmt.Sprintf("- Bringing %s in total; prefer fares that include them", bags))
+	}
+	if len(lines) == 0 {
+		return "No additional context provided"
+	}
+	return strings.Join(lines, "\n")
+}
+
+// baggageDetails describes the party's bags, e.g. "2 checked bags, 1 cabin bag"
+func baggageDetails(b models.Baggage) string {
+	var parts []string
+	if b.CheckedBags > 0 {
+		parts = append(parts, utils.Plural(b.CheckedBags, "checked bag", "checked bags"))
+	}
+	if b.CabinBags > 0 {
+		parts = append(parts, utils.Plural(b.CabinBags, "cabin bag", "cabin bags"))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// passengerDetails describes the party, e.g. "2 adults, 2 children (ages 5, 8), 1 infant"
+func passengerDetails(p models.Passengers) string {
+	parts := []string{utils.Plural(max(p.Adults, 1), "adult", "adults")}
+	if p.Children > 0 {
+		children := utils.Plural(p.Children, "child", "children")
+		if len(p.ChildAges) > 0 {
+			ages := make([]string, len(p.ChildAges))
+			for i, age := range p.ChildAges {
+				ages[i] = strconv.Itoa(age)
+			}
+			children += fmt.Sprintf(" (ages %s)", strings.Join(ages, ", "))
+		}
+		parts = append(parts, children)
+	}
+	if p.Infants > 0 {
+		parts = append(parts, utils.Plural(p.Infants, "infant", "infants"))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// multiCityDetails lists every leg and marks the one being recommended
+func multiCityDetails(req models.FlightRecommendationRequest) string {
+	var b strings.Builder
+	if req.DepartureWindowEnd != nil {
+		fmt.Fprintf(&b, "- Latest Departure Date: %s\n", req.DepartureWindowEnd.Format(time.RFC3339))
+	}
+	fmt.Fprintf(&b, "- Current Leg: %d of %d\n- Full Itinerary:", req.LegIndex+1, len(req.Legs))
+	for i, leg := range req.Legs {
+		date := "date to be confirmed"
+		if leg.EarliestDate != nil {
+			date = leg.EarliestDate.Format("2006-01-02")
+		}
+		fmt.Fprintf(&b, "\n  %d. %s to %s on %s", i+1, leg.Origin, leg.Destination, date)
+	}
+	return b.String()
+}
+
 // FlightRecommendationDecoder implements the DecodingStrategy interface
 type FlightRecommendationDecoder struct{}
 
 func (d *FlightRecommendationDecoder) DecodeResponse(content string) (*models.FlightRecommendation, error) {
-	var recommendation models.FlightRecommendation
-	if err := json.Unmarshal([]byte(content), &recommendation); err != nil {
+	var response struct {
+		Recommendations       []pricedFlight `json:"recommendations"`
+		ReturnRecommendations []pricedFlight `json:"return_recommendations"`
+		Reasoning             string         `json:"reasoning"`
+	}
+	if err := decodeStrict(content, &response); err != nil {
 		return nil, fmt.Errorf("failed to decode flight recommendations: %w", err)
 	}
+	recommendation := models.FlightRecommendation{
+		Recommendations:       assumeFlightCurrencies(response.Recommendations),
+		ReturnRecommendations: assumeFlightCurrencies(response.ReturnRecommendations),
+		Reasoning:             response.Reasoning,
+	}
+
+	// Fees are bare numbers in the fare's currency
+	for i := range recommendation.Recommendations {
+		assumeFeeCurrency(&recommendation.Recommendations[i])
+		timesFromSegments(&recommendation.Recommendations[i])
+	}
+	for i := range recommendation.ReturnRecommendations {
+		assumeFeeCurrency(&recommendation.ReturnRecommendations[i])
+		timesFromSegments(&recommendation.ReturnRecommendations[i])
+	}
 
 	// Validate recommendations
 	if err := d.validate(&recommendation); err != nil {
@@ -98,22 +266,73 @@ func (d *FlightRecommendationDecoder) DecodeResponse(content string) (*models.Fl
 	return &recommendation, nil
 }
 
+// pricedFlight is a recommended flight as the model gives it, the price a bare number
+// with its currency alongside
+type pricedFlight struct {
+	models.Flight
+	Currency string `json:"currency"`
+}
+
+// assumeFlightCurrencies gives each flight's price the currency given alongside it
+func assumeFlightCurrencies(priced []pricedFlight) []models.Flight {
+	if priced == nil {
+		return nil
+	}
+	flights := make([]models.Flight, len(priced))
+	for i, p := range priced {
+		flights[i] = p.Flight
+		flights[i].Price = p.Price.Assume(p.Currency)
+	}
+	return flights
+}
+
+// assumeFeeCurrency gives the fare's currency to the fees that have none
+func assumeFeeCurrency(flight *models.Flight) {
+	conditions := flight.Conditions
+	if conditions == nil {
+		return
+	}
+	for _, fee := range []*models.Money{
+		conditions.CheckedBagFee, conditions.CabinBagFee, conditions.RefundFee,
+		conditions.ChangeFee, conditions.SeatSelectionFee,
+	} {
+		if fee != nil {
+			*fee = fee.Assume(flight.Price.Currency)
+		}
+	}
+}
+
+// timesFromSegments takes the flight's departure and arrival from its first and last
+// segments, whose local times carry their airports' offsets. Models often give the
+// flight's own times in UTC, which puts evening departures and overnight arrivals on
+// the wrong day.
+func timesFromSegments(flight *models.Flight) {
+	if len(flight.Segments) == 0 {
+		return
+	}
+	if first := flight.Segments[0]; !first.DepartureTime.IsZero() {
+		flight.DepartureTime = first.DepartureTime
+	}
+	if last := flight.Segments[len(flight.Segments)-1]; !last.ArrivalTime.IsZero() {
+		flight.ArrivalTime = last.ArrivalTime
+	}
+}
+
 func (d *FlightRecommendationDecoder) validate(rec *models.FlightRecommendation) error {
 	if len(rec.Recommendations) == 0 {
 		return errors.New("no flight recommendations provided")
 	}
 
 	for i, flight := range rec.Recommendations {
-		if flight.Airline == "" {
-			return fmt.Errorf("missing airline for recommendation %d", i+1)
-		}
-		if flight.FlightNumber == "" {
-			return fmt.Errorf("missing flight number for recommendation %d", i+1)
+		if err := validateFlight(flight); err != nil {
+			return fmt.Errorf("%w for recommendation %d", err, i+1)
 		}
-		if flight.Price <= 0 {
-			return fmt.Errorf("invalid price for recommendation %d", i+1)
+	}
+	// Return flights are optional; the outbound ones can be booked alone
+	for i, flight := range rec.ReturnRecommendations {
+		if err := validateFlight(flight); err != nil {
+			return fmt.Errorf("%w for return recommendation %d", err, i+1)
 		}
-		// Add more validation as needed
 	}
 
 	if rec.Reasoning == "" {
@@ -122,3 +341,19 @@ func (d *FlightRecommendationDecoder) validate(rec *models.FlightRecommendation)
 
 	return nil
 }
+
+func validateFlight(flight models.Flight) error {
+	if flight.Airline == "" {
+		return errors.New("missing airline")
+	}
+	if flight.FlightNumber == "" {
+		return errors.New("missing flight number")
+	}
+	if !flight.Price.IsPositive() {
+		return errors.New("invalid price")
+	}
+	if c := flight.Conditions; c != nil && (c.CheckedBags < 0 || c.CabinBags < 0) {
+		return errors.New("invalid baggage allowance")
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"travel-agent/internal/models"
+)
+
+// HotelRecommendationStrategy implements the PromptStrategy interface
+type HotelRecommendationStrategy struct{}
+
+func (s *HotelRecommendationStrategy) GetSystemPrompt() string {
+	return `You are an AI Hotel Recommendation Assistant. Your task is to recommend hotels at the traveler's destination for the length of their stay, in neighborhoods that suit what they plan to do, and explain your reasoning.
+
+Output must be a valid JSON object with this exact structure:
+{
+    "hotels": [
+        {
+            "name": "string",
+            "neighborhood": "string",
+            "address": "string",
+            "stars": number,
+            "nightly_rate": number,
+            "currency": "ISO 4217 code",
+            "amenities": ["string"],
+            "neighborhood_tags": ["string"]
+        }
+    ],
+    "reasoning": "string explaining why these hotels were recommended"
+}
+
+Recommendation Rules:
+1. Recommend 3 to 5 hotels in the destination city, in different neighborhoods when that gives the traveler a real choice
+2. Give nightly_rate as the price of one room per night, preferably in the traveler's currency, with its ISO 4217 code in currency
+3. List in neighborhood_tags what the neighborhood is known for, in a word or two each (e.g. "museums", "nightlife", "beach", "restaurants", "shopping", "parks")
+4. Favor neighborhoods close to the traveler's planned activities
+5. With dietary restrictions, favor hotels whose restaurants or surroundings cater to them and mention it in amenities
+6. Only recommend hotels that can host every guest in the requested number of rooms
+7. ` + fenceRule + `
+
+Return only the JSON object, no additional text or explanation.`
+}
+
+func (s *HotelRecommendationStrategy) GetUserPrompt(req models.HotelRecommendationRequest) string {
+	return fmt.Sprintf(`Recommend hotels for this stay:
+
+STAY DETAILS:
+- City: %s
+- Check-in: %s
+- Check-out: %s
+- Guests: %s
+- Rooms: %d
+- Currency: %s
+
+Additional Context:
+%s
+
+Format recommendations according to the specified JSON structure.`,
+		req.City,
+		req.CheckIn.Format("2006-01-02"),
+		req.CheckOut.Format("2006-01-02"),
+		passengerDetails(req.Guests),
+		max(req.Rooms, 1),
+		req.Currency,
+		stayContext(req),
+	)
+}
+
+// stayContext lists the activities and dietary restrictions the hotel should suit
+func stayContext(req models.HotelRecommendationRequest) string {
+	var lines []string
+	if len(req.Activities) > 0 {
+		lines = append(lines, "- Planned activities:\n"+fenceList(req.Activities))
+	}
+	if len(req.DietaryRestrictions) > 0 {
+		lines = append(lines, "- Dietary restrictions:\n"+fenceList(req.DietaryRestrictions))
+	}
+	if len(lines) == 0 {
+		return "No additional context provided"
+	}
+	return strings.Join(lines, "\n")
+}
+
+// HotelRecommendationDecoder implements the DecodingStrategy interface
+type HotelRecommendationDecoder struct{}
+
+func (d *HotelRecommendationDecoder) DecodeResponse(content string) (*models.HotelRecommendation, error) {
+	// The rate is a bare number with its currency alongside
+	var response struct {
+		Hotels []struct {
+			models.Hotel
+			Currency string `json:"currency"`
+		} `json:"hotels"`
+		Reasoning string `json:"reasoning"`
+	}
+	if err := decodeStrict(content, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode hotel recommendations: %w", err)
+	}
+	recommendation := models.HotelRecommendation{Reasoning: response.Reasoning}
+	for _, priced := range response.Hotels {
+		hotel := priced.Hotel
+		hotel.NightlyRate = hotel.NightlyRate.Assume(priced.Currency)
+		recommendation.Hotels = append(recommendation.Hotels, hotel)
+	}
+
+	if err := d.validate(&recommendation); err != nil {
+		return nil, fmt.Errorf("invalid hotel recommendations: %w", err)
+	}
+
+	return &recommendation, nil
+}
+
+func (d *HotelRecommendationDecoder) validate(rec *models.HotelRecommendation) error {
+	if len(rec.Hotels) == 0 {
+		return errors.New("no hotel recommendations provided")
+	}
+
+	for i, hotel := range rec.Hotels {
+		if hotel.Name == "" {
+			return fmt.Errorf("missing name for hotel %d", i+1)
+		}
+		if !hotel.NightlyRate.IsPositive() {
+			return fmt.Errorf("invalid nightly rate for hotel %d", i+1)
+		}
+	}
+
+	if rec.Reasoning == "" {
+		return errors.New("missing recommendation reasoning")
+	}
+
+	return nil
+}
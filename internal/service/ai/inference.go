@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"time"
 	"travel-agent/internal/models"
@@ -22,6 +23,23 @@ const (
 type InferenceEngine[T models.TravelOutput, R models.TravelInput] struct {
 	apiKey     string
 	httpClient *http.Client
+	payloads   *utils.PayloadLogger
+}
+
+// engineSettings holds what EngineOptions configure
+type engineSettings struct {
+	payloads *utils.PayloadLogger
+}
+
+// EngineOption configures an InferenceEngine
+type EngineOption func(*engineSettings)
+
+// WithPayloadLogging logs the provider requests and responses the logger samples.
+// Without it payloads are never logged.
+func WithPayloadLogging(logger *utils.PayloadLogger) EngineOption {
+	return func(s *engineSettings) {
+		s.payloads = logger
+	}
 }
 
 // ResponseFormat the format that the response must adhere to
@@ -29,16 +47,22 @@ type ResponseFormat struct {
 	Type string `json:"type"`
 }
 
-func NewInferenceEngine[T models.TravelOutput, R models.TravelInput](apiKey string) (*InferenceEngine[T, R], error) {
+func NewInferenceEngine[T models.TravelOutput, R models.TravelInput](apiKey string, opts ...EngineOption) (*InferenceEngine[T, R], error) {
 	if apiKey == "" {
 		return nil, errors.New("AIProvider API key is required")
 	}
 
+	var settings engineSettings
+	for _, opt := range opts {
+		opt(&settings)
+	}
+
 	return &InferenceEngine[T, R]{
 		apiKey: apiKey,
 		httpClient: &http.Client{
 			Timeout: timeout,
 		},
+		payloads: settings.payloads,
 	}, nil
 }
 
@@ -85,6 +109,12 @@ type PromptStrategy[R any] interface {
 	GetUserPrompt(req R) string
 }
 
+// ExampleProvider is implemented by prompt strategies that send few-shot examples
+// between the system prompt and the user prompt
+type ExampleProvider[R any] interface {
+	GetExampleMessages(req R) []AIProviderMsg
+}
+
 type DecodingStrategy[T any] interface {
 	DecodeResponse(content string) (*T, error)
 }
@@ -99,33 +129,41 @@ func (p *InferenceEngine[T, R]) ProcessRequest(
 	systemPrompt := promptStrategy.GetSystemPrompt()
 	userPrompt := promptStrategy.GetUserPrompt(request)
 
+	// Build the conversation, injecting few-shot examples if the strategy provides them
+	messages := []AIProviderMsg{{Role: "system", Content: systemPrompt}}
+	if exampleProvider, ok := promptStrategy.(ExampleProvider[R]); ok {
+		messages = append(messages, exampleProvider.GetExampleMessages(request)...)
+	}
+	messages = append(messages, AIProviderMsg{Role: "user", Content: userPrompt})
+
 	// Prepare request
 	aiReq := AIProviderRequest{
-		Model: model,
-		Messages: []AIProviderMsg{
-			{Role: "system", Content: systemPrompt},
-			{Role: "user", Content: userPrompt},
-		},
+		Model:    model,
+		Messages: messages,
 		ResponseFormat: ResponseFormat{
 			Type: "json_object",
 		},
 	}
 
-	// Make request
-	resp, err := p.makeRequest(ctx, aiReq)
+	// Make request, logging the payloads of sampled calls
+	logPayloads := p.payloads.Sampled(ctx)
+	start := time.Now()
+	resp, err := p.makeRequest(ctx, aiReq, logPayloads)
 	if err != nil {
+		slog.WarnContext(ctx, "provider request failed", "error", err, "duration_ms", time.Since(start).Milliseconds())
 		return nil, err
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
 			// Log the error but don't override any existing error return
-			fmt.Printf("error closing response body: %v\n", err)
+			slog.WarnContext(ctx, "error closing response body", "error", err)
 		}
 	}()
 
-	// Log response if enabled
-	if err := utils.LogResponseWithoutConsuming(resp); err != nil {
-		fmt.Printf("failed to log response: %v\n", err)
+	if logPayloads {
+		if err := p.payloads.LogResponseWithoutConsuming(ctx, resp); err != nil {
+			slog.WarnContext(ctx, "failed to log response", "error", err)
+		}
 	}
 
 	// Parse response
@@ -134,8 +172,17 @@ func (p *InferenceEngine[T, R]) ProcessRequest(
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
+	slog.DebugContext(ctx, "provider call",
+		"model", model,
+		"status", resp.StatusCode,
+		"duration_ms", time.Since(start).Milliseconds(),
+		"prompt_tokens", aiResp.Usage.PromptTokens,
+		"completion_tokens", aiResp.Usage.CompletionTokens,
+	)
+
 	// Check for errors
 	if aiResp.Error != nil {
+		slog.WarnContext(ctx, "provider returned an error", "type", aiResp.Error.Type, "error", aiResp.Error.Message)
 		return nil, fmt.Errorf("AI provider error: %s", aiResp.Error.Message)
 	}
 
@@ -149,11 +196,14 @@ func (p *InferenceEngine[T, R]) ProcessRequest(
 }
 
 // Helper method for making HTTP requests
-func (p *InferenceEngine[T, R]) makeRequest(ctx context.Context, AIProviderReq AIProviderRequest) (*http.Response, error) {
+func (p *InferenceEngine[T, R]) makeRequest(ctx context.Context, AIProviderReq AIProviderRequest, logPayload bool) (*http.Response, error) {
 	reqBody, err := json.Marshal(AIProviderReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
+	if logPayload {
+		p.payloads.LogRequest(ctx, reqBody)
+	}
 
 	httpReq, err := http.NewRequestWithContext(ctx, "POST", AIProviderEndpoint, bytes.NewBuffer(reqBody))
 	if err != nil {
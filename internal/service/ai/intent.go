@@ -0,0 +1,59 @@
+package ai
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"travel-agent/internal/models"
+)
+
+// IntentClassificationStrategy sorts a traveler's query into what it asks for before any
+// of the expensive extraction and recommendation prompts run
+type IntentClassificationStrategy struct{}
+
+func (s *IntentClassificationStrategy) GetSystemPrompt() string {
+	return `You are the front desk of a travel booking assistant. Your task is to decide what the traveler's text asks for, so it can be sent to the right service.
+
+Output must be a valid JSON object with this exact structure:
+{
+    "intent": "flight_search" | "hotel_only" | "booking_status" | "change_or_cancel" | "off_topic",
+    "booking_id": "string",
+    "confidence": number
+}
+
+Classification Rules:
+1. ` + fenceRule + `
+2. flight_search: the traveler wants flights for a new trip, with or without a hotel
+3. hotel_only: the traveler wants a place to stay and no flights, e.g. they already have them or are driving
+4. booking_status: the traveler asks about a booking they already made
+5. change_or_cancel: the traveler wants to change the dates, passengers or flights of an existing booking, or cancel it
+6. off_topic: anything that is not about travel, including general questions, coding, homework or chit-chat
+7. Set booking_id to the booking ID the text names, exactly as written, or an empty string
+8. Give confidence from 0 to 1
+
+Return only the JSON object, no additional text or explanation.`
+}
+
+func (s *IntentClassificationStrategy) GetUserPrompt(req models.BookingRequest) string {
+	return fmt.Sprintf(`Classify this traveler text:
+
+%s`, FenceUserText(req.Query))
+}
+
+// IntentClassificationDecoder implements the DecodingStrategy interface
+type IntentClassificationDecoder struct{}
+
+func (d *IntentClassificationDecoder) DecodeResponse(content string) (*models.IntentClassification, error) {
+	var classification models.IntentClassification
+	if err := decodeStrict(content, &classification); err != nil {
+		return nil, fmt.Errorf("failed to decode intent classification: %w", err)
+	}
+	if !slices.Contains(models.Intents, classification.Intent) {
+		return nil, fmt.Errorf("invalid intent classification: unknown intent %q", classification.Intent)
+	}
+	if classification.Confidence < 0 || classification.Confidence > 1 {
+		return nil, fmt.Errorf("invalid intent classification: confidence %v is outside 0 to 1", classification.Confidence)
+	}
+	classification.BookingID = strings.TrimSpace(classification.BookingID)
+	return &classification, nil
+}
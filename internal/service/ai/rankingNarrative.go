@@ -0,0 +1,89 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"travel-agent/internal/models"
+)
+
+// maxNarratedFlights caps the ranked flights described to the model
+const maxNarratedFlights = 5
+
+// RankingNarrativeStrategy asks the model to explain a ranking computed by the booking
+// service. The ranked flights are passed in the request's Offers, best first.
+type RankingNarrativeStrategy struct{}
+
+func (s *RankingNarrativeStrategy) GetSystemPrompt() string {
+	return `You are an AI Travel Assistant explaining flight options to a traveler. The flights have already been ranked by a scoring engine; your task is to explain that ranking in plain language.
+
+Output must be a valid JSON object with this exact structure:
+{
+    "reasoning": "string explaining the ranking"
+}
+
+Narrative Rules:
+1. Never change the order, the scores or any flight details
+2. Explain why the first flight ranks best, using the score breakdown
+3. Mention the main trade-off against the runner-up options (e.g. cheaper but with a stop)
+4. Quote prices exactly as given, with their currency
+5. Keep it under 120 words and address the traveler directly
+
+Return only the JSON object, no additional text or explanation.`
+}
+
+func (s *RankingNarrativeStrategy) GetUserPrompt(req models.FlightRecommendationRequest) string {
+	return fmt.Sprintf(`Explain this flight ranking:
+
+TRIP:
+- Route: %s to %s
+- Departure Date: %s
+- Passengers: %s
+
+RANKED FLIGHTS (best first):
+%s
+
+Format the explanation according to the specified JSON structure.`,
+		req.DepartureCity,
+		req.Destination,
+		req.DepartureDate.Format(time.RFC3339),
+		passengerDetails(req.Passengers),
+		rankedFlightDetails(req.Offers),
+	)
+}
+
+// rankedFlightDetails lists each flight with its score and factor breakdown
+func rankedFlightDetails(flights []models.Flight) string {
+	if len(flights) > maxNarratedFlights {
+		flights = flights[:maxNarratedFlights]
+	}
+
+	var b strings.Builder
+	for i, flight := range flights {
+		price := flight.Price
+		if flight.Converted != nil {
+			price = flight.Converted.Price
+		}
+		fmt.Fprintf(&b, "%d. %s %s, %s, score %.2f\n", i+1, flight.Airline, flight.FlightNumber, price, flight.RecommendationScore)
+		for _, factor := range flight.ScoreBreakdown {
+			fmt.Fprintf(&b, "   - %s: %.2f of %.2f (%s)\n", factor.Factor, factor.Contribution, factor.Weight, factor.Detail)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// RankingNarrativeDecoder reads the narrative into a FlightRecommendation's Reasoning
+type RankingNarrativeDecoder struct{}
+
+func (d *RankingNarrativeDecoder) DecodeResponse(content string) (*models.FlightRecommendation, error) {
+	var narrative struct {
+		Reasoning string `json:"reasoning"`
+	}
+	if err := decodeStrict(content, &narrative); err != nil {
+		return nil, fmt.Errorf("failed to decode ranking narrative: %w", err)
+	}
+	if strings.TrimSpace(narrative.Reasoning) == "" {
+		return nil, fmt.Errorf("ranking narrative is empty")
+	}
+	return &models.FlightRecommendation{Reasoning: narrative.Reasoning}, nil
+}
@@ -0,0 +1,132 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"travel-agent/internal/models"
+)
+
+// SuggestionStrategy asks for day-by-day activity and dining suggestions at the destination
+type SuggestionStrategy struct{}
+
+func (s *SuggestionStrategy) GetSystemPrompt() string {
+	return `You are an AI Travel Assistant planning what a traveler could do and where they could eat during their stay. Your task is to suggest activities and dining for each day, close to where the traveler stays, and explain your choices.
+
+Output must be a valid JSON object with this exact structure:
+{
+    "days": [
+        {
+            "day": number,
+            "activities": [
+                {
+                    "name": "string",
+                    "description": "string",
+                    "neighborhood": "string",
+                    "time_of_day": "morning|afternoon|evening|night",
+                    "interest": "the traveler's activity it answers"
+                }
+            ],
+            "dining": [
+                {
+                    "name": "string",
+                    "description": "string",
+                    "neighborhood": "string",
+                    "time_of_day": "morning|afternoon|evening|night",
+                    "cuisine": "string",
+                    "dietary_options": ["string"]
+                }
+            ]
+        }
+    ],
+    "reasoning": "string explaining the plan"
+}
+
+Suggestion Rules:
+1. Give one entry per day of the stay, numbered from 1, with 2 or 3 activities and 2 dining suggestions each
+2. Base the activities on the traveler's stated activities and spread them over the days; without any, suggest the city's highlights
+3. Keep each day's suggestions close to one another and, when known, to the neighborhood the traveler stays in
+4. Every dining suggestion must cater to all the dietary restrictions; list the ones it caters to in dietary_options using the traveler's own words
+5. Suit the suggestions to the party; with children, favor family-friendly places
+6. Only suggest real places in the destination city and never repeat one
+7. ` + fenceRule + `
+
+Return only the JSON object, no additional text or explanation.`
+}
+
+func (s *SuggestionStrategy) GetUserPrompt(req models.SuggestionRequest) string {
+	neighborhood := req.Neighborhood
+	if neighborhood == "" {
+		neighborhood = "not decided yet"
+	}
+	return fmt.Sprintf(`Suggest activities and dining for this stay:
+
+STAY DETAILS:
+- City: %s
+- Staying in: %s
+- First Day: %s
+- Days: %d
+- Party: %s
+
+Additional Context:
+%s
+
+Format the suggestions according to the specified JSON structure.`,
+		req.City,
+		neighborhood,
+		req.StartDate.Format("2006-01-02 (Monday)"),
+		req.Days,
+		passengerDetails(req.Guests),
+		suggestionContext(req),
+	)
+}
+
+// suggestionContext lists the activities and dietary restrictions to plan around
+func suggestionContext(req models.SuggestionRequest) string {
+	var lines []string
+	if len(req.Activities) > 0 {
+		lines = append(lines, "- Activities:\n"+fenceList(req.Activities))
+	}
+	if len(req.DietaryRestrictions) > 0 {
+		lines = append(lines, "- Dietary restrictions:\n"+fenceList(req.DietaryRestrictions))
+	}
+	if len(lines) == 0 {
+		return "No additional context provided"
+	}
+	return strings.Join(lines, "\n")
+}
+
+// SuggestionDecoder implements the DecodingStrategy interface
+type SuggestionDecoder struct{}
+
+func (d *SuggestionDecoder) DecodeResponse(content string) (*models.SuggestionPlan, error) {
+	var plan models.SuggestionPlan
+	if err := decodeStrict(content, &plan); err != nil {
+		return nil, fmt.Errorf("failed to decode suggestions: %w", err)
+	}
+
+	if err := d.validate(&plan); err != nil {
+		return nil, fmt.Errorf("invalid suggestions: %w", err)
+	}
+
+	return &plan, nil
+}
+
+func (d *SuggestionDecoder) validate(plan *models.SuggestionPlan) error {
+	if len(plan.Days) == 0 {
+		return errors.New("no days suggested")
+	}
+
+	for i, day := range plan.Days {
+		if day.Day < 1 {
+			return fmt.Errorf("invalid day number for entry %d", i+1)
+		}
+		for _, suggestion := range append(append([]models.Suggestion(nil), day.Activities...), day.Dining...) {
+			if suggestion.Name == "" {
+				return fmt.Errorf("missing name in a suggestion for day %d", day.Day)
+			}
+		}
+	}
+
+	return nil
+}
@@ -3,15 +3,25 @@ package ai
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 	"travel-agent/internal/models"
+	"travel-agent/internal/service/dates"
 )
 
 // ExtractionPromptStrategy handles the extraction of travel parameters from natural language
-type ExtractionPromptStrategy struct{}
+type ExtractionPromptStrategy struct {
+	// Examples is an optional few-shot library; when nil no examples are sent
+	Examples *ExampleLibrary
+	// MaxExamples caps the number of examples sent with a request
+	MaxExamples int
+	// ExampleTokenBudget caps the estimated tokens spent on examples
+	ExampleTokenBudget int
+}
 
 // Make ExtractionPromptStrategy implement PromptStrategy[ExtractionRequest]
 var _ PromptStrategy[models.BookingRequest] = (*ExtractionPromptStrategy)(nil) // Type assertion for interface compliance
+var _ ExampleProvider[models.BookingRequest] = (*ExtractionPromptStrategy)(nil)
 
 // GetSystemPrompt returns the system prompt for parameter extraction
 func (s *ExtractionPromptStrategy) GetSystemPrompt() string {
@@ -19,35 +29,77 @@ func (s *ExtractionPromptStrategy) GetSystemPrompt() string {
 
 Output must be a valid JSON object with this exact structure:
 {
+    "trip_type": "",
     "departure_city": "city name",
     "destination": "city name",
     "departure_date": null,
     "return_date": null,
+    "departure_date_text": "",
+    "return_date_text": "",
+    "return_origin": "",
+    "return_destination": "",
+    "legs": [
+        {
+            "origin": "city name",
+            "destination": "city name",
+            "earliest_date": null,
+            "latest_date": null,
+            "earliest_date_text": "",
+            "latest_date_text": "",
+            "stay_days": null
+        }
+    ],
+    "passengers": {
+        "adults": 1,
+        "children": 0,
+        "child_ages": [],
+        "infants": 0
+    },
     "preferences": {
         "budget_range": {
             "min": null,
-            "max": null
+            "max": {"amount": 0, "currency": ""},
+            "scope": ""
         },
         "travel_class": "",
+        "departure_time": "",
         "activities": [],
-        "dietary_restrictions": []
-    }
+        "dietary_restrictions": [],
+        "baggage": {"checked_bags": 0, "cabin_bags": 0}
+    },
+    "ambiguous_fields": []
 }
 
 Extraction Rules:
 1. Use null for missing or uncertain values
 2. Format dates as RFC3339 (e.g., "2024-01-15T12:00:00Z")
 3. Use empty arrays [] for missing lists
-4. Convert prices to numbers without currency symbols
+4. Give budget amounts as numbers without currency symbols, with the ISO 4217 code of their currency ("300 euros" is {"amount": 300, "currency": "EUR"}); leave the currency empty when the traveler only writes "$" or names no currency; set budget_range.scope to "total" when the budget covers the whole party ("under $3000 for the four of us") and to "per_person" when it is for each traveler ("$500 each"), leaving it empty otherwise
 5. Normalize city names to official names
 6. Extract both explicit and implicit requirements
 7. Omit unreferenced fields
+8. List in ambiguous_fields the name of every top-level field whose value you had to guess
+9. Set trip_type to "one_way" only when the traveler says so or clearly needs no return flight ("one-way", "no return", "relocating"); return_date must then be null
+10. Set trip_type to "round_trip" when a return date or trip length is given
+11. Set trip_type to "open_jaw" when the return leaves from, or arrives at, a different city than the outbound; put those cities in return_origin and return_destination
+12. Leave trip_type empty when it cannot be determined
+13. Set trip_type to "multi_city" when the traveler visits more than one destination, and list every flight in legs in travel order, including the flight home; use an empty legs array otherwise
+14. For each leg, use earliest_date and latest_date for the departure window and stay_days for the time spent at its destination
+15. Copy the traveler's date phrases verbatim into departure_date_text and return_date_text (e.g. "next Friday", "March 10th", "back on the 3rd", "for a 3-day trip"), and each leg's into its earliest_date_text and latest_date_text (e.g. "the Friday after")
+16. Resolve relative dates against the current date and time given in the request, in the traveler's time zone
+17. Count everyone traveling, including the traveler: children are 2 to 11 years old, infants are under 2, everyone else is an adult
+18. List the children's ages in child_ages when given; leave it empty when they are not ("my two kids" is 2 children with unknown ages)
+19. Assume a single adult when the traveler does not mention anyone else
+20. Set preferences.departure_time to "morning", "afternoon", "evening" or "night" when the traveler says when they want to leave ("an early flight" is morning, "a red-eye" is night); leave it empty otherwise
+21. Set preferences.baggage to the bags the whole party is bringing ("two checked bags" is checked_bags 2, "a suitcase each" for two people is checked_bags 2, "carry-on only" is checked_bags 0); leave the counts 0 when bags are not mentioned
+22. ` + fenceRule + `
 
 Return only the JSON object, no additional text.`
 }
 
 // GetUserPrompt formats the user prompt with the request details
 func (s *ExtractionPromptStrategy) GetUserPrompt(req models.BookingRequest) string {
+	now, location := RequestClock(req)
 	return fmt.Sprintf(`Extract travel parameters from this request:
 
 REQUEST TEXT:
@@ -56,6 +108,9 @@ REQUEST TEXT:
 BOOKING DEADLINE:
 %s
 
+CURRENT DATE AND TIME:
+%s (traveler time zone: %s)
+
 Required Parameters:
 - Departure and destination cities
 - Travel dates
@@ -63,33 +118,335 @@ Required Parameters:
 - Travel preferences and requirements
 
 Format as specified JSON structure.`,
-		req.Query,
-		req.Deadline.Format(time.RFC3339))
+		FenceUserText(req.Query),
+		req.Deadline.Format(time.RFC3339),
+		now.Format("Monday, 2006-01-02T15:04:05Z07:00"),
+		location)
+}
+
+// RequestClock returns the reference time and time zone of a request. Relative dates
+// are resolved against them; invalid or missing values fall back to now and UTC.
+func RequestClock(req models.BookingRequest) (time.Time, *time.Location) {
+	location := time.UTC
+	if req.TimeZone != "" {
+		if loc, err := time.LoadLocation(req.TimeZone); err == nil {
+			location = loc
+		}
+	}
+
+	now := time.Now()
+	if req.ReferenceTime != nil {
+		now = *req.ReferenceTime
+	}
+
+	return now.In(location), location
+}
+
+// GetExampleMessages renders the examples most relevant to the request as
+// user/assistant message pairs, stopping once the token budget is spent
+func (s *ExtractionPromptStrategy) GetExampleMessages(req models.BookingRequest) []AIProviderMsg {
+	maxExamples := s.MaxExamples
+	if maxExamples <= 0 {
+		maxExamples = DefaultMaxExamples
+	}
+	budget := s.ExampleTokenBudget
+	if budget <= 0 {
+		budget = DefaultExampleTokenBudget
+	}
+
+	var messages []AIProviderMsg
+	selected := 0
+	for _, example := range s.Examples.Rank(req.Query) {
+		if selected == maxExamples {
+			break
+		}
+
+		output, err := json.Marshal(example.Output)
+		if err != nil {
+			continue
+		}
+		referenceTime := example.Deadline
+		if example.ReferenceTime != nil {
+			referenceTime = *example.ReferenceTime
+		}
+		userPrompt := s.GetUserPrompt(models.BookingRequest{
+			Query:         example.Query,
+			Deadline:      example.Deadline,
+			TimeZone:      example.TimeZone,
+			ReferenceTime: &referenceTime,
+		})
+
+		cost := EstimateTokens(userPrompt) + EstimateTokens(string(output))
+		if cost > budget {
+			continue
+		}
+		budget -= cost
+		selected++
+
+		messages = append(messages,
+			AIProviderMsg{Role: "user", Content: userPrompt},
+			AIProviderMsg{Role: "assistant", Content: string(output)},
+		)
+	}
+
+	return messages
 }
 
 // ExtractionDecodingStrategy implements DecodingStrategy for travel parameters
-type ExtractionDecodingStrategy struct{}
+type ExtractionDecodingStrategy struct {
+	// AllowIncomplete accepts parameters with missing fields so they can be
+	// asked for in a follow-up. Invalid dates are cleared instead of rejected.
+	AllowIncomplete bool
+	// ReferenceTime and Location anchor relative dates. They default to now and UTC.
+	ReferenceTime time.Time
+	Location      *time.Location
+}
 
-// validate checks if the required fields are present and valid
-func (d *ExtractionDecodingStrategy) validate(params *models.TravelParameters) error {
+// resolver returns the date resolver for the decoder's reference time and location
+func (d *ExtractionDecodingStrategy) resolver() *dates.Resolver {
+	now := d.ReferenceTime
+	if now.IsZero() {
+		now = time.Now()
+	}
+	return dates.NewResolver(now, d.Location)
+}
+
+// resolveDates replaces the model's dates with deterministic resolutions of the
+// traveler's phrases when possible, and records where each date came from
+func (d *ExtractionDecodingStrategy) resolveDates(params *models.TravelParameters, resolver *dates.Resolver) {
+	params.ResolvedDates = nil
+
+	params.DepartureDate = resolveDate(params, FieldDepartureDate, params.DepartureDateText, params.DepartureDate, resolver, resolver.Now())
+
+	anchor := resolver.Now()
+	if params.DepartureDate != nil {
+		anchor = *params.DepartureDate
+	}
+	params.ReturnDate = resolveDate(params, FieldReturnDate, params.ReturnDateText, params.ReturnDate, resolver, anchor)
+
+	// Each leg's phrases are relative to the leg before it ("the Friday after")
+	anchor = resolver.Now()
+	for i := range params.Legs {
+		leg := &params.Legs[i]
+		leg.EarliestDate = resolveDate(params, LegDateField(i, "earliest_date"), leg.EarliestDateText, leg.EarliestDate, resolver, anchor)
+		if leg.EarliestDate != nil {
+			anchor = *leg.EarliestDate
+		}
+		leg.LatestDate = resolveDate(params, LegDateField(i, "latest_date"), leg.LatestDateText, leg.LatestDate, resolver, anchor)
+	}
+}
+
+// LegDateField names a date of the leg at index i in ResolvedDates, e.g. "legs[0].earliest_date"
+func LegDateField(i int, field string) string {
+	return fmt.Sprintf("%s[%d].%s", FieldLegs, i, field)
+}
+
+// resolveDate resolves a single date, preferring the resolver over the model
+func resolveDate(
+	params *models.TravelParameters,
+	field, text string,
+	modelDate *time.Time,
+	resolver *dates.Resolver,
+	anchor time.Time,
+) *time.Time {
+	if resolved, ok := resolver.ResolveFrom(text, anchor); ok {
+		params.ResolvedDates = append(params.ResolvedDates, models.DateResolution{
+			Field:    field,
+			Text:     text,
+			Resolved: resolved,
+			Source:   models.DateSourceResolver,
+		})
+		return &resolved
+	}
+
+	if modelDate == nil {
+		return nil
+	}
+
+	// The model gives dates at midnight UTC; keep its calendar date rather than
+	// shifting it into the previous day west of Greenwich
+	year, month, day := modelDate.Date()
+	local := time.Date(year, month, day, 12, 0, 0, 0, resolver.Now().Location())
+	params.ResolvedDates = append(params.ResolvedDates, models.DateResolution{
+		Field:    field,
+		Text:     text,
+		Resolved: local,
+		Source:   models.DateSourceModel,
+	})
+	return &local
+}
+
+// isPastDate reports whether the date falls on a day before the reference date
+func isPastDate(date time.Time, resolver *dates.Resolver) bool {
+	today := resolver.Today()
+	local := date.In(today.Location())
+	return time.Date(local.Year(), local.Month(), local.Day(), 12, 0, 0, 0, today.Location()).Before(today)
+}
+
+// Names of the travel parameters a booking cannot proceed without
+const (
+	FieldDepartureCity = "departure_city"
+	FieldDestination   = "destination"
+	FieldDepartureDate = "departure_date"
+	FieldReturnDate    = "return_date"
+	FieldLegs          = "legs"
+)
+
+// MissingTravelParameters returns the required fields that have not been extracted yet.
+// A return date is required unless the trip is known to be one-way.
+func MissingTravelParameters(params *models.TravelParameters) []string {
+	var missing []string
 	if params.DepartureCity == "" {
-		return fmt.Errorf("departure city is required")
+		missing = append(missing, FieldDepartureCity)
 	}
 	if params.Destination == "" {
-		return fmt.Errorf("destination is required")
+		missing = append(missing, FieldDestination)
+	}
+	if params.DepartureDate == nil {
+		missing = append(missing, FieldDepartureDate)
+	}
+	if params.TripType == models.TripMultiCity {
+		if !legsComplete(params.Legs) {
+			missing = append(missing, FieldLegs)
+		}
+		return missing
+	}
+	if params.ReturnDate == nil && params.TripType != models.TripOneWay {
+		missing = append(missing, FieldReturnDate)
+	}
+	return missing
+}
+
+// legsComplete reports whether a multi-city trip has at least two fully described legs
+func legsComplete(legs []models.TripLeg) bool {
+	if len(legs) < 2 {
+		return false
+	}
+	for _, leg := range legs {
+		if leg.Origin == "" || leg.Destination == "" || leg.EarliestDate == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeLegs fills leg dates implied by the stay length of the previous leg and
+// mirrors the first leg into the top-level fields
+func normalizeLegs(params *models.TravelParameters) {
+	for i := 1; i < len(params.Legs); i++ {
+		prev := params.Legs[i-1]
+		if params.Legs[i].EarliestDate == nil && prev.EarliestDate != nil && prev.StayDays != nil {
+			next := prev.EarliestDate.AddDate(0, 0, *prev.StayDays)
+			params.Legs[i].EarliestDate = &next
+		}
+		if params.Legs[i].Origin == "" {
+			params.Legs[i].Origin = prev.Destination
+		}
 	}
 
+	if len(params.Legs) == 0 {
+		return
+	}
+	first := params.Legs[0]
+	if params.DepartureCity == "" {
+		params.DepartureCity = first.Origin
+	}
+	if params.Destination == "" {
+		params.Destination = first.Destination
+	}
 	if params.DepartureDate == nil {
-		return fmt.Errorf("departure date is required")
+		params.DepartureDate = first.EarliestDate
 	}
+	params.ReturnDate = nil
+}
 
-	if params.ReturnDate == nil {
-		return fmt.Errorf("return date is required")
+// normalizeTripType reconciles the trip type with the extracted dates and cities
+func normalizeTripType(params *models.TravelParameters) {
+	if params.TripType != models.TripMultiCity {
+		params.Legs = nil
+	}
+
+	switch params.TripType {
+	case models.TripOneWay:
+		params.ReturnDate = nil
+		params.ReturnOrigin = ""
+		params.ReturnDestination = ""
+	case models.TripOpenJaw:
+		if params.ReturnOrigin == "" {
+			params.ReturnOrigin = params.Destination
+		}
+		if params.ReturnDestination == "" {
+			params.ReturnDestination = params.DepartureCity
+		}
+		if params.ReturnOrigin == params.Destination && params.ReturnDestination == params.DepartureCity {
+			params.TripType = models.TripRoundTrip
+		}
+	case models.TripRoundTrip:
+		params.ReturnOrigin = ""
+		params.ReturnDestination = ""
+	case models.TripMultiCity:
+		params.ReturnOrigin = ""
+		params.ReturnDestination = ""
+		normalizeLegs(params)
+		return
+	case "":
+		if params.ReturnDate != nil {
+			params.TripType = models.TripRoundTrip
+		}
+	default:
+		// Unknown values are treated as undetermined
+		params.TripType = ""
+		normalizeTripType(params)
+	}
+}
+
+// MaxPartySize is the largest party that can be booked together; larger groups
+// need a group booking with the airline
+const MaxPartySize = 9
+
+// normalizePassengers defaults to a single adult and moves children whose ages
+// put them in another passenger type
+func normalizePassengers(p *models.Passengers) {
+	p.Adults = max(p.Adults, 0)
+	p.Children = max(p.Children, 0)
+	p.Infants = max(p.Infants, 0)
+
+	ages := p.ChildAges[:0]
+	for _, age := range p.ChildAges {
+		switch {
+		case age < 2:
+			p.Infants++
+			p.Children--
+		case age >= 12:
+			p.Adults++
+			p.Children--
+		default:
+			ages = append(ages, age)
+		}
+	}
+	p.ChildAges = ages
+	p.Children = max(p.Children, len(p.ChildAges))
+
+	if p.Adults == 0 {
+		p.Adults = 1
+	}
+}
+
+// normalizeBaggage drops negative bag counts
+func normalizeBaggage(b *models.Baggage) {
+	b.CheckedBags = max(b.CheckedBags, 0)
+	b.CabinBags = max(b.CabinBags, 0)
+}
+
+// validate checks if the required fields are present and valid
+func (d *ExtractionDecodingStrategy) validate(params *models.TravelParameters, resolver *dates.Resolver) error {
+	if missing := MissingTravelParameters(params); len(missing) > 0 {
+		return fmt.Errorf("%s is required", strings.ReplaceAll(missing[0], "_", " "))
 	}
 
 	// Validate dates if present
 	if params.DepartureDate != nil {
-		if params.DepartureDate.Before(time.Now()) {
+		if isPastDate(*params.DepartureDate, resolver) {
 			return fmt.Errorf("departure date cannot be in the past")
 		}
 	}
@@ -98,6 +455,19 @@ func (d *ExtractionDecodingStrategy) validate(params *models.TravelParameters) e
 			return fmt.Errorf("return date cannot be before departure date")
 		}
 	}
+	for i := 1; i < len(params.Legs); i++ {
+		if params.Legs[i].EarliestDate.Before(*params.Legs[i-1].EarliestDate) {
+			return fmt.Errorf("leg %d departs before leg %d", i+1, i)
+		}
+	}
+
+	passengers := params.Passengers
+	if passengers.Infants > passengers.Adults {
+		return fmt.Errorf("each infant must travel with an adult")
+	}
+	if passengers.Adults+passengers.Children > MaxPartySize {
+		return fmt.Errorf("parties of more than %d passengers need a group booking", MaxPartySize)
+	}
 
 	return nil
 }
@@ -105,14 +475,35 @@ func (d *ExtractionDecodingStrategy) validate(params *models.TravelParameters) e
 func (d *ExtractionDecodingStrategy) DecodeResponse(content string) (*models.TravelParameters, error) {
 	// Parse the JSON content into TravelParameters
 	var params models.TravelParameters
-	if err := json.Unmarshal([]byte(content), &params); err != nil {
+	if err := decodeStrict(content, &params); err != nil {
 		return nil, fmt.Errorf("failed to parse travel parameters: %w", err)
 	}
 
+	resolver := d.resolver()
+	d.resolveDates(&params, resolver)
+	normalizeTripType(&params)
+	normalizePassengers(&params.Passengers)
+	normalizeBaggage(&params.Preferences.Baggage)
+
+	if d.AllowIncomplete {
+		d.clearInvalidDates(&params, resolver)
+		return &params, nil
+	}
+
 	// Validate required fields
-	if err := d.validate(&params); err != nil {
+	if err := d.validate(&params, resolver); err != nil {
 		return nil, fmt.Errorf("invalid travel parameters: %w", err)
 	}
 
 	return &params, nil
 }
+
+// clearInvalidDates drops dates that would fail validation so they are asked for again
+func (d *ExtractionDecodingStrategy) clearInvalidDates(params *models.TravelParameters, resolver *dates.Resolver) {
+	if params.DepartureDate != nil && isPastDate(*params.DepartureDate, resolver) {
+		params.DepartureDate = nil
+	}
+	if params.DepartureDate != nil && params.ReturnDate != nil && params.ReturnDate.Before(*params.DepartureDate) {
+		params.ReturnDate = nil
+	}
+}
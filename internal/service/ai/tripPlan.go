@@ -0,0 +1,159 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"travel-agent/internal/models"
+)
+
+// TripPlanStrategy asks for a time-blocked plan of each day at the destination
+type TripPlanStrategy struct{}
+
+func (s *TripPlanStrategy) GetSystemPrompt() string {
+	return `You are an AI Travel Assistant planning a traveler's stay hour by hour. Your task is to fill each day of the stay with time blocks for activities, meals and rest, fitting the hours the traveler has free, and explain your choices.
+
+Output must be a valid JSON object with this exact structure:
+{
+    "days": [
+        {
+            "day": number,
+            "blocks": [
+                {
+                    "start": "HH:MM",
+                    "end": "HH:MM",
+                    "title": "string",
+                    "description": "string",
+                    "location": "string",
+                    "kind": "activity|meal|rest|transfer"
+                }
+            ]
+        }
+    ],
+    "reasoning": "string explaining the plan"
+}
+
+Planning Rules:
+1. Give one entry per day listed, using its day number, with blocks in order
+2. Use 24-hour local times at the destination; every block must start and end within the free hours of its day
+3. Never overlap blocks and leave time to get from one location to the next
+4. Include the traveler's activities, spread over the days, and lunch and dinner every full day
+5. Every meal must respect all the dietary restrictions; name the restaurant in location
+6. Keep the arrival day light and the departure day close to the hotel
+7. Suit the plan to the party; with children, favor family-friendly places and an earlier end
+8. ` + fenceRule + `
+
+Return only the JSON object, no additional text or explanation.`
+}
+
+func (s *TripPlanStrategy) GetUserPrompt(req models.TripPlanRequest) string {
+	stayingAt := req.Hotel
+	switch {
+	case stayingAt == "":
+		stayingAt = "not decided yet"
+	case req.Neighborhood != "":
+		stayingAt = fmt.Sprintf("%s, %s", req.Hotel, req.Neighborhood)
+	}
+	return fmt.Sprintf(`Plan this stay:
+
+STAY DETAILS:
+- City: %s
+- Staying at: %s
+- Time Zone: %s
+- Party: %s
+
+FREE HOURS:
+%s
+
+Additional Context:
+%s
+
+Format the plan according to the specified JSON structure.`,
+		req.City,
+		stayingAt,
+		req.TimeZone,
+		passengerDetails(req.Guests),
+		planDays(req.Days),
+		planContext(req),
+	)
+}
+
+// planDays lists each day's free hours, with the reason a day is shortened
+func planDays(days []models.PlanDay) string {
+	lines := make([]string, len(days))
+	for i, day := range days {
+		end := day.End.Format("15:04")
+		if !day.End.Before(day.Date.AddDate(0, 0, 1)) {
+			end = "24:00"
+		}
+		lines[i] = fmt.Sprintf("- Day %d (%s): %s to %s",
+			day.Day, day.Date.Format("Monday, Jan 2"), day.Start.Format("15:04"), end)
+		if day.Note != "" {
+			lines[i] += " — " + day.Note
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// planContext lists the activities and dietary restrictions to plan around
+func planContext(req models.TripPlanRequest) string {
+	var lines []string
+	if len(req.Activities) > 0 {
+		lines = append(lines, "- Activities:\n"+fenceList(req.Activities))
+	}
+	if len(req.DietaryRestrictions) > 0 {
+		lines = append(lines, "- Dietary restrictions:\n"+fenceList(req.DietaryRestrictions))
+	}
+	if len(lines) == 0 {
+		return "No additional context provided"
+	}
+	return strings.Join(lines, "\n")
+}
+
+// TripPlanDecoder implements the DecodingStrategy interface
+type TripPlanDecoder struct{}
+
+func (d *TripPlanDecoder) DecodeResponse(content string) (*models.TripPlanDraft, error) {
+	var draft models.TripPlanDraft
+	if err := decodeStrict(content, &draft); err != nil {
+		return nil, fmt.Errorf("failed to decode trip plan: %w", err)
+	}
+
+	if err := d.validate(&draft); err != nil {
+		return nil, fmt.Errorf("invalid trip plan: %w", err)
+	}
+
+	return &draft, nil
+}
+
+func (d *TripPlanDecoder) validate(draft *models.TripPlanDraft) error {
+	if len(draft.Days) == 0 {
+		return errors.New("no days planned")
+	}
+
+	for i, day := range draft.Days {
+		if day.Day < 1 {
+			return fmt.Errorf("invalid day number for entry %d", i+1)
+		}
+		for j, block := range day.Blocks {
+			if block.Title == "" {
+				return fmt.Errorf("missing title for block %d of day %d", j+1, day.Day)
+			}
+			if !validClock(block.Start) || !validClock(block.End) {
+				return fmt.Errorf("invalid time for block %d of day %d", j+1, day.Day)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validClock accepts 24-hour "HH:MM" times, and "24:00" for midnight at the end of a day
+func validClock(s string) bool {
+	if s == "24:00" {
+		return true
+	}
+	_, err := time.Parse("15:04", s)
+	return err == nil
+}
@@ -0,0 +1,235 @@
+package airports
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata" // Time zones must resolve even on hosts without a zoneinfo database
+)
+
+//go:embed airports.json
+var defaultAirports []byte
+
+// City is a metropolitan area and the airports serving it. Code is the IATA metro
+// code ("NYC", "LON") or, for single-airport cities, the airport code.
+type City struct {
+	Code      string    `json:"code"`
+	Name      string    `json:"name"`
+	Country   string    `json:"country"` // ISO 3166-1 alpha-2
+	TimeZone  string    `json:"time_zone"`
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+	Aliases   []string  `json:"aliases,omitempty"` // Abbreviations and names in other languages
+	Airports  []Airport `json:"airports"`
+}
+
+type Airport struct {
+	IATA      string  `json:"iata"`
+	Name      string  `json:"name"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Codes returns the IATA codes of the city's airports
+func (c *City) Codes() []string {
+	codes := make([]string, len(c.Airports))
+	for i, airport := range c.Airports {
+		codes[i] = airport.IATA
+	}
+	return codes
+}
+
+// Location returns the city's time zone
+func (c *City) Location() *time.Location {
+	location, err := time.LoadLocation(c.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// Database resolves the city and airport names travelers and models use to canonical cities
+type Database struct {
+	cities []City
+	byName map[string]*City // Folded names, aliases and codes
+}
+
+var (
+	defaultOnce     sync.Once
+	defaultDatabase *Database
+	defaultErr      error
+)
+
+// Default returns the database bundled with the binary. It is parsed once and shared.
+func Default() (*Database, error) {
+	defaultOnce.Do(func() {
+		defaultDatabase, defaultErr = Parse(defaultAirports)
+	})
+	return defaultDatabase, defaultErr
+}
+
+// Load reads a JSON array of cities from disk
+func Load(filename string) (*Database, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading airports file: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse builds a database from a JSON array of cities. Every name, alias and code
+// must identify a single city.
+func Parse(data []byte) (*Database, error) {
+	var cities []City
+	if err := json.Unmarshal(data, &cities); err != nil {
+		return nil, fmt.Errorf("parsing airports: %w", err)
+	}
+
+	db := &Database{
+		cities: cities,
+		byName: make(map[string]*City),
+	}
+	for i := range db.cities {
+		city := &db.cities[i]
+		if city.Code == "" || city.Name == "" {
+			return nil, fmt.Errorf("city %d: code and name are required", i+1)
+		}
+		if len(city.Airports) == 0 {
+			return nil, fmt.Errorf("city %s: at least one airport is required", city.Code)
+		}
+		if _, err := time.LoadLocation(city.TimeZone); err != nil {
+			return nil, fmt.Errorf("city %s: invalid time zone %q", city.Code, city.TimeZone)
+		}
+
+		keys := append([]string{city.Code, city.Name}, city.Aliases...)
+		for _, airport := range city.Airports {
+			keys = append(keys, airport.IATA, airport.Name)
+		}
+		for _, key := range keys {
+			if err := db.add(key, city); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return db, nil
+}
+
+func (db *Database) add(name string, city *City) error {
+	key := fold(name)
+	if key == "" {
+		return nil
+	}
+	if existing, ok := db.byName[key]; ok && existing != city {
+		return fmt.Errorf("%q refers to both %s and %s", name, existing.Code, city.Code)
+	}
+	db.byName[key] = city
+	return nil
+}
+
+// Len returns the number of cities in the database
+func (db *Database) Len() int {
+	return len(db.cities)
+}
+
+// Cities returns every city in the database, in file order
+func (db *Database) Cities() []*City {
+	cities := make([]*City, len(db.cities))
+	for i := range db.cities {
+		cities[i] = &db.cities[i]
+	}
+	return cities
+}
+
+// Resolve finds the city for a name, alias, metro code, airport code or airport name.
+// Case and accents are ignored, so "NYC", "Nueva York" and "new york city" all
+// resolve to New York. Forms like "Paris, France" and "New York (JFK)" are accepted.
+func (db *Database) Resolve(name string) (*City, bool) {
+	if db == nil {
+		return nil, false
+	}
+
+	for _, candidate := range candidates(name) {
+		if city, ok := db.byName[fold(candidate)]; ok {
+			return city, true
+		}
+	}
+	return nil, false
+}
+
+// Airport finds an airport by IATA code along with the city it serves
+func (db *Database) Airport(code string) (*Airport, *City, bool) {
+	city, ok := db.Resolve(code)
+	if !ok {
+		return nil, nil, false
+	}
+	for i := range city.Airports {
+		if strings.EqualFold(city.Airports[i].IATA, code) {
+			return &city.Airports[i], city, true
+		}
+	}
+	return nil, nil, false
+}
+
+// SameCity reports whether two names refer to the same city. known is false when
+// either name is not in the database, in which case same is meaningless.
+func (db *Database) SameCity(a, b string) (same, known bool) {
+	cityA, okA := db.Resolve(a)
+	cityB, okB := db.Resolve(b)
+	if !okA || !okB {
+		return false, false
+	}
+	return cityA == cityB, true
+}
+
+// earthRadiusKm is the mean radius used for great-circle distances
+const earthRadiusKm = 6371
+
+// Distance returns the great-circle distance between two airports in kilometers
+func Distance(a, b *Airport) float64 {
+	lat1, lat2 := a.Latitude*math.Pi/180, b.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+// candidates lists the forms of a name worth looking up: the name itself, the part
+// in parentheses, the part before them, and the part before the first comma
+func candidates(name string) []string {
+	name = strings.TrimSpace(name)
+	forms := []string{name}
+
+	if open := strings.Index(name, "("); open >= 0 {
+		if end := strings.Index(name[open:], ")"); end > 0 {
+			forms = append(forms, name[open+1:open+end], name[:open])
+		}
+	}
+	if comma := strings.Index(name, ","); comma > 0 {
+		forms = append(forms, name[:comma])
+	}
+	return forms
+}
+
+// accents maps accented letters to their base letter
+var accents = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ä", "a", "ã", "a", "å", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "ö", "o", "õ", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ñ", "n", "ç", "c", "ğ", "g", "ş", "s", "ı", "i",
+)
+
+// fold lowercases a name, strips accents and punctuation and collapses spaces
+func fold(name string) string {
+	name = accents.Replace(strings.ToLower(name))
+	name = strings.NewReplacer(".", "", "'", "", "-", " ", "–", " ", "/", " ", ",", " ").Replace(name)
+	return strings.Join(strings.Fields(name), " ")
+}
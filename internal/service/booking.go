@@ -3,9 +3,13 @@ package service
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"time"
+	"travel-agent/internal/logging"
 	"travel-agent/internal/models"
 	"travel-agent/internal/service/ai"
+	"travel-agent/internal/service/airports"
+	"travel-agent/internal/service/currency"
 
 	"github.com/google/uuid"
 )
@@ -28,19 +32,93 @@ type FlightRecommender interface {
 	) (*models.FlightRecommendation, error)
 }
 
+// BookingStore persists processed bookings so later requests can build on them
+type BookingStore interface {
+	Save(ctx context.Context, record *models.BookingRecord) error
+	Get(ctx context.Context, id string) (*models.BookingRecord, error)
+}
+
 type BookingService struct {
-	paramExtractor    TravelParameterExtractor
-	flightRecommender FlightRecommender
+	paramExtractor     TravelParameterExtractor
+	flightRecommender  FlightRecommender
+	examples           *ai.ExampleLibrary
+	maxExamples        int
+	exampleTokenBudget int
+	defaults           RecommendationDefaults
+	airports           *airports.Database
+	rates              currency.RateProvider
+	currency           string // Default currency, the default budget is in it
+	flightSearch       FlightSearchProvider
+	hotelRecommender   HotelRecommender
+	groundTransport    GroundTransportProvider
+	bookings           BookingStore
+	weights            RankingWeights
+	narrative          bool // The model explains the ranking of searched flights
+	screening          ScreeningPolicy
+	classifier         InjectionClassifier
+	audits             AuditStore
+	intentClassifier   IntentClassifier
+}
+
+// BookingOption configures optional collaborators of the BookingService
+type BookingOption func(*BookingService)
+
+// WithAirportDatabase replaces the bundled airport database used to check flight cities
+func WithAirportDatabase(db *airports.Database) BookingOption {
+	return func(s *BookingService) {
+		s.airports = db
+	}
+}
+
+// WithBookingStore saves every processed booking with its travel parameters
+func WithBookingStore(bookings BookingStore) BookingOption {
+	return func(s *BookingService) {
+		s.bookings = bookings
+	}
+}
+
+// WithExampleLibrary enables few-shot examples for parameter extraction
+func WithExampleLibrary(examples *ai.ExampleLibrary, maxExamples, tokenBudget int) BookingOption {
+	return func(s *BookingService) {
+		s.examples = examples
+		s.maxExamples = maxExamples
+		s.exampleTokenBudget = tokenBudget
+	}
 }
 
 func NewBookingService(
 	paramExtractor TravelParameterExtractor,
 	flightRecommender FlightRecommender,
+	opts ...BookingOption,
 ) *BookingService {
-	return &BookingService{
+	s := &BookingService{
 		paramExtractor:    paramExtractor,
 		flightRecommender: flightRecommender,
+		defaults: RecommendationDefaults{
+			TravelClass: "economy",
+			MaxBudget:   2000,
+		},
+		currency: defaultCurrency,
+		weights:  defaultRankingWeights,
+		screening: ScreeningPolicy{
+			Action:        models.ScreeningReject,
+			MinConfidence: defaultClassifierConfidence,
+		},
+	}
+	// Without the bundled database flight cities are not checked
+	if db, err := airports.Default(); err == nil {
+		s.airports = db
+	}
+	// Without any rates only prices already in the traveler's currency are accepted
+	if table, err := currency.DefaultTable(); err == nil {
+		s.rates = table
+	} else {
+		s.rates = &currency.Table{Base: defaultCurrency, Rates: map[string]float64{defaultCurrency: 1}}
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // ProcessBooking orchestrates the booking flow
@@ -49,104 +127,305 @@ func (s *BookingService) ProcessBooking(ctx context.Context, req models.BookingR
 		return nil, fmt.Errorf("query cannot be empty")
 	}
 
+	// Pin the reference time so every relative date in the request resolves against the same "now"
+	if req.ReferenceTime == nil {
+		now := time.Now()
+		req.ReferenceTime = &now
+	}
+
+	// Screen the query before any of it reaches the model
+	id := uuid.New().String()
+	ctx = logging.WithBookingID(ctx, id)
+	quarantined, err := s.screenQuery(ctx, req, models.ScreeningSourceBooking, id)
+	if err != nil {
+		return nil, err
+	}
+	if quarantined {
+		return quarantinedBooking(id, req), nil
+	}
+
+	// Only flight searches go on to extraction and recommendation
+	classification := s.classifyIntent(ctx, req)
+	slog.InfoContext(ctx, "query classified", "intent", classification.Intent)
+	if response, routed, err := s.routeIntent(ctx, id, req, classification); routed {
+		return response, err
+	}
+
 	// Extract travel parameters
-	travelParams, err := s.extractTravelParameters(ctx, req.Query, req.Deadline)
+	travelParams, err := s.extractTravelParameters(ctx, req, false)
 	if err != nil {
 		return nil, fmt.Errorf("parameter extraction failed: %w", err)
 	}
 
+	return s.completeBooking(ctx, id, req, travelParams)
+}
+
+// completeBooking runs the recommendation stage once the travel parameters are known
+func (s *BookingService) completeBooking(ctx context.Context, id string, req models.BookingRequest, travelParams *models.TravelParameters) (*models.BookingResponse, error) {
+	ctx = logging.WithBookingID(ctx, id)
+	var response *models.BookingResponse
+	var err error
+	if tripTypeOf(travelParams) == models.TripMultiCity {
+		response, err = s.completeMultiCityBooking(ctx, id, req, travelParams)
+	} else {
+		response, err = s.completeSingleTripBooking(ctx, id, req, travelParams)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, location := ai.RequestClock(req)
+	response.Intent = models.IntentFlightSearch
+	response.TimeZone = location.String()
+	response.ResolvedDates = travelParams.ResolvedDates
+
+	if s.bookings != nil {
+		record := &models.BookingRecord{Booking: *response, Parameters: *travelParams}
+		if err := s.bookings.Save(ctx, record); err != nil {
+			return nil, fmt.Errorf("failed to save booking: %w", err)
+		}
+	}
+
+	slog.InfoContext(ctx, "booking processed",
+		"trip_type", response.TripType,
+		"rejected_flights", len(response.RejectedFlights),
+		"hotels", response.Hotels != nil,
+		"ground_transport", response.GroundTransport != nil,
+	)
+	return response, nil
+}
+
+// completeSingleTripBooking handles one-way, round-trip and open-jaw trips
+func (s *BookingService) completeSingleTripBooking(ctx context.Context, id string, req models.BookingRequest, travelParams *models.TravelParameters) (*models.BookingResponse, error) {
 	// Get flight recommendations
-	recommendations, err := s.getFlightRecommendations(ctx, travelParams)
+	recommendations, rejected, err := s.getFlightRecommendations(ctx, req, travelParams)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get flight recommendations: %w", err)
+		return nil, withRejections(fmt.Errorf("failed to get flight recommendations: %w", err), rejected)
 	}
 
 	// Create booking response
-	response, err := s.createBookingResponse(req, recommendations, req.Deadline)
+	response, err := s.createBookingResponse(id, req, tripTypeOf(travelParams), recommendations, req.Deadline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create booking response: %w", err)
+	}
+
+	response.Currency = s.travelerCurrency(ctx, req, travelParams)
+	response.RejectedFlights = rejected
+	passengers := passengersOf(travelParams)
+	response.Passengers = &passengers
+	if party := seatsRequired(passengers) + passengers.Infants; party > 1 {
+		response.Message += fmt.Sprintf(" for %d passengers", party)
+	}
+	response.Hotels = s.recommendHotels(ctx, travelParams, response)
+	response.GroundTransport = s.recommendGroundTransport(ctx, travelParams, response)
+
+	return response, nil
+}
+
+// completeMultiCityBooking recommends a flight per leg and attaches the combined itinerary
+func (s *BookingService) completeMultiCityBooking(ctx context.Context, id string, req models.BookingRequest, travelParams *models.TravelParameters) (*models.BookingResponse, error) {
+	itinerary, rejected, err := s.getMultiCityItinerary(ctx, req, travelParams)
+	if err != nil {
+		return nil, withRejections(fmt.Errorf("failed to get flight recommendations: %w", err), rejected)
+	}
+
+	legFlights := make([]models.Flight, len(itinerary.Legs))
+	for i, leg := range itinerary.Legs {
+		legFlights[i] = leg.Flight
+	}
+
+	response, err := s.createBookingResponse(id, req, models.TripMultiCity, &models.FlightRecommendation{Recommendations: legFlights}, req.Deadline)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create booking response: %w", err)
 	}
 
+	passengers := passengersOf(travelParams)
+	response.Passengers = &passengers
+	response.Currency = itinerary.TotalPrice.Currency
+	response.Itinerary = itinerary
+	response.RejectedFlights = rejected
+	response.Message = fmt.Sprintf("Planned a %d-leg trip for %s", len(itinerary.Legs), itinerary.TotalPrice)
+	if !itinerary.Feasible {
+		response.Message += "; some connections need attention"
+	}
+
 	return response, nil
 }
 
-// getFlightRecommendations fetches flight recommendations from the AI engine
-func (s *BookingService) getFlightRecommendations(ctx context.Context, params *models.TravelParameters) (*models.FlightRecommendation, error) {
-	flightRecommendationStrategy := &ai.FlightRecommendationStrategy{}
-	decodingStrategy := &ai.FlightRecommendationDecoder{}
+// getFlightRecommendations fetches candidate flights, ranks the ones passing the sanity
+// checks and returns them best first along with the rejected ones
+func (s *BookingService) getFlightRecommendations(
+	ctx context.Context,
+	req models.BookingRequest,
+	params *models.TravelParameters,
+) (*models.FlightRecommendation, []models.FlightRejection, error) {
+	if params.DepartureDate == nil {
+		return nil, nil, fmt.Errorf("departure date is required")
+	}
 
 	aiReq := models.FlightRecommendationRequest{
-		DepartureCity: params.DepartureCity,
-		Destination:   params.Destination,
-		DepartureDate: *params.DepartureDate,
-		ReturnDate:    *params.ReturnDate,
-		// hardcoded values for now
-		PreferredClass: "economy",
-		MaxBudget:      2000.0,
-		Passengers:     1,
+		TripType:          tripTypeOf(params),
+		DepartureCity:     params.DepartureCity,
+		Destination:       params.Destination,
+		DepartureDate:     *params.DepartureDate,
+		ReturnDate:        params.ReturnDate,
+		ReturnOrigin:      params.ReturnOrigin,
+		ReturnDestination: params.ReturnDestination,
+		Passengers:        passengersOf(params),
+		Currency:          s.travelerCurrency(ctx, req, params),
+	}
+	constraints, err := s.applyPreferences(ctx, &aiReq, params, req.Profile)
+	if err != nil {
+		return nil, nil, err
 	}
+	s.applyRoute(&aiReq, &constraints, params.DepartureCity, params.Destination)
 
-	recommendations, err := s.flightRecommender.ProcessRequest(
-		ctx,
-		flightRecommendationStrategy,
-		aiReq,
-		decodingStrategy,
-	)
+	recommendations, err := s.candidateFlights(ctx, aiReq, constraints)
 	if err != nil {
-		return nil, fmt.Errorf("AI recommendation failed: %w", err)
+		return nil, nil, err
 	}
 
-	return recommendations, nil
+	flights, err := priceFlights(s.convertFlights(ctx, recommendations.Recommendations, aiReq.Currency), aiReq.Passengers, aiReq.Baggage)
+	if err != nil {
+		return nil, nil, err
+	}
+	flights, rejected, err := checkFlights(flights, aiReq, constraints, flightRules)
+	if err != nil {
+		return nil, rejected, err
+	}
+	flights = rankFlights(describeFlights(flights), aiReq, s.weights, s.airports)
+	recommendations.Recommendations = flights
+	// The model's reasoning argued for its own order, which the ranking replaces
+	recommendations.Reasoning = s.explainFlights(ctx, aiReq, flights)
+
+	// Without a way back the outbound flights can still be booked on their own
+	if aiReq.ReturnDate != nil {
+		returnFlights, returnRejected, err := s.getReturnFlights(ctx, aiReq, constraints, recommendations.ReturnRecommendations)
+		rejected = append(rejected, returnRejected...)
+		if err != nil {
+			slog.WarnContext(ctx, "return flights unavailable, booking goes ahead one-way", "error", err)
+		} else {
+			recommendations.ReturnRecommendations = returnFlights
+			recommendations.Itineraries = pairFlights(flights, returnFlights, constraints.maxPrice)
+		}
+	}
+
+	return recommendations, rejected, nil
+}
+
+// tripTypeOf resolves the trip type used for recommendations. Without a
+// return date the trip can only be booked as one-way.
+func tripTypeOf(params *models.TravelParameters) models.TripType {
+	if params.TripType == models.TripMultiCity && len(params.Legs) > 0 {
+		return models.TripMultiCity
+	}
+	if params.ReturnDate == nil {
+		return models.TripOneWay
+	}
+	if params.TripType == "" || params.TripType == models.TripOneWay {
+		return models.TripRoundTrip
+	}
+	return params.TripType
 }
 
 // extractTravelParameters handles the AI parameter extraction
-func (s *BookingService) extractTravelParameters(ctx context.Context, query string, deadline time.Time) (*models.TravelParameters, error) {
-	extractionStrategy := &ai.ExtractionPromptStrategy{}
-	decodingStrategy := &ai.ExtractionDecodingStrategy{}
+func (s *BookingService) extractTravelParameters(
+	ctx context.Context,
+	req models.BookingRequest,
+	allowIncomplete bool,
+) (*models.TravelParameters, error) {
+	ctx = logging.WithStage(ctx, "extraction")
+	now, location := ai.RequestClock(req)
+	decodingStrategy := &ai.ExtractionDecodingStrategy{
+		AllowIncomplete: allowIncomplete,
+		ReferenceTime:   now,
+		Location:        location,
+	}
 
-	aiReq := models.BookingRequest{
-		Query:    query,
-		Deadline: deadline,
+	extractionStrategy := &ai.ExtractionPromptStrategy{
+		Examples:           s.examples,
+		MaxExamples:        s.maxExamples,
+		ExampleTokenBudget: s.exampleTokenBudget,
 	}
 
 	params, err := s.paramExtractor.ProcessRequest(
 		ctx,
 		extractionStrategy,
-		aiReq,
+		req,
 		decodingStrategy,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("AI extraction failed: %w", err)
 	}
+	s.canonicalizeCities(params)
 
 	return params, nil
 }
 
 // createBookingResponse creates the booking response from extracted parameters
 func (s *BookingService) createBookingResponse(
+	id string,
 	req models.BookingRequest,
+	tripType models.TripType,
 	params *models.FlightRecommendation,
 	deadline time.Time,
 ) (*models.BookingResponse, error) {
+	if len(params.Recommendations) == 0 {
+		return nil, fmt.Errorf("no flight recommendations available")
+	}
+
+	// The best pairing decides the outbound flight of a round trip
+	selected := params.Recommendations[0]
+	var roundTrip *models.RoundTripItinerary
+	if len(params.Itineraries) > 0 {
+		roundTrip = &params.Itineraries[0]
+		selected = roundTrip.Outbound
+	}
+
+	message := fmt.Sprintf("Searching for flights to %s", selected.ArrivalCity)
+	switch {
+	case tripType == models.TripOneWay:
+		message = fmt.Sprintf("Searching for one-way flights to %s", selected.ArrivalCity)
+	case roundTrip != nil:
+		message += fmt.Sprintf(", returning on %s, %s in total", roundTrip.Return.DepartureTime.Format("Jan 2"), roundTrip.TotalPrice)
+	case tripType == models.TripRoundTrip || tripType == models.TripOpenJaw:
+		message += "; no return flight found"
+	}
+
 	now := time.Now()
 	response := &models.BookingResponse{
-		ID:     uuid.New().String(),
-		Status: models.StatusProcessing,
-		Query:  req.Query,
+		ID:       id,
+		Status:   models.StatusProcessing,
+		Query:    req.Query,
+		TripType: tripType,
 		FlightDetails: &models.Flight{
-			Airline:       params.Recommendations[0].Airline,
-			FlightNumber:  params.Recommendations[0].FlightNumber,
-			Price:         params.Recommendations[0].Price,
-			Currency:      "USD",
-			DepartureCity: params.Recommendations[0].DepartureCity,
-			ArrivalCity:   params.Recommendations[0].ArrivalCity,
-			DepartureTime: params.Recommendations[0].DepartureTime,
-			ArrivalTime:   params.Recommendations[0].ArrivalTime,
+			OfferID:             selected.OfferID,
+			Airline:             selected.Airline,
+			FlightNumber:        selected.FlightNumber,
+			Price:               selected.Price,
+			DepartureCity:       selected.DepartureCity,
+			ArrivalCity:         selected.ArrivalCity,
+			DepartureTime:       selected.DepartureTime,
+			ArrivalTime:         selected.ArrivalTime,
+			LayoverCount:        selected.LayoverCount,
+			Segments:            selected.Segments,
+			TotalDuration:       selected.TotalDuration,
+			Fare:                selected.Fare,
+			Converted:           selected.Converted,
+			Conditions:          selected.Conditions,
+			Warnings:            selected.Warnings,
+			RecommendationScore: selected.RecommendationScore,
+			ScoreBreakdown:      selected.ScoreBreakdown,
 		},
+		RoundTrip: roundTrip,
+		Reasoning: params.Reasoning,
 		Deadline:  deadline,
 		CreatedAt: now,
 		UpdatedAt: now,
-		Message:   fmt.Sprintf("Searching for flights to %s", params.Recommendations[0].ArrivalCity),
+		Message:   message,
+	}
+	if len(params.Itineraries) > 1 {
+		response.RoundTripOptions = params.Itineraries[1:]
 	}
 
 	return response, nil
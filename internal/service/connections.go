@@ -0,0 +1,154 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service/airports"
+)
+
+// Minimum connection times. Changing airports within a city also means crossing it.
+const (
+	minConnectionDomestic      = 45 * time.Minute
+	minConnectionInternational = time.Hour
+	minConnectionAirportChange = 3 * time.Hour
+)
+
+// segmentTimeTolerance is how far the first and last segments may be from the
+// flight's own departure and arrival times
+const segmentTimeTolerance = time.Minute
+
+// checkSegments rejects flights whose segments don't chain: each one must land
+// before it takes off again, in the city the next one leaves from
+func checkSegments(flight models.Flight, _ models.FlightRecommendationRequest, c flightConstraints) string {
+	segments := flight.Segments
+	if len(segments) == 0 {
+		return ""
+	}
+
+	for i, seg := range segments {
+		if !seg.ArrivalTime.After(seg.DepartureTime) {
+			return fmt.Sprintf("segment %s arrives before it departs", seg.FlightNumber)
+		}
+		if i == 0 {
+			continue
+		}
+		prev := segments[i-1]
+		if !sameCity(c.airports, prev.Destination, seg.Origin) {
+			return fmt.Sprintf("segment %s departs from %s, but %s lands at %s", seg.FlightNumber, seg.Origin, prev.FlightNumber, prev.Destination)
+		}
+		if !seg.DepartureTime.After(prev.ArrivalTime) {
+			return fmt.Sprintf("segment %s departs before %s lands", seg.FlightNumber, prev.FlightNumber)
+		}
+	}
+
+	first, last := segments[0], segments[len(segments)-1]
+	if first.DepartureTime.Sub(flight.DepartureTime).Abs() > segmentTimeTolerance ||
+		last.ArrivalTime.Sub(flight.ArrivalTime).Abs() > segmentTimeTolerance {
+		return "segments don't match the flight's departure and arrival times"
+	}
+	return ""
+}
+
+// checkConnectionTimes flags connections shorter than the minimum connection time.
+// Such itineraries can be sold but risk a missed connection.
+func checkConnectionTimes(flight models.Flight, _ models.FlightRecommendationRequest, c flightConstraints) string {
+	var problems []string
+	for i := 1; i < len(flight.Segments); i++ {
+		prev, seg := flight.Segments[i-1], flight.Segments[i]
+		layover := seg.DepartureTime.Sub(prev.ArrivalTime)
+		minimum, airportChange := minimumConnectionTime(c.airports, prev, seg)
+		if layover >= minimum {
+			continue
+		}
+
+		if airportChange {
+			problems = append(problems, fmt.Sprintf("connection from %s to %s is only %s, less than the %s needed to change airports",
+				prev.Destination, seg.Origin, models.Duration(layover), models.Duration(minimum)))
+		} else {
+			problems = append(problems, fmt.Sprintf("connection at %s is only %s, less than the %s minimum",
+				seg.Origin, models.Duration(layover), models.Duration(minimum)))
+		}
+	}
+	return strings.Join(problems, "; ")
+}
+
+// minimumConnectionTime is the time needed between two segments. Connections are
+// domestic when both segments stay in one country; unknown airports count as
+// international.
+func minimumConnectionTime(db *airports.Database, arriving, departing models.Segment) (time.Duration, bool) {
+	if !strings.EqualFold(arriving.Destination, departing.Origin) {
+		return minConnectionAirportChange, true
+	}
+
+	countries := make(map[string]bool)
+	for _, code := range []string{arriving.Origin, arriving.Destination, departing.Destination} {
+		_, city, ok := db.Airport(code)
+		if !ok {
+			return minConnectionInternational, false
+		}
+		countries[city.Country] = true
+	}
+	if len(countries) == 1 {
+		return minConnectionDomestic, false
+	}
+	return minConnectionInternational, false
+}
+
+// localDepartureTime is when the flight leaves in its origin's local time. The time zone
+// is the first segment's airport's or the departure city's when the database knows
+// them, and otherwise the offset the first segment was given in.
+func localDepartureTime(flight models.Flight, db *airports.Database) time.Time {
+	if len(flight.Segments) > 0 {
+		if _, city, ok := db.Airport(flight.Segments[0].Origin); ok {
+			return flight.DepartureTime.In(city.Location())
+		}
+	}
+	if city, ok := db.Resolve(flight.DepartureCity); ok {
+		return flight.DepartureTime.In(city.Location())
+	}
+	if len(flight.Segments) > 0 {
+		return flight.DepartureTime.In(flight.Segments[0].DepartureTime.Location())
+	}
+	return flight.DepartureTime
+}
+
+// sameCity compares airports by the city they serve, or by code when unknown
+func sameCity(db *airports.Database, a, b string) bool {
+	if same, known := db.SameCity(a, b); known {
+		return same
+	}
+	return strings.EqualFold(a, b)
+}
+
+// describeFlights derives what can be computed instead of trusted: each segment's
+// duration and layover, the layover count and the total duration
+func describeFlights(flights []models.Flight) []models.Flight {
+	described := make([]models.Flight, len(flights))
+	for i, flight := range flights {
+		if len(flight.Segments) > 0 {
+			segments := append([]models.Segment(nil), flight.Segments...)
+			for j := range segments {
+				segments[j].Duration = models.Duration(segments[j].ArrivalTime.Sub(segments[j].DepartureTime))
+				segments[j].Layover = 0
+				if j > 0 {
+					segments[j].Layover = models.Duration(segments[j].DepartureTime.Sub(segments[j-1].ArrivalTime))
+				}
+			}
+			flight.Segments = segments
+			flight.LayoverCount = len(segments) - 1
+		}
+		flight.TotalDuration = models.Duration(flight.ArrivalTime.Sub(flight.DepartureTime))
+		described[i] = flight
+	}
+	return described
+}
+
+// layoversOf counts connections from the segments when the flight has them
+func layoversOf(flight models.Flight) int {
+	if len(flight.Segments) > 0 {
+		return len(flight.Segments) - 1
+	}
+	return flight.LayoverCount
+}
@@ -0,0 +1,432 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"travel-agent/internal/logging"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service/ai"
+	"travel-agent/internal/store"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrConversationNotFound = errors.New("conversation not found")
+	ErrConversationClosed   = errors.New("conversation is no longer awaiting input")
+	// ErrConversationFailed is returned, along with the failed conversation, when the
+	// booking it gathered the parameters for could not be completed
+	ErrConversationFailed = errors.New("booking failed")
+)
+
+// ConversationStore persists conversations between turns
+type ConversationStore interface {
+	Save(ctx context.Context, conversation *models.Conversation) error
+	Get(ctx context.Context, id string) (*models.Conversation, error)
+}
+
+// followUpQuestions holds the question asked for each missing field
+var followUpQuestions = map[string]string{
+	ai.FieldDepartureCity: "Which city will you be flying from?",
+	ai.FieldDestination:   "Where would you like to go?",
+	ai.FieldDepartureDate: "What date would you like to leave?",
+	ai.FieldReturnDate:    "When would you like to come back? If you don't need a return flight, just tell me it's one-way.",
+	ai.FieldLegs:          "Which cities would you like to visit, in order, and on which dates?",
+}
+
+// ConversationService gathers missing travel parameters over several turns
+// before handing them to the booking flow
+type ConversationService struct {
+	bookings *BookingService
+	store    ConversationStore
+	locks    *keyedLocks
+}
+
+func NewConversationService(bookings *BookingService, store ConversationStore) *ConversationService {
+	return &ConversationService{
+		bookings: bookings,
+		store:    store,
+		locks:    newKeyedLocks(),
+	}
+}
+
+// StartConversation extracts what it can from the initial query and asks for the rest
+func (s *ConversationService) StartConversation(ctx context.Context, req models.BookingRequest) (*models.Conversation, error) {
+	if req.Query == "" {
+		return nil, fmt.Errorf("query cannot be empty")
+	}
+
+	now := time.Now()
+	referenceTime := now
+	if req.ReferenceTime != nil {
+		referenceTime = *req.ReferenceTime
+	}
+
+	conversation := &models.Conversation{
+		ID:            uuid.New().String(),
+		Status:        models.ConversationAwaitingInput,
+		Deadline:      req.Deadline,
+		TimeZone:      req.TimeZone,
+		ReferenceTime: &referenceTime,
+		Profile:       req.Profile,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	conversation.Messages = append(conversation.Messages, models.ConversationMessage{
+		Role:      models.RoleTraveler,
+		Content:   req.Query,
+		CreatedAt: now,
+	})
+
+	ctx = logging.WithConversationID(ctx, conversation.ID)
+	quarantined, err := s.bookings.screenQuery(ctx, req, models.ScreeningSourceConversation, conversation.ID)
+	if err != nil {
+		return nil, err
+	}
+	if quarantined {
+		if err := s.quarantine(ctx, conversation); err != nil {
+			return nil, err
+		}
+		return conversation, nil
+	}
+
+	if err := s.advance(ctx, conversation); err != nil {
+		return failedConversation(conversation, err)
+	}
+
+	return conversation, nil
+}
+
+// AddMessage records the traveler's answer, merges it into the parameters and
+// either asks the next question or completes the booking. Messages to the same
+// conversation are handled one at a time.
+func (s *ConversationService) AddMessage(ctx context.Context, id string, content string) (*models.Conversation, error) {
+	if content == "" {
+		return nil, fmt.Errorf("message content cannot be empty")
+	}
+
+	unlock := s.locks.lock(id)
+	defer unlock()
+
+	conversation, err := s.GetConversation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if conversation.Status != models.ConversationAwaitingInput {
+		return nil, ErrConversationClosed
+	}
+
+	// Only the new message is screened; the earlier ones already were
+	ctx = logging.WithConversationID(ctx, id)
+	quarantined, err := s.bookings.screenQuery(ctx, models.BookingRequest{Query: content}, models.ScreeningSourceConversation, id)
+	if err != nil {
+		return nil, err
+	}
+
+	conversation.Messages = append(conversation.Messages, models.ConversationMessage{
+		Role:      models.RoleTraveler,
+		Content:   content,
+		CreatedAt: time.Now(),
+	})
+	if quarantined {
+		if err := s.quarantine(ctx, conversation); err != nil {
+			return nil, err
+		}
+		return conversation, nil
+	}
+
+	if err := s.advance(ctx, conversation); err != nil {
+		return failedConversation(conversation, err)
+	}
+
+	return conversation, nil
+}
+
+// failedConversation returns the conversation along with err once the failure has been
+// saved, so the caller still learns its ID and status
+func failedConversation(conversation *models.Conversation, err error) (*models.Conversation, error) {
+	if errors.Is(err, ErrConversationFailed) {
+		return conversation, err
+	}
+	return nil, err
+}
+
+// GetConversation returns a stored conversation
+func (s *ConversationService) GetConversation(ctx context.Context, id string) (*models.Conversation, error) {
+	conversation, err := s.store.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrConversationNotFound
+		}
+		return nil, fmt.Errorf("failed to load conversation: %w", err)
+	}
+	return conversation, nil
+}
+
+// advance re-extracts the parameters from the whole transcript and decides the next step
+func (s *ConversationService) advance(ctx context.Context, conversation *models.Conversation) error {
+	extracted, err := s.bookings.extractTravelParameters(ctx, models.BookingRequest{
+		Query:         transcript(conversation.Messages),
+		Deadline:      conversation.Deadline,
+		TimeZone:      conversation.TimeZone,
+		ReferenceTime: conversation.ReferenceTime,
+	}, true)
+	if err != nil {
+		return fmt.Errorf("parameter extraction failed: %w", err)
+	}
+
+	conversation.Parameters = mergeTravelParameters(conversation.Parameters, extracted)
+	conversation.MissingFields = s.pendingFields(conversation)
+	conversation.UpdatedAt = time.Now()
+
+	if len(conversation.MissingFields) > 0 {
+		conversation.AskedFields = append(conversation.AskedFields, conversation.MissingFields...)
+		conversation.Messages = append(conversation.Messages, models.ConversationMessage{
+			Role:      models.RoleAgent,
+			Content:   followUpMessage(conversation.MissingFields, conversation.Parameters),
+			CreatedAt: conversation.UpdatedAt,
+		})
+		return s.save(ctx, conversation)
+	}
+
+	// All parameters are known, start the recommendation stage
+	bookingReq := models.BookingRequest{
+		Query:         conversation.Messages[0].Content,
+		Deadline:      conversation.Deadline,
+		TimeZone:      conversation.TimeZone,
+		ReferenceTime: conversation.ReferenceTime,
+		Profile:       conversation.Profile,
+	}
+	booking, err := s.bookings.completeBooking(ctx, uuid.New().String(), bookingReq, conversation.Parameters)
+	if err != nil {
+		conversation.Status = models.ConversationFailed
+		conversation.Messages = append(conversation.Messages, models.ConversationMessage{
+			Role:      models.RoleAgent,
+			Content:   "Sorry, I couldn't find flights for this trip.",
+			CreatedAt: conversation.UpdatedAt,
+		})
+		if saveErr := s.save(ctx, conversation); saveErr != nil {
+			return saveErr
+		}
+		return fmt.Errorf("%w: %w", ErrConversationFailed, err)
+	}
+
+	conversation.Status = models.ConversationCompleted
+	conversation.Booking = booking
+	conversation.Messages = append(conversation.Messages, models.ConversationMessage{
+		Role:      models.RoleAgent,
+		Content:   booking.Message,
+		CreatedAt: conversation.UpdatedAt,
+	})
+	return s.save(ctx, conversation)
+}
+
+// quarantine holds the conversation for review without sending the flagged message to
+// the model
+func (s *ConversationService) quarantine(ctx context.Context, conversation *models.Conversation) error {
+	conversation.Status = models.ConversationQuarantined
+	conversation.UpdatedAt = time.Now()
+	conversation.Messages = append(conversation.Messages, models.ConversationMessage{
+		Role:      models.RoleAgent,
+		Content:   "Your request has been held for review.",
+		CreatedAt: conversation.UpdatedAt,
+	})
+	return s.save(ctx, conversation)
+}
+
+// pendingFields returns the missing fields plus ambiguous fields that have not been confirmed yet
+func (s *ConversationService) pendingFields(conversation *models.Conversation) []string {
+	pending := ai.MissingTravelParameters(conversation.Parameters)
+	for _, field := range conversation.Parameters.AmbiguousFields {
+		if _, known := followUpQuestions[field]; !known {
+			continue
+		}
+		if slices.Contains(pending, field) || slices.Contains(conversation.AskedFields, field) {
+			continue
+		}
+		pending = append(pending, field)
+	}
+	return pending
+}
+
+func (s *ConversationService) save(ctx context.Context, conversation *models.Conversation) error {
+	if err := s.store.Save(ctx, conversation); err != nil {
+		return fmt.Errorf("failed to save conversation: %w", err)
+	}
+	return nil
+}
+
+// followUpMessage asks for missing fields and confirms the ones the model guessed
+func followUpMessage(fields []string, params *models.TravelParameters) string {
+	missing := ai.MissingTravelParameters(params)
+
+	questions := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if slices.Contains(missing, field) {
+			questions = append(questions, followUpQuestions[field])
+			continue
+		}
+		questions = append(questions, fmt.Sprintf("Just to confirm, is %s the right %s?",
+			describeField(field, params), strings.ReplaceAll(field, "_", " ")))
+	}
+
+	return strings.Join(questions, " ")
+}
+
+// describeField renders the current value of a field for confirmation questions
+func describeField(field string, params *models.TravelParameters) string {
+	switch field {
+	case ai.FieldDepartureCity:
+		return params.DepartureCity
+	case ai.FieldDestination:
+		return params.Destination
+	case ai.FieldDepartureDate:
+		return params.DepartureDate.Format("Monday, January 2, 2006")
+	case ai.FieldReturnDate:
+		return params.ReturnDate.Format("Monday, January 2, 2006")
+	case ai.FieldLegs:
+		cities := make([]string, 0, len(params.Legs)+1)
+		for i, leg := range params.Legs {
+			if i == 0 {
+				cities = append(cities, leg.Origin)
+			}
+			cities = append(cities, leg.Destination)
+		}
+		return strings.Join(cities, " → ")
+	}
+	return ""
+}
+
+// transcript renders the conversation so the extractor sees every answer in context
+func transcript(messages []models.ConversationMessage) string {
+	var b strings.Builder
+	for _, msg := range messages {
+		role := "Traveler"
+		if msg.Role == models.RoleAgent {
+			role = "Agent"
+		}
+		fmt.Fprintf(&b, "%s: %s\n", role, msg.Content)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// mergeTravelParameters fills the known parameters with the newly extracted values.
+// Values from the latest extraction win since they account for the traveler's answers.
+// Every field of TravelParameters is merged here, so a field added there needs a rule too.
+func mergeTravelParameters(base, update *models.TravelParameters) *models.TravelParameters {
+	if base == nil {
+		merged := *update
+		return &merged
+	}
+
+	merged := *base
+	if update.TripType != "" {
+		merged.TripType = update.TripType
+	}
+	if update.DepartureCity != "" {
+		merged.DepartureCity = update.DepartureCity
+	}
+	if update.Destination != "" {
+		merged.Destination = update.Destination
+	}
+	// A date and the phrase it was resolved from change together
+	if update.DepartureDate != nil {
+		merged.DepartureDate = update.DepartureDate
+		merged.DepartureDateText = update.DepartureDateText
+	}
+	if update.ReturnDate != nil {
+		merged.ReturnDate = update.ReturnDate
+		merged.ReturnDateText = update.ReturnDateText
+	}
+	if update.ReturnOrigin != "" {
+		merged.ReturnOrigin = update.ReturnOrigin
+	}
+	if update.ReturnDestination != "" {
+		merged.ReturnDestination = update.ReturnDestination
+	}
+	if len(update.Legs) > 0 {
+		merged.Legs = update.Legs
+	}
+	if update.Passengers.Adults > 0 {
+		merged.Passengers = update.Passengers
+	}
+	if merged.TripType == models.TripOneWay {
+		merged.ReturnDate = nil
+		merged.ReturnDateText = ""
+	}
+	merged.ResolvedDates = mergeDateResolutions(base, update, &merged)
+
+	prefs := &merged.Preferences
+	if update.Preferences.BudgetRange.Min != nil {
+		prefs.BudgetRange.Min = update.Preferences.BudgetRange.Min
+	}
+	if update.Preferences.BudgetRange.Max != nil {
+		prefs.BudgetRange.Max = update.Preferences.BudgetRange.Max
+	}
+	if update.Preferences.BudgetRange.Scope != "" {
+		prefs.BudgetRange.Scope = update.Preferences.BudgetRange.Scope
+	}
+	if update.Preferences.TravelClass != "" {
+		prefs.TravelClass = update.Preferences.TravelClass
+	}
+	if update.Preferences.DepartureTime != "" {
+		prefs.DepartureTime = update.Preferences.DepartureTime
+	}
+	if update.Preferences.Baggage != (models.Baggage{}) {
+		prefs.Baggage = update.Preferences.Baggage
+	}
+	prefs.Activities = mergeUnique(prefs.Activities, update.Preferences.Activities)
+	prefs.DietaryRestrictions = mergeUnique(prefs.DietaryRestrictions, update.Preferences.DietaryRestrictions)
+
+	merged.AmbiguousFields = update.AmbiguousFields
+
+	return &merged
+}
+
+// mergeDateResolutions keeps the resolution of each date in the merged parameters,
+// taken from the extraction that date came from
+func mergeDateResolutions(base, update, merged *models.TravelParameters) []models.DateResolution {
+	var resolutions []models.DateResolution
+	keep := func(params *models.TravelParameters, matches func(field string) bool) {
+		for _, resolution := range params.ResolvedDates {
+			if matches(resolution.Field) {
+				resolutions = append(resolutions, resolution)
+			}
+		}
+	}
+	field := func(name string) func(string) bool {
+		return func(field string) bool { return field == name }
+	}
+	// from is the extraction a date was taken from
+	from := func(updated bool) *models.TravelParameters {
+		if updated {
+			return update
+		}
+		return base
+	}
+
+	if merged.DepartureDate != nil {
+		keep(from(update.DepartureDate != nil), field(ai.FieldDepartureDate))
+	}
+	if merged.ReturnDate != nil {
+		keep(from(update.ReturnDate != nil), field(ai.FieldReturnDate))
+	}
+	keep(from(len(update.Legs) > 0), func(field string) bool {
+		return strings.HasPrefix(field, ai.FieldLegs+"[")
+	})
+	return resolutions
+}
+
+func mergeUnique(base, update []string) []string {
+	merged := append([]string(nil), base...)
+	for _, value := range update {
+		if !slices.Contains(merged, value) {
+			merged = append(merged, value)
+		}
+	}
+	return merged
+}
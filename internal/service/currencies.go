@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service/currency"
+)
+
+// defaultCurrency is assumed for flights the model didn't give a currency for
+const defaultCurrency = "USD"
+
+// WithRateProvider replaces the bundled exchange rates used to convert prices
+func WithRateProvider(provider currency.RateProvider) BookingOption {
+	return func(s *BookingService) {
+		s.rates = provider
+	}
+}
+
+// WithDefaultCurrency sets the currency used when neither the query nor the profile
+// names one. The default budget is in this currency.
+func WithDefaultCurrency(code string) BookingOption {
+	return func(s *BookingService) {
+		if code = currency.Normalize(code); code != "" {
+			s.currency = code
+		}
+	}
+}
+
+// travelerCurrency picks the currency prices are shown and budgets compared in: the one
+// named in the query, then the one the model extracted for the budget, then the
+// profile's, then the default. Currencies without a rate are skipped.
+func (s *BookingService) travelerCurrency(ctx context.Context, req models.BookingRequest, params *models.TravelParameters) string {
+	detected, _ := currency.Detect(req.Query)
+	candidates := []string{detected}
+	if budget := params.Preferences.BudgetRange; budget.Max != nil {
+		candidates = append(candidates, budget.Max.Currency)
+	} else if budget.Min != nil {
+		candidates = append(candidates, budget.Min.Currency)
+	}
+	if req.Profile != nil {
+		candidates = append(candidates, req.Profile.Currency)
+	}
+
+	for _, code := range candidates {
+		code = currency.Normalize(code)
+		if code == "" {
+			continue
+		}
+		if _, err := s.rates.Rate(ctx, code, s.currency); err == nil {
+			return code
+		}
+	}
+	return s.currency
+}
+
+// convertFlights sets the converted adult fare on flights priced in another currency.
+// Flights whose currency has no rate are left unconverted and rejected by the rules.
+func (s *BookingService) convertFlights(ctx context.Context, flights []models.Flight, to string) []models.Flight {
+	converted := make([]models.Flight, len(flights))
+	for i, flight := range flights {
+		flight.Price = flight.Price.Assume(defaultCurrency)
+		flight.Converted = nil
+		if from := flight.Price.Currency; from != to {
+			if rate, err := s.rates.Rate(ctx, from, to); err == nil {
+				flight.Converted = &models.ConvertedPrice{
+					Price: flight.Price.Exchange(rate, to),
+					Rate:  rate,
+				}
+			}
+		}
+		converted[i] = flight
+	}
+	return converted
+}
+
+// travelerPrice returns the adult fare in the traveler's currency
+func travelerPrice(flight models.Flight) models.Money {
+	if flight.Converted != nil {
+		return flight.Converted.Price
+	}
+	return flight.Price
+}
+
+// travelerTotal returns the party's fare in the traveler's currency
+func travelerTotal(flight models.Flight) models.Money {
+	if flight.Converted != nil {
+		return flight.Converted.Total
+	}
+	return flight.Fare.Total
+}
+
+// travelerBaggageFees returns what the party's extra bags cost in the traveler's
+// currency, if anything
+func travelerBaggageFees(flight models.Flight) *models.Money {
+	if flight.Fare == nil || flight.Fare.BaggageFees == nil {
+		return nil
+	}
+	fees := *flight.Fare.BaggageFees
+	if flight.Converted != nil {
+		fees = fees.Exchange(flight.Converted.Rate, flight.Converted.Price.Currency)
+	}
+	return &fees
+}
+
+func checkCurrency(flight models.Flight, req models.FlightRecommendationRequest, _ flightConstraints) string {
+	if req.Currency != "" && flight.Price.Currency != req.Currency && flight.Converted == nil {
+		return fmt.Sprintf("is priced in %s, which can't be converted to %s", flight.Price.Currency, req.Currency)
+	}
+	return ""
+}
@@ -0,0 +1,149 @@
+package currency
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"travel-agent/internal/models"
+)
+
+//go:embed rates.json
+var defaultRates []byte
+
+// ErrUnknownCurrency is returned when a rate source has no rate for a currency
+var ErrUnknownCurrency = errors.New("unknown currency")
+
+// RateProvider gives the exchange rate between two ISO 4217 currencies, such that
+// an amount in from multiplied by the rate is the amount in to
+type RateProvider interface {
+	Rate(ctx context.Context, from, to string) (float64, error)
+}
+
+// Table is a fixed set of rates against a base currency, typically loaded from a file
+// so conversions work offline
+type Table struct {
+	Base  string             `json:"base"`
+	AsOf  string             `json:"as_of"` // Date the rates were taken, informational
+	Rates map[string]float64 `json:"rates"` // Units of each currency per unit of Base
+}
+
+var _ RateProvider = (*Table)(nil)
+
+// DefaultTable returns the rates bundled with the binary
+func DefaultTable() (*Table, error) {
+	return ParseTable(defaultRates)
+}
+
+// LoadTable reads a rates table from disk
+func LoadTable(filename string) (*Table, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading rates file: %w", err)
+	}
+	return ParseTable(data)
+}
+
+// ParseTable builds a table from JSON. The base currency must have a rate of 1.
+func ParseTable(data []byte) (*Table, error) {
+	var table Table
+	if err := json.Unmarshal(data, &table); err != nil {
+		return nil, fmt.Errorf("parsing rates: %w", err)
+	}
+
+	table.Base = Normalize(table.Base)
+	if table.Base == "" {
+		return nil, fmt.Errorf("rates: base currency is required")
+	}
+	rates := make(map[string]float64, len(table.Rates))
+	for code, rate := range table.Rates {
+		if rate <= 0 {
+			return nil, fmt.Errorf("rates: %s must be positive", code)
+		}
+		rates[Normalize(code)] = rate
+	}
+	if rates[table.Base] != 1 {
+		return nil, fmt.Errorf("rates: base currency %s must have a rate of 1", table.Base)
+	}
+	table.Rates = rates
+
+	return &table, nil
+}
+
+// Rate converts through the base currency
+func (t *Table) Rate(ctx context.Context, from, to string) (float64, error) {
+	from, to = Normalize(from), Normalize(to)
+	if from == to {
+		return 1, nil
+	}
+
+	fromRate, ok := t.Rates[from]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownCurrency, from)
+	}
+	toRate, ok := t.Rates[to]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownCurrency, to)
+	}
+	return toRate / fromRate, nil
+}
+
+// Convert converts an amount into another currency using the provider. Amounts
+// without a currency are taken to already be in to.
+func Convert(ctx context.Context, provider RateProvider, amount models.Money, to string) (models.Money, error) {
+	to = Normalize(to)
+	if amount.Currency == "" || amount.Currency == to {
+		return amount.Assume(to), nil
+	}
+	rate, err := provider.Rate(ctx, amount.Currency, to)
+	if err != nil {
+		return models.Money{}, err
+	}
+	return amount.Exchange(rate, to), nil
+}
+
+// Normalize uppercases a currency code, returning "" when it isn't three letters
+func Normalize(code string) string {
+	return models.NormalizeCurrency(code)
+}
+
+// currencyMentions maps the ways travelers write amounts to currency codes. ISO codes
+// must be uppercase so words like "pen" don't match; a bare "$", "pesos" or "pounds"
+// (often a weight) is ambiguous and not detected.
+var currencyMentions = []struct {
+	pattern *regexp.Regexp
+	code    string
+}{
+	{regexp.MustCompile(`\bUSD\b|\bUS\$|(?i:\b(us dollars?|american dollars?|d[oó]lares)\b)`), "USD"},
+	{regexp.MustCompile(`\bEUR\b|€|(?i:\beuros?\b)`), "EUR"},
+	{regexp.MustCompile(`\bGBP\b|£|(?i:\b(pounds sterling|british pounds?|quid)\b)`), "GBP"},
+	{regexp.MustCompile(`\bCOP\b|(?i:\b(pesos colombianos|colombian pesos?)\b)`), "COP"},
+	{regexp.MustCompile(`\bMXN\b|(?i:\b(pesos mexicanos|mexican pesos?)\b)`), "MXN"},
+	{regexp.MustCompile(`\bARS\b|(?i:\b(pesos argentinos|argentine pesos?)\b)`), "ARS"},
+	{regexp.MustCompile(`\bCLP\b|(?i:\b(pesos chilenos|chilean pesos?)\b)`), "CLP"},
+	{regexp.MustCompile(`\bBRL\b|\bR\$|(?i:\b(reais|brazilian reals?)\b)`), "BRL"},
+	{regexp.MustCompile(`\bPEN\b|(?i:\b(soles|peruvian soles)\b)`), "PEN"},
+	{regexp.MustCompile(`\bCAD\b|\bC\$|(?i:\bcanadian dollars?\b)`), "CAD"},
+	{regexp.MustCompile(`\bAUD\b|\bA\$|(?i:\baustralian dollars?\b)`), "AUD"},
+	{regexp.MustCompile(`\bCHF\b|(?i:\bswiss francs?\b)`), "CHF"},
+	{regexp.MustCompile(`\bJPY\b|¥|(?i:\byen\b)`), "JPY"},
+}
+
+// Detect finds the currency a query talks about. It only answers when exactly one
+// currency is mentioned.
+func Detect(text string) (string, bool) {
+	found := ""
+	for _, mention := range currencyMentions {
+		if !mention.pattern.MatchString(text) {
+			continue
+		}
+		if found != "" && found != mention.code {
+			return "", false
+		}
+		found = mention.code
+	}
+	return found, found != ""
+}
@@ -0,0 +1,277 @@
+package dates
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // Time zones must resolve even on hosts without a zoneinfo database
+)
+
+// Resolver turns relative and partial date phrases ("next Friday", "March 10th",
+// "back on the 3rd") into calendar dates. Results are deterministic for a given
+// reference time and location, and always fall at noon in the traveler's time zone
+// so that converting to UTC never moves them to another day.
+type Resolver struct {
+	now time.Time
+}
+
+// NewResolver creates a resolver anchored at the reference time in the traveler's location
+func NewResolver(reference time.Time, location *time.Location) *Resolver {
+	if location == nil {
+		location = time.UTC
+	}
+	return &Resolver{now: reference.In(location)}
+}
+
+// Now returns the reference time in the traveler's location
+func (r *Resolver) Now() time.Time {
+	return r.now
+}
+
+// Today returns the reference date at noon
+func (r *Resolver) Today() time.Time {
+	return atNoon(r.now)
+}
+
+// Resolve resolves a phrase relative to the reference time
+func (r *Resolver) Resolve(phrase string) (time.Time, bool) {
+	return r.ResolveFrom(phrase, r.now)
+}
+
+// ResolveFrom resolves a phrase whose partial dates ("the 3rd", "March 10"), offsets
+// ("a week later") and weekends are relative to anchor, typically the departure date
+// when resolving a return. Phrases relative to today ("tomorrow", "in 3 days")
+// always use the reference time. Phrases naming a span rather than a day, such as
+// "next month", are left unresolved for the model to place.
+func (r *Resolver) ResolveFrom(phrase string, anchor time.Time) (time.Time, bool) {
+	text := normalize(phrase)
+	if text == "" {
+		return time.Time{}, false
+	}
+	anchor = atNoon(anchor.In(r.now.Location()))
+	today := r.Today()
+
+	if t, err := time.ParseInLocation("2006-01-02", text, r.now.Location()); err == nil {
+		return atNoon(t), true
+	}
+
+	switch text {
+	case "today", "tonight":
+		return today, true
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), true
+	case "day after tomorrow":
+		return today.AddDate(0, 0, 2), true
+	case "next week":
+		return startOfWeek(today).AddDate(0, 0, 7), true
+	case "this weekend", "weekend":
+		// Relative to a later departure, a weekend is when the trip ends: its Sunday
+		if anchor.After(today) {
+			return nextWeekday(anchor, time.Sunday, true), true
+		}
+		return nextWeekday(today, time.Saturday, true), true
+	case "next weekend":
+		return startOfWeek(today).AddDate(0, 0, 7+5), true
+	}
+
+	if m := inOffsetPattern.FindStringSubmatch(text); m != nil {
+		if n, ok := parseCount(m[1]); ok {
+			return addUnit(today, n, m[2]), true
+		}
+	}
+
+	if m := laterOffsetPattern.FindStringSubmatch(text); m != nil {
+		if n, ok := parseCount(m[1]); ok {
+			return addUnit(anchor, n, m[2]), true
+		}
+	}
+
+	if m := afterOffsetPattern.FindStringSubmatch(text); m != nil {
+		if n, ok := parseCount(m[1]); ok {
+			return addUnit(anchor, n, m[2]), true
+		}
+	}
+
+	if m := weekdayPattern.FindStringSubmatch(text); m != nil {
+		weekday := weekdays[m[2]]
+		switch m[1] {
+		case "next":
+			// "next Friday" is the Friday of next week
+			return startOfWeek(today).AddDate(0, 0, 7+daysFromMonday(weekday)), true
+		case "this":
+			return nextWeekday(anchor, weekday, true), true
+		default:
+			return nextWeekday(anchor, weekday, false), true
+		}
+	}
+
+	if m := dayOfMonthPattern.FindStringSubmatch(text); m != nil {
+		day, _ := strconv.Atoi(m[1])
+		return nextDayOfMonth(anchor, day)
+	}
+
+	if m := monthDayPattern.FindStringSubmatch(text); m != nil {
+		return r.monthDay(m[1], m[2], m[3], anchor)
+	}
+
+	if m := dayMonthPattern.FindStringSubmatch(text); m != nil {
+		return r.monthDay(m[2], m[1], m[3], anchor)
+	}
+
+	return time.Time{}, false
+}
+
+// monthDay resolves a month and day, using the first occurrence on or after anchor when
+// the year is not given
+func (r *Resolver) monthDay(monthName, dayText, yearText string, anchor time.Time) (time.Time, bool) {
+	month, ok := months[monthName]
+	if !ok {
+		return time.Time{}, false
+	}
+	day, err := strconv.Atoi(dayText)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	if yearText != "" {
+		year, err := strconv.Atoi(yearText)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return validDate(year, month, day, anchor.Location())
+	}
+
+	for year := anchor.Year(); year <= anchor.Year()+1; year++ {
+		t, ok := validDate(year, month, day, anchor.Location())
+		if ok && !t.Before(anchor) {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+var (
+	inOffsetPattern    = regexp.MustCompile(`^in (\w+) (day|night|week|month)s?$`)
+	laterOffsetPattern = regexp.MustCompile(`^(\w+)[ -](day|night|week|month)s?(?: later| after| trip| stay)?$`)
+	afterOffsetPattern = regexp.MustCompile(`^after (\w+) (day|night|week|month)s?$`)
+	weekdayPattern     = regexp.MustCompile(`^(?:the )?(?:(next|this) )?(monday|tuesday|wednesday|thursday|friday|saturday|sunday|mon|tue|tues|wed|thu|thur|thurs|fri|sat|sun)(?: after)?$`)
+	monthDayPattern    = regexp.MustCompile(`^([a-z]+) (\d{1,2})(?: (\d{4}))?$`)
+	dayMonthPattern    = regexp.MustCompile(`^(\d{1,2}) (?:of )?([a-z]+)(?: (\d{4}))?$`)
+	dayOfMonthPattern  = regexp.MustCompile(`^(?:the )?(\d{1,2})$`)
+	ordinalPattern     = regexp.MustCompile(`(\d+)(?:st|nd|rd|th)\b`)
+)
+
+// leadingWords are dropped from phrases since they don't change the date
+var leadingWords = []string{"back on ", "returning on ", "returning ", "leaving on ", "leaving ", "on ", "by ", "for a ", "for "}
+
+var weekdays = map[string]time.Weekday{
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+	"sunday": time.Sunday, "sun": time.Sunday,
+}
+
+var months = map[string]time.Month{
+	"january": time.January, "jan": time.January,
+	"february": time.February, "feb": time.February,
+	"march": time.March, "mar": time.March,
+	"april": time.April, "apr": time.April,
+	"may":  time.May,
+	"june": time.June, "jun": time.June,
+	"july": time.July, "jul": time.July,
+	"august": time.August, "aug": time.August,
+	"september": time.September, "sep": time.September, "sept": time.September,
+	"october": time.October, "oct": time.October,
+	"november": time.November, "nov": time.November,
+	"december": time.December, "dec": time.December,
+}
+
+var counts = map[string]int{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6,
+	"seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12, "fourteen": 14,
+}
+
+// normalize lowercases the phrase, strips punctuation, ordinal suffixes and filler words
+func normalize(phrase string) string {
+	text := strings.ToLower(strings.TrimSpace(phrase))
+	text = strings.NewReplacer(",", " ", ".", " ").Replace(text)
+	text = ordinalPattern.ReplaceAllString(text, "$1")
+	text = strings.Join(strings.Fields(text), " ")
+
+	for _, word := range leadingWords {
+		text = strings.TrimPrefix(text, word)
+	}
+	return text
+}
+
+func parseCount(text string) (int, bool) {
+	if n, ok := counts[text]; ok {
+		return n, true
+	}
+	n, err := strconv.Atoi(text)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+func addUnit(t time.Time, n int, unit string) time.Time {
+	switch unit {
+	case "week":
+		return t.AddDate(0, 0, 7*n)
+	case "month":
+		return t.AddDate(0, n, 0)
+	default:
+		return t.AddDate(0, 0, n)
+	}
+}
+
+func atNoon(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 12, 0, 0, 0, t.Location())
+}
+
+func daysFromMonday(weekday time.Weekday) int {
+	return (int(weekday) + 6) % 7
+}
+
+// startOfWeek returns the Monday of the week containing t
+func startOfWeek(t time.Time) time.Time {
+	return t.AddDate(0, 0, -daysFromMonday(t.Weekday()))
+}
+
+// nextWeekday returns the next given weekday after t, or t itself if inclusive and it matches
+func nextWeekday(t time.Time, weekday time.Weekday, inclusive bool) time.Time {
+	days := (int(weekday) - int(t.Weekday()) + 7) % 7
+	if days == 0 && !inclusive {
+		days = 7
+	}
+	return t.AddDate(0, 0, days)
+}
+
+// nextDayOfMonth returns the first date on or after t falling on the given day of the month
+func nextDayOfMonth(t time.Time, day int) (time.Time, bool) {
+	for i := 0; i < 12; i++ {
+		candidate, ok := validDate(t.Year(), t.Month()+time.Month(i), day, t.Location())
+		if ok && !candidate.Before(t) {
+			return candidate, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// validDate builds a date at noon, rejecting days the month doesn't have
+func validDate(year int, month time.Month, day int, location *time.Location) (time.Time, bool) {
+	if day < 1 || day > 31 {
+		return time.Time{}, false
+	}
+	t := time.Date(year, month, day, 12, 0, 0, 0, location)
+	if t.Day() != day {
+		return time.Time{}, false
+	}
+	return t, true
+}
@@ -0,0 +1,311 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service/airports"
+)
+
+// ErrFlightsRejected is returned when every flight found breaks a rejecting rule
+var ErrFlightsRejected = errors.New("every recommended flight was rejected")
+
+// RejectedFlightsError carries the rejected flights when none was left, so the traveler
+// learns why
+type RejectedFlightsError struct {
+	Rejected []models.FlightRejection
+	err      error
+}
+
+func (e *RejectedFlightsError) Error() string { return e.err.Error() }
+func (e *RejectedFlightsError) Unwrap() error { return e.err }
+
+// withRejections attaches the rejected flights to an error caused by all of them being
+// rejected, and returns other errors unchanged
+func withRejections(err error, rejected []models.FlightRejection) error {
+	if !errors.Is(err, ErrFlightsRejected) {
+		return err
+	}
+	return &RejectedFlightsError{Rejected: rejected, err: err}
+}
+
+// durationTolerance is how far TotalDuration may be from the timestamps before the
+// flight is flagged; models tend to round durations
+const durationTolerance = 30 * time.Minute
+
+// flightConstraints are the limits a recommended flight must respect: the requested
+// route and the preferences the traveler or their profile stated
+type flightConstraints struct {
+	travelClass     string
+	maxPrice        models.Money // Party's total in the traveler's currency, bags included; zero means no limit
+	defaultMaxPrice models.Money // Configured budget for the party, only flagged
+	maxLayovers     *int
+	// origin and destination are set when the requested cities are in the airport database
+	origin      *airports.City
+	destination *airports.City
+	airports    *airports.Database
+}
+
+type ruleSeverity int
+
+const (
+	ruleFlag   ruleSeverity = iota // The flight is kept with a warning
+	ruleReject                     // The flight is discarded
+)
+
+// flightRule is a sanity check on a model-generated flight. check returns the reason
+// the flight breaks the rule, or an empty string.
+type flightRule struct {
+	name     string
+	severity ruleSeverity
+	check    func(flight models.Flight, req models.FlightRecommendationRequest, c flightConstraints) string
+}
+
+// flightRules are run over every recommendation, in order
+var flightRules = []flightRule{
+	{"flight_number", ruleReject, checkFlightNumber},
+	{"arrival_before_departure", ruleReject, checkArrivalAfterDeparture},
+	{"duration", ruleFlag, checkDuration},
+	{"segments", ruleReject, checkSegments},
+	{"connection_time", ruleFlag, checkConnectionTimes},
+	{"route", ruleReject, checkRoute},
+	{"unknown_city", ruleFlag, checkKnownCities},
+	{"dates", ruleReject, checkDepartureWindow},
+	{"seats", ruleReject, checkSeats},
+	{"travel_class", ruleReject, checkTravelClass},
+	{"layovers", ruleReject, checkLayovers},
+	{"currency", ruleReject, checkCurrency},
+	{"budget", ruleReject, checkBudget},
+	{"default_budget", ruleFlag, checkDefaultBudget},
+	{"baggage", ruleFlag, checkBaggage},
+}
+
+// legFlightRules skip the departure window for multi-city legs, where it is reported
+// as an itinerary issue instead
+var legFlightRules = withoutRule(flightRules, "dates")
+
+// checkFlights runs the rules over the recommendations. Flights breaking a rejecting
+// rule are dropped and reported; flights breaking only flagging rules are kept with
+// warnings. It fails with ErrFlightsRejected when no flight is left.
+func checkFlights(
+	flights []models.Flight,
+	req models.FlightRecommendationRequest,
+	c flightConstraints,
+	rules []flightRule,
+) ([]models.Flight, []models.FlightRejection, error) {
+	kept := make([]models.Flight, 0, len(flights))
+	var rejected []models.FlightRejection
+
+	for _, flight := range flights {
+		var reasons []string
+		flight.Warnings = nil
+		for _, rule := range rules {
+			reason := rule.check(flight, req, c)
+			if reason == "" {
+				continue
+			}
+			if rule.severity == ruleReject {
+				reasons = append(reasons, reason)
+			} else {
+				flight.Warnings = append(flight.Warnings, reason)
+			}
+		}
+
+		if len(reasons) > 0 {
+			rejected = append(rejected, models.FlightRejection{
+				Airline:      flight.Airline,
+				FlightNumber: flight.FlightNumber,
+				Reasons:      reasons,
+			})
+			continue
+		}
+		kept = append(kept, flight)
+	}
+
+	if len(kept) == 0 {
+		return nil, rejected, fmt.Errorf("%w: %s", ErrFlightsRejected, describeRejections(rejected))
+	}
+	return kept, rejected, nil
+}
+
+func describeRejections(rejected []models.FlightRejection) string {
+	descriptions := make([]string, len(rejected))
+	for i, r := range rejected {
+		descriptions[i] = fmt.Sprintf("%s (%s)", r.FlightNumber, strings.Join(r.Reasons, ", "))
+	}
+	return strings.Join(descriptions, "; ")
+}
+
+func withoutRule(rules []flightRule, name string) []flightRule {
+	filtered := make([]flightRule, 0, len(rules))
+	for _, rule := range rules {
+		if rule.name != name {
+			filtered = append(filtered, rule)
+		}
+	}
+	return filtered
+}
+
+// flightNumberPattern matches an IATA (2 characters) or ICAO (3 letters) airline
+// designator followed by up to four digits and an optional suffix, e.g. "BA123" or "AVA 10"
+var flightNumberPattern = regexp.MustCompile(`^([A-Z0-9]{2}|[A-Z]{3}) ?[0-9]{1,4}[A-Z]?$`)
+
+func checkFlightNumber(flight models.Flight, _ models.FlightRecommendationRequest, _ flightConstraints) string {
+	number := strings.ToUpper(strings.TrimSpace(flight.FlightNumber))
+	m := flightNumberPattern.FindStringSubmatch(number)
+	// A designator made only of digits is ambiguous with the flight number itself
+	if m == nil || strings.Trim(m[1], "0123456789") == "" {
+		return fmt.Sprintf("flight number %q is not valid", flight.FlightNumber)
+	}
+	return ""
+}
+
+func checkArrivalAfterDeparture(flight models.Flight, _ models.FlightRecommendationRequest, _ flightConstraints) string {
+	if flight.DepartureTime.IsZero() || flight.ArrivalTime.IsZero() {
+		return "departure or arrival time is missing"
+	}
+	if !flight.ArrivalTime.After(flight.DepartureTime) {
+		return "arrives before it departs"
+	}
+	return ""
+}
+
+func checkDuration(flight models.Flight, _ models.FlightRecommendationRequest, _ flightConstraints) string {
+	stated := flight.TotalDuration.Std()
+	if stated == 0 || flight.DepartureTime.IsZero() || flight.ArrivalTime.IsZero() {
+		return ""
+	}
+	actual := flight.ArrivalTime.Sub(flight.DepartureTime)
+	if (stated - actual).Abs() > durationTolerance {
+		return fmt.Sprintf("total duration %s doesn't match the %s between departure and arrival", flight.TotalDuration, models.Duration(actual))
+	}
+	return ""
+}
+
+// Cities missing from the database can't be checked here; checkKnownCities flags them
+func checkRoute(flight models.Flight, _ models.FlightRecommendationRequest, c flightConstraints) string {
+	var problems []string
+	if city, ok := c.airports.Resolve(flight.DepartureCity); ok && c.origin != nil && city != c.origin {
+		problems = append(problems, fmt.Sprintf("departs from %s, not %s", city.Name, c.origin.Name))
+	}
+	if city, ok := c.airports.Resolve(flight.ArrivalCity); ok && c.destination != nil && city != c.destination {
+		problems = append(problems, fmt.Sprintf("arrives in %s, not %s", city.Name, c.destination.Name))
+	}
+	return strings.Join(problems, " and ")
+}
+
+// checkKnownCities flags flights whose route can't be checked because a city, requested
+// or flown, is not in the database
+func checkKnownCities(flight models.Flight, req models.FlightRecommendationRequest, c flightConstraints) string {
+	if c.airports == nil {
+		return ""
+	}
+	var unknown []string
+	for _, name := range []string{req.DepartureCity, req.Destination, flight.DepartureCity, flight.ArrivalCity} {
+		if _, ok := c.airports.Resolve(name); name != "" && !ok && !slices.Contains(unknown, name) {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("route not checked, %s not a known city", strings.Join(unknown, " and "))
+}
+
+// checkDepartureWindow compares calendar dates: the requested date in the traveler's
+// time zone and the departure in the origin airport's local time
+func checkDepartureWindow(flight models.Flight, req models.FlightRecommendationRequest, c flightConstraints) string {
+	if req.DepartureDate.IsZero() || flight.DepartureTime.IsZero() {
+		return ""
+	}
+
+	first := calendarDate(req.DepartureDate)
+	last := first
+	if req.DepartureWindowEnd != nil {
+		last = calendarDate(*req.DepartureWindowEnd)
+	}
+	local := localDepartureTime(flight, c.airports)
+	if departs := calendarDate(local); departs.Before(first) || departs.After(last) {
+		return fmt.Sprintf("departs on %s, outside the requested dates", local.Format("2006-01-02"))
+	}
+	return ""
+}
+
+func checkSeats(flight models.Flight, req models.FlightRecommendationRequest, _ flightConstraints) string {
+	if seats := seatsRequired(req.Passengers); flight.AvailableSeats < seats {
+		return fmt.Sprintf("only %d seats available for %d passengers", flight.AvailableSeats, seats)
+	}
+	return ""
+}
+
+func checkTravelClass(flight models.Flight, _ models.FlightRecommendationRequest, c flightConstraints) string {
+	if c.travelClass != "" && flight.Class != "" && normalizeTravelClass(flight.Class) != c.travelClass {
+		return fmt.Sprintf("is %s, not %s", flight.Class, c.travelClass)
+	}
+	return ""
+}
+
+func checkLayovers(flight models.Flight, _ models.FlightRecommendationRequest, c flightConstraints) string {
+	if layovers := layoversOf(flight); c.maxLayovers != nil && layovers > *c.maxLayovers {
+		return fmt.Sprintf("has %d layovers, more than %d", layovers, *c.maxLayovers)
+	}
+	return ""
+}
+
+// Budgets are compared with what the whole party pays, bags included, converted into
+// the traveler's currency. Flights must be priced first.
+func checkBudget(flight models.Flight, _ models.FlightRecommendationRequest, c flightConstraints) string {
+	if flight.Fare == nil {
+		return ""
+	}
+	if total := travelerTotal(flight); exceedsBudget(total, c.maxPrice) {
+		return fmt.Sprintf("costs %s for the party, above the %s budget", total, c.maxPrice.Amount())
+	}
+	return ""
+}
+
+func checkDefaultBudget(flight models.Flight, _ models.FlightRecommendationRequest, c flightConstraints) string {
+	if flight.Fare == nil {
+		return ""
+	}
+	if total := travelerTotal(flight); exceedsBudget(total, c.defaultMaxPrice) {
+		return fmt.Sprintf("costs %s for the party, above the usual %s budget", total, c.defaultMaxPrice.Amount())
+	}
+	return ""
+}
+
+// checkBaggage flags flights where the party's bags can't be priced: the allowance
+// isn't known, or the extra bags needed have no fee
+func checkBaggage(flight models.Flight, req models.FlightRecommendationRequest, _ flightConstraints) string {
+	if req.Baggage.CheckedBags <= 0 && req.Baggage.CabinBags <= 0 {
+		return ""
+	}
+	conditions := flight.Conditions
+	if conditions == nil {
+		return "baggage allowance unknown, bag fees not included"
+	}
+	checked, cabin := extraBags(conditions, req.Passengers, req.Baggage)
+	if _, ok := feeIn(conditions.CheckedBagFee, flight.Price.Currency); checked > 0 && !ok {
+		return fmt.Sprintf("includes %d checked bags per passenger, the fee for %d more is unknown", conditions.CheckedBags, checked)
+	}
+	if _, ok := feeIn(conditions.CabinBagFee, flight.Price.Currency); cabin > 0 && !ok {
+		return fmt.Sprintf("includes %d cabin bags per passenger, the fee for %d more is unknown", conditions.CabinBags, cabin)
+	}
+	return ""
+}
+
+// exceedsBudget reports whether price is above a set budget. Fares that couldn't be
+// converted into the budget's currency are left to the currency rule.
+func exceedsBudget(price, budget models.Money) bool {
+	return budget.IsPositive() && price.Currency == budget.Currency && price.Cmp(budget) > 0
+}
+
+// calendarDate returns midnight UTC of the date t falls on in its own location
+func calendarDate(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,357 @@
+package flights
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"travel-agent/internal/models"
+)
+
+// maxSearchDays caps the dates searched for a flexible departure window
+const maxSearchDays = 7
+
+// Client searches a Duffel-style REST API for flight offers
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for the API at baseURL. The key is sent as a bearer
+// token when set; the bundled stub server doesn't need one.
+func NewClient(baseURL, apiKey string, timeout time.Duration) *Client {
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// Search asks for offers on every date of the departure window, up to maxSearchDays,
+// cheapest first. Each date is an offer request of its own: the slices of a request
+// are the legs of one journey, so listing the dates together would price a single trip
+// flying all of them. Return flights are searched separately, like any one-way trip.
+func (c *Client) Search(ctx context.Context, req models.FlightSearchRequest) ([]models.Flight, error) {
+	if req.DepartureDate.IsZero() {
+		return nil, fmt.Errorf("departure date is required")
+	}
+
+	passengers := passengersOf(req.Passengers)
+	last := req.DepartureDate
+	if req.LatestDate != nil && req.LatestDate.After(last) {
+		last = *req.LatestDate
+	}
+
+	var flights []models.Flight
+	for day, days := req.DepartureDate, 0; !day.After(last) && days < maxSearchDays; day, days = day.AddDate(0, 0, 1), days+1 {
+		date := day.Format("2006-01-02")
+		offers, err := c.offerRequest(ctx, offerRequest{
+			Slices: []sliceRequest{{
+				Origin:        req.Origin,
+				Destination:   req.Destination,
+				DepartureDate: date,
+			}},
+			Passengers:     passengers,
+			CabinClass:     req.TravelClass,
+			MaxConnections: req.MaxLayovers,
+			Currency:       req.Currency,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("searching %s: %w", date, err)
+		}
+
+		// A malformed offer is skipped rather than failing the whole search
+		for _, raw := range offers {
+			var o offer
+			if err := json.Unmarshal(raw, &o); err != nil {
+				slog.WarnContext(ctx, "skipping undecodable flight offer", "date", date, "error", err)
+				continue
+			}
+			flight, err := toFlight(o, passengers)
+			if err != nil {
+				slog.WarnContext(ctx, "skipping flight offer", "offer_id", o.ID, "date", date, "error", err)
+				continue
+			}
+			flights = append(flights, flight)
+		}
+	}
+
+	sort.SliceStable(flights, func(i, j int) bool {
+		return flights[i].Price.Cmp(flights[j].Price) < 0
+	})
+	return flights, nil
+}
+
+func (c *Client) offerRequest(ctx context.Context, body offerRequest) ([]json.RawMessage, error) {
+	payload, err := json.Marshal(offerRequestEnvelope{Data: body})
+	if err != nil {
+		return nil, fmt.Errorf("encoding offer request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+offerRequestsPath, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("creating offer request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+	if c.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("searching flights: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading search response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("flight search failed with status %d: %s", resp.StatusCode, errorMessage(data))
+	}
+
+	var envelope offerResponseEnvelope[json.RawMessage]
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("decoding search response: %w", err)
+	}
+	return envelope.Data.Offers, nil
+}
+
+// errorMessage extracts the API's error messages, falling back to the raw body
+func errorMessage(body []byte) string {
+	var parsed errorResponse
+	if err := json.Unmarshal(body, &parsed); err == nil && len(parsed.Errors) > 0 {
+		messages := make([]string, len(parsed.Errors))
+		for i, e := range parsed.Errors {
+			messages[i] = e.Message
+			if messages[i] == "" {
+				messages[i] = e.Title
+			}
+		}
+		return strings.Join(messages, "; ")
+	}
+	return strings.TrimSpace(string(body))
+}
+
+func passengersOf(p models.Passengers) []passengerRequest {
+	passengers := make([]passengerRequest, 0, p.Adults+p.Children+p.Infants)
+	for i := 0; i < max(p.Adults, 1); i++ {
+		passengers = append(passengers, passengerRequest{Type: "adult"})
+	}
+	for i := 0; i < p.Children; i++ {
+		child := passengerRequest{Type: "child"}
+		if i < len(p.ChildAges) {
+			child.Age = p.ChildAges[i]
+		}
+		passengers = append(passengers, child)
+	}
+	for i := 0; i < p.Infants; i++ {
+		passengers = append(passengers, passengerRequest{Type: "infant_without_seat"})
+	}
+	return passengers
+}
+
+// toFlight maps the first slice of an offer to a flight. Price is the adult fare, as
+// elsewhere in the service; child and infant fares are derived from it.
+func toFlight(o offer, passengers []passengerRequest) (models.Flight, error) {
+	if len(o.Slices) == 0 || len(o.Slices[0].Segments) == 0 {
+		return models.Flight{}, fmt.Errorf("no segments")
+	}
+	slice := o.Slices[0]
+
+	price, err := adultFare(o, passengers)
+	if err != nil {
+		return models.Flight{}, err
+	}
+	segments, err := toSegments(slice.Segments)
+	if err != nil {
+		return models.Flight{}, err
+	}
+	first, last := segments[0], segments[len(segments)-1]
+
+	return models.Flight{
+		OfferID:        o.ID,
+		Airline:        o.Owner.Name,
+		FlightNumber:   first.FlightNumber,
+		DepartureCity:  cityName(slice.Origin),
+		DepartureTime:  first.DepartureTime,
+		ArrivalCity:    cityName(slice.Destination),
+		ArrivalTime:    last.ArrivalTime,
+		Class:          o.CabinClass,
+		LayoverCount:   len(segments) - 1,
+		Segments:       segments,
+		TotalDuration:  models.Duration(last.ArrivalTime.Sub(first.DepartureTime)),
+		AvailableSeats: o.AvailableSeats,
+		Price:          price,
+		Conditions:     toConditions(o, slice),
+	}, nil
+}
+
+// Baggage types in allowances and services
+const (
+	baggageChecked = "checked"
+	baggageCarryOn = "carry_on"
+)
+
+// toConditions reads the fare family and baggage allowance of the slice and the
+// offer's refund and change rules. Allowances are taken from the first segment's first
+// passenger, who is an adult. Offers saying nothing about either are left without.
+func toConditions(o offer, slice offerSlice) *models.FareConditions {
+	conditions := &models.FareConditions{FareFamily: slice.FareBrandName}
+	known := slice.FareBrandName != ""
+
+	if passengers := slice.Segments[0].Passengers; len(passengers) > 0 {
+		known = true
+		for _, bag := range passengers[0].Baggages {
+			switch bag.Type {
+			case baggageChecked:
+				conditions.CheckedBags += bag.Quantity
+			case baggageCarryOn:
+				conditions.CabinBags += bag.Quantity
+			}
+		}
+	}
+	if refund := o.Conditions.RefundBeforeDeparture; refund != nil {
+		known = true
+		conditions.Refundable = refund.Allowed
+		conditions.RefundFee = penalty(refund)
+	}
+	if change := o.Conditions.ChangeBeforeDeparture; change != nil {
+		known = true
+		conditions.Changeable = change.Allowed
+		conditions.ChangeFee = penalty(change)
+	}
+	currency := models.NormalizeCurrency(o.TotalCurrency)
+	for _, service := range o.AvailableServices {
+		fee, err := models.ParseMoney(service.TotalAmount, service.TotalCurrency)
+		if err != nil {
+			continue
+		}
+		switch {
+		case service.Type == "baggage" && service.Metadata.Type == baggageChecked:
+			conditions.CheckedBagFee = cheaperFee(conditions.CheckedBagFee, fee, currency)
+		case service.Type == "baggage" && service.Metadata.Type == baggageCarryOn:
+			conditions.CabinBagFee = cheaperFee(conditions.CabinBagFee, fee, currency)
+		case service.Type == "seat":
+			conditions.SeatSelectionFee = cheaperFee(conditions.SeatSelectionFee, fee, currency)
+		}
+	}
+
+	if !known {
+		return nil
+	}
+	return conditions
+}
+
+// penalty is the fee for a refund or change that is allowed, when the offer gives one
+func penalty(c *offerCondition) *models.Money {
+	if !c.Allowed || c.PenaltyAmount == "" {
+		return nil
+	}
+	fee, err := models.ParseMoney(c.PenaltyAmount, c.PenaltyCurrency)
+	if err != nil {
+		return nil
+	}
+	return &fee
+}
+
+// cheaperFee keeps the lowest price offered for the same kind of service, preferring
+// fees in the offer's currency so the pick doesn't depend on the order they are listed
+func cheaperFee(current *models.Money, fee models.Money, currency string) *models.Money {
+	if current != nil && !fee.Less(*current, currency) {
+		return current
+	}
+	return &fee
+}
+
+// toSegments maps an offer's segments, working out the layover before each connection
+func toSegments(segments []offerSegment) ([]models.Segment, error) {
+	mapped := make([]models.Segment, len(segments))
+	for i, seg := range segments {
+		flightNumber := seg.MarketingCarrierFlightNumber
+		if code := seg.MarketingCarrier.IATACode; code != "" && !strings.HasPrefix(flightNumber, code) {
+			flightNumber = code + flightNumber
+		}
+		departure, err := seg.departure()
+		if err != nil {
+			return nil, fmt.Errorf("segment %d departure: %w", i+1, err)
+		}
+		arrival, err := seg.arrival()
+		if err != nil {
+			return nil, fmt.Errorf("segment %d arrival: %w", i+1, err)
+		}
+		duration, err := models.ParseDuration(seg.Duration)
+		if err != nil {
+			duration = models.Duration(arrival.Sub(departure))
+		}
+
+		mapped[i] = models.Segment{
+			Airline:       seg.MarketingCarrier.Name,
+			Carrier:       seg.MarketingCarrier.IATACode,
+			FlightNumber:  flightNumber,
+			Origin:        seg.Origin.IATACode,
+			Destination:   seg.Destination.IATACode,
+			DepartureTime: departure,
+			ArrivalTime:   arrival,
+			Aircraft:      seg.Aircraft.Name,
+			Duration:      duration,
+		}
+		if i > 0 {
+			mapped[i].Layover = models.Duration(departure.Sub(mapped[i-1].ArrivalTime))
+		}
+	}
+	return mapped, nil
+}
+
+// adultFare reads the adult's fare. When the offer has no breakdown, the total is for
+// the whole party, so it is shared among the passengers with a seat; the service prices
+// the party from the adult fare again.
+func adultFare(o offer, passengers []passengerRequest) (models.Money, error) {
+	for _, p := range o.Passengers {
+		if p.Type == "adult" {
+			return parseFare(p.Amount, o.TotalCurrency)
+		}
+	}
+
+	total, err := parseFare(o.TotalAmount, o.TotalCurrency)
+	if err != nil {
+		return models.Money{}, err
+	}
+	seats := 0
+	for _, p := range passengers {
+		if p.Type != "infant_without_seat" {
+			seats++
+		}
+	}
+	return total.Scale(1 / float64(max(seats, 1))), nil
+}
+
+func parseFare(amount, currency string) (models.Money, error) {
+	price, err := models.ParseMoney(amount, currency)
+	if err != nil {
+		return models.Money{}, err
+	}
+	if !price.IsPositive() {
+		return models.Money{}, fmt.Errorf("invalid price %q", amount)
+	}
+	return price, nil
+}
+
+func cityName(p place) string {
+	if p.CityName != "" {
+		return p.CityName
+	}
+	return p.IATACode
+}
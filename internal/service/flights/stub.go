@@ -0,0 +1,513 @@
+package flights
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service/airports"
+)
+
+//go:embed inventory.json
+var defaultInventory []byte
+
+// The stub prices children and lap infants like most airlines do, as a share of the
+// adult fare
+const (
+	stubChildFareRatio  = 0.75
+	stubInfantFareRatio = 0.10
+	stubConnectionTime  = time.Hour
+)
+
+// Schedule is a recurring flight in the stub inventory. It operates at the same local
+// time on every listed weekday, so the fixture never goes stale.
+type Schedule struct {
+	Airline      string   `json:"airline"`
+	Carrier      string   `json:"carrier"`        // IATA airline code
+	FlightNumber string   `json:"flight_number"`  // Without the carrier code
+	Origin       string   `json:"origin"`         // Airport IATA code
+	Destination  string   `json:"destination"`    // Airport IATA code
+	Via          []string `json:"via,omitempty"`  // Connection airports, in order
+	Departs      string   `json:"departs"`        // Local time at the origin, HH:MM
+	Duration     string   `json:"duration"`       // Gate to gate including connections, e.g. "8h30m"
+	Days         []int    `json:"days,omitempty"` // ISO weekdays it operates on (1 is Monday), every day when empty
+	CabinClass   string   `json:"cabin_class"`
+	Fare         string   `json:"fare"` // Adult fare
+	Currency     string   `json:"currency"`
+	Seats        int      `json:"seats"`
+	Aircraft     string   `json:"aircraft,omitempty"` // Chosen from the flying time when empty
+
+	// Optional: the dates the schedule operates between (YYYY-MM-DD, inclusive) and
+	// how the fare rises as departure approaches
+	Effective    string     `json:"effective,omitempty"`
+	Discontinued string     `json:"discontinued,omitempty"`
+	FareCurve    []FareStep `json:"fare_curve,omitempty"`
+
+	// Optional: the fare's conditions, given only when FareFamily is set. Bags are per
+	// passenger with a seat and fees are in Currency. Extra bags and seats without a
+	// fee aren't sold; refunds and changes without one are free.
+	FareFamily  string `json:"fare_family,omitempty"`
+	CheckedBags int    `json:"checked_bags,omitempty"`
+	CabinBags   int    `json:"cabin_bags,omitempty"`
+	BagFee      string `json:"bag_fee,omitempty"` // Per extra checked bag
+	Refundable  bool   `json:"refundable,omitempty"`
+	RefundFee   string `json:"refund_fee,omitempty"`
+	Changeable  bool   `json:"changeable,omitempty"`
+	ChangeFee   string `json:"change_fee,omitempty"`
+	SeatFee     string `json:"seat_fee,omitempty"`
+
+	duration     time.Duration
+	fare         models.Money
+	effective    time.Time
+	discontinued time.Time
+}
+
+// FareStep raises the fare by Multiplier when departure is at most DaysBefore days away.
+// The step with the fewest days that still applies wins.
+type FareStep struct {
+	DaysBefore int     `json:"days_before"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+// Inventory is the fixture behind the stub server
+type Inventory struct {
+	schedules []Schedule
+	airports  *airports.Database
+}
+
+// DefaultInventory returns the schedules bundled with the binary
+func DefaultInventory(db *airports.Database) (*Inventory, error) {
+	return ParseInventory(defaultInventory, db)
+}
+
+// LoadInventory reads a JSON array of schedules from disk
+func LoadInventory(filename string, db *airports.Database) (*Inventory, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading inventory file: %w", err)
+	}
+	return ParseInventory(data, db)
+}
+
+// ParseInventory builds an inventory from a JSON array of schedules. Airports are
+// looked up in db for their city and time zone.
+func ParseInventory(data []byte, db *airports.Database) (*Inventory, error) {
+	var schedules []Schedule
+	if err := json.Unmarshal(data, &schedules); err != nil {
+		return nil, fmt.Errorf("parsing inventory: %w", err)
+	}
+	return NewInventory(schedules, db)
+}
+
+// NewInventory validates schedules, such as those from the schedgen generator, and
+// builds an inventory from them
+func NewInventory(schedules []Schedule, db *airports.Database) (*Inventory, error) {
+	schedules = append([]Schedule(nil), schedules...)
+	for i := range schedules {
+		s := &schedules[i]
+		if s.Carrier == "" || s.FlightNumber == "" || s.Origin == "" || s.Destination == "" {
+			return nil, fmt.Errorf("schedule %d: carrier, flight number, origin and destination are required", i+1)
+		}
+		if _, err := time.Parse("15:04", s.Departs); err != nil {
+			return nil, fmt.Errorf("schedule %s%s: invalid departure time %q", s.Carrier, s.FlightNumber, s.Departs)
+		}
+		duration, err := time.ParseDuration(s.Duration)
+		if err != nil || duration <= stubConnectionTime*time.Duration(len(s.Via)) {
+			return nil, fmt.Errorf("schedule %s%s: invalid duration %q", s.Carrier, s.FlightNumber, s.Duration)
+		}
+		s.duration = duration
+		if s.fare, err = models.ParseMoney(s.Fare, s.Currency); err != nil || !s.fare.IsPositive() || s.fare.Currency == "" {
+			return nil, fmt.Errorf("schedule %s%s: invalid fare %q %q", s.Carrier, s.FlightNumber, s.Fare, s.Currency)
+		}
+		if s.effective, err = parseScheduleDate(s.Effective); err != nil {
+			return nil, fmt.Errorf("schedule %s%s: invalid effective date %q", s.Carrier, s.FlightNumber, s.Effective)
+		}
+		if s.discontinued, err = parseScheduleDate(s.Discontinued); err != nil {
+			return nil, fmt.Errorf("schedule %s%s: invalid discontinued date %q", s.Carrier, s.FlightNumber, s.Discontinued)
+		}
+		for _, step := range s.FareCurve {
+			if step.DaysBefore < 0 || step.Multiplier <= 0 {
+				return nil, fmt.Errorf("schedule %s%s: invalid fare step %+v", s.Carrier, s.FlightNumber, step)
+			}
+		}
+		if s.CheckedBags < 0 || s.CabinBags < 0 {
+			return nil, fmt.Errorf("schedule %s%s: invalid baggage allowance", s.Carrier, s.FlightNumber)
+		}
+		for _, fee := range []string{s.BagFee, s.RefundFee, s.ChangeFee, s.SeatFee} {
+			if _, err := parseFee(fee, s.Currency); err != nil {
+				return nil, fmt.Errorf("schedule %s%s: invalid fee %q", s.Carrier, s.FlightNumber, fee)
+			}
+		}
+	}
+
+	return &Inventory{schedules: schedules, airports: db}, nil
+}
+
+// parseFee reads an optional fee in the schedule's currency
+func parseFee(value, currency string) (*models.Money, error) {
+	if value == "" {
+		return nil, nil
+	}
+	fee, err := models.ParseMoney(value, currency)
+	if err != nil || fee.Minor < 0 {
+		return nil, fmt.Errorf("invalid fee %q", value)
+	}
+	return &fee, nil
+}
+
+func parseScheduleDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// NewStubServer serves the inventory through the same REST API the Client speaks
+func NewStubServer(inventory *Inventory) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+offerRequestsPath, func(w http.ResponseWriter, r *http.Request) {
+		var envelope offerRequestEnvelope
+		if err := json.NewDecoder(r.Body).Decode(&envelope); err != nil {
+			writeStubError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+
+		offers, err := inventory.offers(envelope.Data)
+		if err != nil {
+			writeStubError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+
+		var response offerResponseEnvelope[offer]
+		response.Data.Offers = offers
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(response)
+	})
+	return mux
+}
+
+func writeStubError(w http.ResponseWriter, status int, message string) {
+	var response errorResponse
+	response.Errors = append(response.Errors, struct {
+		Title   string `json:"title"`
+		Message string `json:"message"`
+	}{Title: http.StatusText(status), Message: message})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// stubLeg is a schedule flown on a date, matching one slice of a request
+type stubLeg struct {
+	schedule Schedule
+	date     time.Time
+	slice    offerSlice
+}
+
+// departure and arrival read back the times the stub wrote, so they always parse
+func (l stubLeg) departure() time.Time {
+	t, _ := l.slice.Segments[0].departure()
+	return t
+}
+
+func (l stubLeg) arrival() time.Time {
+	t, _ := l.slice.Segments[len(l.slice.Segments)-1].arrival()
+	return t
+}
+
+// offers prices every journey that flies one matching schedule per slice, cheapest
+// first. As in the Duffel API, the slices are the legs of one journey: each leg leaves
+// after the one before lands and the offer is priced for all of them.
+func (inv *Inventory) offers(req offerRequest) ([]offer, error) {
+	if len(req.Slices) == 0 {
+		return nil, fmt.Errorf("at least one slice is required")
+	}
+	seats := 0
+	for _, p := range req.Passengers {
+		if p.Type != "infant_without_seat" {
+			seats++
+		}
+	}
+	if seats == 0 {
+		return nil, fmt.Errorf("at least one passenger needing a seat is required")
+	}
+
+	journeys := [][]stubLeg{nil}
+	for _, slice := range req.Slices {
+		date, err := time.Parse("2006-01-02", slice.DepartureDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid departure date %q", slice.DepartureDate)
+		}
+		var extended [][]stubLeg
+		for _, s := range inv.schedules {
+			if !inv.serves(s.Origin, slice.Origin) || !inv.serves(s.Destination, slice.Destination) {
+				continue
+			}
+			if !s.operatesOn(date) || s.Seats < seats {
+				continue
+			}
+			if req.CabinClass != "" && !strings.EqualFold(req.CabinClass, s.CabinClass) {
+				continue
+			}
+			if req.MaxConnections != nil && len(s.Via) > *req.MaxConnections {
+				continue
+			}
+			leg := stubLeg{schedule: s, date: date, slice: inv.slice(s, date, req.Passengers)}
+			for _, journey := range journeys {
+				if n := len(journey); n > 0 {
+					previous := journey[n-1]
+					if !leg.departure().After(previous.arrival()) || s.fare.Currency != journey[0].schedule.fare.Currency {
+						continue
+					}
+				}
+				extended = append(extended, append(journey[:len(journey):len(journey)], leg))
+			}
+		}
+		journeys = extended
+	}
+
+	offers := make([]offer, 0, len(journeys))
+	for _, journey := range journeys {
+		o, err := inv.offer(journey, req.Passengers)
+		if err != nil {
+			return nil, err
+		}
+		offers = append(offers, o)
+	}
+
+	sort.SliceStable(offers, func(i, j int) bool {
+		a, _ := models.ParseMoney(offers[i].TotalAmount, offers[i].TotalCurrency)
+		b, _ := models.ParseMoney(offers[j].TotalAmount, offers[j].TotalCurrency)
+		return a.Float() < b.Float()
+	})
+	return offers, nil
+}
+
+// serves reports whether an airport matches a requested place: the airport itself,
+// its city's code or the city's name
+func (inv *Inventory) serves(airport, requested string) bool {
+	if same, known := inv.airports.SameCity(airport, requested); known {
+		return same
+	}
+	return strings.EqualFold(airport, requested)
+}
+
+func (s Schedule) operatesOn(date time.Time) bool {
+	if !s.effective.IsZero() && date.Before(s.effective) {
+		return false
+	}
+	if !s.discontinued.IsZero() && date.After(s.discontinued) {
+		return false
+	}
+	if len(s.Days) == 0 {
+		return true
+	}
+	iso := int(date.Weekday())
+	if iso == 0 {
+		iso = 7
+	}
+	for _, day := range s.Days {
+		if day == iso {
+			return true
+		}
+	}
+	return false
+}
+
+// fareAt returns the adult fare for a departure booked at the given time
+func (s Schedule) fareAt(departure, bookedAt time.Time) models.Money {
+	daysLeft := int(departure.Sub(bookedAt).Hours() / 24)
+	multiplier, closest := 1.0, -1
+	for _, step := range s.FareCurve {
+		if daysLeft <= step.DaysBefore && (closest < 0 || step.DaysBefore < closest) {
+			multiplier, closest = step.Multiplier, step.DaysBefore
+		}
+	}
+	return s.fare.Scale(multiplier)
+}
+
+// slice flies a schedule on a date, segment by segment
+func (inv *Inventory) slice(s Schedule, date time.Time, passengers []passengerRequest) offerSlice {
+	departs, _ := time.Parse("15:04", s.Departs)
+	departure := time.Date(date.Year(), date.Month(), date.Day(), departs.Hour(), departs.Minute(), 0, 0, inv.location(s.Origin))
+
+	stops := append(append([]string{s.Origin}, s.Via...), s.Destination)
+	legs := len(stops) - 1
+	flying := (s.duration - stubConnectionTime*time.Duration(legs-1)) / time.Duration(legs)
+
+	var segments []offerSegment
+	at := departure
+	for i := 0; i < legs; i++ {
+		arrives := at.Add(flying)
+		segments = append(segments, offerSegment{
+			MarketingCarrier:             carrier{Name: s.Airline, IATACode: s.Carrier},
+			MarketingCarrierFlightNumber: s.FlightNumber,
+			Origin:                       inv.place(stops[i]),
+			Destination:                  inv.place(stops[i+1]),
+			DepartingAt:                  at.In(inv.location(stops[i])).Format(localTimeLayout),
+			ArrivingAt:                   arrives.In(inv.location(stops[i+1])).Format(localTimeLayout),
+			Duration:                     isoDuration(flying),
+			Aircraft:                     aircraft{Name: s.aircraftFor(flying)},
+			Passengers:                   s.allowances(passengers),
+		})
+		at = arrives.Add(stubConnectionTime)
+	}
+
+	return offerSlice{
+		Origin:        inv.place(s.Origin),
+		Destination:   inv.place(s.Destination),
+		Duration:      isoDuration(s.duration),
+		FareBrandName: s.FareFamily,
+		Segments:      segments,
+	}
+}
+
+// offer prices a journey for every passenger. The owner, conditions and extras are
+// those of the first leg's airline.
+func (inv *Inventory) offer(journey []stubLeg, passengers []passengerRequest) (offer, error) {
+	first := journey[0].schedule
+	total := models.Money{Currency: first.fare.Currency}
+	fares := make([]models.Money, len(passengers))
+	keys := make([]string, len(journey))
+	slices := make([]offerSlice, len(journey))
+	seats := first.Seats
+	for i, leg := range journey {
+		s := leg.schedule
+		keys[i] = fmt.Sprintf("%s%s_%s", s.Carrier, s.FlightNumber, leg.date.Format("20060102"))
+		slices[i] = leg.slice
+		seats = min(seats, s.Seats)
+
+		adult := s.fareAt(leg.departure(), time.Now())
+		for j, p := range passengers {
+			fare := adult
+			switch p.Type {
+			case "child":
+				fare = fare.Scale(stubChildFareRatio)
+			case "infant_without_seat":
+				fare = fare.Scale(stubInfantFareRatio)
+			}
+			var err error
+			if fares[j], err = fares[j].Add(fare); err != nil {
+				return offer{}, fmt.Errorf("pricing %s%s: %w", s.Carrier, s.FlightNumber, err)
+			}
+			if total, err = total.Add(fare); err != nil {
+				return offer{}, fmt.Errorf("pricing %s%s: %w", s.Carrier, s.FlightNumber, err)
+			}
+		}
+	}
+
+	pricing := make([]offerPricing, len(passengers))
+	for i, p := range passengers {
+		pricing[i] = offerPricing{Type: p.Type, Amount: fares[i].Amount()}
+	}
+
+	return offer{
+		ID:                fmt.Sprintf("off_%s_%s", strings.Join(keys, "_"), first.CabinClass),
+		Owner:             carrier{Name: first.Airline, IATACode: first.Carrier},
+		CabinClass:        first.CabinClass,
+		AvailableSeats:    seats,
+		TotalAmount:       total.Amount(),
+		TotalCurrency:     total.Currency,
+		Passengers:        pricing,
+		Slices:            slices,
+		Conditions:        first.conditions(),
+		AvailableServices: first.services(),
+	}, nil
+}
+
+// allowances gives every passenger the schedule's baggage allowance; lap infants have none
+func (s Schedule) allowances(passengers []passengerRequest) []segmentPassenger {
+	if s.FareFamily == "" {
+		return nil
+	}
+	allowances := make([]segmentPassenger, len(passengers))
+	for i, p := range passengers {
+		if p.Type == "infant_without_seat" {
+			continue
+		}
+		allowances[i].Baggages = []baggageAllowance{
+			{Type: baggageChecked, Quantity: s.CheckedBags},
+			{Type: baggageCarryOn, Quantity: s.CabinBags},
+		}
+	}
+	return allowances
+}
+
+func (s Schedule) conditions() offerConditions {
+	if s.FareFamily == "" {
+		return offerConditions{}
+	}
+	return offerConditions{
+		RefundBeforeDeparture: s.condition(s.Refundable, s.RefundFee),
+		ChangeBeforeDeparture: s.condition(s.Changeable, s.ChangeFee),
+	}
+}
+
+func (s Schedule) condition(allowed bool, fee string) *offerCondition {
+	c := &offerCondition{Allowed: allowed}
+	if allowed && fee != "" {
+		c.PenaltyAmount, c.PenaltyCurrency = fee, s.Currency
+	}
+	return c
+}
+
+// services sells extra checked bags and seat selection when the schedule prices them
+func (s Schedule) services() []offerService {
+	if s.FareFamily == "" {
+		return nil
+	}
+	var services []offerService
+	if s.BagFee != "" {
+		bag := offerService{Type: "baggage", TotalAmount: s.BagFee, TotalCurrency: s.Currency}
+		bag.Metadata.Type = baggageChecked
+		services = append(services, bag)
+	}
+	if s.SeatFee != "" {
+		services = append(services, offerService{Type: "seat", TotalAmount: s.SeatFee, TotalCurrency: s.Currency})
+	}
+	return services
+}
+
+func (inv *Inventory) place(code string) place {
+	if _, city, ok := inv.airports.Airport(code); ok {
+		return place{IATACode: code, CityName: city.Name, TimeZone: city.TimeZone}
+	}
+	return place{IATACode: code}
+}
+
+func (inv *Inventory) location(code string) *time.Location {
+	if _, city, ok := inv.airports.Airport(code); ok {
+		return city.Location()
+	}
+	return time.UTC
+}
+
+// aircraftFor returns the schedule's aircraft, or a typical type for the flying time
+func (s Schedule) aircraftFor(flying time.Duration) string {
+	switch {
+	case s.Aircraft != "":
+		return s.Aircraft
+	case flying < 4*time.Hour:
+		return "Airbus A320neo"
+	case flying < 9*time.Hour:
+		return "Boeing 787-9"
+	}
+	return "Airbus A350-900"
+}
+
+// isoDuration formats a duration as ISO 8601, e.g. PT8H30M
+func isoDuration(d time.Duration) string {
+	hours := int(d.Hours())
+	minutes := int(d.Minutes()) % 60
+	if minutes == 0 {
+		return fmt.Sprintf("PT%dH", hours)
+	}
+	return fmt.Sprintf("PT%dH%dM", hours, minutes)
+}
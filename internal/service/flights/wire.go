@@ -0,0 +1,176 @@
+package flights
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// The REST API follows the shape of the Duffel offer request API: the request lists
+// the slices of one journey, e.g. the way out and the way back, and its passengers, and
+// the response returns offers for the whole journey, each with its slices and segments.
+// Amounts are decimal strings so they survive JSON exactly, and segment times are the
+// local times at their airports, without an offset.
+
+const offerRequestsPath = "/air/offer_requests"
+
+type offerRequestEnvelope struct {
+	Data offerRequest `json:"data"`
+}
+
+type offerRequest struct {
+	Slices         []sliceRequest     `json:"slices"`
+	Passengers     []passengerRequest `json:"passengers"`
+	CabinClass     string             `json:"cabin_class,omitempty"`
+	MaxConnections *int               `json:"max_connections,omitempty"`
+	Currency       string             `json:"currency,omitempty"`
+}
+
+type sliceRequest struct {
+	Origin        string `json:"origin"`
+	Destination   string `json:"destination"`
+	DepartureDate string `json:"departure_date"` // YYYY-MM-DD
+}
+
+type passengerRequest struct {
+	Type string `json:"type"` // adult, child or infant_without_seat
+	Age  int    `json:"age,omitempty"`
+}
+
+// offerResponseEnvelope lists the offers found. The client reads them as
+// json.RawMessage and decodes each on its own, so a malformed offer can be skipped
+// without losing the rest.
+type offerResponseEnvelope[T offer | json.RawMessage] struct {
+	Data struct {
+		Offers []T `json:"offers"`
+	} `json:"data"`
+}
+
+type errorResponse struct {
+	Errors []struct {
+		Title   string `json:"title"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+type offer struct {
+	ID             string          `json:"id"`
+	Owner          carrier         `json:"owner"`
+	CabinClass     string          `json:"cabin_class"`
+	AvailableSeats int             `json:"available_seats"`
+	TotalAmount    string          `json:"total_amount"` // For every passenger and slice
+	TotalCurrency  string          `json:"total_currency"`
+	Passengers     []offerPricing  `json:"passengers"`
+	Slices         []offerSlice    `json:"slices"`
+	Conditions     offerConditions `json:"conditions"`
+	// AvailableServices are the extras sold with the offer, such as more bags
+	AvailableServices []offerService `json:"available_services"`
+}
+
+// offerConditions say whether the fare can be refunded or changed; nil when unknown
+type offerConditions struct {
+	RefundBeforeDeparture *offerCondition `json:"refund_before_departure"`
+	ChangeBeforeDeparture *offerCondition `json:"change_before_departure"`
+}
+
+type offerCondition struct {
+	Allowed         bool   `json:"allowed"`
+	PenaltyAmount   string `json:"penalty_amount"`
+	PenaltyCurrency string `json:"penalty_currency"`
+}
+
+// offerService is an extra priced per unit for one passenger
+type offerService struct {
+	Type          string `json:"type"` // baggage or seat
+	TotalAmount   string `json:"total_amount"`
+	TotalCurrency string `json:"total_currency"`
+	Metadata      struct {
+		Type string `json:"type"` // For baggage: checked or carry_on
+	} `json:"metadata"`
+}
+
+type carrier struct {
+	Name     string `json:"name"`
+	IATACode string `json:"iata_code"`
+}
+
+// offerPricing is the fare of one passenger
+type offerPricing struct {
+	Type   string `json:"type"`
+	Amount string `json:"amount"`
+}
+
+type offerSlice struct {
+	Origin        place          `json:"origin"`
+	Destination   place          `json:"destination"`
+	Duration      string         `json:"duration"` // ISO 8601, e.g. PT8H30M
+	FareBrandName string         `json:"fare_brand_name"`
+	Segments      []offerSegment `json:"segments"`
+}
+
+type place struct {
+	IATACode string `json:"iata_code"`
+	CityName string `json:"city_name"`
+	TimeZone string `json:"time_zone"` // IANA name, e.g. Europe/London
+}
+
+type offerSegment struct {
+	MarketingCarrier             carrier  `json:"marketing_carrier"`
+	MarketingCarrierFlightNumber string   `json:"marketing_carrier_flight_number"`
+	Origin                       place    `json:"origin"`
+	Destination                  place    `json:"destination"`
+	DepartingAt                  string   `json:"departing_at"` // Local time at the origin
+	ArrivingAt                   string   `json:"arriving_at"`  // Local time at the destination
+	Duration                     string   `json:"duration"`     // ISO 8601
+	Aircraft                     aircraft `json:"aircraft"`
+	// Passengers give each passenger's baggage allowance on the segment
+	Passengers []segmentPassenger `json:"passengers"`
+}
+
+type segmentPassenger struct {
+	Baggages []baggageAllowance `json:"baggages"`
+}
+
+type baggageAllowance struct {
+	Type     string `json:"type"` // checked or carry_on
+	Quantity int    `json:"quantity"`
+}
+
+type aircraft struct {
+	Name     string `json:"name"`
+	IATACode string `json:"iata_code"`
+}
+
+// localTimeLayout is how segment times are given: the airport's local time, no offset
+const localTimeLayout = "2006-01-02T15:04:05"
+
+// departure and arrival read the segment's times in the time zones of its airports
+func (s offerSegment) departure() (time.Time, error) {
+	return parseLocalTime(s.DepartingAt, s.Origin)
+}
+
+func (s offerSegment) arrival() (time.Time, error) {
+	return parseLocalTime(s.ArrivingAt, s.Destination)
+}
+
+// parseLocalTime reads a local time in the place's time zone. Times with an offset are
+// accepted too; places without a time zone are taken to be on UTC.
+func parseLocalTime(value string, p place) (time.Time, error) {
+	location := time.UTC
+	if p.TimeZone != "" {
+		loc, err := time.LoadLocation(p.TimeZone)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time zone %q for %s", p.TimeZone, p.IATACode)
+		}
+		location = loc
+	}
+
+	if t, err := time.ParseInLocation(localTimeLayout, value, location); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q at %s", value, p.IATACode)
+	}
+	return t.In(location), nil
+}
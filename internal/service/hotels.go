@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+	"travel-agent/internal/logging"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service/ai"
+	"unicode"
+)
+
+type HotelRecommender interface {
+	ProcessRequest(
+		ctx context.Context,
+		strategy ai.PromptStrategy[models.HotelRecommendationRequest],
+		request models.HotelRecommendationRequest,
+		decoder ai.DecodingStrategy[models.HotelRecommendation],
+	) (*models.HotelRecommendation, error)
+}
+
+// guestsPerRoom is how many guests needing a bed share a room; lap infants don't count
+const guestsPerRoom = 2
+
+// WithHotelRecommender adds hotel options for the stay to round-trip and open-jaw bookings
+func WithHotelRecommender(recommender HotelRecommender) BookingOption {
+	return func(s *BookingService) {
+		s.hotelRecommender = recommender
+	}
+}
+
+// recommendHotels suggests hotels at the destination between the outbound arrival and
+// the return departure. Hotels are optional: without a recommender, a stay of at least
+// one night, or a usable answer from the model, the booking goes ahead without them.
+func (s *BookingService) recommendHotels(
+	ctx context.Context,
+	params *models.TravelParameters,
+	response *models.BookingResponse,
+) *models.HotelStay {
+	if s.hotelRecommender == nil {
+		return nil
+	}
+	checkIn, checkOut, ok := s.stayDates(params, response)
+	if !ok {
+		return nil
+	}
+
+	ctx = logging.WithStage(ctx, "hotels")
+	passengers := passengersOf(params)
+	hotelReq := models.HotelRecommendationRequest{
+		City:                params.Destination,
+		CheckIn:             checkIn,
+		CheckOut:            checkOut,
+		Guests:              passengers,
+		Rooms:               roomsFor(passengers),
+		Currency:            response.Currency,
+		Activities:          params.Preferences.Activities,
+		DietaryRestrictions: params.Preferences.DietaryRestrictions,
+	}
+	recommendation, err := s.hotelRecommender.ProcessRequest(
+		ctx,
+		&ai.HotelRecommendationStrategy{},
+		hotelReq,
+		&ai.HotelRecommendationDecoder{},
+	)
+	if err != nil {
+		slog.WarnContext(ctx, "hotel recommendations failed, booking goes ahead without them", "error", err)
+		return nil
+	}
+
+	nights := int(checkOut.Sub(checkIn).Hours() / 24)
+	hotels := s.priceHotels(ctx, recommendation.Hotels, nights, hotelReq.Rooms, hotelReq.Currency)
+	hotels = rankHotels(hotels, hotelReq.Activities, hotelReq.Currency)
+
+	return &models.HotelStay{
+		City:      params.Destination,
+		CheckIn:   checkIn,
+		CheckOut:  checkOut,
+		Nights:    nights,
+		Rooms:     hotelReq.Rooms,
+		Hotels:    hotels,
+		Reasoning: recommendation.Reasoning,
+	}
+}
+
+// stayDates takes check-in from the day the outbound flight lands and check-out from the
+// day the return flight leaves, at the destination, falling back to the trip dates.
+// One-way trips have no stay.
+func (s *BookingService) stayDates(params *models.TravelParameters, response *models.BookingResponse) (time.Time, time.Time, bool) {
+	if params.DepartureDate == nil || params.ReturnDate == nil {
+		return time.Time{}, time.Time{}, false
+	}
+	st, ok := stayAt(s.airports, params, response)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	checkIn, checkOut := calendarDate(st.first), calendarDate(st.leaving)
+	return checkIn, checkOut, checkOut.After(checkIn)
+}
+
+// roomsFor gives every two guests needing a bed a room
+func roomsFor(passengers models.Passengers) int {
+	return max((seatsRequired(passengers)+guestsPerRoom-1)/guestsPerRoom, 1)
+}
+
+// priceHotels totals the stay for every room, converting hotels priced in another
+// currency into the traveler's when a rate is known
+func (s *BookingService) priceHotels(ctx context.Context, hotels []models.Hotel, nights, rooms int, to string) []models.Hotel {
+	priced := make([]models.Hotel, len(hotels))
+	for i, hotel := range hotels {
+		hotel.NightlyRate = hotel.NightlyRate.Assume(to)
+		hotel.TotalPrice = hotel.NightlyRate.Mul(nights * rooms)
+		hotel.Converted = nil
+		if from := hotel.NightlyRate.Currency; from != to {
+			if rate, err := s.rates.Rate(ctx, from, to); err == nil {
+				hotel.Converted = &models.ConvertedPrice{
+					Price: hotel.NightlyRate.Exchange(rate, to),
+					Total: hotel.TotalPrice.Exchange(rate, to),
+					Rate:  rate,
+				}
+			}
+		}
+		priced[i] = hotel
+	}
+	return priced
+}
+
+// rankHotels scores each neighborhood against the activities and sorts the best fit
+// first, the cheaper first on a tie. Hotels that couldn't be priced in the traveler's
+// currency come after the ones that were.
+func rankHotels(hotels []models.Hotel, activities []string, currency string) []models.Hotel {
+	ranked := make([]models.Hotel, len(hotels))
+	for i, hotel := range hotels {
+		hotel.NeighborhoodFit, hotel.FitDetail = neighborhoodFit(hotel, activities)
+		ranked[i] = hotel
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].NeighborhoodFit != ranked[j].NeighborhoodFit {
+			return ranked[i].NeighborhoodFit > ranked[j].NeighborhoodFit
+		}
+		return hotelNightlyRate(ranked[i]).Less(hotelNightlyRate(ranked[j]), currency)
+	})
+	return ranked
+}
+
+// hotelNightlyRate returns the nightly rate in the traveler's currency when converted
+func hotelNightlyRate(hotel models.Hotel) models.Money {
+	if hotel.Converted != nil {
+		return hotel.Converted.Price
+	}
+	return hotel.NightlyRate
+}
+
+// activityInterests groups the words travelers and neighborhood descriptions use for
+// the same kind of place. A keyword matches any word starting with it.
+var activityInterests = map[string][]string{
+	"culture":   {"museum", "art", "galler", "histor", "architect", "culture", "cathedral", "church", "monument", "theater", "theatre", "opera", "sightseeing"},
+	"food":      {"food", "dining", "restaurant", "cuisine", "gastronom", "wine", "tapas", "culinary", "cooking", "café", "cafe"},
+	"nightlife": {"nightlife", "bar", "club", "party", "parties", "pub", "music", "concert", "cocktail"},
+	"beach":     {"beach", "swim", "surf", "snorkel", "diving", "seaside", "coast", "waterfront"},
+	"nature":    {"hiking", "hike", "nature", "park", "mountain", "outdoor", "trek", "garden", "wildlife", "cycling"},
+	"shopping":  {"shop", "boutique", "mall", "market", "fashion"},
+	"business":  {"business", "conference", "meeting", "office", "convention", "financial"},
+	"family":    {"kid", "family", "children", "zoo", "aquarium", "amusement"},
+}
+
+// interestsOf maps a phrase to the interests it mentions. Phrases matching none stand
+// for themselves, so "skiing" still matches a neighborhood tagged "skiing".
+func interestsOf(phrase string) []string {
+	phrase = strings.ToLower(strings.TrimSpace(phrase))
+	words := strings.FieldsFunc(phrase, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+
+	var interests []string
+	for interest, keywords := range activityInterests {
+		if mentionsAny(words, keywords) {
+			interests = append(interests, interest)
+		}
+	}
+	if len(interests) == 0 && phrase != "" {
+		interests = append(interests, phrase)
+	}
+	return interests
+}
+
+func mentionsAny(words, keywords []string) bool {
+	for _, word := range words {
+		for _, keyword := range keywords {
+			if strings.HasPrefix(word, keyword) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// neighborhoodFit is the share of activities the hotel's neighborhood suits
+func neighborhoodFit(hotel models.Hotel, activities []string) (float64, string) {
+	offered := make(map[string]bool)
+	for _, tag := range hotel.NeighborhoodTags {
+		for _, interest := range interestsOf(tag) {
+			offered[interest] = true
+		}
+	}
+
+	var suited, missed []string
+	for _, activity := range activities {
+		if strings.TrimSpace(activity) == "" {
+			continue
+		}
+		match := false
+		for _, interest := range interestsOf(activity) {
+			if offered[interest] {
+				match = true
+				break
+			}
+		}
+		if match {
+			suited = append(suited, activity)
+		} else {
+			missed = append(missed, activity)
+		}
+	}
+
+	total := len(suited) + len(missed)
+	if total == 0 {
+		return 0, ""
+	}
+
+	var parts []string
+	if len(suited) > 0 {
+		parts = append(parts, "suits "+strings.Join(suited, ", "))
+	}
+	if len(missed) > 0 {
+		parts = append(parts, "not known for "+strings.Join(missed, ", "))
+	}
+	detail := fmt.Sprintf("%s: %s", neighborhoodName(hotel), strings.Join(parts, "; "))
+	return round2(float64(len(suited)) / float64(total)), detail
+}
+
+func neighborhoodName(hotel models.Hotel) string {
+	if hotel.Neighborhood != "" {
+		return hotel.Neighborhood
+	}
+	return "the neighborhood"
+}
@@ -0,0 +1,105 @@
+// Package injection spots travel queries that try to steer the model instead of
+// describing a trip
+package injection
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Threshold is the score at which a query is flagged. One strong signal reaches it on
+// its own; weak signals need company.
+const Threshold = 1.0
+
+// Signal names
+const (
+	SignalOverride      = "instruction_override"
+	SignalRoleChange    = "role_change"
+	SignalPromptLeak    = "prompt_leak"
+	SignalRoleMarker    = "role_marker"
+	SignalJailbreak     = "jailbreak"
+	SignalOutputControl = "output_control"
+	SignalSchemaSpoof   = "schema_spoof"
+	SignalFenceSpoof    = "fence_spoof"
+	SignalHiddenText    = "hidden_text"
+)
+
+type pattern struct {
+	signal string
+	weight float64
+	re     *regexp.Regexp
+}
+
+var patterns = []pattern{
+	{SignalOverride, 1, regexp.MustCompile(`\b(ignore|disregard|forget|override|bypass)\b.{0,40}\b(instructions?|rules|prompts?|directions|guidelines|constraints)\b`)},
+	{SignalOverride, 1, regexp.MustCompile(`\b(ignora|olvida|omite)\w*\b.{0,40}\b(instrucciones|reglas|indicaciones)\b`)},
+	{SignalOverride, 1, regexp.MustCompile(`\bnew (instructions|rules|task)\s*:`)},
+	{SignalRoleChange, 0.5, regexp.MustCompile(`\byou are (now|no longer)\b|\bact as\b|\bpretend (to be|you are)\b|\bfrom now on\b|\broleplay\b`)},
+	{SignalPromptLeak, 1, regexp.MustCompile(`\b(reveal|show|print|repeat|output|leak|display)\b.{0,30}\b(system prompt|your (instructions|prompt|rules)|initial prompt|hidden prompt)`)},
+	{SignalRoleMarker, 1, regexp.MustCompile(`(?m)^\s*(system|assistant|developer)\s*:|<\|?(im_start|im_end|system|endoftext)\|?>|\[/?inst\]|<</?sys>>`)},
+	{SignalJailbreak, 1, regexp.MustCompile(`\b(jailbreak|jailbroken|dan mode|developer mode|god mode)\b`)},
+	{SignalOutputControl, 0.5, regexp.MustCompile(`\b(respond|reply|answer|output|return)\b\s+(only\s+)?(with|in)\b.{0,30}\b(json|format|the following|this)\b`)},
+	{SignalSchemaSpoof, 0.5, regexp.MustCompile(`"(trip_type|departure_city|budget_range|recommendations|reasoning|ambiguous_fields)"\s*:`)},
+}
+
+// Result is what the heuristics found in a query
+type Result struct {
+	Score   float64  `json:"score"`
+	Signals []string `json:"signals,omitempty"` // Sorted, each listed once
+}
+
+// Flagged reports whether the query should be treated as an injection attempt
+func (r Result) Flagged() bool {
+	return r.Score >= Threshold
+}
+
+// Scan looks for injection signals in text. Each signal counts once however often it
+// appears. markers are delimiters the prompt fences user text with; text containing
+// them is trying to break out of the fence.
+func Scan(text string, markers ...string) Result {
+	weights := make(map[string]float64)
+	add := func(signal string, weight float64) {
+		weights[signal] = max(weights[signal], weight)
+	}
+
+	cleaned, hidden := stripInvisible(text)
+	if hidden {
+		add(SignalHiddenText, 0.5)
+	}
+	for _, marker := range markers {
+		if marker != "" && strings.Contains(cleaned, marker) {
+			add(SignalFenceSpoof, 1)
+		}
+	}
+
+	normalized := strings.ToLower(cleaned)
+	for _, p := range patterns {
+		if p.re.MatchString(normalized) {
+			add(p.signal, p.weight)
+		}
+	}
+
+	var result Result
+	for signal, weight := range weights {
+		result.Score += weight
+		result.Signals = append(result.Signals, signal)
+	}
+	sort.Strings(result.Signals)
+	return result
+}
+
+// stripInvisible removes zero-width and other format characters, which are used to
+// hide instructions or split keywords, and reports whether there were any
+func stripInvisible(text string) (string, bool) {
+	hidden := false
+	cleaned := strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Cf, r) {
+			hidden = true
+			return -1
+		}
+		return r
+	}, text)
+	return cleaned, hidden
+}
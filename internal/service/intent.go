@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+	"travel-agent/internal/logging"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service/ai"
+)
+
+// ErrIncompleteStay is returned for hotel-only queries missing where or when to stay
+var ErrIncompleteStay = errors.New("a hotel search needs a destination and check-in and check-out dates")
+
+// minIntentConfidence is how sure the classifier must be for a query to leave the
+// flight search pipeline
+const minIntentConfidence = 0.6
+
+// intentCounts counts booking queries by intent, published at /debug/vars
+var intentCounts = expvar.NewMap("booking_intents")
+
+// bookingIDPattern finds a booking ID written in a query; IDs are lowercase UUIDs
+var bookingIDPattern = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+
+// declineMessages are the polite refusals for intents the agent doesn't handle
+var declineMessages = map[models.Intent]string{
+	models.IntentChangeOrCancel: "Sorry, bookings can't be changed or cancelled here yet. Please contact the airline or hotel with your booking details.",
+	models.IntentOffTopic:       "Sorry, I can only help with travel: finding flights and hotels and checking on your bookings.",
+}
+
+// IntentClassifier asks the model what a query asks for
+type IntentClassifier interface {
+	ProcessRequest(
+		ctx context.Context,
+		strategy ai.PromptStrategy[models.BookingRequest],
+		request models.BookingRequest,
+		decoder ai.DecodingStrategy[models.IntentClassification],
+	) (*models.IntentClassification, error)
+}
+
+// WithIntentClassifier routes each booking query by what it asks for. Without it every
+// query is treated as a flight search.
+func WithIntentClassifier(classifier IntentClassifier) BookingOption {
+	return func(s *BookingService) {
+		s.intentClassifier = classifier
+	}
+}
+
+// classifyIntent decides which pipeline a query goes to and counts it. Queries the
+// classifier can't place, or isn't sure about, go to the flight search as before.
+func (s *BookingService) classifyIntent(ctx context.Context, req models.BookingRequest) models.IntentClassification {
+	classification := models.IntentClassification{Intent: models.IntentFlightSearch}
+	if s.intentClassifier != nil {
+		ctx := logging.WithStage(ctx, "intent")
+		result, err := s.intentClassifier.ProcessRequest(ctx, &ai.IntentClassificationStrategy{}, req, &ai.IntentClassificationDecoder{})
+		switch {
+		case err != nil:
+			slog.WarnContext(ctx, "intent classifier failed, treating the query as a flight search", "error", err)
+		case result.Confidence >= minIntentConfidence:
+			classification = *result
+		}
+	}
+	if classification.BookingID == "" {
+		classification.BookingID = strings.ToLower(bookingIDPattern.FindString(req.Query))
+	}
+
+	intentCounts.Add(string(classification.Intent), 1)
+	return classification
+}
+
+// routeIntent answers queries that are not flight searches. It reports false for flight
+// searches, which go through the full booking flow.
+func (s *BookingService) routeIntent(
+	ctx context.Context,
+	id string,
+	req models.BookingRequest,
+	classification models.IntentClassification,
+) (*models.BookingResponse, bool, error) {
+	var response *models.BookingResponse
+	var err error
+	switch classification.Intent {
+	case models.IntentHotelOnly:
+		response, err = s.processHotelOnly(ctx, id, req)
+	case models.IntentBookingStatus:
+		response, err = s.bookingStatus(ctx, classification.BookingID)
+	case models.IntentChangeOrCancel, models.IntentOffTopic:
+		response = declinedBooking(id, req, classification.Intent)
+	default:
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, true, err
+	}
+	response.Intent = classification.Intent
+	return response, true, nil
+}
+
+// processHotelOnly recommends hotels for a stay without looking for flights. The trip
+// dates are the check-in and check-out, and the booking is stored like any other.
+func (s *BookingService) processHotelOnly(ctx context.Context, id string, req models.BookingRequest) (*models.BookingResponse, error) {
+	params, err := s.extractTravelParameters(ctx, req, true)
+	if err != nil {
+		return nil, fmt.Errorf("parameter extraction failed: %w", err)
+	}
+	if params.Destination == "" || params.DepartureDate == nil || params.ReturnDate == nil {
+		return nil, ErrIncompleteStay
+	}
+
+	now := time.Now()
+	passengers := passengersOf(params)
+	_, location := ai.RequestClock(req)
+	response := &models.BookingResponse{
+		ID:            id,
+		Status:        models.StatusProcessing,
+		Intent:        models.IntentHotelOnly,
+		Query:         req.Query,
+		Deadline:      req.Deadline,
+		Passengers:    &passengers,
+		Currency:      s.travelerCurrency(ctx, req, params),
+		TimeZone:      location.String(),
+		ResolvedDates: params.ResolvedDates,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	response.Hotels = s.recommendHotels(ctx, params, response)
+	if response.Hotels == nil || len(response.Hotels.Hotels) == 0 {
+		return nil, fmt.Errorf("no hotel recommendations available")
+	}
+	stay := response.Hotels
+	response.Message = fmt.Sprintf("Found hotels in %s from %s to %s", stay.City, stay.CheckIn.Format("Jan 2"), stay.CheckOut.Format("Jan 2"))
+
+	if s.bookings != nil {
+		record := &models.BookingRecord{Booking: *response, Parameters: *params}
+		if err := s.bookings.Save(ctx, record); err != nil {
+			return nil, fmt.Errorf("failed to save booking: %w", err)
+		}
+	}
+	return response, nil
+}
+
+// bookingStatus answers with the stored booking the query names
+func (s *BookingService) bookingStatus(ctx context.Context, bookingID string) (*models.BookingResponse, error) {
+	if s.bookings == nil || bookingID == "" {
+		return nil, ErrBookingNotFound
+	}
+	record, err := loadBooking(ctx, s.bookings, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	response := record.Booking
+	return &response, nil
+}
+
+// declinedBooking is the polite refusal for a query the agent doesn't handle; nothing is
+// stored and nothing more is sent to the model
+func declinedBooking(id string, req models.BookingRequest, intent models.Intent) *models.BookingResponse {
+	now := time.Now()
+	return &models.BookingResponse{
+		ID:        id,
+		Status:    models.StatusDeclined,
+		Query:     req.Query,
+		Deadline:  req.Deadline,
+		Message:   declineMessages[intent],
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
@@ -0,0 +1,42 @@
+package service
+
+import "sync"
+
+// keyedLocks serializes read-modify-write cycles on one stored item, such as the turns
+// of a conversation or the edits of a booking's plan, so two requests arriving at once
+// can't both load the same version and have one overwrite the other
+type keyedLocks struct {
+	mu   sync.Mutex
+	held map[string]*keyedLock
+}
+
+type keyedLock struct {
+	sync.Mutex
+	users int
+}
+
+func newKeyedLocks() *keyedLocks {
+	return &keyedLocks{held: make(map[string]*keyedLock)}
+}
+
+// lock waits for the key's turn and returns the function releasing it
+func (l *keyedLocks) lock(key string) func() {
+	l.mu.Lock()
+	lock, ok := l.held[key]
+	if !ok {
+		lock = &keyedLock{}
+		l.held[key] = lock
+	}
+	lock.users++
+	l.mu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		l.mu.Lock()
+		if lock.users--; lock.users == 0 {
+			delete(l.held, key)
+		}
+		l.mu.Unlock()
+	}
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service/airports"
+)
+
+// minSelfTransfer is the minimum time between arriving on one leg and departing on the
+// next. Legs are ticketed separately, so this is longer than an airline connection.
+const minSelfTransfer = 3 * time.Hour
+
+// getMultiCityItinerary recommends flights for each leg and combines them into one
+// itinerary. It also returns the flights rejected by the sanity checks on every leg.
+func (s *BookingService) getMultiCityItinerary(
+	ctx context.Context,
+	req models.BookingRequest,
+	params *models.TravelParameters,
+) (*models.MultiCityItinerary, []models.FlightRejection, error) {
+	if len(params.Legs) < 2 {
+		return nil, nil, fmt.Errorf("multi-city trips need at least two legs")
+	}
+
+	// Legs may be priced in different currencies, so the total is in the traveler's
+	travelerCurrency := s.travelerCurrency(ctx, req, params)
+	itinerary := &models.MultiCityItinerary{
+		Feasible:   true,
+		TotalPrice: models.Money{Currency: travelerCurrency},
+	}
+	var rejected []models.FlightRejection
+
+	passengers := passengersOf(params)
+
+	var previous *models.Flight
+	for i, leg := range params.Legs {
+		if leg.EarliestDate == nil {
+			return nil, rejected, fmt.Errorf("leg %d has no departure date", i+1)
+		}
+
+		aiReq := models.FlightRecommendationRequest{
+			TripType:           models.TripMultiCity,
+			DepartureCity:      leg.Origin,
+			Destination:        leg.Destination,
+			DepartureDate:      *leg.EarliestDate,
+			DepartureWindowEnd: leg.LatestDate,
+			Legs:               params.Legs,
+			LegIndex:           i,
+			Passengers:         passengers,
+			Currency:           travelerCurrency,
+		}
+		constraints, err := s.applyPreferences(ctx, &aiReq, params, req.Profile)
+		if err != nil {
+			return nil, rejected, err
+		}
+		s.applyRoute(&aiReq, &constraints, leg.Origin, leg.Destination)
+
+		recommendations, err := s.candidateFlights(ctx, aiReq, constraints)
+		if err != nil {
+			return nil, rejected, fmt.Errorf("leg %d: %w", i+1, err)
+		}
+		if len(recommendations.Recommendations) == 0 {
+			return nil, rejected, fmt.Errorf("no flights recommended for leg %d", i+1)
+		}
+
+		priced, err := priceFlights(s.convertFlights(ctx, recommendations.Recommendations, aiReq.Currency), passengers, aiReq.Baggage)
+		if err != nil {
+			return nil, rejected, fmt.Errorf("leg %d: %w", i+1, err)
+		}
+		checked, legRejected, err := checkFlights(priced, aiReq, constraints, legFlightRules)
+		for _, rejection := range legRejected {
+			rejection.Leg = i + 1
+			rejected = append(rejected, rejection)
+		}
+		if err != nil {
+			return nil, rejected, fmt.Errorf("leg %d: %w", i+1, err)
+		}
+		options := rankFlights(describeFlights(checked), aiReq, s.weights, s.airports)
+
+		flight, issues := selectLegFlight(leg, previous, options, s.airports)
+		for _, issue := range issues {
+			itinerary.Issues = append(itinerary.Issues, fmt.Sprintf("leg %d: %s", i+1, issue))
+		}
+
+		itinerary.Legs = append(itinerary.Legs, models.LegRecommendation{
+			Leg:          leg,
+			Flight:       flight,
+			Alternatives: len(options) - 1,
+		})
+		if itinerary.TotalPrice, err = itinerary.TotalPrice.Add(travelerTotal(flight)); err != nil {
+			return nil, rejected, fmt.Errorf("leg %d: %w", i+1, err)
+		}
+		previous = &itinerary.Legs[len(itinerary.Legs)-1].Flight
+	}
+
+	itinerary.Feasible = len(itinerary.Issues) == 0
+
+	return itinerary, rejected, nil
+}
+
+// selectLegFlight picks the best-scored flight that fits the leg's window and connects
+// with the previous leg. If none does, the best flight is returned with the reasons.
+func selectLegFlight(
+	leg models.TripLeg,
+	previous *models.Flight,
+	options []models.Flight,
+	db *airports.Database,
+) (models.Flight, []string) {
+	ranked := append([]models.Flight(nil), options...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].RecommendationScore > ranked[j].RecommendationScore
+	})
+
+	for _, flight := range ranked {
+		if len(legFeasibilityIssues(leg, previous, flight, db)) == 0 {
+			return flight, nil
+		}
+	}
+
+	return ranked[0], legFeasibilityIssues(leg, previous, ranked[0], db)
+}
+
+// legFeasibilityIssues checks the flight against the leg's date window and the previous leg.
+// Flights that fail the sanity checks have already been rejected. The dates are
+// calendar days, compared with the day the flight leaves in its origin's local time.
+func legFeasibilityIssues(leg models.TripLeg, previous *models.Flight, flight models.Flight, db *airports.Database) []string {
+	var issues []string
+
+	first := calendarDate(*leg.EarliestDate)
+	last := first
+	if leg.LatestDate != nil {
+		last = calendarDate(*leg.LatestDate)
+	}
+	if departs := calendarDate(localDepartureTime(flight, db)); departs.Before(first) || departs.After(last) {
+		issues = append(issues, fmt.Sprintf("%s departs outside the requested dates", flight.FlightNumber))
+	}
+
+	if previous != nil && flight.DepartureTime.Before(previous.ArrivalTime.Add(minSelfTransfer)) {
+		issues = append(issues, fmt.Sprintf("%s departs less than %s after %s arrives",
+			flight.FlightNumber, minSelfTransfer, previous.FlightNumber))
+	}
+
+	return issues
+}
@@ -0,0 +1,325 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"travel-agent/internal/logging"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service/ai"
+	"travel-agent/internal/service/airports"
+)
+
+var (
+	ErrPlanNotFound    = errors.New("booking has no plan yet")
+	ErrPlanDayNotFound = errors.New("plan has no such day")
+	ErrInvalidPlan     = errors.New("invalid plan")
+)
+
+const (
+	// arrivalAllowance covers immigration, bags and the transfer to the hotel after landing
+	arrivalAllowance = 90 * time.Minute
+	// departureAllowance is how long before the return flight the traveler leaves for the airport
+	departureAllowance = 3 * time.Hour
+)
+
+type TripPlanner interface {
+	ProcessRequest(
+		ctx context.Context,
+		strategy ai.PromptStrategy[models.TripPlanRequest],
+		request models.TripPlanRequest,
+		decoder ai.DecodingStrategy[models.TripPlanDraft],
+	) (*models.TripPlanDraft, error)
+}
+
+// PlanService plans a booking's stay hour by hour around the real flight times and
+// stores the plan with the booking
+type PlanService struct {
+	bookings BookingStore
+	planner  TripPlanner
+	airports *airports.Database
+	// locks serializes the saves of each booking's plan so concurrent edits aren't lost
+	locks *keyedLocks
+}
+
+func NewPlanService(bookings BookingStore, planner TripPlanner) *PlanService {
+	s := &PlanService{
+		bookings: bookings,
+		planner:  planner,
+		locks:    newKeyedLocks(),
+	}
+	// Without the bundled database plans use the arrival flight's time zone
+	if db, err := airports.Default(); err == nil {
+		s.airports = db
+	}
+	return s
+}
+
+// CreatePlan plans every day of the stay, replacing any earlier plan. Blocks the model
+// puts outside a day's free hours are trimmed to them, and overlapping blocks dropped.
+// The model is asked without holding the booking's lock; the plan is saved under it.
+func (s *PlanService) CreatePlan(ctx context.Context, bookingID string) (*models.TripPlan, error) {
+	ctx = logging.With(ctx, logging.KeyBookingID, bookingID, logging.KeyStage, "plan")
+	record, err := loadBooking(ctx, s.bookings, bookingID)
+	if err != nil {
+		return nil, err
+	}
+
+	params, booking := &record.Parameters, &record.Booking
+	days, location, ok := s.planDays(params, booking)
+	if !ok {
+		return nil, ErrNoStay
+	}
+
+	req := models.TripPlanRequest{
+		City:                params.Destination,
+		TimeZone:            location.String(),
+		Days:                days,
+		Guests:              passengersOf(params),
+		Activities:          params.Preferences.Activities,
+		DietaryRestrictions: params.Preferences.DietaryRestrictions,
+	}
+	if booking.Hotels != nil && len(booking.Hotels.Hotels) > 0 {
+		req.Hotel = booking.Hotels.Hotels[0].Name
+		req.Neighborhood = booking.Hotels.Hotels[0].Neighborhood
+	}
+
+	draft, err := s.planner.ProcessRequest(ctx, &ai.TripPlanStrategy{}, req, &ai.TripPlanDecoder{})
+	if err != nil {
+		return nil, fmt.Errorf("AI trip planning failed: %w", err)
+	}
+
+	unlock := s.locks.lock(bookingID)
+	defer unlock()
+
+	// Reload so a change saved while the model was planning isn't overwritten
+	record, err = loadBooking(ctx, s.bookings, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	record.Plan = &models.TripPlan{
+		BookingID: booking.ID,
+		City:      req.City,
+		TimeZone:  req.TimeZone,
+		Days:      fitDraft(days, draft.Days),
+		Reasoning: draft.Reasoning,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.bookings.Save(ctx, record); err != nil {
+		return nil, fmt.Errorf("failed to save plan: %w", err)
+	}
+	return record.Plan, nil
+}
+
+// GetPlan returns the stored plan of a booking
+func (s *PlanService) GetPlan(ctx context.Context, bookingID string) (*models.TripPlan, error) {
+	record, err := loadBooking(ctx, s.bookings, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	if record.Plan == nil {
+		return nil, ErrPlanNotFound
+	}
+	return record.Plan, nil
+}
+
+// UpdatePlanDay replaces the blocks of one day. They must fit the day's free hours
+// and not overlap; they are stored in order, in the destination's time zone. Edits to
+// the same booking are applied one at a time.
+func (s *PlanService) UpdatePlanDay(ctx context.Context, bookingID string, day int, update models.PlanDayUpdate) (*models.TripPlan, error) {
+	unlock := s.locks.lock(bookingID)
+	defer unlock()
+
+	record, err := loadBooking(ctx, s.bookings, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	if record.Plan == nil {
+		return nil, ErrPlanNotFound
+	}
+
+	plan := *record.Plan
+	plan.Days = append([]models.PlanDay(nil), plan.Days...)
+	index := -1
+	for i := range plan.Days {
+		if plan.Days[i].Day == day {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, ErrPlanDayNotFound
+	}
+
+	location, err := time.LoadLocation(plan.TimeZone)
+	if err != nil {
+		location = time.UTC
+	}
+	blocks, err := checkBlocks(plan.Days[index], update.Blocks, location)
+	if err != nil {
+		return nil, err
+	}
+	plan.Days[index].Blocks = blocks
+	plan.UpdatedAt = time.Now()
+
+	record.Plan = &plan
+	if err := s.bookings.Save(ctx, record); err != nil {
+		return nil, fmt.Errorf("failed to save plan: %w", err)
+	}
+	return record.Plan, nil
+}
+
+// planDays lays out the days from the outbound arrival to the return departure in the
+// destination's time zone. The arrival day starts once the traveler has reached the
+// hotel and the departure day ends when they leave for the airport. Without a return
+// flight the plan covers the return date, or a few days for one-way trips.
+func (s *PlanService) planDays(params *models.TravelParameters, booking *models.BookingResponse) ([]models.PlanDay, *time.Location, bool) {
+	st, ok := stayAt(s.airports, params, booking)
+	if !ok || st.arrival.IsZero() {
+		return nil, nil, false
+	}
+	outbound := booking.FlightDetails
+	first, last := st.first, st.lastDay()
+
+	var days []models.PlanDay
+	for date := first; !date.After(last) && len(days) < maxStayDays; date = date.AddDate(0, 0, 1) {
+		day := models.PlanDay{
+			Day:   len(days) + 1,
+			Date:  date,
+			Start: date,
+			End:   date.AddDate(0, 0, 1),
+		}
+		var notes []string
+		if date.Equal(first) {
+			day.Start = st.arrival.Add(arrivalAllowance)
+			notes = append(notes, fmt.Sprintf("%s lands at %s; the plan starts once you've reached the hotel",
+				outbound.FlightNumber, st.arrival.Format("15:04")))
+		}
+		if !st.departure.IsZero() && date.Equal(last) {
+			day.End = st.departure.Add(-departureAllowance)
+			notes = append(notes, fmt.Sprintf("%s leaves at %s; the plan ends in time to get to the airport",
+				st.departing, st.departure.Format("15:04")))
+		}
+		// A late arrival or early departure can leave no free time at all
+		if day.Start.After(day.End) {
+			day.Start = day.End
+		}
+		day.Note = strings.Join(notes, "; ")
+		days = append(days, day)
+	}
+	return days, st.location, true
+}
+
+// fitDraft places the model's blocks on their days, trimming them to each day's free
+// hours. Blocks ending before they start, left empty by trimming, or overlapping an
+// earlier block are dropped.
+func fitDraft(days []models.PlanDay, drafts []models.DraftDay) []models.PlanDay {
+	byDay := make(map[int][]models.DraftBlock)
+	for _, draft := range drafts {
+		if _, ok := byDay[draft.Day]; !ok {
+			byDay[draft.Day] = draft.Blocks
+		}
+	}
+
+	fitted := make([]models.PlanDay, len(days))
+	for i, day := range days {
+		var blocks []models.PlanBlock
+		for _, draft := range byDay[day.Day] {
+			start, end := clockTime(day.Date, draft.Start), clockTime(day.Date, draft.End)
+			if start.Before(day.Start) {
+				start = day.Start
+			}
+			if end.After(day.End) {
+				end = day.End
+			}
+			if !end.After(start) {
+				continue
+			}
+			blocks = append(blocks, models.PlanBlock{
+				Start:       start,
+				End:         end,
+				Title:       draft.Title,
+				Description: draft.Description,
+				Location:    draft.Location,
+				Kind:        draft.Kind,
+			})
+		}
+		day.Blocks = dropOverlaps(blocks)
+		fitted[i] = day
+	}
+	return fitted
+}
+
+// clockTime gives a local "HH:MM" time on the date; "24:00" is the following midnight
+func clockTime(date time.Time, clock string) time.Time {
+	if clock == "24:00" {
+		return date.AddDate(0, 0, 1)
+	}
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return date
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), t.Hour(), t.Minute(), 0, 0, date.Location())
+}
+
+// dropOverlaps sorts the blocks and keeps each one starting after the last kept ends
+func dropOverlaps(blocks []models.PlanBlock) []models.PlanBlock {
+	sort.SliceStable(blocks, func(i, j int) bool {
+		return blocks[i].Start.Before(blocks[j].Start)
+	})
+	kept := make([]models.PlanBlock, 0, len(blocks))
+	for _, block := range blocks {
+		if n := len(kept); n > 0 && block.Start.Before(kept[n-1].End) {
+			continue
+		}
+		kept = append(kept, block)
+	}
+	return kept
+}
+
+// endClock gives the local time a day's free hours end, "24:00" when they run to midnight
+func endClock(day models.PlanDay, location *time.Location) string {
+	if !day.End.Before(day.Date.AddDate(0, 0, 1)) {
+		return "24:00"
+	}
+	return day.End.In(location).Format("15:04")
+}
+
+// checkBlocks validates a traveler's edit of a day. Unlike the model's blocks, nothing
+// is trimmed or dropped: an edit that doesn't fit is rejected.
+func checkBlocks(day models.PlanDay, blocks []models.PlanBlock, location *time.Location) ([]models.PlanBlock, error) {
+	checked := make([]models.PlanBlock, len(blocks))
+	for i, block := range blocks {
+		switch {
+		case strings.TrimSpace(block.Title) == "":
+			return nil, fmt.Errorf("%w: block %d has no title", ErrInvalidPlan, i+1)
+		case !block.End.After(block.Start):
+			return nil, fmt.Errorf("%w: block %d ends before it starts", ErrInvalidPlan, i+1)
+		case block.Start.Before(day.Start) || block.End.After(day.End):
+			return nil, fmt.Errorf("%w: block %d is outside the free hours of day %d, %s to %s",
+				ErrInvalidPlan, i+1, day.Day, day.Start.In(location).Format("15:04"), endClock(day, location))
+		}
+		block.Start, block.End = block.Start.In(location), block.End.In(location)
+		checked[i] = block
+	}
+
+	sort.SliceStable(checked, func(i, j int) bool {
+		return checked[i].Start.Before(checked[j].Start)
+	})
+	for i := 1; i < len(checked); i++ {
+		if checked[i].Start.Before(checked[i-1].End) {
+			return nil, fmt.Errorf("%w: %q overlaps %q", ErrInvalidPlan, checked[i].Title, checked[i-1].Title)
+		}
+	}
+	return checked, nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
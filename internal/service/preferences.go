@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service/ai"
+	"travel-agent/internal/service/currency"
+)
+
+// RecommendationDefaults are used when neither the traveler nor their profile states a
+// preference. Unlike stated preferences they only guide the model: flights above the
+// default budget are flagged rather than rejected. MaxBudget is in the default currency.
+type RecommendationDefaults struct {
+	TravelClass string
+	MaxBudget   float64
+}
+
+// WithRecommendationDefaults overrides the default cabin and budget sent to the recommender
+func WithRecommendationDefaults(defaults RecommendationDefaults) BookingOption {
+	return func(s *BookingService) {
+		s.defaults = defaults
+	}
+}
+
+// applyPreferences fills the recommendation request from the extracted preferences, then
+// the caller's profile, then the configured defaults, and returns the constraints to enforce.
+// Budgets are converted into aiReq.Currency, the traveler's currency.
+func (s *BookingService) applyPreferences(
+	ctx context.Context,
+	aiReq *models.FlightRecommendationRequest,
+	params *models.TravelParameters,
+	profile *models.TravelerProfile,
+) (flightConstraints, error) {
+	if profile == nil {
+		profile = &models.TravelerProfile{}
+	}
+	prefs := params.Preferences
+
+	var constraints flightConstraints
+	profileCurrency := profile.Currency
+	if profileCurrency == "" {
+		profileCurrency = s.currency
+	}
+	// Amounts without a currency are in assumed, typically the traveler's
+	toTraveler := func(amount models.Money, assumed, source string) (models.Money, error) {
+		converted, err := currency.Convert(ctx, s.rates, amount.Assume(assumed), aiReq.Currency)
+		if err != nil {
+			return models.Money{}, fmt.Errorf("converting the %s budget: %w", source, err)
+		}
+		return converted, nil
+	}
+
+	switch {
+	case prefs.TravelClass != "":
+		constraints.travelClass = normalizeTravelClass(prefs.TravelClass)
+		aiReq.PreferredClass = constraints.travelClass
+	case profile.TravelClass != "":
+		constraints.travelClass = normalizeTravelClass(profile.TravelClass)
+		aiReq.PreferredClass = constraints.travelClass
+	default:
+		aiReq.PreferredClass = normalizeTravelClass(s.defaults.TravelClass)
+	}
+
+	// The constraints limit what the party pays in total, the request tells the model
+	// what each traveler with a seat may spend. Lap infants don't share the budget, as
+	// they don't share a party's fare.
+	party := seatsRequired(aiReq.Passengers)
+	switch {
+	case prefs.BudgetRange.Max != nil && prefs.BudgetRange.Max.IsPositive():
+		limit, err := toTraveler(*prefs.BudgetRange.Max, aiReq.Currency, "requested")
+		if err != nil {
+			return constraints, err
+		}
+		constraints.maxPrice, aiReq.MaxBudget = partyBudget(limit, prefs.BudgetRange.Scope, party)
+	case profile.MaxBudget != nil && profile.MaxBudget.IsPositive():
+		limit, err := toTraveler(*profile.MaxBudget, profileCurrency, "profile")
+		if err != nil {
+			return constraints, err
+		}
+		constraints.maxPrice, aiReq.MaxBudget = partyBudget(limit, models.BudgetPerPerson, party)
+	default:
+		limit, err := toTraveler(models.NewMoney(s.defaults.MaxBudget, s.currency), s.currency, "default")
+		if err != nil {
+			return constraints, err
+		}
+		constraints.defaultMaxPrice, aiReq.MaxBudget = partyBudget(limit, models.BudgetPerPerson, party)
+	}
+
+	if prefs.BudgetRange.Min != nil && prefs.BudgetRange.Min.IsPositive() {
+		limit, err := toTraveler(*prefs.BudgetRange.Min, aiReq.Currency, "requested")
+		if err != nil {
+			return constraints, err
+		}
+		_, aiReq.MinBudget = partyBudget(limit, prefs.BudgetRange.Scope, party)
+	}
+
+	if profile.MaxLayovers != nil {
+		constraints.maxLayovers = profile.MaxLayovers
+		aiReq.MaxLayovers = profile.MaxLayovers
+	}
+
+	aiReq.PreferredDepartureTime = normalizeDayPart(prefs.DepartureTime)
+	aiReq.DietaryRestrictions = mergeUnique(prefs.DietaryRestrictions, profile.DietaryRestrictions)
+	aiReq.Baggage = prefs.Baggage
+
+	return constraints, nil
+}
+
+// partyBudget splits a budget into the party's total and each traveler's share. Budgets
+// are per person unless the traveler gave one for the whole party.
+func partyBudget(budget models.Money, scope models.BudgetScope, party int) (total, each models.Money) {
+	party = max(party, 1)
+	if scope == models.BudgetTotal {
+		return budget, budget.Scale(1 / float64(party))
+	}
+	return budget.Mul(party), budget
+}
+
+// applyRoute adds the airports serving the requested cities to the recommendation
+// request and makes the flights' cities a constraint
+func (s *BookingService) applyRoute(
+	aiReq *models.FlightRecommendationRequest,
+	constraints *flightConstraints,
+	origin, destination string,
+) {
+	constraints.airports = s.airports
+	if city, ok := s.airports.Resolve(origin); ok {
+		constraints.origin = city
+		aiReq.DepartureAirports = city.Codes()
+	}
+	if city, ok := s.airports.Resolve(destination); ok {
+		constraints.destination = city
+		aiReq.DestinationAirports = city.Codes()
+	}
+}
+
+// canonicalizeCities replaces the extracted city names with the database's, so "NYC"
+// and "Nueva York" both become New York in the prompts and the stored booking. Names it
+// can't resolve are kept and their fields marked ambiguous, for a conversation to
+// confirm; the flights recommended for them are flagged.
+func (s *BookingService) canonicalizeCities(params *models.TravelParameters) {
+	if s.airports == nil {
+		return
+	}
+	canonical := func(name *string, field string) {
+		if *name == "" {
+			return
+		}
+		if city, ok := s.airports.Resolve(*name); ok {
+			*name = city.Name
+			return
+		}
+		if !slices.Contains(params.AmbiguousFields, field) {
+			params.AmbiguousFields = append(params.AmbiguousFields, field)
+		}
+	}
+
+	canonical(&params.DepartureCity, ai.FieldDepartureCity)
+	canonical(&params.Destination, ai.FieldDestination)
+	canonical(&params.ReturnOrigin, "return_origin")
+	canonical(&params.ReturnDestination, "return_destination")
+	if len(params.Legs) > 0 {
+		legs := slices.Clone(params.Legs)
+		for i := range legs {
+			canonical(&legs[i].Origin, ai.FieldLegs)
+			canonical(&legs[i].Destination, ai.FieldLegs)
+		}
+		params.Legs = legs
+	}
+}
+
+// normalizeTravelClass maps cabin names like "Premium Economy" to "premium_economy"
+func normalizeTravelClass(class string) string {
+	class = strings.ToLower(strings.TrimSpace(class))
+	return strings.Join(strings.FieldsFunc(class, func(r rune) bool {
+		return r == ' ' || r == '-' || r == '_'
+	}), "_")
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"fmt"
+
+	"travel-agent/internal/models"
+)
+
+// Fares for children and lap infants, as a share of the adult fare
+const (
+	childFareRatio  = 0.75
+	infantFareRatio = 0.10
+)
+
+// passengersOf returns the party to book for, a single adult when none was extracted
+func passengersOf(params *models.TravelParameters) models.Passengers {
+	passengers := params.Passengers
+	if passengers.Adults == 0 {
+		passengers.Adults = 1
+	}
+	return passengers
+}
+
+// seatsRequired counts the passengers that need a seat; infants travel on a lap
+func seatsRequired(passengers models.Passengers) int {
+	return passengers.Adults + passengers.Children
+}
+
+// priceForParty derives the fare of each passenger type from the adult fare. Each fare
+// is rounded to the currency's minor units before the total is added up.
+func priceForParty(flight models.Flight, passengers models.Passengers) (*models.FareBreakdown, error) {
+	adult := flight.Price.Assume(defaultCurrency)
+	fare := &models.FareBreakdown{
+		Adult: adult,
+		Total: adult.Mul(passengers.Adults),
+	}
+	var err error
+	if passengers.Children > 0 {
+		child := adult.Scale(childFareRatio)
+		fare.Child = &child
+		if fare.Total, err = fare.Total.Add(child.Mul(passengers.Children)); err != nil {
+			return nil, err
+		}
+	}
+	if passengers.Infants > 0 {
+		infant := adult.Scale(infantFareRatio)
+		fare.Infant = &infant
+		if fare.Total, err = fare.Total.Add(infant.Mul(passengers.Infants)); err != nil {
+			return nil, err
+		}
+	}
+
+	return fare, nil
+}
+
+// extraBags counts the bags the party brings beyond the fare's allowance for everyone
+// with a seat. Lap infants have no allowance.
+func extraBags(conditions *models.FareConditions, passengers models.Passengers, baggage models.Baggage) (checked, cabin int) {
+	seats := seatsRequired(passengers)
+	checked = max(baggage.CheckedBags-conditions.CheckedBags*seats, 0)
+	cabin = max(baggage.CabinBags-conditions.CabinBags*seats, 0)
+	return checked, cabin
+}
+
+// addBaggageFees adds the extra bags the party needs to the fare. Bags whose fee isn't
+// known are counted but not priced; the baggage rule flags those flights.
+func addBaggageFees(fare *models.FareBreakdown, conditions *models.FareConditions, passengers models.Passengers, baggage models.Baggage) error {
+	if conditions == nil {
+		return nil
+	}
+	checked, cabin := extraBags(conditions, passengers, baggage)
+	fare.ExtraBags = checked + cabin
+	if fare.ExtraBags == 0 {
+		return nil
+	}
+
+	fees := models.Money{Currency: fare.Total.Currency}
+	var err error
+	if fee, ok := feeIn(conditions.CheckedBagFee, fees.Currency); ok && checked > 0 {
+		if fees, err = fees.Add(fee.Mul(checked)); err != nil {
+			return err
+		}
+	}
+	if fee, ok := feeIn(conditions.CabinBagFee, fees.Currency); ok && cabin > 0 {
+		if fees, err = fees.Add(fee.Mul(cabin)); err != nil {
+			return err
+		}
+	}
+	if fees.IsPositive() {
+		total, err := fare.Total.Add(fees)
+		if err != nil {
+			return err
+		}
+		fare.BaggageFees = &fees
+		fare.Total = total
+	}
+	return nil
+}
+
+// feeIn returns a known fee in the fare's currency; fees in another currency are
+// treated as unknown
+func feeIn(fee *models.Money, currency string) (models.Money, bool) {
+	if fee == nil {
+		return models.Money{}, false
+	}
+	amount := fee.Assume(currency)
+	return amount, amount.Currency == currency
+}
+
+// priceFlights prices each flight for the party and the bags it brings, in the
+// flight's currency and, when converted, in the traveler's. A fare that can't be added
+// up, e.g. one too large to represent, fails the whole search.
+func priceFlights(flights []models.Flight, passengers models.Passengers, baggage models.Baggage) ([]models.Flight, error) {
+	priced := make([]models.Flight, len(flights))
+	for i, flight := range flights {
+		fare, err := priceForParty(flight, passengers)
+		if err == nil {
+			err = addBaggageFees(fare, flight.Conditions, passengers, baggage)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("pricing flight %s: %w", flight.FlightNumber, err)
+		}
+		flight.Fare = fare
+		if flight.Converted != nil {
+			converted := *flight.Converted
+			converted.Total = flight.Fare.Total.Exchange(converted.Rate, converted.Price.Currency)
+			flight.Converted = &converted
+		}
+		priced[i] = flight
+	}
+	return priced, nil
+}
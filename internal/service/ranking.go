@@ -0,0 +1,262 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service/airports"
+	"travel-agent/pkg/utils"
+)
+
+// Ranking factors, as named in a flight's score breakdown
+const (
+	factorPrice         = "price"
+	factorStops         = "stops"
+	factorDuration      = "duration"
+	factorDepartureTime = "departure_time"
+)
+
+// RankingWeights sets how much each factor counts towards a flight's recommendation
+// score. Only their ratios matter, and a zero weight ignores the factor.
+type RankingWeights struct {
+	Price         float64
+	Stops         float64
+	Duration      float64
+	DepartureTime float64
+}
+
+var defaultRankingWeights = RankingWeights{Price: 0.4, Stops: 0.25, Duration: 0.2, DepartureTime: 0.15}
+
+// WithRankingWeights overrides how flights are scored. Weights that are negative or
+// add up to nothing are ignored.
+func WithRankingWeights(weights RankingWeights) BookingOption {
+	return func(s *BookingService) {
+		if weights.Price < 0 || weights.Stops < 0 || weights.Duration < 0 || weights.DepartureTime < 0 {
+			return
+		}
+		if weights.Price+weights.Stops+weights.Duration+weights.DepartureTime > 0 {
+			s.weights = weights
+		}
+	}
+}
+
+// WithRankingNarrative has the model explain the ranking of searched flights. Without
+// it the explanation is a plain summary and the model isn't called at all.
+func WithRankingNarrative(enabled bool) BookingOption {
+	return func(s *BookingService) {
+		s.narrative = enabled
+	}
+}
+
+// dayParts are the departure windows a traveler can ask for, in local hours at the
+// origin. Night wraps around midnight.
+var dayParts = map[string]struct{ from, to int }{
+	"morning":   {5, 12},
+	"afternoon": {12, 17},
+	"evening":   {17, 21},
+	"night":     {21, 29},
+}
+
+// normalizeDayPart maps a departure time preference to a day part, or "" when it
+// isn't one
+func normalizeDayPart(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	value = strings.TrimSuffix(value, "s") // "mornings"
+	if value == "late night" || value == "red-eye" || value == "red eye" {
+		return "night"
+	}
+	if _, ok := dayParts[value]; ok {
+		return value
+	}
+	return ""
+}
+
+// maxRankedFlights caps the flights kept once ranked
+const maxRankedFlights = 20
+
+// rankFlights scores every flight against the others and sorts them best first, keeping
+// the best maxRankedFlights. Ties keep the order they came in. Prices are compared for
+// the whole party, baggage fees included, in the traveler's currency. Departure times are
+// scored in the origin's local time.
+func rankFlights(flights []models.Flight, aiReq models.FlightRecommendationRequest, weights RankingWeights, db *airports.Database) []models.Flight {
+	if len(flights) == 0 {
+		return flights
+	}
+
+	total := weights.Price + weights.Stops + weights.Duration + weights.DepartureTime
+	cheapest, dearest := math.Inf(1), math.Inf(-1)
+	shortest := time.Duration(math.MaxInt64)
+	for _, flight := range flights {
+		price := travelerTotal(flight).Float()
+		cheapest, dearest = math.Min(cheapest, price), math.Max(dearest, price)
+		if d := flightDuration(flight); d > 0 && d < shortest {
+			shortest = d
+		}
+	}
+
+	ranked := make([]models.Flight, len(flights))
+	for i, flight := range flights {
+		factors := []models.FactorScore{
+			priceFactor(flight, cheapest, dearest, weights.Price/total),
+			stopsFactor(flight, weights.Stops/total),
+			durationFactor(flight, shortest, weights.Duration/total),
+			departureTimeFactor(localDepartureTime(flight, db), aiReq.PreferredDepartureTime, weights.DepartureTime/total),
+		}
+
+		var score float64
+		for _, factor := range factors {
+			score += factor.Contribution
+		}
+		flight.RecommendationScore = round2(score)
+		flight.ScoreBreakdown = factors
+		ranked[i] = flight
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].RecommendationScore > ranked[j].RecommendationScore
+	})
+	if len(ranked) > maxRankedFlights {
+		ranked = ranked[:maxRankedFlights]
+	}
+	return ranked
+}
+
+func newFactor(name string, score, weight float64, detail string) models.FactorScore {
+	return models.FactorScore{
+		Factor:       name,
+		Score:        round2(score),
+		Weight:       round2(weight),
+		Contribution: round2(score * weight),
+		Detail:       detail,
+	}
+}
+
+// priceFactor scores the cheapest flight 1 and the dearest 0, linearly in between
+func priceFactor(flight models.Flight, cheapest, dearest, weight float64) models.FactorScore {
+	price := travelerTotal(flight)
+	described := price.String()
+	if fees := travelerBaggageFees(flight); fees != nil {
+		described = fmt.Sprintf("%s including %s in bag fees", price, fees)
+	}
+	if dearest <= cheapest {
+		return newFactor(factorPrice, 1, weight, fmt.Sprintf("%s, the only fare", described))
+	}
+
+	score := (dearest - price.Float()) / (dearest - cheapest)
+	var detail string
+	switch {
+	case price.Float() <= cheapest:
+		detail = fmt.Sprintf("%s, the cheapest", described)
+	case cheapest <= 0:
+		// A free award fare has no percentage to compare with, so give the difference
+		detail = fmt.Sprintf("%s, %s above the cheapest", described, models.NewMoney(price.Float()-cheapest, price.Currency))
+	default:
+		detail = fmt.Sprintf("%s, %.0f%% above the cheapest", described, (price.Float()/cheapest-1)*100)
+	}
+	return newFactor(factorPrice, score, weight, detail)
+}
+
+// stopsFactor halves the score with the first connection and keeps lowering it after
+func stopsFactor(flight models.Flight, weight float64) models.FactorScore {
+	detail := "direct"
+	switch {
+	case flight.LayoverCount == 1:
+		detail = "1 stop"
+	case flight.LayoverCount > 1:
+		detail = fmt.Sprintf("%d stops", flight.LayoverCount)
+	}
+	if len(flight.Segments) > 1 {
+		var stops []string
+		for _, seg := range flight.Segments[1:] {
+			stops = append(stops, fmt.Sprintf("%s (%s)", seg.Origin, seg.Layover))
+		}
+		detail += " in " + strings.Join(stops, ", ")
+	}
+	return newFactor(factorStops, 1/float64(1+max(flight.LayoverCount, 0)), weight, detail)
+}
+
+// durationFactor compares the time from departure to arrival with the fastest flight
+func durationFactor(flight models.Flight, shortest time.Duration, weight float64) models.FactorScore {
+	d := flightDuration(flight)
+	if d <= 0 {
+		return newFactor(factorDuration, 0, weight, "unknown duration")
+	}
+
+	detail := models.Duration(d).String()
+	if d > shortest {
+		detail += fmt.Sprintf(", %s longer than the fastest", models.Duration(d-shortest))
+	}
+	return newFactor(factorDuration, float64(shortest)/float64(d), weight, detail)
+}
+
+// departureTimeFactor scores the local departure time against the requested part of the
+// day, or against convenient hours when none was requested
+func departureTimeFactor(local time.Time, preferred string, weight float64) models.FactorScore {
+	minutes := local.Hour()*60 + local.Minute()
+	detail := "departs " + local.Format("15:04")
+
+	if part, ok := dayParts[preferred]; ok {
+		from, to := part.from*60, part.to*60
+		if minutes < from && to > 24*60 {
+			minutes += 24 * 60 // After midnight, still the night before
+		}
+		switch {
+		case minutes >= from && minutes < to:
+			return newFactor(factorDepartureTime, 1, weight, detail+", in the "+preferred+" as requested")
+		case minutes >= from-120 && minutes < to+120:
+			return newFactor(factorDepartureTime, 0.5, weight, detail+", close to the "+preferred)
+		}
+		return newFactor(factorDepartureTime, 0, weight, detail+", not in the "+preferred)
+	}
+
+	switch {
+	case minutes >= 8*60 && minutes < 20*60:
+		return newFactor(factorDepartureTime, 1, weight, detail)
+	case minutes >= 6*60 && minutes < 22*60:
+		return newFactor(factorDepartureTime, 0.6, weight, detail+", early or late")
+	}
+	return newFactor(factorDepartureTime, 0.2, weight, detail+", overnight")
+}
+
+func flightDuration(flight models.Flight) time.Duration {
+	return flight.ArrivalTime.Sub(flight.DepartureTime)
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// explainRanking summarizes the ranking when the model isn't asked for a narrative
+func explainRanking(flights []models.Flight, weights RankingWeights) string {
+	if len(flights) == 0 {
+		return ""
+	}
+
+	total := weights.Price + weights.Stops + weights.Duration + weights.DepartureTime
+	var parts []string
+	for _, w := range []struct {
+		name   string
+		weight float64
+	}{
+		{"price", weights.Price},
+		{"stops", weights.Stops},
+		{"duration", weights.Duration},
+		{"departure time", weights.DepartureTime},
+	} {
+		if w.weight > 0 {
+			parts = append(parts, fmt.Sprintf("%s %.0f%%", w.name, w.weight/total*100))
+		}
+	}
+
+	best := flights[0]
+	details := make([]string, len(best.ScoreBreakdown))
+	for i, factor := range best.ScoreBreakdown {
+		details[i] = factor.Detail
+	}
+	return fmt.Sprintf("Ranked %s by %s. %s %s scores %.2f: %s.",
+		utils.Plural(len(flights), "flight", "flights"), strings.Join(parts, ", "),
+		best.Airline, best.FlightNumber, best.RecommendationScore, strings.Join(details, "; "))
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+	"travel-agent/internal/models"
+)
+
+// minTurnaround is the least time between landing and flying back on a round trip
+const minTurnaround = 3 * time.Hour
+
+// maxRoundTripOptions caps the outbound and return pairings returned with a booking
+const maxRoundTripOptions = 5
+
+// directionReturn marks the rejections of flights back on a round trip
+const directionReturn = "return"
+
+// returnRequestFor turns the recommendation request around for the flights back. Open-jaw
+// trips return along their own route; the preferences carry over.
+func returnRequestFor(aiReq models.FlightRecommendationRequest) models.FlightRecommendationRequest {
+	returnReq := aiReq
+	returnReq.DepartureCity, returnReq.Destination = aiReq.Destination, aiReq.DepartureCity
+	if aiReq.ReturnOrigin != "" {
+		returnReq.DepartureCity = aiReq.ReturnOrigin
+	}
+	if aiReq.ReturnDestination != "" {
+		returnReq.Destination = aiReq.ReturnDestination
+	}
+	returnReq.DepartureDate = *aiReq.ReturnDate
+	returnReq.ReturnDate = nil
+	returnReq.ReturnOrigin, returnReq.ReturnDestination = "", ""
+	returnReq.DepartureWindowEnd = nil
+	returnReq.DepartureAirports, returnReq.DestinationAirports = nil, nil
+	return returnReq
+}
+
+// getReturnFlights checks and ranks the flights back. The model recommends them along
+// with the outbound flights; a search provider is searched again.
+func (s *BookingService) getReturnFlights(
+	ctx context.Context,
+	aiReq models.FlightRecommendationRequest,
+	constraints flightConstraints,
+	recommended []models.Flight,
+) ([]models.Flight, []models.FlightRejection, error) {
+	returnReq := returnRequestFor(aiReq)
+	constraints.origin, constraints.destination = nil, nil
+	s.applyRoute(&returnReq, &constraints, returnReq.DepartureCity, returnReq.Destination)
+
+	candidates := recommended
+	if s.flightSearch != nil {
+		offers, err := s.candidateFlights(ctx, returnReq, constraints)
+		if err != nil {
+			return nil, nil, err
+		}
+		candidates = offers.Recommendations
+	}
+	if len(candidates) == 0 {
+		return nil, nil, fmt.Errorf("no return flights from %s to %s", returnReq.DepartureCity, returnReq.Destination)
+	}
+
+	flights, err := priceFlights(s.convertFlights(ctx, candidates, returnReq.Currency), returnReq.Passengers, returnReq.Baggage)
+	if err != nil {
+		return nil, nil, err
+	}
+	flights, rejected, err := checkFlights(flights, returnReq, constraints, flightRules)
+	for i := range rejected {
+		rejected[i].Direction = directionReturn
+	}
+	if err != nil {
+		return nil, rejected, err
+	}
+	return rankFlights(describeFlights(flights), returnReq, s.weights, s.airports), rejected, nil
+}
+
+// pairFlights combines every outbound flight with every return flight leaving after it
+// lands. Pairings are priced for the party, dropped when the pair goes over the budget
+// and ordered by the mean of their scores, the cheaper first on a tie.
+func pairFlights(outbound, inbound []models.Flight, budget models.Money) []models.RoundTripItinerary {
+	var pairings []models.RoundTripItinerary
+	for _, out := range outbound {
+		for _, back := range inbound {
+			if back.DepartureTime.Before(out.ArrivalTime.Add(minTurnaround)) {
+				continue
+			}
+			// Fares in different currencies can't be added up into one price
+			total, err := travelerTotal(out).Add(travelerTotal(back))
+			if err != nil || exceedsBudget(total, budget) {
+				continue
+			}
+			pairings = append(pairings, models.RoundTripItinerary{
+				Outbound:            out,
+				Return:              back,
+				TotalPrice:          total,
+				RecommendationScore: round2((out.RecommendationScore + back.RecommendationScore) / 2),
+			})
+		}
+	}
+
+	sort.SliceStable(pairings, func(i, j int) bool {
+		if pairings[i].RecommendationScore != pairings[j].RecommendationScore {
+			return pairings[i].RecommendationScore > pairings[j].RecommendationScore
+		}
+		return pairings[i].TotalPrice.Cmp(pairings[j].TotalPrice) < 0
+	})
+	if len(pairings) > maxRoundTripOptions {
+		pairings = pairings[:maxRoundTripOptions]
+	}
+	return pairings
+}
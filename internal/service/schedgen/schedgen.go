@@ -0,0 +1,308 @@
+// Package schedgen generates synthetic but plausible flight schedules from the airport
+// database, for the flight stub server and test fixtures. The same seed and options
+// always produce the same schedules.
+package schedgen
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"strconv"
+	"time"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service/airports"
+	"travel-agent/internal/service/currency"
+	"travel-agent/internal/service/flights"
+)
+
+// Block times assume a typical jet cruise speed plus time to taxi, climb and descend.
+// Connections match the stub server's fixed connection time.
+const (
+	cruiseSpeedKmh     = 820
+	taxiTime           = 30 * time.Minute
+	connectionTime     = time.Hour
+	longHaulKm         = 4000
+	businessMinKm      = 1500
+	maxSeatsEconomy    = 9
+	maxSeatsBusiness   = 4
+	baseFareUSD        = 45
+	fareUSDPerKm       = 0.085
+	connectionDiscount = 0.85
+	businessMarkup     = 3.5
+)
+
+// Carrier is an airline flying out of a hub city
+type Carrier struct {
+	Name     string
+	Code     string // IATA airline code
+	Hub      string // City code in the airport database
+	Currency string // Fares are sold in it
+}
+
+// DefaultCarriers is a carrier for most regions covered by the bundled airport database
+var DefaultCarriers = []Carrier{
+	{"American Airlines", "AA", "MIA", "USD"},
+	{"Delta Air Lines", "DL", "ATL", "USD"},
+	{"United Airlines", "UA", "CHI", "USD"},
+	{"Air Canada", "AC", "YTO", "CAD"},
+	{"Aeromexico", "AM", "MEX", "MXN"},
+	{"Copa Airlines", "CM", "PTY", "USD"},
+	{"Avianca", "AV", "BOG", "USD"},
+	{"LATAM", "LA", "SCL", "USD"},
+	{"British Airways", "BA", "LON", "GBP"},
+	{"Aer Lingus", "EI", "DUB", "EUR"},
+	{"Air France", "AF", "PAR", "EUR"},
+	{"KLM", "KL", "AMS", "EUR"},
+	{"Iberia", "IB", "MAD", "EUR"},
+	{"TAP Air Portugal", "TP", "LIS", "EUR"},
+	{"Lufthansa", "LH", "FRA", "EUR"},
+	{"Swiss", "LX", "ZRH", "CHF"},
+	{"Turkish Airlines", "TK", "IST", "USD"},
+	{"Emirates", "EK", "DXB", "AED"},
+	{"Air India", "AI", "DEL", "INR"},
+	{"Singapore Airlines", "SQ", "SIN", "SGD"},
+	{"Korean Air", "KE", "SEL", "KRW"},
+	{"Japan Airlines", "JL", "TYO", "JPY"},
+	{"Qantas", "QF", "SYD", "AUD"},
+}
+
+// Options controls what is generated
+type Options struct {
+	Seed        uint64
+	From, To    time.Time // The schedules operate between these dates, inclusive
+	Routes      int       // Round-trip routes out of each hub; 4 when zero
+	Connections int       // One-stop routes through each hub; 2 when zero
+	Carriers    []Carrier // DefaultCarriers when empty
+}
+
+// Generate builds schedules for every carrier whose hub is in db. Each route is flown
+// both ways, longer routes in business too, and every fare rises as departure approaches.
+func Generate(db *airports.Database, opts Options) ([]flights.Schedule, error) {
+	if opts.From.IsZero() || opts.To.Before(opts.From) {
+		return nil, fmt.Errorf("a date range ending after it starts is required")
+	}
+	if opts.Routes == 0 {
+		opts.Routes = 4
+	}
+	if opts.Connections == 0 {
+		opts.Connections = 2
+	}
+	if len(opts.Carriers) == 0 {
+		opts.Carriers = DefaultCarriers
+	}
+	rates, err := currency.DefaultTable()
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{
+		rng:   rand.New(rand.NewPCG(opts.Seed, opts.Seed^0x5DEECE66D)),
+		opts:  opts,
+		rates: rates,
+	}
+
+	var schedules []flights.Schedule
+	for _, c := range opts.Carriers {
+		hub, ok := db.Resolve(c.Hub)
+		if !ok {
+			continue
+		}
+		destinations := g.destinations(db.Cities(), hub, opts.Routes)
+		number := 100 + g.rng.IntN(400)*2
+
+		for _, city := range destinations {
+			from, to := g.airport(hub), g.airport(city)
+			out, back := g.route(c, number, from, to, nil), g.route(c, number+1, to, from, nil)
+			schedules = append(schedules, out...)
+			schedules = append(schedules, back...)
+			number += 2
+		}
+
+		// One-stop routes pair two of the hub's destinations, e.g. Cali to Madrid via Bogotá
+		number = 4000 + g.rng.IntN(100)*10
+		for i := 0; i < opts.Connections && len(destinations) >= 2; i++ {
+			a, b := g.pair(len(destinations))
+			via := g.airport(hub)
+			schedules = append(schedules, g.route(c, number, g.airport(destinations[a]), g.airport(destinations[b]), &via)...)
+			number += 2
+		}
+	}
+
+	if len(schedules) == 0 {
+		return nil, fmt.Errorf("no carrier hub is in the airport database")
+	}
+	return schedules, nil
+}
+
+type generator struct {
+	rng   *rand.Rand
+	opts  Options
+	rates currency.RateProvider
+}
+
+// stop is an airport along with its code, for distance and naming
+type stop struct {
+	code    string
+	airport *airports.Airport
+}
+
+// destinations picks n cities other than the hub, in the database's order so the
+// choice only depends on the seed
+func (g *generator) destinations(cities []*airports.City, hub *airports.City, n int) []*airports.City {
+	var others []*airports.City
+	for _, city := range cities {
+		if city != hub {
+			others = append(others, city)
+		}
+	}
+	g.rng.Shuffle(len(others), func(i, j int) { others[i], others[j] = others[j], others[i] })
+	if n < len(others) {
+		others = others[:n]
+	}
+	return others
+}
+
+func (g *generator) pair(n int) (int, int) {
+	a := g.rng.IntN(n)
+	b := (a + 1 + g.rng.IntN(n-1)) % n
+	return a, b
+}
+
+// airport picks a city's main airport most of the time, and one of the others otherwise
+func (g *generator) airport(city *airports.City) stop {
+	i := 0
+	if len(city.Airports) > 1 && g.rng.Float64() < 0.3 {
+		i = 1 + g.rng.IntN(len(city.Airports)-1)
+	}
+	return stop{code: city.Airports[i].IATA, airport: &city.Airports[i]}
+}
+
+// route builds the economy schedule of a flight and, on longer routes, its business cabin
+func (g *generator) route(c Carrier, number int, from, to stop, via *stop) []flights.Schedule {
+	legs := []stop{from, to}
+	if via != nil {
+		legs = []stop{from, *via, to}
+	}
+
+	var distance float64
+	var duration time.Duration
+	for i := 0; i+1 < len(legs); i++ {
+		km := airports.Distance(legs[i].airport, legs[i+1].airport)
+		distance += km
+		duration += BlockTime(km)
+	}
+	duration += connectionTime * time.Duration(len(legs)-2)
+
+	fare := (baseFareUSD + fareUSDPerKm*distance) * (0.85 + 0.4*g.rng.Float64())
+	if via != nil {
+		fare *= connectionDiscount
+	}
+
+	base := flights.Schedule{
+		Airline:      c.Name,
+		Carrier:      c.Code,
+		FlightNumber: strconv.Itoa(number),
+		Origin:       from.code,
+		Destination:  to.code,
+		Departs:      g.departure(distance),
+		Duration:     formatDuration(duration),
+		Days:         g.days(distance),
+		Effective:    g.opts.From.Format("2006-01-02"),
+		Discontinued: g.opts.To.Format("2006-01-02"),
+	}
+	if via != nil {
+		base.Via = []string{via.code}
+	} else {
+		base.Aircraft = g.aircraft(distance)
+	}
+
+	economy := base
+	economy.CabinClass = "economy"
+	economy.Seats = 2 + g.rng.IntN(maxSeatsEconomy-1)
+	economy.Fare, economy.Currency = g.price(fare, c.Currency)
+	economy.FareCurve = g.fareCurve()
+	schedules := []flights.Schedule{economy}
+
+	if distance >= businessMinKm {
+		business := base
+		business.CabinClass = "business"
+		business.Seats = 1 + g.rng.IntN(maxSeatsBusiness)
+		business.Fare, business.Currency = g.price(fare*businessMarkup, c.Currency)
+		business.FareCurve = g.fareCurve()
+		schedules = append(schedules, business)
+	}
+	return schedules
+}
+
+// aircraft picks a type suited to the distance; connecting flights leave it to the stub
+func (g *generator) aircraft(km float64) string {
+	var types []string
+	switch {
+	case km < 2500:
+		types = []string{"Airbus A320neo", "Boeing 737-800", "Airbus A321neo"}
+	case km < 7000:
+		types = []string{"Boeing 787-9", "Airbus A330-300", "Boeing 767-300ER"}
+	default:
+		types = []string{"Airbus A350-900", "Boeing 777-300ER", "Boeing 787-10"}
+	}
+	return types[g.rng.IntN(len(types))]
+}
+
+// BlockTime estimates gate-to-gate time for a distance, rounded to 5 minutes
+func BlockTime(km float64) time.Duration {
+	flying := time.Duration(km / cruiseSpeedKmh * float64(time.Hour))
+	return (flying + taxiTime).Round(5 * time.Minute)
+}
+
+// departure picks a local departure time: long-haul flights mostly leave in the
+// afternoon and evening, shorter ones throughout the day
+func (g *generator) departure(km float64) string {
+	first, last := 6*60, 21*60
+	if km >= longHaulKm {
+		first, last = 13*60, 23*60
+	}
+	minutes := first + g.rng.IntN((last-first)/5+1)*5
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// days flies short routes daily and long ones on three to six days a week
+func (g *generator) days(km float64) []int {
+	if km < longHaulKm {
+		return nil
+	}
+	days := g.rng.Perm(7)[:3+g.rng.IntN(4)]
+	for i := range days {
+		days[i]++
+	}
+	sort.Ints(days)
+	return days
+}
+
+// fareCurve raises fares in steps over the last three weeks before departure
+func (g *generator) fareCurve() []flights.FareStep {
+	jitter := func(low, high float64) float64 {
+		return math.Round((low+(high-low)*g.rng.Float64())*100) / 100
+	}
+	return []flights.FareStep{
+		{DaysBefore: 21, Multiplier: jitter(1.05, 1.15)},
+		{DaysBefore: 14, Multiplier: jitter(1.2, 1.35)},
+		{DaysBefore: 7, Multiplier: jitter(1.4, 1.6)},
+		{DaysBefore: 2, Multiplier: jitter(1.7, 2.0)},
+	}
+}
+
+// price converts a USD fare into the carrier's currency, falling back to USD when
+// there's no rate
+func (g *generator) price(usd float64, code string) (string, string) {
+	fare, err := currency.Convert(context.Background(), g.rates, models.NewMoney(usd, "USD"), code)
+	if err != nil {
+		fare = models.NewMoney(usd, "USD")
+	}
+	return fare.Amount(), fare.Currency
+}
+
+func formatDuration(d time.Duration) string {
+	return fmt.Sprintf("%dh%dm", int(d.Hours()), int(d.Minutes())%60)
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"travel-agent/internal/logging"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service/ai"
+	"travel-agent/internal/service/injection"
+
+	"github.com/google/uuid"
+)
+
+// ErrRequestRejected is returned for queries flagged as prompt-injection attempts
+// when the screening policy rejects them
+var ErrRequestRejected = errors.New("request rejected: it looks like an attempt to instruct the assistant rather than describe a trip")
+
+// defaultClassifierConfidence is how sure the classifier must be to flag a query
+const defaultClassifierConfidence = 0.8
+
+// InjectionClassifier asks the model whether a query is a prompt-injection attempt
+type InjectionClassifier interface {
+	ProcessRequest(
+		ctx context.Context,
+		strategy ai.PromptStrategy[models.BookingRequest],
+		request models.BookingRequest,
+		decoder ai.DecodingStrategy[models.InjectionAssessment],
+	) (*models.InjectionAssessment, error)
+}
+
+// AuditStore keeps a record of every flagged query
+type AuditStore interface {
+	Save(ctx context.Context, audit *models.ScreeningAudit) error
+}
+
+// ScreeningPolicy decides what happens to flagged queries
+type ScreeningPolicy struct {
+	Action string // reject or quarantine; reject when empty
+	// MinConfidence is how sure the classifier must be to flag a query on its own
+	MinConfidence float64
+}
+
+// WithScreening sets what happens to queries flagged as prompt-injection attempts and
+// where they are recorded. Without it flagged queries are rejected and not recorded.
+func WithScreening(policy ScreeningPolicy, audits AuditStore) BookingOption {
+	return func(s *BookingService) {
+		if policy.Action == models.ScreeningQuarantine {
+			s.screening.Action = models.ScreeningQuarantine
+		}
+		if policy.MinConfidence > 0 {
+			s.screening.MinConfidence = policy.MinConfidence
+		}
+		s.audits = audits
+	}
+}
+
+// WithInjectionClassifier has the model check queries the heuristics let through
+func WithInjectionClassifier(classifier InjectionClassifier) BookingOption {
+	return func(s *BookingService) {
+		s.classifier = classifier
+	}
+}
+
+// screenQuery checks a traveler's text for prompt injection before it reaches the
+// model: first with heuristics, then with the classifier when one is configured. A
+// classifier that fails lets the query through, since the heuristics already passed it.
+// Flagged queries are recorded with the action taken; rejected ones fail with
+// ErrRequestRejected and quarantined ones are reported as flagged.
+func (s *BookingService) screenQuery(ctx context.Context, req models.BookingRequest, source, referenceID string) (bool, error) {
+	ctx = logging.WithStage(ctx, "screening")
+	found := injection.Scan(req.Query, ai.FenceOpen, ai.FenceClose)
+	flagged := found.Flagged()
+
+	var assessment *models.InjectionAssessment
+	if !flagged && s.classifier != nil {
+		result, err := s.classifier.ProcessRequest(ctx, &ai.InjectionClassifierStrategy{}, req, &ai.InjectionClassifierDecoder{})
+		if err != nil {
+			slog.WarnContext(ctx, "injection classifier failed, query let through", "error", err)
+		} else {
+			assessment = result
+			flagged = result.Injection && result.Confidence >= s.screening.MinConfidence
+		}
+	}
+	if !flagged {
+		return false, nil
+	}
+
+	audit := &models.ScreeningAudit{
+		ID:          uuid.New().String(),
+		Source:      source,
+		ReferenceID: referenceID,
+		Query:       req.Query,
+		Score:       found.Score,
+		Signals:     found.Signals,
+		Classifier:  assessment,
+		Action:      s.screening.Action,
+		CreatedAt:   time.Now(),
+	}
+	// The query itself stays in the audit record, out of the logs
+	slog.WarnContext(ctx, "query flagged as prompt injection",
+		"audit_id", audit.ID,
+		"action", audit.Action,
+		"score", audit.Score,
+		"signals", audit.Signals,
+		"classified", assessment != nil,
+	)
+	if s.audits != nil {
+		if err := s.audits.Save(ctx, audit); err != nil {
+			return false, fmt.Errorf("failed to record screening: %w", err)
+		}
+	}
+
+	if audit.Action == models.ScreeningReject {
+		return false, ErrRequestRejected
+	}
+	return true, nil
+}
+
+// quarantinedBooking is the response for a booking held for review; no flight is
+// looked for and nothing is sent to the model
+func quarantinedBooking(id string, req models.BookingRequest) *models.BookingResponse {
+	now := time.Now()
+	return &models.BookingResponse{
+		ID:        id,
+		Status:    models.StatusQuarantined,
+		Query:     req.Query,
+		Deadline:  req.Deadline,
+		Message:   "Your request has been held for review",
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
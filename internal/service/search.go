@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"travel-agent/internal/logging"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service/ai"
+)
+
+// FlightSearchProvider finds real flight offers. With one configured, flights are ranked
+// from its offers instead of being recommended by the model.
+type FlightSearchProvider interface {
+	Search(ctx context.Context, req models.FlightSearchRequest) ([]models.Flight, error)
+}
+
+// WithFlightSearch makes recommendations come from a search provider's offers
+func WithFlightSearch(provider FlightSearchProvider) BookingOption {
+	return func(s *BookingService) {
+		s.flightSearch = provider
+	}
+}
+
+// candidateFlights returns the flights to check and rank: the offers found by the
+// search provider when there is one, or the model's recommendations otherwise
+func (s *BookingService) candidateFlights(
+	ctx context.Context,
+	aiReq models.FlightRecommendationRequest,
+	constraints flightConstraints,
+) (*models.FlightRecommendation, error) {
+	ctx = logging.WithStage(ctx, "flights")
+	if s.flightSearch == nil {
+		recommendations, err := s.flightRecommender.ProcessRequest(
+			ctx,
+			&ai.FlightRecommendationStrategy{},
+			aiReq,
+			&ai.FlightRecommendationDecoder{},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("AI recommendation failed: %w", err)
+		}
+		return recommendations, nil
+	}
+
+	offers, err := s.flightSearch.Search(ctx, searchRequestFor(aiReq, constraints))
+	if err != nil {
+		return nil, fmt.Errorf("flight search failed: %w", err)
+	}
+	if len(offers) == 0 {
+		return nil, fmt.Errorf("no flights found from %s to %s", aiReq.DepartureCity, aiReq.Destination)
+	}
+	return &models.FlightRecommendation{Recommendations: offers}, nil
+}
+
+// explainFlights writes the reasoning for ranked flights that came without one. The
+// model writes it when the narrative is enabled; if that fails, or it isn't, the
+// ranking is summarized instead.
+func (s *BookingService) explainFlights(ctx context.Context, aiReq models.FlightRecommendationRequest, ranked []models.Flight) string {
+	if s.narrative && s.flightRecommender != nil {
+		ctx = logging.WithStage(ctx, "narrative")
+		aiReq.Offers = ranked
+		narrative, err := s.flightRecommender.ProcessRequest(
+			ctx,
+			&ai.RankingNarrativeStrategy{},
+			aiReq,
+			&ai.RankingNarrativeDecoder{},
+		)
+		if err == nil {
+			return narrative.Reasoning
+		}
+		slog.WarnContext(ctx, "ranking narrative failed, summarizing instead", "error", err)
+	}
+	return explainRanking(ranked, s.weights)
+}
+
+// searchRequestFor searches by city code when the city is known, so every airport
+// serving it is included
+func searchRequestFor(aiReq models.FlightRecommendationRequest, c flightConstraints) models.FlightSearchRequest {
+	req := models.FlightSearchRequest{
+		Origin:        aiReq.DepartureCity,
+		Destination:   aiReq.Destination,
+		DepartureDate: aiReq.DepartureDate,
+		LatestDate:    aiReq.DepartureWindowEnd,
+		Passengers:    aiReq.Passengers,
+		TravelClass:   aiReq.PreferredClass,
+		MaxLayovers:   c.maxLayovers,
+		Currency:      aiReq.Currency,
+	}
+	if c.origin != nil {
+		req.Origin = c.origin.Code
+	}
+	if c.destination != nil {
+		req.Destination = c.destination.Code
+	}
+	return req
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"time"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service/airports"
+)
+
+// stay is the traveler's time at a single destination. Its days are local midnights in
+// the destination's time zone, so a flight landing after midnight there starts the stay
+// on the next day whatever offset its arrival time was given in.
+type stay struct {
+	location *time.Location
+	// arrival is when the outbound flight lands; zero before one is found
+	arrival time.Time
+	// departure is when the return flight leaves, departing its number; zero without one
+	departure time.Time
+	departing string
+	// first is the arrival day and leaving the day the traveler leaves. One-way trips
+	// get a few days from arrival and aren't returning.
+	first, leaving time.Time
+	returning      bool
+}
+
+// stayAt works out the stay from the outbound and return flights, falling back to the
+// trip dates before flights are found. Multi-city trips have no single destination.
+// Without the destination in db the outbound arrival's time zone is used.
+func stayAt(db *airports.Database, params *models.TravelParameters, booking *models.BookingResponse) (stay, bool) {
+	if params.TripType == models.TripMultiCity || params.Destination == "" {
+		return stay{}, false
+	}
+
+	var s stay
+	outbound := booking.FlightDetails
+	if outbound != nil && outbound.ArrivalTime.IsZero() {
+		outbound = nil
+	}
+	s.location = time.UTC
+	if outbound != nil {
+		s.location = outbound.ArrivalTime.Location()
+	}
+	if city, ok := db.Resolve(params.Destination); ok {
+		s.location = city.Location()
+	}
+
+	switch {
+	case outbound != nil:
+		s.arrival = outbound.ArrivalTime.In(s.location)
+		s.first = startOfDay(s.arrival)
+	case params.DepartureDate != nil:
+		s.first = dateIn(*params.DepartureDate, s.location)
+	default:
+		return stay{}, false
+	}
+
+	s.returning = true
+	switch {
+	case booking.RoundTrip != nil:
+		s.departure = booking.RoundTrip.Return.DepartureTime.In(s.location)
+		s.departing = booking.RoundTrip.Return.FlightNumber
+		s.leaving = startOfDay(s.departure)
+	case params.ReturnDate != nil:
+		s.leaving = dateIn(*params.ReturnDate, s.location)
+	default:
+		s.leaving = s.first.AddDate(0, 0, defaultStayDays)
+		s.returning = false
+	}
+	return s, !s.leaving.Before(s.first)
+}
+
+// nights counts the nights from arrival to leaving, at least one and at most
+// maxStayDays
+func (s stay) nights() int {
+	return min(max(daysBetween(s.first, s.leaving), 1), maxStayDays)
+}
+
+// lastDay is the last day with time to spend at the destination: the day the traveler
+// leaves, or the last of the days a one-way trip gets
+func (s stay) lastDay() time.Time {
+	if s.returning {
+		return s.leaving
+	}
+	return s.leaving.AddDate(0, 0, -1)
+}
+
+// dateIn returns midnight in loc of the calendar date t falls on in its own location,
+// as trip dates are dates rather than instants
+func dateIn(t time.Time, loc *time.Location) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
+
+// daysBetween counts the calendar days from one local midnight to another, which may be
+// 23 or 25 hours apart across a daylight saving change
+func daysBetween(from, to time.Time) int {
+	return int(calendarDate(to).Sub(calendarDate(from)).Hours() / 24)
+}
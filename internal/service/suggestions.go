@@ -0,0 +1,220 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"travel-agent/internal/logging"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service/ai"
+	"travel-agent/internal/service/airports"
+	"travel-agent/internal/store"
+	"unicode"
+)
+
+var (
+	ErrBookingNotFound = errors.New("booking not found")
+	ErrNoStay          = errors.New("booking has no stay at a single destination")
+)
+
+const (
+	// defaultStayDays is how many days one-way trips get suggestions and plans for
+	defaultStayDays = 3
+	maxStayDays     = 14
+)
+
+type SuggestionRecommender interface {
+	ProcessRequest(
+		ctx context.Context,
+		strategy ai.PromptStrategy[models.SuggestionRequest],
+		request models.SuggestionRequest,
+		decoder ai.DecodingStrategy[models.SuggestionPlan],
+	) (*models.SuggestionPlan, error)
+}
+
+// SuggestionStore keeps the suggestions made for each booking
+type SuggestionStore interface {
+	Save(ctx context.Context, record *models.SuggestionRecord) error
+	Get(ctx context.Context, bookingID string) (*models.SuggestionRecord, error)
+}
+
+// SuggestionService turns a stored booking's activities and dietary restrictions into
+// day-by-day suggestions for the stay
+type SuggestionService struct {
+	bookings    BookingStore
+	suggestions SuggestionStore
+	recommender SuggestionRecommender
+	airports    *airports.Database
+	// locks has a booking's concurrent reads wait for one set of suggestions to be made
+	locks *keyedLocks
+}
+
+func NewSuggestionService(bookings BookingStore, suggestions SuggestionStore, recommender SuggestionRecommender) *SuggestionService {
+	s := &SuggestionService{
+		bookings:    bookings,
+		suggestions: suggestions,
+		recommender: recommender,
+		locks:       newKeyedLocks(),
+	}
+	// Without the bundled database days follow the arrival flight's time zone
+	if db, err := airports.Default(); err == nil {
+		s.airports = db
+	}
+	return s
+}
+
+// Suggest plans activities and dining for each day between arrival and the return
+// departure. Dining suggestions not catering to every dietary restriction are dropped.
+// The suggestions are stored, and made again only once the booking changes what the
+// model would be asked.
+func (s *SuggestionService) Suggest(ctx context.Context, bookingID string) (*models.Suggestions, error) {
+	ctx = logging.With(ctx, logging.KeyBookingID, bookingID, logging.KeyStage, "suggestions")
+	record, err := loadBooking(ctx, s.bookings, bookingID)
+	if err != nil {
+		return nil, err
+	}
+
+	params, booking := &record.Parameters, &record.Booking
+	start, days, ok := s.suggestionDays(params, booking)
+	if !ok {
+		return nil, ErrNoStay
+	}
+
+	req := models.SuggestionRequest{
+		City:                params.Destination,
+		StartDate:           start,
+		Days:                days,
+		Guests:              passengersOf(params),
+		Activities:          params.Preferences.Activities,
+		DietaryRestrictions: params.Preferences.DietaryRestrictions,
+	}
+	if booking.Hotels != nil && len(booking.Hotels.Hotels) > 0 {
+		req.Neighborhood = booking.Hotels.Hotels[0].Neighborhood
+	}
+
+	unlock := s.locks.lock(bookingID)
+	defer unlock()
+
+	cached, err := s.suggestions.Get(ctx, bookingID)
+	switch {
+	case err == nil && sameSuggestionRequest(cached.Request, req):
+		return &cached.Suggestions, nil
+	case err != nil && !errors.Is(err, store.ErrNotFound):
+		return nil, fmt.Errorf("failed to load suggestions: %w", err)
+	}
+
+	plan, err := s.recommender.ProcessRequest(ctx, &ai.SuggestionStrategy{}, req, &ai.SuggestionDecoder{})
+	if err != nil {
+		return nil, fmt.Errorf("AI suggestions failed: %w", err)
+	}
+
+	suggestions := &models.Suggestions{
+		BookingID: booking.ID,
+		City:      req.City,
+		StartDate: start,
+		Reasoning: plan.Reasoning,
+	}
+	suggestions.Days, suggestions.Excluded = arrangeSuggestions(plan.Days, start, days, req.DietaryRestrictions)
+
+	if err := s.suggestions.Save(ctx, &models.SuggestionRecord{Request: req, Suggestions: *suggestions}); err != nil {
+		return nil, fmt.Errorf("failed to save suggestions: %w", err)
+	}
+	return suggestions, nil
+}
+
+// sameSuggestionRequest compares requests as they are sent to the model, so a stored
+// request matches however its times and lists were decoded
+func sameSuggestionRequest(a, b models.SuggestionRequest) bool {
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(encodedA, encodedB)
+}
+
+// loadBooking reads a stored booking, reporting a missing one as ErrBookingNotFound
+func loadBooking(ctx context.Context, bookings BookingStore, id string) (*models.BookingRecord, error) {
+	record, err := bookings.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrBookingNotFound
+		}
+		return nil, fmt.Errorf("failed to load booking: %w", err)
+	}
+	return record, nil
+}
+
+// suggestionDays covers the stay from the day the outbound flight lands to the day the
+// return leaves, the same days the hotel is booked for. One-way trips get a few days
+// from arrival. Multi-city trips have no single destination to plan for.
+func (s *SuggestionService) suggestionDays(params *models.TravelParameters, booking *models.BookingResponse) (time.Time, int, bool) {
+	st, ok := stayAt(s.airports, params, booking)
+	if !ok {
+		return time.Time{}, 0, false
+	}
+	return calendarDate(st.first), st.nights(), true
+}
+
+// arrangeSuggestions keeps the days within the stay in order, dates them and drops the
+// dining suggestions that don't cater to every dietary restriction
+func arrangeSuggestions(days []models.DaySuggestions, start time.Time, count int, restrictions []string) ([]models.DaySuggestions, []string) {
+	var arranged []models.DaySuggestions
+	var excluded []string
+	seen := make(map[int]bool)
+	for _, day := range days {
+		if day.Day < 1 || day.Day > count || seen[day.Day] {
+			continue
+		}
+		seen[day.Day] = true
+		day.Date = start.AddDate(0, 0, day.Day-1)
+
+		var dining []models.Suggestion
+		for _, place := range day.Dining {
+			if catersTo(place, restrictions) {
+				dining = append(dining, place)
+			} else {
+				excluded = append(excluded, place.Name)
+			}
+		}
+		day.Dining = dining
+		arranged = append(arranged, day)
+	}
+
+	sort.Slice(arranged, func(i, j int) bool {
+		return arranged[i].Day < arranged[j].Day
+	})
+	return arranged, excluded
+}
+
+// catersTo reports whether a place's dietary options cover every restriction. Options
+// are compared loosely, so "gluten free" matches "Gluten-Free", and a vegan place
+// caters to vegetarians.
+func catersTo(place models.Suggestion, restrictions []string) bool {
+	options := make(map[string]bool)
+	for _, option := range place.DietaryOptions {
+		options[dietaryKey(option)] = true
+	}
+	if options["vegan"] {
+		options["vegetarian"] = true
+	}
+
+	for _, restriction := range restrictions {
+		key := dietaryKey(restriction)
+		if key != "" && !options[key] {
+			return false
+		}
+	}
+	return true
+}
+
+func dietaryKey(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, s)
+}
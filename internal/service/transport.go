@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"travel-agent/internal/logging"
+	"travel-agent/internal/models"
+)
+
+// GroundTransportProvider finds ways from an airport into town
+type GroundTransportProvider interface {
+	Options(ctx context.Context, req models.GroundTransportRequest) ([]models.TransportOption, error)
+}
+
+// WithGroundTransport adds options from the arrival airport to the hotel or city center
+func WithGroundTransport(provider GroundTransportProvider) BookingOption {
+	return func(s *BookingService) {
+		s.groundTransport = provider
+	}
+}
+
+// recommendGroundTransport suggests how to get from the airport the selected flight
+// lands at to the recommended hotel, or the city center without one. Like hotels,
+// ground transport is optional and never fails the booking.
+func (s *BookingService) recommendGroundTransport(
+	ctx context.Context,
+	params *models.TravelParameters,
+	response *models.BookingResponse,
+) *models.GroundTransport {
+	flight := response.FlightDetails
+	if s.groundTransport == nil || flight == nil || flight.ArrivalTime.IsZero() {
+		return nil
+	}
+	airport := s.arrivalAirport(flight)
+	if airport == "" {
+		return nil
+	}
+
+	req := models.GroundTransportRequest{
+		Airport:     airport,
+		City:        params.Destination,
+		ArrivalTime: flight.ArrivalTime,
+		Destination: transportDestination(params, response),
+		Passengers:  passengersOf(params),
+	}
+	if trip := response.RoundTrip; trip != nil {
+		req.ReturnTime = &trip.Return.DepartureTime
+	}
+
+	ctx = logging.WithStage(ctx, "ground_transport")
+	options, err := s.groundTransport.Options(ctx, req)
+	if err != nil {
+		slog.WarnContext(ctx, "ground transport lookup failed, booking goes ahead without it", "error", err)
+		return nil
+	}
+	if len(options) == 0 {
+		return nil
+	}
+
+	return &models.GroundTransport{
+		Airport:     airport,
+		Destination: req.Destination,
+		ArrivalTime: flight.ArrivalTime,
+		Options:     rankTransport(s.priceTransport(ctx, options, response.Currency), response.Currency),
+	}
+}
+
+// arrivalAirport is where the flight's last segment lands or, without segments, the
+// arrival city's main airport
+func (s *BookingService) arrivalAirport(flight *models.Flight) string {
+	if n := len(flight.Segments); n > 0 && flight.Segments[n-1].Destination != "" {
+		return strings.ToUpper(flight.Segments[n-1].Destination)
+	}
+	city, ok := s.airports.Resolve(flight.ArrivalCity)
+	if !ok {
+		return ""
+	}
+	for _, code := range city.Codes() {
+		if strings.EqualFold(code, flight.ArrivalCity) {
+			return code
+		}
+	}
+	return city.Airports[0].IATA
+}
+
+// transportDestination names the recommended hotel, or the city center without one
+func transportDestination(params *models.TravelParameters, response *models.BookingResponse) string {
+	if stay := response.Hotels; stay != nil && len(stay.Hotels) > 0 {
+		hotel := stay.Hotels[0]
+		if hotel.Neighborhood != "" {
+			return fmt.Sprintf("%s, %s", hotel.Name, hotel.Neighborhood)
+		}
+		return hotel.Name
+	}
+	return params.Destination + " city center"
+}
+
+// priceTransport converts options priced in another currency into the traveler's
+// when a rate is known
+func (s *BookingService) priceTransport(ctx context.Context, options []models.TransportOption, to string) []models.TransportOption {
+	priced := make([]models.TransportOption, len(options))
+	for i, option := range options {
+		option.Price = option.Price.Assume(to)
+		option.Converted = nil
+		if from := option.Price.Currency; from != to {
+			if rate, err := s.rates.Rate(ctx, from, to); err == nil {
+				option.Converted = &models.ConvertedPrice{
+					Price: option.Price.Exchange(rate, to),
+					Total: option.Price.Exchange(rate, to),
+					Rate:  rate,
+				}
+			}
+		}
+		priced[i] = option
+	}
+	return priced
+}
+
+// rankTransport sorts the options by when they get the traveler there, the cheaper
+// first on a tie. Options that couldn't be priced in the traveler's currency come after
+// the ones that were.
+func rankTransport(options []models.TransportOption, currency string) []models.TransportOption {
+	sort.SliceStable(options, func(i, j int) bool {
+		if !options[i].Arrives.Equal(options[j].Arrives) {
+			return options[i].Arrives.Before(options[j].Arrives)
+		}
+		return transportPrice(options[i]).Less(transportPrice(options[j]), currency)
+	})
+	return options
+}
+
+// transportPrice returns the price in the traveler's currency when converted
+func transportPrice(option models.TransportOption) models.Money {
+	if option.Converted != nil {
+		return option.Converted.Total
+	}
+	return option.Price
+}
@@ -0,0 +1,242 @@
+package transport
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service/airports"
+)
+
+//go:embed fixture.json
+var defaultFixture []byte
+
+const (
+	// exitTime is how long it takes from landing to leaving the terminal with bags
+	exitTime = 45 * time.Minute
+	// maxWait leaves out scheduled services that aren't running again soon, such as
+	// the first train of the morning after a late arrival
+	maxWait = 2 * time.Hour
+	// defaultRentalDays prices car rentals when the return flight isn't known
+	defaultRentalDays = 1
+)
+
+// What a route's price is for
+const (
+	perPerson  = "person"
+	perVehicle = "vehicle"
+	perDay     = "day"
+)
+
+// Route is a way into town from an airport. Scheduled routes (trains and buses) run
+// every Every from First to Last, local time at the airport, and stop at To; the
+// others leave when the traveler is ready and go to their destination.
+type Route struct {
+	Airport  string `json:"airport"` // IATA code
+	Mode     string `json:"mode"`
+	Provider string `json:"provider"`
+	To       string `json:"to,omitempty"`
+	Duration string `json:"duration"` // e.g. "35m"
+	First    string `json:"first,omitempty"`
+	Last     string `json:"last,omitempty"` // Before First for services running past midnight
+	Every    string `json:"every,omitempty"`
+	Price    string `json:"price"`
+	Currency string `json:"currency"`
+	Per      string `json:"per"`                // person, vehicle or day
+	Capacity int    `json:"capacity,omitempty"` // Seats per vehicle
+	Pickup   string `json:"pickup,omitempty"`   // Extra wait before leaving, e.g. at the rental desk
+	Notes    string `json:"notes,omitempty"`
+
+	duration time.Duration
+	every    time.Duration
+	pickup   time.Duration
+	first    time.Duration // Since local midnight
+	last     time.Duration
+	price    models.Money
+}
+
+// Fixture offers ground transport from a fixed table of routes
+type Fixture struct {
+	routes   []Route
+	airports *airports.Database
+}
+
+// DefaultFixture returns the routes bundled with the binary
+func DefaultFixture(db *airports.Database) (*Fixture, error) {
+	return ParseFixture(defaultFixture, db)
+}
+
+// LoadFixture reads a JSON array of routes from disk
+func LoadFixture(filename string, db *airports.Database) (*Fixture, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading transport fixture: %w", err)
+	}
+	return ParseFixture(data, db)
+}
+
+// ParseFixture builds a fixture from a JSON array of routes. Airports are looked up in
+// db for their time zone.
+func ParseFixture(data []byte, db *airports.Database) (*Fixture, error) {
+	var routes []Route
+	if err := json.Unmarshal(data, &routes); err != nil {
+		return nil, fmt.Errorf("parsing transport fixture: %w", err)
+	}
+
+	for i := range routes {
+		r := &routes[i]
+		name := fmt.Sprintf("route %d (%s %s)", i+1, r.Airport, r.Provider)
+		if r.Airport == "" || r.Mode == "" || r.Provider == "" {
+			return nil, fmt.Errorf("route %d: airport, mode and provider are required", i+1)
+		}
+		r.Airport = strings.ToUpper(r.Airport)
+
+		var err error
+		if r.duration, err = time.ParseDuration(r.Duration); err != nil || r.duration <= 0 {
+			return nil, fmt.Errorf("%s: invalid duration %q", name, r.Duration)
+		}
+		if r.price, err = models.ParseMoney(r.Price, r.Currency); err != nil || !r.price.IsPositive() || r.price.Currency == "" {
+			return nil, fmt.Errorf("%s: invalid price %q %q", name, r.Price, r.Currency)
+		}
+		switch r.Per {
+		case perPerson:
+		case perVehicle, perDay:
+			if r.Capacity <= 0 {
+				return nil, fmt.Errorf("%s: capacity is required when priced per %s", name, r.Per)
+			}
+		default:
+			return nil, fmt.Errorf("%s: price must be per person, vehicle or day, not %q", name, r.Per)
+		}
+		if r.Pickup != "" {
+			if r.pickup, err = time.ParseDuration(r.Pickup); err != nil || r.pickup < 0 {
+				return nil, fmt.Errorf("%s: invalid pickup time %q", name, r.Pickup)
+			}
+		}
+
+		if r.First == "" && r.Last == "" && r.Every == "" {
+			continue
+		}
+		if r.first, err = clock(r.First); err != nil {
+			return nil, fmt.Errorf("%s: invalid first service %q", name, r.First)
+		}
+		if r.last, err = clock(r.Last); err != nil {
+			return nil, fmt.Errorf("%s: invalid last service %q", name, r.Last)
+		}
+		if r.last < r.first {
+			r.last += 24 * time.Hour
+		}
+		if r.every, err = time.ParseDuration(r.Every); err != nil || r.every <= 0 {
+			return nil, fmt.Errorf("%s: invalid frequency %q", name, r.Every)
+		}
+	}
+
+	return &Fixture{routes: routes, airports: db}, nil
+}
+
+// clock parses a local "HH:MM" time into the time since midnight
+func clock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Options lists the routes from the airport, each leaving once the traveler has left
+// the terminal. Scheduled routes not leaving within maxWait are left out.
+func (f *Fixture) Options(ctx context.Context, req models.GroundTransportRequest) ([]models.TransportOption, error) {
+	if req.Airport == "" || req.ArrivalTime.IsZero() {
+		return nil, fmt.Errorf("airport and arrival time are required")
+	}
+
+	location := req.ArrivalTime.Location()
+	if city, ok := f.airports.Resolve(req.Airport); ok {
+		location = city.Location()
+	}
+	ready := req.ArrivalTime.In(location).Add(exitTime)
+
+	var options []models.TransportOption
+	for _, route := range f.routes {
+		if route.Airport != strings.ToUpper(req.Airport) {
+			continue
+		}
+		departs, ok := route.nextDeparture(ready)
+		if !ok {
+			continue
+		}
+
+		option := models.TransportOption{
+			Mode:     route.Mode,
+			Provider: route.Provider,
+			From:     route.Airport,
+			To:       route.To,
+			Departs:  departs,
+			Arrives:  departs.Add(route.duration),
+			Duration: models.Duration(route.duration),
+			Notes:    route.Notes,
+		}
+		if option.To == "" {
+			option.To = req.Destination
+		}
+		option.Price, option.Vehicles, option.RentalDays = route.priceFor(req, departs)
+		options = append(options, option)
+	}
+	return options, nil
+}
+
+// nextDeparture is the first service at or after ready, if it leaves within maxWait. Routes without a timetable
+// leave as soon as the traveler is ready, after any pickup wait.
+func (r Route) nextDeparture(ready time.Time) (time.Time, bool) {
+	if r.every == 0 {
+		return ready.Add(r.pickup), true
+	}
+
+	year, month, day := ready.Date()
+	// Yesterday's services may still be running after midnight
+	for _, midnight := range []time.Time{
+		time.Date(year, month, day-1, 0, 0, 0, 0, ready.Location()),
+		time.Date(year, month, day, 0, 0, 0, 0, ready.Location()),
+	} {
+		first, last := midnight.Add(r.first), midnight.Add(r.last)
+		if ready.After(last) {
+			continue
+		}
+		departs := first
+		if ready.After(first) {
+			waits := (ready.Sub(first) + r.every - 1) / r.every
+			departs = first.Add(waits * r.every)
+		}
+		if !departs.After(last) {
+			return departs, departs.Sub(ready) <= maxWait
+		}
+	}
+	return time.Time{}, false
+}
+
+// priceFor prices the route for the whole party. Lap infants ride free on trains and
+// buses but need a car seat.
+func (r Route) priceFor(req models.GroundTransportRequest, departs time.Time) (models.Money, int, int) {
+	seats := max(req.Passengers.Adults, 1) + req.Passengers.Children
+	switch r.Per {
+	case perVehicle:
+		vehicles := vehiclesFor(seats+req.Passengers.Infants, r.Capacity)
+		return r.price.Mul(vehicles), vehicles, 0
+	case perDay:
+		vehicles := vehiclesFor(seats+req.Passengers.Infants, r.Capacity)
+		days := defaultRentalDays
+		if req.ReturnTime != nil && req.ReturnTime.After(departs) {
+			days = int((req.ReturnTime.Sub(departs) + 24*time.Hour - 1) / (24 * time.Hour))
+		}
+		return r.price.Mul(vehicles * days), vehicles, days
+	default:
+		return r.price.Mul(seats), 0, 0
+	}
+}
+
+func vehiclesFor(people, capacity int) int {
+	return max((people+capacity-1)/capacity, 1)
+}
@@ -0,0 +1,36 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"travel-agent/internal/models"
+)
+
+// MemoryAuditStore keeps screening audit records in memory. Data is lost on restart.
+type MemoryAuditStore struct {
+	mu     sync.RWMutex
+	audits []models.ScreeningAudit
+}
+
+func NewMemoryAuditStore() *MemoryAuditStore {
+	return &MemoryAuditStore{}
+}
+
+// Save appends an audit record
+func (s *MemoryAuditStore) Save(ctx context.Context, audit *models.ScreeningAudit) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record := *audit
+	record.Signals = append([]string(nil), audit.Signals...)
+	s.audits = append(s.audits, record)
+	return nil
+}
+
+// List returns a copy of every audit record, oldest first
+func (s *MemoryAuditStore) List(ctx context.Context) ([]models.ScreeningAudit, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]models.ScreeningAudit(nil), s.audits...), nil
+}
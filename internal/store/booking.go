@@ -0,0 +1,41 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"travel-agent/internal/models"
+)
+
+// MemoryBookingStore keeps processed bookings in memory. Data is lost on restart.
+type MemoryBookingStore struct {
+	mu       sync.RWMutex
+	bookings map[string]models.BookingRecord
+}
+
+func NewMemoryBookingStore() *MemoryBookingStore {
+	return &MemoryBookingStore{
+		bookings: make(map[string]models.BookingRecord),
+	}
+}
+
+// Save creates or replaces a booking. Records are replaced as a whole, so callers
+// change a booking by saving a new record rather than editing the one they got.
+func (s *MemoryBookingStore) Save(ctx context.Context, record *models.BookingRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.bookings[record.Booking.ID] = *record
+	return nil
+}
+
+// Get returns a copy of the booking record
+func (s *MemoryBookingStore) Get(ctx context.Context, id string) (*models.BookingRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, ok := s.bookings[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &record, nil
+}
@@ -0,0 +1,57 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"travel-agent/internal/models"
+)
+
+// ErrNotFound is returned when a record does not exist
+var ErrNotFound = errors.New("not found")
+
+// MemoryConversationStore keeps conversations in memory. Data is lost on restart.
+type MemoryConversationStore struct {
+	mu            sync.RWMutex
+	conversations map[string]models.Conversation
+}
+
+func NewMemoryConversationStore() *MemoryConversationStore {
+	return &MemoryConversationStore{
+		conversations: make(map[string]models.Conversation),
+	}
+}
+
+// Save creates or replaces a conversation
+func (s *MemoryConversationStore) Save(ctx context.Context, conversation *models.Conversation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.conversations[conversation.ID] = cloneConversation(*conversation)
+	return nil
+}
+
+// Get returns a copy of the conversation so callers cannot mutate the stored value
+func (s *MemoryConversationStore) Get(ctx context.Context, id string) (*models.Conversation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	conversation, ok := s.conversations[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	clone := cloneConversation(conversation)
+	return &clone, nil
+}
+
+func cloneConversation(c models.Conversation) models.Conversation {
+	c.Messages = append([]models.ConversationMessage(nil), c.Messages...)
+	c.MissingFields = append([]string(nil), c.MissingFields...)
+	c.AskedFields = append([]string(nil), c.AskedFields...)
+	if c.Parameters != nil {
+		params := *c.Parameters
+		c.Parameters = &params
+	}
+	return c
+}
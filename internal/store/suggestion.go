@@ -0,0 +1,49 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"travel-agent/internal/models"
+)
+
+// MemorySuggestionStore keeps the suggestions made for each booking in memory. Data is
+// lost on restart.
+type MemorySuggestionStore struct {
+	mu          sync.RWMutex
+	suggestions map[string]models.SuggestionRecord
+}
+
+func NewMemorySuggestionStore() *MemorySuggestionStore {
+	return &MemorySuggestionStore{
+		suggestions: make(map[string]models.SuggestionRecord),
+	}
+}
+
+// Save creates or replaces the suggestions of the record's booking
+func (s *MemorySuggestionStore) Save(ctx context.Context, record *models.SuggestionRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.suggestions[record.Suggestions.BookingID] = cloneSuggestionRecord(*record)
+	return nil
+}
+
+// Get returns a copy of a booking's suggestions
+func (s *MemorySuggestionStore) Get(ctx context.Context, bookingID string) (*models.SuggestionRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, ok := s.suggestions[bookingID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	clone := cloneSuggestionRecord(record)
+	return &clone, nil
+}
+
+func cloneSuggestionRecord(r models.SuggestionRecord) models.SuggestionRecord {
+	r.Suggestions.Days = append([]models.DaySuggestions(nil), r.Suggestions.Days...)
+	r.Suggestions.Excluded = append([]string(nil), r.Suggestions.Excluded...)
+	return r
+}
@@ -0,0 +1,130 @@
+// Package redact masks personal data in text headed for the logs
+package redact
+
+import (
+	"regexp"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Placeholders replacing what was masked
+const (
+	Email    = "[email]"
+	Phone    = "[phone]"
+	Passport = "[passport]"
+	Name     = "[name]"
+)
+
+type rule struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+// Rules run in order: emails before phone numbers, so digits in an address are not taken
+// for a phone.
+var rules = []rule{
+	{regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), Email},
+	{regexp.MustCompile(`\+\d{1,3}[\s.-]?(?:\(?\d{1,4}\)?[\s.-]?){2,5}\d{2,4}\b`), Phone},
+	{regexp.MustCompile(`(?:\(\d{3}\)\s?|\b\d{3}[\s.-])\d{3}[\s.-]\d{4}\b`), Phone},
+}
+
+// Document numbers are only taken for passport numbers in the clause that mentions a
+// passport, so flight and order numbers such as UA123456 elsewhere are kept
+var (
+	passportClause = regexp.MustCompile(`(?i)\b(?:passport|pasaporte)[^;\n{}]{0,80}`)
+	documentNumber = regexp.MustCompile(`\b[A-Z]{0,2}\d{6,9}\b`)
+)
+
+// nameRule masks the names following its prefix, the first submatch. Names found by
+// a rule that remembers them are also masked wherever else they appear in the text.
+type nameRule struct {
+	re       *regexp.Regexp
+	remember bool
+}
+
+const (
+	// personName is one to three capitalized words
+	personName = `\p{Lu}[\p{L}'-]+(?:\s+\p{Lu}[\p{L}'-]+){0,2}`
+	// fullName has at least a first and a last name
+	fullName = `\p{Lu}[\p{L}'-]+(?:\s+\p{Lu}[\p{L}'-]+){1,2}`
+	// moreNames continues a list of names, as in "Ana López and Luis Pérez"
+	moreNames = `(?:\s+(?:and|y|&)\s+` + personName + `)*`
+)
+
+// titles come before a name rather than being one
+const titles = `Mr|Mrs|Ms|Miss|Dr|Sr|Sra`
+
+var title = regexp.MustCompile(`^(?:` + titles + `)$`)
+
+// nameSeparator splits a list of names matched by a rule
+var nameSeparator = regexp.MustCompile(`\s+(?:and|y|&)\s+`)
+
+// Quotes may be escaped, as in a JSON document carried in a JSON string. A single
+// capitalized word after "with" may be an airline as well as a companion, and the words
+// after "for" may be a place, as in "flights for Buenos Aires", so they are masked where
+// they appear but not remembered.
+var nameRules = []nameRule{
+	{regexp.MustCompile(`(?i)(\\?"(?:name|given_name|family_name|first_name|last_name|full_name|passenger_name|traveler_name|traveller_name)\\?"\s*:\s*\\?")[^"\\]+`), true},
+	{regexp.MustCompile(`\b((?:` + titles + `)\.?\s+)` + personName), true},
+	{regexp.MustCompile(`(\b(?i:my name is|me llamo|mi nombre es|i'm|i am|soy)\s+)` + personName + moreNames), true},
+	{regexp.MustCompile(`((?i:travell?ers?|passengers?|pasajeros?|viajeros?)\s*:\s*)` + personName + moreNames), true},
+	{regexp.MustCompile(`(\b(?:for|para)\s+)` + fullName + moreNames), false},
+	{regexp.MustCompile(`(\b(?:with|con)\s+)` + fullName + moreNames), true},
+	{regexp.MustCompile(`(\b(?:with|con)\s+)` + personName + moreNames), false},
+}
+
+// word is a run of letters, as names are compared when masked elsewhere in the text
+var word = regexp.MustCompile(`[\p{L}'-]+`)
+
+// String masks emails, phone numbers, passport numbers and names in s. Names are only
+// recognized where the text says they are one: name fields of JSON documents, titles
+// such as "Mrs.", introductions such as "my name is", labels such as "Traveler:" and
+// the people a trip is booked for or with. Names found by most rules are then masked
+// everywhere.
+func String(s string) string {
+	for _, r := range rules {
+		s = r.re.ReplaceAllString(s, r.replacement)
+	}
+	s = passportClause.ReplaceAllStringFunc(s, func(clause string) string {
+		return documentNumber.ReplaceAllString(clause, Passport)
+	})
+
+	known := map[string]bool{}
+	for _, r := range nameRules {
+		s = r.re.ReplaceAllStringFunc(s, func(match string) string {
+			prefix := match[:r.re.FindStringSubmatchIndex(match)[3]]
+			names := nameSeparator.Split(match[len(prefix):], -1)
+			separators := nameSeparator.FindAllString(match[len(prefix):], -1)
+
+			masked := prefix
+			for i, name := range names {
+				if title.MatchString(name) {
+					masked += name
+				} else {
+					masked += Name
+				}
+				if i < len(separators) {
+					masked += separators[i]
+				}
+				if !r.remember {
+					continue
+				}
+				for _, w := range word.FindAllString(name, -1) {
+					if first, _ := utf8.DecodeRuneInString(w); unicode.IsUpper(first) && utf8.RuneCountInString(w) > 2 {
+						known[w] = true
+					}
+				}
+			}
+			return masked
+		})
+	}
+	if len(known) == 0 {
+		return s
+	}
+	return word.ReplaceAllStringFunc(s, func(w string) string {
+		if known[w] {
+			return Name
+		}
+		return w
+	})
+}
@@ -2,11 +2,14 @@ package utils
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"math/rand/v2"
 	"net/http"
 	"time"
+	"travel-agent/pkg/redact"
 )
 
 // TimeNow returns the current time in UTC
@@ -14,22 +17,65 @@ func TimeNow() time.Time {
 	return time.Now().UTC()
 }
 
-func LogResponseWithoutConsuming(resp *http.Response) error {
+// Plural counts n of something, e.g. "1 flight" or "3 flights"
+func Plural(n int, singular, plural string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, singular)
+	}
+	return fmt.Sprintf("%d %s", n, plural)
+}
+
+// PayloadLogger logs the payloads exchanged with a provider. Payloads carry travelers'
+// names, dates and routes, so they are only logged at the debug level, for a sample
+// of calls, and always redacted.
+type PayloadLogger struct {
+	SampleRate float64 // Share of calls logged, from 0 to 1
+	// Logger writes the payloads; slog.Default() when nil
+	Logger *slog.Logger
+	// Sample draws the number compared with SampleRate; rand.Float64 when nil
+	Sample func() float64
+}
+
+// Sampled decides whether a call's payloads are logged. The request and response of a
+// call should share one decision.
+func (l *PayloadLogger) Sampled(ctx context.Context) bool {
+	if l == nil || l.SampleRate <= 0 || !l.logger().Enabled(ctx, slog.LevelDebug) {
+		return false
+	}
+	sample := l.Sample
+	if sample == nil {
+		sample = rand.Float64
+	}
+	return sample() < l.SampleRate
+}
+
+// LogRequest logs a redacted request body
+func (l *PayloadLogger) LogRequest(ctx context.Context, body []byte) {
+	l.logger().DebugContext(ctx, "provider request", "body", redact.String(string(body)))
+}
+
+// LogResponseWithoutConsuming logs the response status and redacted body, leaving the
+// body to be read again
+func (l *PayloadLogger) LogResponseWithoutConsuming(ctx context.Context, resp *http.Response) error {
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return fmt.Errorf("error reading response body: %w", err)
 	}
 
-	// Log response details
-	log.Printf("\nHTTP Response Details:")
-	log.Printf("Status Code: %v", resp.Status)
-	log.Printf("Body: %s", string(body))
+	l.logger().DebugContext(ctx, "provider response", "status", resp.Status, "body", redact.String(string(body)))
 
 	// Restore the body
 	resp.Body = io.NopCloser(bytes.NewBuffer(body))
 	return nil
 }
 
+func (l *PayloadLogger) logger() *slog.Logger {
+	if l.Logger != nil {
+		return l.Logger
+	}
+	return slog.Default()
+}
+
 func MustParseTime(timeStr string) time.Time {
 	t, err := time.Parse(time.RFC3339, timeStr)
 	if err != nil {
@@ -0,0 +1,224 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service"
+	"travel-agent/internal/service/airports"
+	"travel-agent/internal/store"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAirportDatabase_Resolve(t *testing.T) {
+	db, err := airports.Default()
+	require.NoError(t, err)
+	require.Greater(t, db.Len(), 0)
+
+	tests := []struct {
+		name     string
+		wantCode string
+	}{
+		{"NYC", "NYC"},
+		{"New York", "NYC"},
+		{"new york city", "NYC"},
+		{"Nueva York", "NYC"},
+		{"JFK", "NYC"},
+		{"Newark Liberty International", "NYC"},
+		{"New York (JFK)", "NYC"},
+		{"London", "LON"},
+		{"Londres", "LON"},
+		{"LHR", "LON"},
+		{"Bogota", "BOG"},
+		{"Bogotá, Colombia", "BOG"},
+		{"Cucuta", "CUC"},
+		{"Washington D.C.", "WAS"},
+		{"Ciudad de México", "MEX"},
+		{"São Paulo", "SAO"},
+		{"Sao Paulo", "SAO"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			city, ok := db.Resolve(tt.name)
+			require.True(t, ok)
+			assert.Equal(t, tt.wantCode, city.Code)
+		})
+	}
+
+	_, ok := db.Resolve("Atlantis")
+	assert.False(t, ok)
+
+	city, ok := db.Resolve("NYC")
+	require.True(t, ok)
+	assert.ElementsMatch(t, []string{"JFK", "LGA", "EWR"}, city.Codes())
+	assert.Equal(t, "America/New_York", city.Location().String())
+
+	airport, city, ok := db.Airport("lgw")
+	require.True(t, ok)
+	assert.Equal(t, "Gatwick", airport.Name)
+	assert.Equal(t, "London", city.Name)
+
+	same, known := db.SameCity("NYC", "Nueva York")
+	assert.True(t, known)
+	assert.True(t, same)
+	_, known = db.SameCity("NYC", "Atlantis")
+	assert.False(t, known)
+}
+
+func TestParseAirportDatabase(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{
+			name: "Valid city",
+			data: `[{"code": "BOG", "name": "Bogotá", "time_zone": "America/Bogota",
+				"airports": [{"iata": "BOG", "name": "El Dorado"}]}]`,
+		},
+		{
+			name:    "City without airports",
+			data:    `[{"code": "BOG", "name": "Bogotá", "time_zone": "America/Bogota", "airports": []}]`,
+			wantErr: true,
+		},
+		{
+			name: "Invalid time zone",
+			data: `[{"code": "BOG", "name": "Bogotá", "time_zone": "Mars/Olympus",
+				"airports": [{"iata": "BOG", "name": "El Dorado"}]}]`,
+			wantErr: true,
+		},
+		{
+			name: "Alias shared by two cities",
+			data: `[{"code": "POR", "name": "Portland", "time_zone": "America/Los_Angeles",
+				"airports": [{"iata": "PDX", "name": "Portland International"}]},
+				{"code": "PWM", "name": "Portland", "time_zone": "America/New_York",
+				"airports": [{"iata": "PWM", "name": "Portland International Jetport"}]}]`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := airports.Parse([]byte(tt.data))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestBookingService_ChecksFlightCities(t *testing.T) {
+	departure := time.Now().Add(48 * time.Hour)
+
+	flight := func(number, from, to string) models.Flight {
+		return models.Flight{
+			Airline:        "British Airways",
+			FlightNumber:   number,
+			AvailableSeats: 9,
+			DepartureCity:  from,
+			ArrivalCity:    to,
+			DepartureTime:  departure,
+			ArrivalTime:    departure.Add(7 * time.Hour),
+			Price:          models.NewMoney(800, "USD"),
+		}
+	}
+
+	tests := []struct {
+		name        string
+		flights     []models.Flight
+		wantFlight  string
+		wantWarning string
+		wantErr     bool
+	}{
+		{
+			name:       "Aliases and airport codes match the request",
+			flights:    []models.Flight{flight("BA112", "JFK", "London Heathrow (LHR)")},
+			wantFlight: "BA112",
+		},
+		{
+			name:       "Flights to another city are rejected",
+			flights:    []models.Flight{flight("BA304", "New York", "Paris"), flight("BA178", "Nueva York", "Londres")},
+			wantFlight: "BA178",
+		},
+		{
+			name:        "Unknown cities are flagged, not rejected",
+			flights:     []models.Flight{flight("BA999", "Gotham", "London")},
+			wantFlight:  "BA999",
+			wantWarning: "route not checked, Gotham not a known city",
+		},
+		{
+			name:    "No flight serves the route",
+			flights: []models.Flight{flight("BA304", "Boston", "London")},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockExtractor := new(MockTravelParameterExtractor)
+			mockRecommender := new(MockFlightRecommender)
+
+			mockExtractor.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(&models.TravelParameters{
+					TripType:      models.TripOneWay,
+					DepartureCity: "NYC",
+					Destination:   "London",
+					DepartureDate: &departure,
+				}, nil)
+			mockRecommender.On("ProcessRequest", mock.Anything, mock.Anything,
+				mock.MatchedBy(func(req models.FlightRecommendationRequest) bool {
+					return req.DepartureCity == "New York" &&
+						assert.ObjectsAreEqual([]string{"JFK", "LGA", "EWR"}, req.DepartureAirports) &&
+						len(req.DestinationAirports) == 5
+				}), mock.Anything).
+				Return(&models.FlightRecommendation{Recommendations: tt.flights, Reasoning: "test"}, nil)
+
+			svc := service.NewBookingService(mockExtractor, mockRecommender)
+			response, err := svc.ProcessBooking(context.Background(), models.BookingRequest{
+				Query:    "One-way from NYC to London",
+				Deadline: time.Now().Add(24 * time.Hour),
+			})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantFlight, response.FlightDetails.FlightNumber)
+			if tt.wantWarning != "" {
+				assert.Contains(t, response.FlightDetails.Warnings, tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestBookingService_UnknownCityIsAmbiguous(t *testing.T) {
+	departure := time.Now().Add(48 * time.Hour)
+	mockExtractor := new(MockTravelParameterExtractor)
+	mockExtractor.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(&models.TravelParameters{
+			TripType:      models.TripOneWay,
+			DepartureCity: "Nueva York",
+			Destination:   "Gotham",
+			DepartureDate: &departure,
+		}, nil)
+
+	conversations := service.NewConversationService(service.NewBookingService(mockExtractor, nil), store.NewMemoryConversationStore())
+	conversation, err := conversations.StartConversation(context.Background(), models.BookingRequest{
+		Query:    "One-way from Nueva York to Gotham",
+		Deadline: time.Now().Add(24 * time.Hour),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "New York", conversation.Parameters.DepartureCity)
+	assert.Equal(t, "Gotham", conversation.Parameters.Destination)
+	assert.Equal(t, models.ConversationAwaitingInput, conversation.Status)
+	assert.Equal(t, []string{"destination"}, conversation.MissingFields)
+	assert.Contains(t, conversation.Messages[len(conversation.Messages)-1].Content, "is Gotham the right destination?")
+}
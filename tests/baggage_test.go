@@ -0,0 +1,243 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service"
+	"travel-agent/internal/service/ai"
+	"travel-agent/internal/service/flights"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractionDecodingStrategy_Baggage(t *testing.T) {
+	departure := time.Now().Add(48 * time.Hour).UTC().Format(time.RFC3339)
+
+	tests := []struct {
+		name    string
+		baggage string
+		want    models.Baggage
+	}{
+		{
+			name:    "Bags are counted for the party",
+			baggage: `{"checked_bags": 2, "cabin_bags": 1}`,
+			want:    models.Baggage{CheckedBags: 2, CabinBags: 1},
+		},
+		{
+			name:    "No bags mentioned",
+			baggage: `{}`,
+			want:    models.Baggage{},
+		},
+		{
+			name:    "Negative counts are dropped",
+			baggage: `{"checked_bags": -1, "cabin_bags": 2}`,
+			want:    models.Baggage{CabinBags: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := fmt.Sprintf(`{"trip_type": "one_way", "departure_city": "Madrid", "destination": "Lisbon",
+				"departure_date": %q, "preferences": {"baggage": %s}}`, departure, tt.baggage)
+
+			params, err := (&ai.ExtractionDecodingStrategy{}).DecodeResponse(content)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, params.Preferences.Baggage)
+		})
+	}
+}
+
+func TestFlightRecommendationDecoder_Conditions(t *testing.T) {
+	content := `{"recommendations": [{"airline": "TAP", "flight_number": "TP1023", "price": 120, "currency": "EUR",
+		"conditions": {"fare_family": "Discount", "checked_bags": 0, "cabin_bags": 1, "checked_bag_fee": 35,
+		"refundable": false, "changeable": true, "change_fee": 60, "seat_selection_fee": null}}],
+		"reasoning": "cheapest direct flight"}`
+
+	rec, err := (&ai.FlightRecommendationDecoder{}).DecodeResponse(content)
+	require.NoError(t, err)
+
+	conditions := rec.Recommendations[0].Conditions
+	require.NotNil(t, conditions)
+	assert.Equal(t, "Discount", conditions.FareFamily)
+	assert.Equal(t, 1, conditions.CabinBags)
+	fee := models.NewMoney(35, "EUR")
+	assert.Equal(t, &fee, conditions.CheckedBagFee, "fees are in the fare's currency")
+	change := models.NewMoney(60, "EUR")
+	assert.Equal(t, &change, conditions.ChangeFee)
+	assert.False(t, conditions.Refundable)
+	assert.Nil(t, conditions.SeatSelectionFee)
+
+	_, err = (&ai.FlightRecommendationDecoder{}).DecodeResponse(`{"recommendations": [{"airline": "TAP",
+		"flight_number": "TP1023", "price": 120, "conditions": {"checked_bags": -1}}], "reasoning": "x"}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid baggage allowance")
+}
+
+func TestFlightClient_FareConditions(t *testing.T) {
+	offers, err := newStubClient(t).Search(context.Background(), models.FlightSearchRequest{
+		Origin:        "JFK",
+		Destination:   "LHR",
+		DepartureDate: searchDate(),
+		Passengers:    models.Passengers{Adults: 1, Infants: 1},
+		TravelClass:   "economy",
+	})
+	require.NoError(t, err)
+
+	found := make(map[string]models.Flight)
+	for _, offer := range offers {
+		found[offer.FlightNumber] = offer
+	}
+
+	light := found["VS4"].Conditions
+	require.NotNil(t, light)
+	assert.Equal(t, "Economy Light", light.FareFamily)
+	assert.Equal(t, 0, light.CheckedBags)
+	assert.Equal(t, 1, light.CabinBags)
+	bagFee, seatFee := models.NewMoney(85, "USD"), models.NewMoney(35, "USD")
+	assert.Equal(t, &bagFee, light.CheckedBagFee)
+	assert.Equal(t, &seatFee, light.SeatSelectionFee)
+	assert.False(t, light.Refundable)
+	assert.False(t, light.Changeable)
+
+	standard := found["BA178"].Conditions
+	require.NotNil(t, standard)
+	assert.Equal(t, 1, standard.CheckedBags)
+	assert.True(t, standard.Changeable)
+	changeFee := models.NewMoney(100, "USD")
+	assert.Equal(t, &changeFee, standard.ChangeFee)
+}
+
+func TestFlightClient_ServiceFeesPreferTheFareCurrency(t *testing.T) {
+	date := searchDate().Format("2006-01-02")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"data": {"offers": [{"id": "off_1", "owner": {"name": "Test Air", "iata_code": "TA"},
+			"total_amount": "500.00", "total_currency": "USD",
+			"available_services": [
+				{"type": "baggage", "total_amount": "20.00", "total_currency": "GBP", "metadata": {"type": "checked"}},
+				{"type": "baggage", "total_amount": "60.00", "total_currency": "USD", "metadata": {"type": "checked"}},
+				{"type": "baggage", "total_amount": "45.00", "total_currency": "USD", "metadata": {"type": "checked"}},
+				{"type": "seat", "total_amount": "10.00", "total_currency": "EUR"},
+				{"type": "seat", "total_amount": "30.00", "total_currency": "USD"}
+			],
+			"slices": [{"fare_brand_name": "Basic", "segments": [{
+				"marketing_carrier": {"iata_code": "TA"}, "marketing_carrier_flight_number": "1",
+				"origin": {"iata_code": "JFK"}, "destination": {"iata_code": "LHR"},
+				"departing_at": "%[1]sT19:00:00Z", "arriving_at": "%[1]sT23:00:00Z"}]}]}]}}`, date)
+	}))
+	defer server.Close()
+
+	offers, err := flights.NewClient(server.URL, "", time.Second).Search(context.Background(), models.FlightSearchRequest{
+		Origin:        "JFK",
+		Destination:   "LHR",
+		DepartureDate: searchDate(),
+	})
+	require.NoError(t, err)
+	require.Len(t, offers, 1)
+
+	// Fees in another currency can't be compared with the fare's, whatever their order
+	conditions := offers[0].Conditions
+	require.NotNil(t, conditions)
+	bagFee, seatFee := models.NewMoney(45, "USD"), models.NewMoney(30, "USD")
+	assert.Equal(t, &bagFee, conditions.CheckedBagFee)
+	assert.Equal(t, &seatFee, conditions.SeatSelectionFee)
+}
+
+func TestBookingService_BaggageFees(t *testing.T) {
+	departure := time.Now().Add(72 * time.Hour).UTC().Truncate(24 * time.Hour).Add(9 * time.Hour)
+
+	fee := func(amount float64, currency string) *models.Money {
+		m := models.NewMoney(amount, currency)
+		return &m
+	}
+	flight := func(number string, price models.Money, conditions *models.FareConditions) models.Flight {
+		return models.Flight{
+			Airline:        "Iberia",
+			FlightNumber:   number,
+			DepartureCity:  "Madrid",
+			ArrivalCity:    "Lisbon",
+			DepartureTime:  departure,
+			ArrivalTime:    departure.Add(75 * time.Minute),
+			AvailableSeats: 9,
+			Price:          price,
+			Conditions:     conditions,
+		}
+	}
+	basic := flight("IB1", models.NewMoney(300, "USD"),
+		&models.FareConditions{FareFamily: "Basic", CabinBags: 1, CheckedBagFee: fee(70, "USD")})
+	standard := flight("IB2", models.NewMoney(380, "USD"),
+		&models.FareConditions{FareFamily: "Standard", CheckedBags: 1, CabinBags: 1, CheckedBagFee: fee(50, "USD")})
+	unknown := flight("IB3", models.NewMoney(450, "USD"), nil)
+	euros := flight("IB4", models.NewMoney(400, "EUR"),
+		&models.FareConditions{FareFamily: "Basic", CabinBags: 1, CheckedBagFee: fee(40, "EUR")})
+
+	book := func(t *testing.T, baggage models.Baggage, flights ...models.Flight) *models.Flight {
+		mockExtractor := new(MockTravelParameterExtractor)
+		mockRecommender := new(MockFlightRecommender)
+		params := &models.TravelParameters{
+			TripType:      models.TripOneWay,
+			DepartureCity: "Madrid",
+			Destination:   "Lisbon",
+			DepartureDate: &departure,
+		}
+		params.Preferences.Baggage = baggage
+		mockExtractor.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(params, nil)
+		mockRecommender.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(&models.FlightRecommendation{Recommendations: flights, Reasoning: "test"}, nil)
+
+		svc := service.NewBookingService(mockExtractor, mockRecommender,
+			service.WithRankingWeights(service.RankingWeights{Price: 1}))
+		response, err := svc.ProcessBooking(context.Background(), models.BookingRequest{
+			Query:    "Madrid to Lisbon",
+			Deadline: time.Now().Add(24 * time.Hour),
+		})
+		require.NoError(t, err)
+		return response.FlightDetails
+	}
+
+	t.Run("Extra bags make the basic fare dearer", func(t *testing.T) {
+		best := book(t, models.Baggage{CheckedBags: 2}, basic, standard, unknown)
+		assert.Equal(t, "IB2", best.FlightNumber)
+		require.NotNil(t, best.Fare)
+		assert.Equal(t, 1, best.Fare.ExtraBags)
+		assert.Equal(t, fee(50, "USD"), best.Fare.BaggageFees)
+		assert.Equal(t, models.NewMoney(430, "USD"), best.Fare.Total)
+		assert.Equal(t, "430.00 USD including 50.00 USD in bag fees, the cheapest", best.ScoreBreakdown[0].Detail)
+		assert.Equal(t, "Standard", best.Conditions.FareFamily)
+	})
+
+	t.Run("Without bags the cheapest fare wins", func(t *testing.T) {
+		best := book(t, models.Baggage{}, basic, standard, unknown)
+		assert.Equal(t, "IB1", best.FlightNumber)
+		assert.Nil(t, best.Fare.BaggageFees)
+		assert.Equal(t, models.NewMoney(300, "USD"), best.Fare.Total)
+		assert.Empty(t, best.Warnings)
+	})
+
+	t.Run("Bag fees are converted with the fare", func(t *testing.T) {
+		best := book(t, models.Baggage{CheckedBags: 2}, euros)
+		assert.Equal(t, models.NewMoney(480, "EUR"), best.Fare.Total)
+		require.NotNil(t, best.Converted)
+		assert.Equal(t, best.Fare.Total.Exchange(best.Converted.Rate, "USD"), best.Converted.Total)
+	})
+
+	t.Run("Flights without a known allowance are flagged", func(t *testing.T) {
+		best := book(t, models.Baggage{CheckedBags: 1}, unknown)
+		assert.Equal(t, []string{"baggage allowance unknown, bag fees not included"}, best.Warnings)
+	})
+
+	t.Run("Extra bags without a fee are flagged", func(t *testing.T) {
+		noFee := flight("IB5", models.NewMoney(250, "USD"), &models.FareConditions{FareFamily: "Basic"})
+		best := book(t, models.Baggage{CheckedBags: 1}, noFee)
+		assert.Equal(t, []string{"includes 0 checked bags per passenger, the fee for 1 more is unknown"}, best.Warnings)
+		assert.Equal(t, 1, best.Fare.ExtraBags)
+		assert.Equal(t, models.NewMoney(250, "USD"), best.Fare.Total)
+	})
+}
@@ -53,6 +53,103 @@ func (m *MockFlightRecommender) ProcessRequest(
 	return args.Get(0).(*models.FlightRecommendation), args.Error(1)
 }
 
+type MockHotelRecommender struct {
+	mock.Mock
+}
+
+func (m *MockHotelRecommender) ProcessRequest(
+	ctx context.Context,
+	strategy ai.PromptStrategy[models.HotelRecommendationRequest],
+	request models.HotelRecommendationRequest,
+	decoder ai.DecodingStrategy[models.HotelRecommendation],
+) (*models.HotelRecommendation, error) {
+	args := m.Called(ctx, strategy, request, decoder)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.HotelRecommendation), args.Error(1)
+}
+
+type MockSuggestionRecommender struct {
+	mock.Mock
+}
+
+func (m *MockSuggestionRecommender) ProcessRequest(
+	ctx context.Context,
+	strategy ai.PromptStrategy[models.SuggestionRequest],
+	request models.SuggestionRequest,
+	decoder ai.DecodingStrategy[models.SuggestionPlan],
+) (*models.SuggestionPlan, error) {
+	args := m.Called(ctx, strategy, request, decoder)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SuggestionPlan), args.Error(1)
+}
+
+type MockTripPlanner struct {
+	mock.Mock
+}
+
+func (m *MockTripPlanner) ProcessRequest(
+	ctx context.Context,
+	strategy ai.PromptStrategy[models.TripPlanRequest],
+	request models.TripPlanRequest,
+	decoder ai.DecodingStrategy[models.TripPlanDraft],
+) (*models.TripPlanDraft, error) {
+	args := m.Called(ctx, strategy, request, decoder)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TripPlanDraft), args.Error(1)
+}
+
+type MockGroundTransportProvider struct {
+	mock.Mock
+}
+
+func (m *MockGroundTransportProvider) Options(ctx context.Context, req models.GroundTransportRequest) ([]models.TransportOption, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.TransportOption), args.Error(1)
+}
+
+type MockInjectionClassifier struct {
+	mock.Mock
+}
+
+func (m *MockInjectionClassifier) ProcessRequest(
+	ctx context.Context,
+	strategy ai.PromptStrategy[models.BookingRequest],
+	request models.BookingRequest,
+	decoder ai.DecodingStrategy[models.InjectionAssessment],
+) (*models.InjectionAssessment, error) {
+	args := m.Called(ctx, strategy, request, decoder)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.InjectionAssessment), args.Error(1)
+}
+
+type MockIntentClassifier struct {
+	mock.Mock
+}
+
+func (m *MockIntentClassifier) ProcessRequest(
+	ctx context.Context,
+	strategy ai.PromptStrategy[models.BookingRequest],
+	request models.BookingRequest,
+	decoder ai.DecodingStrategy[models.IntentClassification],
+) (*models.IntentClassification, error) {
+	args := m.Called(ctx, strategy, request, decoder)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.IntentClassification), args.Error(1)
+}
+
 func TestBookingService_ProcessBooking(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -82,13 +179,14 @@ func TestBookingService_ProcessBooking(t *testing.T) {
 				flightRec := &models.FlightRecommendation{
 					Recommendations: []models.Flight{
 						{
-							Airline:       "British Airways",
-							FlightNumber:  "BA123",
-							Price:         800.0,
-							DepartureCity: "NYC",
-							ArrivalCity:   "London",
-							DepartureTime: time.Now(),
-							ArrivalTime:   time.Now().Add(7 * time.Hour),
+							Airline:        "British Airways",
+							FlightNumber:   "BA123",
+							AvailableSeats: 9,
+							Price:          models.NewMoney(800, "USD"),
+							DepartureCity:  "NYC",
+							ArrivalCity:    "London",
+							DepartureTime:  time.Now(),
+							ArrivalTime:    time.Now().Add(7 * time.Hour),
 						},
 					},
 				}
@@ -104,6 +202,47 @@ func TestBookingService_ProcessBooking(t *testing.T) {
 				assert.Equal(t, "London", response.FlightDetails.ArrivalCity)
 			},
 		},
+		{
+			name: "One-way booking",
+			request: models.BookingRequest{
+				Query:    "One-way from NYC to London tomorrow",
+				Deadline: time.Now().Add(24 * time.Hour),
+			},
+			setupMocks: func(extractor *MockTravelParameterExtractor, recommender *MockFlightRecommender) {
+				departure := time.Now().Add(24 * time.Hour)
+				extractor.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+					Return(&models.TravelParameters{
+						TripType:      models.TripOneWay,
+						DepartureCity: "NYC",
+						Destination:   "London",
+						DepartureDate: &departure,
+					}, nil)
+
+				recommender.On("ProcessRequest", mock.Anything, mock.Anything,
+					mock.MatchedBy(func(req models.FlightRecommendationRequest) bool {
+						return req.TripType == models.TripOneWay && req.ReturnDate == nil
+					}), mock.Anything).
+					Return(&models.FlightRecommendation{
+						Recommendations: []models.Flight{
+							{
+								Airline:        "British Airways",
+								FlightNumber:   "BA123",
+								AvailableSeats: 9,
+								Price:          models.NewMoney(800, "USD"),
+								DepartureCity:  "NYC",
+								ArrivalCity:    "London",
+								DepartureTime:  departure,
+								ArrivalTime:    departure.Add(7 * time.Hour),
+							},
+						},
+					}, nil)
+			},
+			expectedError: false,
+			validate: func(t *testing.T, response *models.BookingResponse) {
+				assert.Equal(t, models.TripOneWay, response.TripType)
+				assert.Equal(t, "Searching for one-way flights to London", response.Message)
+			},
+		},
 		{
 			name: "Empty query",
 			request: models.BookingRequest{
@@ -319,14 +458,14 @@ func TestBookingHandler_GetBooking(t *testing.T) {
 			Status: models.StatusProcessing,
 			Query:  "I want to fly from NYC to London next week",
 			FlightDetails: &models.Flight{
-				Airline:       "British Airways",
-				FlightNumber:  "BA123",
-				Price:         800.0,
-				Currency:      "USD",
-				DepartureCity: "NYC",
-				ArrivalCity:   "London",
-				DepartureTime: now.Add(24 * time.Hour),
-				ArrivalTime:   now.Add(31 * time.Hour),
+				Airline:        "British Airways",
+				FlightNumber:   "BA123",
+				AvailableSeats: 9,
+				Price:          models.NewMoney(800, "USD"),
+				DepartureCity:  "NYC",
+				ArrivalCity:    "London",
+				DepartureTime:  now.Add(24 * time.Hour),
+				ArrivalTime:    now.Add(31 * time.Hour),
 			},
 			Deadline:  now.Add(48 * time.Hour),
 			CreatedAt: now,
@@ -447,13 +586,14 @@ func TestBookingFlow(t *testing.T) {
 				).Return(&models.FlightRecommendation{
 					Recommendations: []models.Flight{
 						{
-							Airline:       "British Airways",
-							FlightNumber:  "BA123",
-							Price:         800.0,
-							DepartureCity: "NYC",
-							ArrivalCity:   "London",
-							DepartureTime: departureTime,
-							ArrivalTime:   departureTime.Add(7 * time.Hour),
+							Airline:        "British Airways",
+							FlightNumber:   "BA123",
+							AvailableSeats: 9,
+							Price:          models.NewMoney(800, "USD"),
+							DepartureCity:  "NYC",
+							ArrivalCity:    "London",
+							DepartureTime:  departureTime,
+							ArrivalTime:    departureTime.Add(7 * time.Hour),
 						},
 					},
 				}, nil)
@@ -0,0 +1,311 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"travel-agent/internal/handlers"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service"
+	"travel-agent/internal/store"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConversationFlow(t *testing.T) {
+	departureTime := time.Now().Add(24 * time.Hour)
+	returnTime := departureTime.Add(7 * 24 * time.Hour)
+
+	mockExtractor := new(MockTravelParameterExtractor)
+	mockRecommender := new(MockFlightRecommender)
+
+	// The first turn is missing the return date
+	mockExtractor.On("ProcessRequest", mock.Anything, mock.Anything,
+		mock.MatchedBy(func(req models.BookingRequest) bool {
+			return !strings.Contains(req.Query, "Agent:")
+		}), mock.Anything).
+		Return(&models.TravelParameters{
+			DepartureCity: "NYC",
+			Destination:   "London",
+			DepartureDate: &departureTime,
+		}, nil).Once()
+
+	// The answer fills it in
+	mockExtractor.On("ProcessRequest", mock.Anything, mock.Anything,
+		mock.MatchedBy(func(req models.BookingRequest) bool {
+			return strings.Contains(req.Query, "Traveler: A week later")
+		}), mock.Anything).
+		Return(&models.TravelParameters{
+			ReturnDate: &returnTime,
+		}, nil).Once()
+
+	mockRecommender.On("ProcessRequest", mock.Anything, mock.Anything,
+		mock.MatchedBy(func(req models.FlightRecommendationRequest) bool {
+			return req.DepartureCity == "New York" && req.ReturnDate != nil && req.ReturnDate.Equal(returnTime)
+		}), mock.Anything).
+		Return(&models.FlightRecommendation{
+			Recommendations: []models.Flight{
+				{
+					Airline:        "British Airways",
+					FlightNumber:   "BA123",
+					AvailableSeats: 9,
+					Price:          models.NewMoney(800, "USD"),
+					DepartureCity:  "NYC",
+					ArrivalCity:    "London",
+					DepartureTime:  departureTime,
+					ArrivalTime:    departureTime.Add(7 * time.Hour),
+				},
+			},
+		}, nil).Once()
+
+	bookingService := service.NewBookingService(mockExtractor, mockRecommender)
+	conversationService := service.NewConversationService(bookingService, store.NewMemoryConversationStore())
+	handler := handlers.NewConversationHandler(conversationService)
+
+	// Start the conversation
+	body, err := json.Marshal(models.BookingRequest{
+		Query:    "I want to fly from NYC to London tomorrow",
+		Deadline: time.Now().Add(48 * time.Hour),
+	})
+	require.NoError(t, err)
+
+	createResp := httptest.NewRecorder()
+	handler.CreateConversation(createResp, httptest.NewRequest(http.MethodPost, "/conversations", bytes.NewBuffer(body)))
+	require.Equal(t, http.StatusCreated, createResp.Code)
+
+	var conversation models.Conversation
+	require.NoError(t, json.Unmarshal(createResp.Body.Bytes(), &conversation))
+	assert.Equal(t, models.ConversationAwaitingInput, conversation.Status)
+	assert.Equal(t, []string{"return_date"}, conversation.MissingFields)
+	require.Len(t, conversation.Messages, 2)
+	assert.Equal(t, models.RoleAgent, conversation.Messages[1].Role)
+	assert.Contains(t, conversation.Messages[1].Content, "come back")
+
+	// Answer the follow-up question
+	body, err = json.Marshal(models.ConversationMessageRequest{Content: "A week later"})
+	require.NoError(t, err)
+
+	replyReq := httptest.NewRequest(http.MethodPost, "/conversations/"+conversation.ID+"/messages", bytes.NewBuffer(body))
+	replyReq.SetPathValue("id", conversation.ID)
+	replyResp := httptest.NewRecorder()
+	handler.AddMessage(replyResp, replyReq)
+	require.Equal(t, http.StatusOK, replyResp.Code)
+
+	var completed models.Conversation
+	require.NoError(t, json.Unmarshal(replyResp.Body.Bytes(), &completed))
+	assert.Equal(t, models.ConversationCompleted, completed.Status)
+	assert.Empty(t, completed.MissingFields)
+	require.NotNil(t, completed.Booking)
+	assert.Equal(t, "British Airways", completed.Booking.FlightDetails.Airline)
+	assert.Equal(t, "New York", completed.Parameters.DepartureCity, "city names are canonical")
+
+	// A completed conversation no longer accepts answers
+	closedReq := httptest.NewRequest(http.MethodPost, "/conversations/"+conversation.ID+"/messages", bytes.NewBuffer(body))
+	closedReq.SetPathValue("id", conversation.ID)
+	closedResp := httptest.NewRecorder()
+	handler.AddMessage(closedResp, closedReq)
+	assert.Equal(t, http.StatusConflict, closedResp.Code)
+
+	mockExtractor.AssertExpectations(t)
+	mockRecommender.AssertExpectations(t)
+}
+
+func TestConversationFlow_LaterTurnChangesDateAndBaggage(t *testing.T) {
+	firstDeparture := time.Now().Add(24 * time.Hour)
+	departureTime := firstDeparture.Add(24 * time.Hour)
+	returnTime := departureTime.Add(7 * 24 * time.Hour)
+
+	mockExtractor := new(MockTravelParameterExtractor)
+	mockRecommender := new(MockFlightRecommender)
+
+	mockExtractor.On("ProcessRequest", mock.Anything, mock.Anything,
+		mock.MatchedBy(func(req models.BookingRequest) bool {
+			return !strings.Contains(req.Query, "Agent:")
+		}), mock.Anything).
+		Return(&models.TravelParameters{
+			DepartureCity:     "NYC",
+			Destination:       "London",
+			DepartureDate:     &firstDeparture,
+			DepartureDateText: "tomorrow",
+			ResolvedDates: []models.DateResolution{
+				{Field: "departure_date", Text: "tomorrow", Resolved: firstDeparture, Source: models.DateSourceResolver},
+			},
+		}, nil).Once()
+
+	// The answer moves the departure and adds the bags and a budget for the whole party
+	answer := "Make it the day after tomorrow, back a week later. Two checked bags, under $3000 for all of us."
+	mockExtractor.On("ProcessRequest", mock.Anything, mock.Anything,
+		mock.MatchedBy(func(req models.BookingRequest) bool {
+			return strings.Contains(req.Query, "Traveler: "+answer)
+		}), mock.Anything).
+		Return(func() *models.TravelParameters {
+			params := &models.TravelParameters{
+				DepartureDate:     &departureTime,
+				DepartureDateText: "the day after tomorrow",
+				ReturnDate:        &returnTime,
+				ReturnDateText:    "a week later",
+				ResolvedDates: []models.DateResolution{
+					{Field: "departure_date", Text: "the day after tomorrow", Resolved: departureTime, Source: models.DateSourceResolver},
+					{Field: "return_date", Text: "a week later", Resolved: returnTime, Source: models.DateSourceResolver},
+				},
+			}
+			budget := models.NewMoney(3000, "USD")
+			params.Preferences.BudgetRange.Max = &budget
+			params.Preferences.BudgetRange.Scope = models.BudgetTotal
+			params.Preferences.Baggage = models.Baggage{CheckedBags: 2}
+			return params
+		}(), nil).Once()
+
+	mockRecommender.On("ProcessRequest", mock.Anything, mock.Anything,
+		mock.MatchedBy(func(req models.FlightRecommendationRequest) bool {
+			return req.DepartureDate.Equal(departureTime) && req.Baggage.CheckedBags == 2
+		}), mock.Anything).
+		Return(&models.FlightRecommendation{
+			Recommendations: []models.Flight{
+				{
+					Airline:        "British Airways",
+					FlightNumber:   "BA123",
+					AvailableSeats: 9,
+					Price:          models.NewMoney(800, "USD"),
+					DepartureCity:  "NYC",
+					ArrivalCity:    "London",
+					DepartureTime:  departureTime,
+					ArrivalTime:    departureTime.Add(7 * time.Hour),
+				},
+			},
+		}, nil).Once()
+
+	conversations := service.NewConversationService(
+		service.NewBookingService(mockExtractor, mockRecommender), store.NewMemoryConversationStore())
+	ctx := context.Background()
+
+	conversation, err := conversations.StartConversation(ctx, models.BookingRequest{
+		Query:    "I want to fly from NYC to London tomorrow",
+		Deadline: time.Now().Add(72 * time.Hour),
+	})
+	require.NoError(t, err)
+	require.Equal(t, models.ConversationAwaitingInput, conversation.Status)
+
+	completed, err := conversations.AddMessage(ctx, conversation.ID, answer)
+	require.NoError(t, err)
+	require.Equal(t, models.ConversationCompleted, completed.Status)
+
+	params := completed.Parameters
+	assert.Equal(t, "the day after tomorrow", params.DepartureDateText)
+	assert.Equal(t, "a week later", params.ReturnDateText)
+	assert.Equal(t, models.BudgetTotal, params.Preferences.BudgetRange.Scope)
+	assert.Equal(t, 2, params.Preferences.Baggage.CheckedBags)
+
+	require.NotNil(t, completed.Booking)
+	require.Len(t, completed.Booking.ResolvedDates, 2)
+	assert.True(t, completed.Booking.ResolvedDates[0].Resolved.Equal(departureTime), "resolved dates follow the new departure")
+	assert.Equal(t, "return_date", completed.Booking.ResolvedDates[1].Field)
+
+	mockExtractor.AssertExpectations(t)
+	mockRecommender.AssertExpectations(t)
+}
+
+func TestConversationHandler_GetConversation(t *testing.T) {
+	handler := handlers.NewConversationHandler(
+		service.NewConversationService(service.NewBookingService(nil, nil), store.NewMemoryConversationStore()),
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/conversations/unknown", nil)
+	req.SetPathValue("id", "unknown")
+	w := httptest.NewRecorder()
+	handler.GetConversation(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestConversationFlow_FailedBookingReturnsConversation(t *testing.T) {
+	departureTime := time.Now().Add(24 * time.Hour)
+	returnTime := departureTime.Add(7 * 24 * time.Hour)
+
+	mockExtractor := new(MockTravelParameterExtractor)
+	mockRecommender := new(MockFlightRecommender)
+	mockExtractor.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(&models.TravelParameters{
+			DepartureCity: "NYC",
+			Destination:   "London",
+			DepartureDate: &departureTime,
+			ReturnDate:    &returnTime,
+		}, nil)
+	mockRecommender.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil, assert.AnError)
+
+	conversations := service.NewConversationService(service.NewBookingService(mockExtractor, mockRecommender), store.NewMemoryConversationStore())
+	handler := handlers.NewConversationHandler(conversations)
+
+	body, err := json.Marshal(models.BookingRequest{
+		Query:    "NYC to London tomorrow, back a week later",
+		Deadline: time.Now().Add(48 * time.Hour),
+	})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	handler.CreateConversation(w, httptest.NewRequest(http.MethodPost, "/conversations", bytes.NewBuffer(body)))
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var conversation models.Conversation
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &conversation))
+	require.NotEmpty(t, conversation.ID)
+	assert.Equal(t, models.ConversationFailed, conversation.Status)
+	assert.Contains(t, conversation.Messages[len(conversation.Messages)-1].Content, "couldn't find flights")
+
+	stored, err := conversations.GetConversation(context.Background(), conversation.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ConversationFailed, stored.Status)
+}
+
+func TestConversationService_ConcurrentMessagesAreAllKept(t *testing.T) {
+	departureTime := time.Now().Add(24 * time.Hour)
+
+	// Every turn still lacks the return date, so the conversation keeps asking
+	mockExtractor := new(MockTravelParameterExtractor)
+	mockExtractor.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Run(func(mock.Arguments) { time.Sleep(2 * time.Millisecond) }).
+		Return(&models.TravelParameters{
+			DepartureCity: "NYC",
+			Destination:   "London",
+			DepartureDate: &departureTime,
+		}, nil)
+
+	conversations := service.NewConversationService(service.NewBookingService(mockExtractor, nil), store.NewMemoryConversationStore())
+	ctx := context.Background()
+	conversation, err := conversations.StartConversation(ctx, models.BookingRequest{
+		Query:    "NYC to London tomorrow",
+		Deadline: time.Now().Add(48 * time.Hour),
+	})
+	require.NoError(t, err)
+
+	const answers = 8
+	var wg sync.WaitGroup
+	for i := range answers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := conversations.AddMessage(ctx, conversation.ID, fmt.Sprintf("answer %d", i))
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	stored, err := conversations.GetConversation(ctx, conversation.ID)
+	require.NoError(t, err)
+	var travelerMessages int
+	for _, message := range stored.Messages {
+		if message.Role == models.RoleTraveler {
+			travelerMessages++
+		}
+	}
+	assert.Equal(t, answers+1, travelerMessages)
+}
@@ -0,0 +1,209 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service"
+	"travel-agent/internal/service/currency"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCurrencyTable(t *testing.T) {
+	table, err := currency.ParseTable([]byte(`{"base": "usd", "rates": {"USD": 1, "EUR": 0.5, "gbp": 0.25}}`))
+	require.NoError(t, err)
+
+	rate, err := table.Rate(context.Background(), "EUR", "GBP")
+	require.NoError(t, err)
+	assert.InDelta(t, 0.5, rate, 1e-9)
+
+	rate, err = table.Rate(context.Background(), "xyz", "XYZ")
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, rate)
+
+	_, err = table.Rate(context.Background(), "USD", "XYZ")
+	assert.True(t, errors.Is(err, currency.ErrUnknownCurrency))
+
+	_, err = currency.ParseTable([]byte(`{"base": "USD", "rates": {"USD": 2}}`))
+	assert.Error(t, err)
+
+	bundled, err := currency.DefaultTable()
+	require.NoError(t, err)
+	for _, code := range []string{"USD", "EUR", "GBP", "COP", "MXN", "JPY"} {
+		_, err := bundled.Rate(context.Background(), code, "USD")
+		assert.NoError(t, err, code)
+	}
+}
+
+func TestDetectCurrency(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"Flights to Madrid under 400 euros", "EUR"},
+		{"Budget of €500 for Paris", "EUR"},
+		{"Máximo 2.000.000 pesos colombianos", "COP"},
+		{"London trip, £300 max", "GBP"},
+		{"Up to 800 USD", "USD"},
+		{"Tokyo for 90000 yen", "JPY"},
+		{"Under $500", ""},
+		{"Two bags of 50 pounds each", ""},
+		{"Bring a pen", ""},
+		{"Between 300 euros and 300 USD", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, ok := currency.Detect(tt.query)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.want != "", ok)
+		})
+	}
+}
+
+func TestBookingService_Currency(t *testing.T) {
+	departure := time.Now().Add(48 * time.Hour)
+	moneyPtr := func(v float64, code string) *models.Money {
+		m := models.NewMoney(v, code)
+		return &m
+	}
+
+	rates, err := currency.ParseTable([]byte(`{"base": "USD", "rates": {"USD": 1, "EUR": 0.5, "GBP": 0.25}}`))
+	require.NoError(t, err)
+
+	flight := func(number string, price float64, code string) models.Flight {
+		return models.Flight{
+			Airline:        "Iberia",
+			FlightNumber:   number,
+			AvailableSeats: 9,
+			DepartureCity:  "New York",
+			ArrivalCity:    "Madrid",
+			DepartureTime:  departure,
+			ArrivalTime:    departure.Add(8 * time.Hour),
+			Price:          models.NewMoney(price, code),
+		}
+	}
+	budget := func(max float64, code string) models.Preferences {
+		var p models.Preferences
+		p.BudgetRange.Max = moneyPtr(max, code)
+		return p
+	}
+
+	tests := []struct {
+		name          string
+		query         string
+		preferences   models.Preferences
+		profile       *models.TravelerProfile
+		flights       []models.Flight
+		matchReq      func(req models.FlightRecommendationRequest) bool
+		wantCurrency  string
+		wantFlight    string
+		wantConverted *models.ConvertedPrice
+		wantRejection string
+	}{
+		{
+			name:        "Budget in euros is compared with converted fares",
+			query:       "New York to Madrid under 400 euros",
+			preferences: budget(400, "EUR"),
+			flights:     []models.Flight{flight("IB901", 900, "USD"), flight("IB700", 700, "USD")},
+			matchReq: func(req models.FlightRecommendationRequest) bool {
+				return req.Currency == "EUR" && req.MaxBudget == models.NewMoney(400, "EUR")
+			},
+			wantCurrency: "EUR",
+			wantFlight:   "IB700",
+			wantConverted: &models.ConvertedPrice{
+				Price: models.NewMoney(350, "EUR"),
+				Total: models.NewMoney(350, "EUR"),
+				Rate:  0.5,
+			},
+			wantRejection: "costs 450.00 EUR",
+		},
+		{
+			name:    "Profile currency and budget",
+			query:   "New York to Madrid",
+			profile: &models.TravelerProfile{Currency: "GBP", MaxBudget: moneyPtr(100, "")},
+			flights: []models.Flight{flight("IB180", 180, "EUR")},
+			matchReq: func(req models.FlightRecommendationRequest) bool {
+				return req.Currency == "GBP" && req.MaxBudget == models.NewMoney(100, "GBP")
+			},
+			wantCurrency: "GBP",
+			wantFlight:   "IB180",
+			wantConverted: &models.ConvertedPrice{
+				Price: models.NewMoney(90, "GBP"),
+				Total: models.NewMoney(90, "GBP"),
+				Rate:  0.5,
+			},
+		},
+		{
+			name:    "Default budget is converted into the traveler's currency",
+			query:   "New York to Madrid",
+			profile: &models.TravelerProfile{Currency: "EUR"},
+			flights: []models.Flight{flight("IB300", 300, "EUR")},
+			matchReq: func(req models.FlightRecommendationRequest) bool {
+				return req.Currency == "EUR" && req.MaxBudget == models.NewMoney(1000, "EUR")
+			},
+			wantCurrency: "EUR",
+			wantFlight:   "IB300",
+		},
+		{
+			name:         "Query currency wins over the profile",
+			query:        "New York to Madrid, 400 EUR max",
+			preferences:  budget(400, ""),
+			profile:      &models.TravelerProfile{Currency: "GBP"},
+			flights:      []models.Flight{flight("IB350", 350, "EUR")},
+			matchReq:     func(req models.FlightRecommendationRequest) bool { return req.Currency == "EUR" },
+			wantCurrency: "EUR",
+			wantFlight:   "IB350",
+		},
+		{
+			name:          "Fares in an unknown currency are rejected",
+			query:         "New York to Madrid",
+			flights:       []models.Flight{flight("IB999", 100, "XYZ"), flight("IB500", 500, "")},
+			matchReq:      func(req models.FlightRecommendationRequest) bool { return req.Currency == "USD" },
+			wantCurrency:  "USD",
+			wantFlight:    "IB500",
+			wantRejection: "can't be converted to USD",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockExtractor := new(MockTravelParameterExtractor)
+			mockRecommender := new(MockFlightRecommender)
+
+			mockExtractor.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(&models.TravelParameters{
+					TripType:      models.TripOneWay,
+					DepartureCity: "New York",
+					Destination:   "Madrid",
+					DepartureDate: &departure,
+					Preferences:   tt.preferences,
+				}, nil)
+			mockRecommender.On("ProcessRequest", mock.Anything, mock.Anything, mock.MatchedBy(tt.matchReq), mock.Anything).
+				Return(&models.FlightRecommendation{Recommendations: tt.flights, Reasoning: "test"}, nil)
+
+			svc := service.NewBookingService(mockExtractor, mockRecommender, service.WithRateProvider(rates))
+			response, err := svc.ProcessBooking(context.Background(), models.BookingRequest{
+				Query:    tt.query,
+				Deadline: time.Now().Add(24 * time.Hour),
+				Profile:  tt.profile,
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantCurrency, response.Currency)
+			assert.Equal(t, tt.wantFlight, response.FlightDetails.FlightNumber)
+			assert.NotEmpty(t, response.FlightDetails.Price.Currency)
+			assert.Equal(t, tt.wantConverted, response.FlightDetails.Converted)
+			if tt.wantRejection != "" {
+				require.Len(t, response.RejectedFlights, 1)
+				assert.Contains(t, response.RejectedFlights[0].Reasons[0], tt.wantRejection)
+			}
+			mockRecommender.AssertExpectations(t)
+		})
+	}
+}
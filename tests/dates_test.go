@@ -0,0 +1,214 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service/ai"
+	"travel-agent/internal/service/dates"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolver_Resolve(t *testing.T) {
+	bogota, err := time.LoadLocation("America/Bogota")
+	require.NoError(t, err)
+
+	// Wednesday, March 5, 2025 at 10:00 in Bogotá
+	reference := time.Date(2025, time.March, 5, 10, 0, 0, 0, bogota)
+	resolver := dates.NewResolver(reference, bogota)
+
+	tests := []struct {
+		phrase string
+		want   string
+	}{
+		{"today", "2025-03-05"},
+		{"tomorrow", "2025-03-06"},
+		{"day after tomorrow", "2025-03-07"},
+		{"in 3 days", "2025-03-08"},
+		{"in two weeks", "2025-03-19"},
+		{"Friday", "2025-03-07"},
+		{"this Wednesday", "2025-03-05"},
+		{"Wednesday", "2025-03-12"},
+		{"next Friday", "2025-03-14"},
+		{"next week", "2025-03-10"},
+		{"next weekend", "2025-03-15"},
+		{"weekend", "2025-03-08"},
+		{"March 10th", "2025-03-10"},
+		{"10 March", "2025-03-10"},
+		{"the 20th", "2025-03-20"},
+		{"January 5", "2026-01-05"},
+		{"Feb 29 2028", "2028-02-29"},
+		{"2025-04-01", "2025-04-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.phrase, func(t *testing.T) {
+			got, ok := resolver.Resolve(tt.phrase)
+			require.True(t, ok)
+			assert.Equal(t, tt.want, got.Format("2006-01-02"))
+			assert.Equal(t, 12, got.Hour())
+			assert.Equal(t, bogota, got.Location())
+		})
+	}
+
+	for _, phrase := range []string{"", "sometime soon", "next month", "February 30", "the 32nd"} {
+		_, ok := resolver.Resolve(phrase)
+		assert.False(t, ok, phrase)
+	}
+}
+
+func TestResolver_ResolveFrom(t *testing.T) {
+	reference := time.Date(2025, time.March, 5, 10, 0, 0, 0, time.UTC)
+	resolver := dates.NewResolver(reference, time.UTC)
+	departure := time.Date(2025, time.March, 20, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		phrase string
+		want   string
+	}{
+		{"back on the 3rd", "2025-04-03"},
+		{"returning on the 25th", "2025-03-25"},
+		{"a week later", "2025-03-27"},
+		{"3-day trip", "2025-03-23"},
+		{"after 10 days", "2025-03-30"},
+		{"the Friday after", "2025-03-21"},
+		{"March 10", "2026-03-10"},
+		{"weekend", "2025-03-23"},
+		// Phrases relative to today ignore the anchor
+		{"tomorrow", "2025-03-06"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.phrase, func(t *testing.T) {
+			got, ok := resolver.ResolveFrom(tt.phrase, departure)
+			require.True(t, ok)
+			assert.Equal(t, tt.want, got.Format("2006-01-02"))
+		})
+	}
+}
+
+func TestResolver_TimeZone(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+
+	// Late on March 5 in UTC is already March 6 in Tokyo
+	reference := time.Date(2025, time.March, 5, 20, 0, 0, 0, time.UTC)
+
+	utc, ok := dates.NewResolver(reference, time.UTC).Resolve("tomorrow")
+	require.True(t, ok)
+	assert.Equal(t, "2025-03-06", utc.Format("2006-01-02"))
+
+	local, ok := dates.NewResolver(reference, tokyo).Resolve("tomorrow")
+	require.True(t, ok)
+	assert.Equal(t, "2025-03-07", local.Format("2006-01-02"))
+}
+
+func TestExtractionDecodingStrategy_ResolvesDates(t *testing.T) {
+	bogota, err := time.LoadLocation("America/Bogota")
+	require.NoError(t, err)
+
+	decoder := &ai.ExtractionDecodingStrategy{
+		ReferenceTime: time.Date(2025, time.March, 5, 10, 0, 0, 0, bogota),
+		Location:      bogota,
+	}
+
+	t.Run("Resolver overrides the model", func(t *testing.T) {
+		// The model picked this Friday, the traveler meant next week's
+		params, err := decoder.DecodeResponse(`{"trip_type": "round_trip", "departure_city": "Bogotá",
+			"destination": "Madrid", "departure_date": "2025-03-07T00:00:00Z", "return_date": "2025-03-03T00:00:00Z",
+			"departure_date_text": "next Friday", "return_date_text": "back on the 3rd"}`)
+		require.NoError(t, err)
+
+		assert.Equal(t, "2025-03-14", params.DepartureDate.Format("2006-01-02"))
+		assert.Equal(t, "2025-04-03", params.ReturnDate.Format("2006-01-02"))
+		require.Len(t, params.ResolvedDates, 2)
+		assert.Equal(t, ai.FieldDepartureDate, params.ResolvedDates[0].Field)
+		assert.Equal(t, models.DateSourceResolver, params.ResolvedDates[0].Source)
+		assert.Equal(t, "next Friday", params.ResolvedDates[0].Text)
+		assert.Equal(t, ai.FieldReturnDate, params.ResolvedDates[1].Field)
+		assert.Equal(t, models.DateSourceResolver, params.ResolvedDates[1].Source)
+	})
+
+	t.Run("Falls back to the model date", func(t *testing.T) {
+		params, err := decoder.DecodeResponse(`{"trip_type": "one_way", "departure_city": "Bogotá",
+			"destination": "Madrid", "departure_date": "2025-04-20T12:00:00-05:00", "return_date": null,
+			"departure_date_text": "Easter Sunday"}`)
+		require.NoError(t, err)
+
+		assert.Equal(t, "2025-04-20", params.DepartureDate.Format("2006-01-02"))
+		require.Len(t, params.ResolvedDates, 1)
+		assert.Equal(t, models.DateSourceModel, params.ResolvedDates[0].Source)
+	})
+
+	t.Run("Resolves each leg against the one before it", func(t *testing.T) {
+		params, err := decoder.DecodeResponse(`{"trip_type": "multi_city", "legs": [
+			{"origin": "Bogotá", "destination": "Madrid", "earliest_date": "2025-03-07T00:00:00Z",
+				"earliest_date_text": "next Friday"},
+			{"origin": "Madrid", "destination": "Rome", "earliest_date": "2025-03-08T00:00:00Z",
+				"earliest_date_text": "the Friday after"},
+			{"origin": "Rome", "destination": "Bogotá", "earliest_date": null,
+				"earliest_date_text": "a week later", "latest_date_text": "the Sunday after"}
+		]}`)
+		require.NoError(t, err)
+
+		require.Len(t, params.Legs, 3)
+		assert.Equal(t, "2025-03-14", params.Legs[0].EarliestDate.Format("2006-01-02"))
+		assert.Equal(t, "2025-03-21", params.Legs[1].EarliestDate.Format("2006-01-02"))
+		assert.Equal(t, "2025-03-28", params.Legs[2].EarliestDate.Format("2006-01-02"))
+		assert.Equal(t, "2025-03-30", params.Legs[2].LatestDate.Format("2006-01-02"))
+		assert.Equal(t, bogota, params.Legs[1].EarliestDate.Location())
+		assert.Equal(t, "2025-03-14", params.DepartureDate.Format("2006-01-02"))
+
+		require.Len(t, params.ResolvedDates, 4)
+		assert.Equal(t, ai.LegDateField(1, "earliest_date"), params.ResolvedDates[1].Field)
+		assert.Equal(t, "the Friday after", params.ResolvedDates[1].Text)
+		assert.Equal(t, models.DateSourceResolver, params.ResolvedDates[1].Source)
+		assert.Equal(t, "legs[2].latest_date", params.ResolvedDates[3].Field)
+	})
+
+	t.Run("Past dates are judged in the traveler's time zone", func(t *testing.T) {
+		// A midnight UTC date from the model keeps its calendar day in Bogotá
+		params, err := decoder.DecodeResponse(`{"trip_type": "one_way", "departure_city": "Bogotá",
+			"destination": "Madrid", "departure_date": "2025-03-05T00:00:00Z", "return_date": null}`)
+		require.NoError(t, err)
+		require.NotNil(t, params.DepartureDate)
+
+		_, err = decoder.DecodeResponse(`{"trip_type": "one_way", "departure_city": "Bogotá",
+			"destination": "Madrid", "departure_date": "2025-03-03T12:00:00Z", "return_date": null}`)
+		assert.Error(t, err)
+	})
+}
+
+func TestExtractionDecodingStrategy_WeekendNextMonthExample(t *testing.T) {
+	library, err := ai.DefaultExampleLibrary()
+	require.NoError(t, err)
+
+	query := "Weekend in Rome from Berlin next month, I'm vegetarian and want to see museums, between 200 and 400 euros"
+	var example *ai.ExtractionExample
+	for _, candidate := range library.Rank(query) {
+		if candidate.Name == "weekend-with-preferences" {
+			example = &candidate
+			break
+		}
+	}
+	require.NotNil(t, example)
+
+	// The model answers with the example's own output
+	output, err := json.Marshal(example.Output)
+	require.NoError(t, err)
+	decoder := &ai.ExtractionDecodingStrategy{ReferenceTime: example.Deadline, Location: time.UTC}
+	params, err := decoder.DecodeResponse(string(output))
+	require.NoError(t, err)
+
+	require.NotNil(t, params.DepartureDate)
+	require.NotNil(t, params.ReturnDate)
+	assert.False(t, params.ReturnDate.Before(*params.DepartureDate), "the return must not precede the departure")
+	assert.Equal(t, example.Output.DepartureDate.Format("2006-01-02"), params.DepartureDate.Format("2006-01-02"))
+	assert.Equal(t, example.Output.ReturnDate.Format("2006-01-02"), params.ReturnDate.Format("2006-01-02"))
+	require.Len(t, params.ResolvedDates, 2)
+	assert.Equal(t, models.DateSourceModel, params.ResolvedDates[0].Source, "next month is a span the model places")
+	assert.Equal(t, models.DateSourceResolver, params.ResolvedDates[1].Source)
+}
@@ -0,0 +1,140 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service/ai"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExampleLibrary(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantLen int
+		wantErr bool
+	}{
+		{
+			name:    "Valid examples",
+			data:    `[{"name": "one", "query": "Paris in May", "deadline": "2025-03-01T12:00:00Z", "output": {}}]`,
+			wantLen: 1,
+		},
+		{
+			name:    "Missing query",
+			data:    `[{"name": "one", "deadline": "2025-03-01T12:00:00Z", "output": {}}]`,
+			wantErr: true,
+		},
+		{
+			name:    "Invalid JSON",
+			data:    `{`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			library, err := ai.ParseExampleLibrary([]byte(tt.data))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantLen, library.Len())
+		})
+	}
+}
+
+func TestExampleLibrary_Rank(t *testing.T) {
+	library, err := ai.DefaultExampleLibrary()
+	require.NoError(t, err)
+	require.Greater(t, library.Len(), 0)
+
+	ranked := library.Rank("A long weekend in Porto leaving from Madrid")
+	require.NotEmpty(t, ranked)
+	assert.Equal(t, "long-weekend-relative-window", ranked[0].Name)
+
+	ranked = library.Rank("Leaving Lima on the 12th, back on the 3rd")
+	require.NotEmpty(t, ranked)
+	assert.Equal(t, "return-day-of-next-month", ranked[0].Name)
+}
+
+func TestExtractionPromptStrategy_GetExampleMessages(t *testing.T) {
+	library, err := ai.DefaultExampleLibrary()
+	require.NoError(t, err)
+
+	request := models.BookingRequest{
+		Query:    "Long weekend in Lisbon, back on the 3rd, budget under 500",
+		Deadline: time.Now().Add(24 * time.Hour),
+	}
+
+	t.Run("Examples are rendered as user/assistant pairs", func(t *testing.T) {
+		strategy := &ai.ExtractionPromptStrategy{Examples: library, MaxExamples: 2, ExampleTokenBudget: 10000}
+		messages := strategy.GetExampleMessages(request)
+		require.Len(t, messages, 4)
+		for i, msg := range messages {
+			if i%2 == 0 {
+				assert.Equal(t, "user", msg.Role)
+				continue
+			}
+			assert.Equal(t, "assistant", msg.Role)
+			var params models.TravelParameters
+			assert.NoError(t, json.Unmarshal([]byte(msg.Content), &params))
+		}
+	})
+
+	t.Run("Token budget caps the examples", func(t *testing.T) {
+		strategy := &ai.ExtractionPromptStrategy{Examples: library, MaxExamples: 5, ExampleTokenBudget: 10}
+		assert.Empty(t, strategy.GetExampleMessages(request))
+	})
+
+	t.Run("No library means no examples", func(t *testing.T) {
+		strategy := &ai.ExtractionPromptStrategy{}
+		assert.Empty(t, strategy.GetExampleMessages(request))
+	})
+}
+
+func TestProcessRequestSendsExamples(t *testing.T) {
+	library, err := ai.ParseExampleLibrary([]byte(`[{
+		"name": "weekend",
+		"query": "Weekend in Rome",
+		"deadline": "2025-03-01T12:00:00Z",
+		"tags": ["weekend"],
+		"output": {"departure_city": "Berlin", "destination": "Rome"}
+	}]`))
+	require.NoError(t, err)
+
+	var received ai.AIProviderRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	originalEndpoint := ai.AIProviderEndpoint
+	ai.AIProviderEndpoint = server.URL
+	defer func() { ai.AIProviderEndpoint = originalEndpoint }()
+
+	engine, err := ai.NewInferenceEngine[models.TravelParameters, models.BookingRequest]("test-key")
+	require.NoError(t, err)
+
+	strategy := &ai.ExtractionPromptStrategy{Examples: library}
+	request := models.BookingRequest{Query: "A weekend in Paris", Deadline: time.Now().Add(time.Hour)}
+	_, _ = engine.ProcessRequest(context.Background(), strategy, request, &ai.ExtractionDecodingStrategy{})
+
+	require.Len(t, received.Messages, 4)
+	assert.Equal(t, "system", received.Messages[0].Role)
+	assert.Equal(t, "user", received.Messages[1].Role)
+	assert.Contains(t, received.Messages[1].Content, "Weekend in Rome")
+	assert.Equal(t, "assistant", received.Messages[2].Role)
+	assert.Equal(t, "user", received.Messages[3].Role)
+	assert.Contains(t, received.Messages[3].Content, "A weekend in Paris")
+}
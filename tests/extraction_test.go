@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"fmt"
+	"testing"
+	"time"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service/ai"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractionDecodingStrategy_TripType(t *testing.T) {
+	departure := time.Now().Add(48 * time.Hour).UTC().Format(time.RFC3339)
+	returning := time.Now().Add(96 * time.Hour).UTC().Format(time.RFC3339)
+
+	tests := []struct {
+		name         string
+		content      string
+		wantTripType models.TripType
+		wantReturn   bool
+		wantErr      bool
+	}{
+		{
+			name: "One-way without return date",
+			content: fmt.Sprintf(`{"trip_type": "one_way", "departure_city": "Bogotá", "destination": "Madrid",
+				"departure_date": %q, "return_date": null}`, departure),
+			wantTripType: models.TripOneWay,
+		},
+		{
+			name: "One-way drops a stray return date",
+			content: fmt.Sprintf(`{"trip_type": "one_way", "departure_city": "Bogotá", "destination": "Madrid",
+				"departure_date": %q, "return_date": %q}`, departure, returning),
+			wantTripType: models.TripOneWay,
+		},
+		{
+			name: "Return date implies round trip",
+			content: fmt.Sprintf(`{"departure_city": "Bogotá", "destination": "Madrid",
+				"departure_date": %q, "return_date": %q}`, departure, returning),
+			wantTripType: models.TripRoundTrip,
+			wantReturn:   true,
+		},
+		{
+			name: "Open jaw back to the origin is a round trip",
+			content: fmt.Sprintf(`{"trip_type": "open_jaw", "departure_city": "Bogotá", "destination": "Madrid",
+				"departure_date": %q, "return_date": %q}`, departure, returning),
+			wantTripType: models.TripRoundTrip,
+			wantReturn:   true,
+		},
+		{
+			name: "Open jaw from another city",
+			content: fmt.Sprintf(`{"trip_type": "open_jaw", "departure_city": "Bogotá", "destination": "Madrid",
+				"departure_date": %q, "return_date": %q, "return_origin": "Rome"}`, departure, returning),
+			wantTripType: models.TripOpenJaw,
+			wantReturn:   true,
+		},
+		{
+			name: "Unknown trip type still needs a return date",
+			content: fmt.Sprintf(`{"departure_city": "Bogotá", "destination": "Madrid",
+				"departure_date": %q, "return_date": null}`, departure),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := &ai.ExtractionDecodingStrategy{}
+			params, err := decoder.DecodeResponse(tt.content)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantTripType, params.TripType)
+			assert.Equal(t, tt.wantReturn, params.ReturnDate != nil)
+		})
+	}
+}
+
+func TestExtractionDecodingStrategy_AllowIncomplete(t *testing.T) {
+	past := time.Now().Add(-48 * time.Hour).UTC().Format(time.RFC3339)
+
+	decoder := &ai.ExtractionDecodingStrategy{AllowIncomplete: true}
+	params, err := decoder.DecodeResponse(fmt.Sprintf(`{"destination": "Madrid", "departure_date": %q}`, past))
+	require.NoError(t, err)
+
+	assert.Nil(t, params.DepartureDate, "past dates are cleared so they are asked for again")
+	assert.Equal(t,
+		[]string{ai.FieldDepartureCity, ai.FieldDepartureDate, ai.FieldReturnDate},
+		ai.MissingTravelParameters(params))
+}
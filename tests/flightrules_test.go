@@ -0,0 +1,263 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"travel-agent/internal/handlers"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBookingService_FlightSanityChecks(t *testing.T) {
+	departure := time.Now().Add(72 * time.Hour).UTC().Truncate(time.Hour)
+	maxBudget := models.NewMoney(1000, "USD")
+
+	valid := func(number string) models.Flight {
+		return models.Flight{
+			Airline:        "British Airways",
+			FlightNumber:   number,
+			AvailableSeats: 9,
+			DepartureCity:  "New York",
+			ArrivalCity:    "London",
+			DepartureTime:  departure,
+			ArrivalTime:    departure.Add(7 * time.Hour),
+			TotalDuration:  models.Duration(7 * time.Hour),
+			Price:          models.NewMoney(800, "USD"),
+		}
+	}
+	with := func(number string, change func(*models.Flight)) models.Flight {
+		f := valid(number)
+		change(&f)
+		return f
+	}
+
+	tests := []struct {
+		name       string
+		flight     models.Flight
+		wantReason string
+		wantWarn   string
+	}{
+		{
+			name:       "Arrival before departure",
+			flight:     with("BA101", func(f *models.Flight) { f.ArrivalTime = departure.Add(-time.Hour) }),
+			wantReason: "arrives before it departs",
+		},
+		{
+			name:       "Invalid flight number",
+			flight:     with("FLIGHT-1", func(f *models.Flight) {}),
+			wantReason: "is not valid",
+		},
+		{
+			name:       "Wrong destination",
+			flight:     with("BA103", func(f *models.Flight) { f.ArrivalCity = "Paris" }),
+			wantReason: "arrives in Paris, not London",
+		},
+		{
+			name: "Outside the requested dates",
+			flight: with("BA104", func(f *models.Flight) {
+				f.DepartureTime = departure.AddDate(0, 0, 3)
+				f.ArrivalTime = f.DepartureTime.Add(7 * time.Hour)
+			}),
+			wantReason: "outside the requested dates",
+		},
+		{
+			name:       "Over budget",
+			flight:     with("BA105", func(f *models.Flight) { f.Price = models.NewMoney(1500, "USD") }),
+			wantReason: "above the 1000.00 budget",
+		},
+		{
+			name:     "Duration doesn't match the timestamps",
+			flight:   with("BA106", func(f *models.Flight) { f.TotalDuration = models.Duration(9*time.Hour + 30*time.Minute) }),
+			wantWarn: "doesn't match",
+		},
+		{
+			name:   "Duration within tolerance",
+			flight: with("BA107", func(f *models.Flight) { f.TotalDuration = models.Duration(7*time.Hour + 20*time.Minute) }),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockExtractor := new(MockTravelParameterExtractor)
+			mockRecommender := new(MockFlightRecommender)
+
+			params := &models.TravelParameters{
+				TripType:      models.TripOneWay,
+				DepartureCity: "NYC",
+				Destination:   "London",
+				DepartureDate: &departure,
+			}
+			params.Preferences.BudgetRange.Max = &maxBudget
+			mockExtractor.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(params, nil)
+			// A valid alternative keeps the booking going so the rejection is reported
+			mockRecommender.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(&models.FlightRecommendation{
+					Recommendations: []models.Flight{tt.flight, valid("BA200")},
+					Reasoning:       "test",
+				}, nil)
+
+			svc := service.NewBookingService(mockExtractor, mockRecommender)
+			response, err := svc.ProcessBooking(context.Background(), models.BookingRequest{
+				Query:    "One-way from NYC to London",
+				Deadline: time.Now().Add(24 * time.Hour),
+			})
+			require.NoError(t, err)
+
+			if tt.wantReason == "" {
+				assert.Empty(t, response.RejectedFlights)
+				assert.Equal(t, tt.flight.FlightNumber, response.FlightDetails.FlightNumber)
+			} else {
+				require.Len(t, response.RejectedFlights, 1)
+				assert.Equal(t, tt.flight.FlightNumber, response.RejectedFlights[0].FlightNumber)
+				require.NotEmpty(t, response.RejectedFlights[0].Reasons)
+				assert.Contains(t, response.RejectedFlights[0].Reasons[0], tt.wantReason)
+				assert.Equal(t, "BA200", response.FlightDetails.FlightNumber)
+			}
+
+			if tt.wantWarn != "" {
+				require.Len(t, response.FlightDetails.Warnings, 1)
+				assert.Contains(t, response.FlightDetails.Warnings[0], tt.wantWarn)
+			}
+		})
+	}
+}
+
+func TestBookingService_AllFlightsRejected(t *testing.T) {
+	departure := time.Now().Add(72 * time.Hour)
+
+	mockExtractor := new(MockTravelParameterExtractor)
+	mockRecommender := new(MockFlightRecommender)
+
+	mockExtractor.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(&models.TravelParameters{
+			TripType:      models.TripOneWay,
+			DepartureCity: "NYC",
+			Destination:   "London",
+			DepartureDate: &departure,
+		}, nil)
+	mockRecommender.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(&models.FlightRecommendation{
+			Recommendations: []models.Flight{{
+				Airline:        "British Airways",
+				FlightNumber:   "BA300",
+				AvailableSeats: 9,
+				DepartureCity:  "NYC",
+				ArrivalCity:    "London",
+				DepartureTime:  departure,
+				ArrivalTime:    departure.Add(-2 * time.Hour),
+				Price:          models.NewMoney(800, "USD"),
+			}},
+			Reasoning: "test",
+		}, nil)
+
+	svc := service.NewBookingService(mockExtractor, mockRecommender)
+	_, err := svc.ProcessBooking(context.Background(), models.BookingRequest{
+		Query:    "One-way from NYC to London",
+		Deadline: time.Now().Add(24 * time.Hour),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "BA300 (arrives before it departs)")
+
+	var rejected *service.RejectedFlightsError
+	require.ErrorAs(t, err, &rejected)
+	require.Len(t, rejected.Rejected, 1)
+	assert.Equal(t, "BA300", rejected.Rejected[0].FlightNumber)
+
+	t.Run("The handler lists the rejected flights", func(t *testing.T) {
+		body, err := json.Marshal(models.BookingRequest{
+			Query:    "One-way from NYC to London",
+			Deadline: time.Now().Add(24 * time.Hour),
+		})
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		handlers.NewBookingHandler(svc).CreateBooking(w, httptest.NewRequest(http.MethodPost, "/api/v1/bookings", bytes.NewBuffer(body)))
+		require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+		var response struct {
+			Error           string                   `json:"error"`
+			RejectedFlights []models.FlightRejection `json:"rejected_flights"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Contains(t, response.Error, "every recommended flight was rejected")
+		require.Len(t, response.RejectedFlights, 1)
+		assert.Equal(t, []string{"arrives before it departs"}, response.RejectedFlights[0].Reasons)
+	})
+}
+
+func TestBookingService_DepartureWindowUsesLocalTime(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	// Requested for March 10 in New York; 19:00 there is already March 11 in UTC
+	requested := time.Date(2030, time.March, 10, 12, 0, 0, 0, newYork)
+	evening := time.Date(2030, time.March, 11, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		flight models.Flight
+	}{
+		{
+			name: "The departure city gives the time zone",
+			flight: models.Flight{
+				DepartureCity: "New York",
+			},
+		},
+		{
+			name: "Otherwise the first segment's offset does",
+			flight: models.Flight{
+				DepartureCity: "Gotham",
+				Segments: []models.Segment{{
+					FlightNumber:  "BA178",
+					Origin:        "GTM",
+					Destination:   "LHR",
+					DepartureTime: evening.In(time.FixedZone("", -5*60*60)),
+					ArrivalTime:   evening.Add(7 * time.Hour),
+				}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flight := tt.flight
+			flight.Airline = "British Airways"
+			flight.FlightNumber = "BA178"
+			flight.AvailableSeats = 9
+			flight.ArrivalCity = "London"
+			flight.DepartureTime = evening
+			flight.ArrivalTime = evening.Add(7 * time.Hour)
+			flight.Price = models.NewMoney(800, "USD")
+
+			mockExtractor := new(MockTravelParameterExtractor)
+			mockRecommender := new(MockFlightRecommender)
+			mockExtractor.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(&models.TravelParameters{
+					TripType:      models.TripOneWay,
+					DepartureCity: "New York",
+					Destination:   "London",
+					DepartureDate: &requested,
+				}, nil)
+			mockRecommender.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(&models.FlightRecommendation{Recommendations: []models.Flight{flight}, Reasoning: "test"}, nil)
+
+			svc := service.NewBookingService(mockExtractor, mockRecommender)
+			response, err := svc.ProcessBooking(context.Background(), models.BookingRequest{
+				Query:    "One-way from New York to London on March 10, leaving in the evening",
+				Deadline: time.Now().Add(24 * time.Hour),
+				TimeZone: "America/New_York",
+			})
+			require.NoError(t, err)
+			assert.Empty(t, response.RejectedFlights)
+			assert.Equal(t, "BA178", response.FlightDetails.FlightNumber)
+		})
+	}
+}
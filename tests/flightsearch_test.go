@@ -0,0 +1,371 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service"
+	"travel-agent/internal/service/airports"
+	"travel-agent/internal/service/flights"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newStubClient(t *testing.T) *flights.Client {
+	t.Helper()
+	db, err := airports.Default()
+	require.NoError(t, err)
+	inventory, err := flights.DefaultInventory(db)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(flights.NewStubServer(inventory))
+	t.Cleanup(server.Close)
+	return flights.NewClient(server.URL, "", 5*time.Second)
+}
+
+func searchDate() time.Time {
+	d := time.Now().AddDate(0, 0, 30)
+	return time.Date(d.Year(), d.Month(), d.Day(), 12, 0, 0, 0, time.UTC)
+}
+
+func TestFlightClient_SearchStub(t *testing.T) {
+	client := newStubClient(t)
+	direct := 0
+
+	offers, err := client.Search(context.Background(), models.FlightSearchRequest{
+		Origin:        "NYC",
+		Destination:   "London",
+		DepartureDate: searchDate(),
+		Passengers:    models.Passengers{Adults: 2, Children: 1},
+		TravelClass:   "economy",
+		MaxLayovers:   &direct,
+	})
+	require.NoError(t, err)
+	require.Len(t, offers, 2)
+
+	cheapest := offers[0]
+	assert.Equal(t, "VS4", cheapest.FlightNumber)
+	assert.Equal(t, "Virgin Atlantic", cheapest.Airline)
+	assert.True(t, strings.HasPrefix(cheapest.OfferID, "off_VS4_"))
+	assert.Equal(t, models.NewMoney(612.40, "USD"), cheapest.Price)
+	assert.Equal(t, "New York", cheapest.DepartureCity)
+	assert.Equal(t, "London", cheapest.ArrivalCity)
+	assert.Equal(t, 0, cheapest.LayoverCount)
+	assert.Equal(t, models.Duration(6*time.Hour+55*time.Minute), cheapest.TotalDuration)
+	assert.Equal(t, 6*time.Hour+55*time.Minute, cheapest.ArrivalTime.Sub(cheapest.DepartureTime))
+	assert.Equal(t, "BA178", offers[1].FlightNumber)
+
+	// Connections are allowed without a limit, and a big party leaves only roomy flights
+	offers, err = client.Search(context.Background(), models.FlightSearchRequest{
+		Origin:        "JFK",
+		Destination:   "LHR",
+		DepartureDate: searchDate(),
+		Passengers:    models.Passengers{Adults: 8, Infants: 1},
+		TravelClass:   "economy",
+	})
+	require.NoError(t, err)
+	require.Len(t, offers, 2)
+	assert.Equal(t, "EI106", offers[0].FlightNumber)
+	assert.Equal(t, 1, offers[0].LayoverCount)
+	assert.Equal(t, "BA178", offers[1].FlightNumber)
+
+	offers, err = client.Search(context.Background(), models.FlightSearchRequest{
+		Origin:        "Bogota",
+		Destination:   "Lisbon",
+		DepartureDate: searchDate(),
+		Passengers:    models.Passengers{Adults: 1},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, offers)
+}
+
+func TestFlightClient_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"errors": [{"title": "Unauthorized", "message": "The access token is invalid"}]}`))
+	}))
+	defer server.Close()
+
+	client := flights.NewClient(server.URL, "secret", time.Second)
+	_, err := client.Search(context.Background(), models.FlightSearchRequest{
+		Origin:        "JFK",
+		Destination:   "LHR",
+		DepartureDate: searchDate(),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "status 401")
+	assert.Contains(t, err.Error(), "The access token is invalid")
+}
+
+func TestBookingService_RanksSearchOffers(t *testing.T) {
+	departure := searchDate()
+	params := &models.TravelParameters{
+		TripType:      models.TripOneWay,
+		DepartureCity: "New York",
+		Destination:   "London",
+		DepartureDate: &departure,
+	}
+	request := models.BookingRequest{Query: "New York to London", Deadline: time.Now().Add(24 * time.Hour)}
+
+	t.Run("Offers are ranked without the model", func(t *testing.T) {
+		mockExtractor := new(MockTravelParameterExtractor)
+		mockRecommender := new(MockFlightRecommender)
+		mockExtractor.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(params, nil)
+
+		svc := service.NewBookingService(mockExtractor, mockRecommender, service.WithFlightSearch(newStubClient(t)))
+		response, err := svc.ProcessBooking(context.Background(), request)
+		require.NoError(t, err)
+
+		// The one-stop Aer Lingus fare is far cheaper than the direct flights
+		flight := response.FlightDetails
+		assert.True(t, strings.HasPrefix(flight.OfferID, "off_EI106_"))
+		assert.Equal(t, models.NewMoney(498.90, "USD"), flight.Price)
+		assert.Equal(t, 0.81, flight.RecommendationScore)
+		require.Len(t, flight.ScoreBreakdown, 4)
+		assert.Equal(t, "stops", flight.ScoreBreakdown[1].Factor)
+		assert.Equal(t, "1 stop in DUB (1h00m)", flight.ScoreBreakdown[1].Detail)
+		require.Len(t, flight.Segments, 2)
+		assert.Equal(t, 1, flight.LayoverCount)
+		assert.Equal(t, models.Duration(time.Hour), flight.Segments[1].Layover)
+		assert.Contains(t, response.Reasoning, "Ranked 3 flights by price 40%")
+		assert.Contains(t, response.Reasoning, "Aer Lingus EI106 scores 0.81")
+		mockRecommender.AssertNotCalled(t, "ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("The model only writes the narrative", func(t *testing.T) {
+		mockExtractor := new(MockTravelParameterExtractor)
+		mockRecommender := new(MockFlightRecommender)
+		mockExtractor.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(params, nil)
+
+		// Flights in the narrative response are ignored
+		mockRecommender.On("ProcessRequest", mock.Anything, mock.AnythingOfType("*ai.RankingNarrativeStrategy"),
+			mock.MatchedBy(func(req models.FlightRecommendationRequest) bool {
+				return len(req.Offers) == 3 && req.Offers[0].FlightNumber == "EI106"
+			}),
+			mock.Anything).
+			Return(&models.FlightRecommendation{
+				Recommendations: []models.Flight{{Airline: "Imaginary Air", FlightNumber: "ZZ1"}},
+				Reasoning:       "Aer Lingus saves you over 100 USD for one stop in Dublin.",
+			}, nil)
+
+		svc := service.NewBookingService(mockExtractor, mockRecommender,
+			service.WithFlightSearch(newStubClient(t)),
+			service.WithRankingNarrative(true),
+		)
+		response, err := svc.ProcessBooking(context.Background(), request)
+		require.NoError(t, err)
+
+		assert.Equal(t, "EI106", response.FlightDetails.FlightNumber)
+		assert.Equal(t, "Aer Lingus saves you over 100 USD for one stop in Dublin.", response.Reasoning)
+		assert.Empty(t, response.RejectedFlights)
+		mockRecommender.AssertExpectations(t)
+	})
+}
+
+func TestFlightClient_SearchesEachDateSeparately(t *testing.T) {
+	var dates []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Data struct {
+				Slices []struct {
+					DepartureDate string `json:"departure_date"`
+				} `json:"slices"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		require.Len(t, body.Data.Slices, 1, "a slice is a leg of the journey, not another date")
+		date := body.Data.Slices[0].DepartureDate
+		dates = append(dates, date)
+
+		// Without a per-passenger breakdown the total is for the whole party
+		total := map[int]string{0: "1800.00", 1: "1500.00", 2: "2100.00"}[len(dates)-1]
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"data": {"offers": [{"id": "off_%s", "owner": {"name": "Test Air", "iata_code": "TA"},
+			"total_amount": %q, "total_currency": "USD", "slices": [{"segments": [{
+				"marketing_carrier": {"iata_code": "TA"}, "marketing_carrier_flight_number": "1",
+				"origin": {"iata_code": "JFK"}, "destination": {"iata_code": "LHR"},
+				"departing_at": "%sT19:00:00Z", "arriving_at": "%sT23:00:00Z"}]}]}]}}`, date, total, date, date)
+	}))
+	defer server.Close()
+
+	departure := searchDate()
+	latest := departure.AddDate(0, 0, 2)
+	client := flights.NewClient(server.URL, "", time.Second)
+	offers, err := client.Search(context.Background(), models.FlightSearchRequest{
+		Origin:        "JFK",
+		Destination:   "LHR",
+		DepartureDate: departure,
+		LatestDate:    &latest,
+		Passengers:    models.Passengers{Adults: 2, Children: 1, Infants: 1},
+	})
+	require.NoError(t, err)
+	require.Len(t, dates, 3)
+	assert.Equal(t, departure.AddDate(0, 0, 1).Format("2006-01-02"), dates[1])
+
+	// Offers from every date are merged cheapest first, priced per seat
+	require.Len(t, offers, 3)
+	assert.Equal(t, "off_"+dates[1], offers[0].OfferID)
+	assert.Equal(t, models.NewMoney(500, "USD"), offers[0].Price)
+	assert.Equal(t, models.NewMoney(600, "USD"), offers[1].Price)
+	assert.Equal(t, models.NewMoney(700, "USD"), offers[2].Price)
+}
+
+func TestFlightClient_SkipsMalformedOffers(t *testing.T) {
+	date := searchDate().Format("2006-01-02")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"data": {"offers": [
+			{"id": "off_bad_type", "total_amount": 450},
+			{"id": "off_bad_time", "total_amount": "400.00", "total_currency": "USD", "slices": [{"segments": [{
+				"origin": {"iata_code": "JFK"}, "destination": {"iata_code": "LHR"},
+				"departing_at": "tomorrow evening", "arriving_at": "%[1]sT07:00:00"}]}]},
+			{"id": "off_good", "owner": {"name": "Test Air"}, "total_amount": "500.00", "total_currency": "USD",
+				"slices": [{"segments": [{
+					"marketing_carrier": {"iata_code": "TA"}, "marketing_carrier_flight_number": "1",
+					"origin": {"iata_code": "JFK", "time_zone": "America/New_York"},
+					"destination": {"iata_code": "LHR", "time_zone": "Europe/London"},
+					"departing_at": "%[1]sT19:00:00", "arriving_at": "%[1]sT23:59:00"}]}]}
+		]}}`, date)
+	}))
+	defer server.Close()
+
+	offers, err := flights.NewClient(server.URL, "", time.Second).Search(context.Background(), models.FlightSearchRequest{
+		Origin:        "JFK",
+		Destination:   "LHR",
+		DepartureDate: searchDate(),
+	})
+	require.NoError(t, err)
+	require.Len(t, offers, 1)
+	assert.Equal(t, "off_good", offers[0].OfferID)
+
+	// Segment times are local to their airports
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	departure, err := time.ParseInLocation("2006-01-02T15:04", date+"T19:00", newYork)
+	require.NoError(t, err)
+	assert.True(t, offers[0].DepartureTime.Equal(departure), "got %s", offers[0].DepartureTime)
+	assert.Equal(t, "Europe/London", offers[0].ArrivalTime.Location().String())
+	assert.Equal(t, "23:59", offers[0].ArrivalTime.Format("15:04"))
+}
+
+func TestFlightStub_SlicesAreOneJourney(t *testing.T) {
+	db, err := airports.Default()
+	require.NoError(t, err)
+	inventory, err := flights.DefaultInventory(db)
+	require.NoError(t, err)
+	server := httptest.NewServer(flights.NewStubServer(inventory))
+	defer server.Close()
+
+	out, back := searchDate(), searchDate().AddDate(0, 0, 3)
+	resp, err := http.Post(server.URL+"/air/offer_requests", "application/json", strings.NewReader(fmt.Sprintf(
+		`{"data": {"passengers": [{"type": "adult"}], "slices": [
+			{"origin": "MDE", "destination": "CUN", "departure_date": %q},
+			{"origin": "CUN", "destination": "MDE", "departure_date": %q}]}}`,
+		out.Format("2006-01-02"), back.Format("2006-01-02"))))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var body struct {
+		Data struct {
+			Offers []struct {
+				ID          string `json:"id"`
+				TotalAmount string `json:"total_amount"`
+				Slices      []struct {
+					Segments []struct {
+						Origin struct {
+							TimeZone string `json:"time_zone"`
+						} `json:"origin"`
+						Destination struct {
+							TimeZone string `json:"time_zone"`
+						} `json:"destination"`
+						DepartingAt string `json:"departing_at"`
+						ArrivingAt  string `json:"arriving_at"`
+					} `json:"segments"`
+				} `json:"slices"`
+			} `json:"offers"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	require.NotEmpty(t, body.Data.Offers)
+
+	// Times are local to each airport, without an offset, as in the Duffel API
+	localTime := func(value, zone string) time.Time {
+		location, err := time.LoadLocation(zone)
+		require.NoError(t, err)
+		parsed, err := time.ParseInLocation("2006-01-02T15:04:05", value, location)
+		require.NoError(t, err)
+		return parsed
+	}
+
+	found := false
+	for _, o := range body.Data.Offers {
+		require.Len(t, o.Slices, 2, o.ID)
+		outbound, inbound := o.Slices[0].Segments[0], o.Slices[1].Segments[0]
+		assert.True(t, localTime(inbound.DepartingAt, inbound.Origin.TimeZone).After(
+			localTime(outbound.ArrivingAt, outbound.Destination.TimeZone)), o.ID)
+		if o.ID == fmt.Sprintf("off_AV1410_%s_AV1411_%s_economy", out.Format("20060102"), back.Format("20060102")) {
+			found = true
+			assert.Equal(t, "655.00", o.TotalAmount, "both ways are priced together")
+		}
+	}
+	assert.True(t, found)
+}
+
+// fixedOffers is a search provider returning the same offers for every search
+type fixedOffers []models.Flight
+
+func (f fixedOffers) Search(context.Context, models.FlightSearchRequest) ([]models.Flight, error) {
+	return f, nil
+}
+
+func TestBookingService_RanksEveryOfferBeforeCapping(t *testing.T) {
+	departure := searchDate()
+	params := &models.TravelParameters{
+		TripType:      models.TripOneWay,
+		DepartureCity: "New York",
+		Destination:   "London",
+		DepartureDate: &departure,
+	}
+
+	// The provider lists the best offer last, past the number of flights kept
+	var offers fixedOffers
+	for i := 0; i < 30; i++ {
+		offers = append(offers, models.Flight{
+			Airline:        "Slow Air",
+			FlightNumber:   fmt.Sprintf("SA%d", i+1),
+			DepartureCity:  "New York",
+			ArrivalCity:    "London",
+			DepartureTime:  departure,
+			ArrivalTime:    departure.Add(12 * time.Hour),
+			LayoverCount:   2,
+			AvailableSeats: 9,
+			Price:          models.NewMoney(900, "USD"),
+		})
+	}
+	best := offers[0]
+	best.Airline, best.FlightNumber = "Fast Air", "FA1"
+	best.ArrivalTime, best.LayoverCount = departure.Add(7*time.Hour), 0
+	best.Price = models.NewMoney(400, "USD")
+	offers = append(offers, best)
+
+	mockExtractor := new(MockTravelParameterExtractor)
+	mockExtractor.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(params, nil)
+	svc := service.NewBookingService(mockExtractor, new(MockFlightRecommender), service.WithFlightSearch(offers))
+
+	response, err := svc.ProcessBooking(context.Background(), models.BookingRequest{
+		Query:    "New York to London",
+		Deadline: time.Now().Add(24 * time.Hour),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "FA1", response.FlightDetails.FlightNumber)
+}
@@ -0,0 +1,205 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service"
+	"travel-agent/internal/service/ai"
+	"travel-agent/internal/service/currency"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBookingService_HotelRecommendations(t *testing.T) {
+	departure := time.Now().Add(72 * time.Hour).UTC().Truncate(24 * time.Hour).Add(20 * time.Hour)
+	returning := departure.AddDate(0, 0, 5).Add(-8 * time.Hour)
+
+	flight := func(number, from, to string, departs time.Time) models.Flight {
+		return models.Flight{
+			Airline:        "Iberia",
+			FlightNumber:   number,
+			AvailableSeats: 9,
+			DepartureCity:  from,
+			ArrivalCity:    to,
+			DepartureTime:  departs,
+			ArrivalTime:    departs.Add(7 * time.Hour),
+			Price:          models.NewMoney(600, "USD"),
+		}
+	}
+
+	hotels := &models.HotelRecommendation{
+		Hotels: []models.Hotel{
+			{
+				Name:             "Hotel Urban",
+				Neighborhood:     "Sol",
+				NightlyRate:      models.NewMoney(180, "USD"),
+				NeighborhoodTags: []string{"museums", "restaurants"},
+			},
+			{
+				Name:             "Only You Boutique",
+				Neighborhood:     "Chueca",
+				NightlyRate:      models.NewMoney(150, "EUR"),
+				NeighborhoodTags: []string{"art galleries", "bars", "shopping"},
+			},
+			{
+				Name:         "Airport Inn",
+				Neighborhood: "Barajas",
+				NightlyRate:  models.NewMoney(90, "USD"),
+			},
+		},
+		Reasoning: "Chueca is lively at night and close to the Prado",
+	}
+
+	setup := func(tripType models.TripType, returnDate *time.Time) (*MockTravelParameterExtractor, *MockFlightRecommender) {
+		mockExtractor := new(MockTravelParameterExtractor)
+		mockRecommender := new(MockFlightRecommender)
+
+		params := &models.TravelParameters{
+			TripType:      tripType,
+			DepartureCity: "New York",
+			Destination:   "Madrid",
+			DepartureDate: &departure,
+			ReturnDate:    returnDate,
+			Passengers:    models.Passengers{Adults: 2, Children: 1},
+		}
+		params.Preferences.Activities = []string{"museums", "nightlife"}
+		params.Preferences.DietaryRestrictions = []string{"vegetarian"}
+		mockExtractor.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(params, nil)
+		mockRecommender.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(&models.FlightRecommendation{
+				Recommendations:       []models.Flight{flight("IB6252", "New York", "Madrid", departure)},
+				ReturnRecommendations: []models.Flight{flight("IB6251", "Madrid", "New York", returning)},
+				Reasoning:             "test",
+			}, nil)
+		return mockExtractor, mockRecommender
+	}
+
+	book := func(t *testing.T, svc *service.BookingService) *models.BookingResponse {
+		response, err := svc.ProcessBooking(context.Background(), models.BookingRequest{
+			Query:    "New York to Madrid with the kids",
+			Deadline: time.Now().Add(24 * time.Hour),
+		})
+		require.NoError(t, err)
+		return response
+	}
+
+	t.Run("Hotels are ranked by how well the neighborhood suits the activities", func(t *testing.T) {
+		mockExtractor, mockRecommender := setup(models.TripRoundTrip, &returning)
+		mockHotels := new(MockHotelRecommender)
+		mockHotels.On("ProcessRequest", mock.Anything, mock.AnythingOfType("*ai.HotelRecommendationStrategy"),
+			mock.Anything, mock.AnythingOfType("*ai.HotelRecommendationDecoder")).Return(hotels, nil)
+
+		svc := service.NewBookingService(mockExtractor, mockRecommender, service.WithHotelRecommender(mockHotels))
+		stay := book(t, svc).Hotels
+		require.NotNil(t, stay)
+
+		// The overnight flight lands the next day; the stay ends the day the return leaves
+		checkIn := departure.Truncate(24*time.Hour).AddDate(0, 0, 1)
+		assert.Equal(t, checkIn, stay.CheckIn)
+		assert.Equal(t, returning.Truncate(24*time.Hour), stay.CheckOut)
+		assert.Equal(t, 4, stay.Nights)
+		assert.Equal(t, 2, stay.Rooms)
+		assert.Equal(t, "Madrid", stay.City)
+		assert.Equal(t, hotels.Reasoning, stay.Reasoning)
+
+		require.Len(t, stay.Hotels, 3)
+		best := stay.Hotels[0]
+		assert.Equal(t, "Only You Boutique", best.Name)
+		assert.Equal(t, 1.0, best.NeighborhoodFit)
+		assert.Equal(t, "Chueca: suits museums, nightlife", best.FitDetail)
+		assert.Equal(t, models.NewMoney(1200, "EUR"), best.TotalPrice)
+		require.NotNil(t, best.Converted)
+		assert.Equal(t, "USD", best.Converted.Total.Currency)
+
+		assert.Equal(t, "Hotel Urban", stay.Hotels[1].Name)
+		assert.Equal(t, 0.5, stay.Hotels[1].NeighborhoodFit)
+		assert.Equal(t, "Sol: suits museums; not known for nightlife", stay.Hotels[1].FitDetail)
+		assert.Equal(t, models.NewMoney(1440, "USD"), stay.Hotels[1].TotalPrice)
+		assert.Zero(t, stay.Hotels[2].NeighborhoodFit)
+
+		mockHotels.AssertCalled(t, "ProcessRequest", mock.Anything, mock.Anything,
+			mock.MatchedBy(func(req models.HotelRecommendationRequest) bool {
+				return req.City == "Madrid" && req.Currency == "USD" && req.Rooms == 2 &&
+					assert.ObjectsAreEqual([]string{"museums", "nightlife"}, req.Activities) &&
+					assert.ObjectsAreEqual([]string{"vegetarian"}, req.DietaryRestrictions)
+			}), mock.Anything)
+	})
+
+	t.Run("Hotels priced in the traveler's currency come before the ones that can't be converted", func(t *testing.T) {
+		mockExtractor, mockRecommender := setup(models.TripRoundTrip, &returning)
+		hotel := func(name string, rate float64, code string) models.Hotel {
+			return models.Hotel{Name: name, Neighborhood: "Barajas", NightlyRate: models.NewMoney(rate, code)}
+		}
+		mockHotels := new(MockHotelRecommender)
+		mockHotels.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(&models.HotelRecommendation{
+				Hotels: []models.Hotel{
+					hotel("Yen Inn", 9000, "JPY"),
+					hotel("Dollar Lodge", 200, "USD"),
+					hotel("Franc Hostel", 100, "CHF"),
+					hotel("Budget Stay", 90, "USD"),
+					hotel("Euro Suites", 120, "EUR"),
+				},
+				Reasoning: "All near the airport",
+			}, nil)
+		rates, err := currency.ParseTable([]byte(`{"base": "USD", "rates": {"USD": 1, "EUR": 0.5}}`))
+		require.NoError(t, err)
+
+		svc := service.NewBookingService(mockExtractor, mockRecommender,
+			service.WithHotelRecommender(mockHotels), service.WithRateProvider(rates))
+		stay := book(t, svc).Hotels
+		require.NotNil(t, stay)
+
+		names := make([]string, len(stay.Hotels))
+		for i, h := range stay.Hotels {
+			names[i] = h.Name
+		}
+		assert.Equal(t, []string{"Budget Stay", "Dollar Lodge", "Euro Suites", "Franc Hostel", "Yen Inn"}, names)
+	})
+
+	t.Run("One-way trips have no stay", func(t *testing.T) {
+		mockExtractor, mockRecommender := setup(models.TripOneWay, nil)
+		mockHotels := new(MockHotelRecommender)
+
+		svc := service.NewBookingService(mockExtractor, mockRecommender, service.WithHotelRecommender(mockHotels))
+		assert.Nil(t, book(t, svc).Hotels)
+		mockHotels.AssertNotCalled(t, "ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("The booking goes ahead when hotels fail", func(t *testing.T) {
+		mockExtractor, mockRecommender := setup(models.TripRoundTrip, &returning)
+		mockHotels := new(MockHotelRecommender)
+		mockHotels.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(nil, errors.New("model unavailable"))
+
+		svc := service.NewBookingService(mockExtractor, mockRecommender, service.WithHotelRecommender(mockHotels))
+		response := book(t, svc)
+		assert.Nil(t, response.Hotels)
+		assert.NotNil(t, response.RoundTrip)
+	})
+}
+
+func TestHotelRecommendationDecoder(t *testing.T) {
+	decoder := &ai.HotelRecommendationDecoder{}
+
+	recommendation, err := decoder.DecodeResponse(`{
+		"hotels": [{"name": "Hotel Urban", "neighborhood": "Sol", "stars": 5, "nightly_rate": 180.5, "currency": "EUR", "neighborhood_tags": ["museums"]}],
+		"reasoning": "Central and close to the Prado"
+	}`)
+	require.NoError(t, err)
+	require.Len(t, recommendation.Hotels, 1)
+	assert.Equal(t, models.NewMoney(180.50, "EUR"), recommendation.Hotels[0].NightlyRate)
+	assert.Equal(t, []string{"museums"}, recommendation.Hotels[0].NeighborhoodTags)
+
+	_, err = decoder.DecodeResponse(`{"hotels": [{"name": "Hotel Urban", "nightly_rate": 0}], "reasoning": "x"}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid nightly rate for hotel 1")
+
+	_, err = decoder.DecodeResponse(`{"hotels": [], "reasoning": "x"}`)
+	assert.Error(t, err)
+}
@@ -0,0 +1,247 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"travel-agent/internal/handlers"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service"
+	"travel-agent/internal/service/ai"
+	"travel-agent/internal/store"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// intentCount reads the booking_intents counter for an intent
+func intentCount(intent models.Intent) int64 {
+	counts, ok := expvar.Get("booking_intents").(*expvar.Map)
+	if !ok {
+		return 0
+	}
+	count, ok := counts.Get(string(intent)).(*expvar.Int)
+	if !ok {
+		return 0
+	}
+	return count.Value()
+}
+
+func classifiedAs(intent models.Intent, bookingID string) *MockIntentClassifier {
+	classifier := new(MockIntentClassifier)
+	classifier.On("ProcessRequest", mock.Anything, mock.AnythingOfType("*ai.IntentClassificationStrategy"),
+		mock.Anything, mock.AnythingOfType("*ai.IntentClassificationDecoder")).
+		Return(&models.IntentClassification{Intent: intent, BookingID: bookingID, Confidence: 0.95}, nil)
+	return classifier
+}
+
+func TestBookingService_IntentRouting(t *testing.T) {
+	request := func(query string) models.BookingRequest {
+		return models.BookingRequest{Query: query, Deadline: time.Now().Add(24 * time.Hour)}
+	}
+	checkIn := time.Now().Add(72 * time.Hour)
+	checkOut := checkIn.Add(72 * time.Hour)
+
+	t.Run("Off-topic and change requests are declined without reaching the pipelines", func(t *testing.T) {
+		for _, tt := range []struct {
+			intent models.Intent
+			query  string
+		}{
+			{intent: models.IntentOffTopic, query: "Write me a poem about Go generics"},
+			{intent: models.IntentChangeOrCancel, query: "Cancel my flight to Rome please"},
+		} {
+			mockExtractor := new(MockTravelParameterExtractor)
+			mockRecommender := new(MockFlightRecommender)
+			svc := service.NewBookingService(mockExtractor, mockRecommender,
+				service.WithIntentClassifier(classifiedAs(tt.intent, "")))
+
+			before := intentCount(tt.intent)
+			response, err := svc.ProcessBooking(context.Background(), request(tt.query))
+			require.NoError(t, err)
+			assert.Equal(t, models.StatusDeclined, response.Status)
+			assert.Equal(t, tt.intent, response.Intent)
+			assert.NotEmpty(t, response.Message)
+			assert.Nil(t, response.FlightDetails)
+			assert.Equal(t, before+1, intentCount(tt.intent))
+			mockExtractor.AssertNotCalled(t, "ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			mockRecommender.AssertNotCalled(t, "ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("Hotel-only queries get hotels without flights", func(t *testing.T) {
+		mockExtractor := new(MockTravelParameterExtractor)
+		mockRecommender := new(MockFlightRecommender)
+		mockHotels := new(MockHotelRecommender)
+		mockExtractor.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything,
+			mock.MatchedBy(func(d ai.DecodingStrategy[models.TravelParameters]) bool {
+				return d.(*ai.ExtractionDecodingStrategy).AllowIncomplete
+			})).
+			Return(&models.TravelParameters{
+				Destination:   "Lisbon",
+				DepartureDate: &checkIn,
+				ReturnDate:    &checkOut,
+				Passengers:    models.Passengers{Adults: 2},
+			}, nil)
+		mockHotels.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(&models.HotelRecommendation{
+				Hotels: []models.Hotel{{Name: "Memmo Alfama", Neighborhood: "Alfama", NightlyRate: models.NewMoney(210, "USD")}},
+			}, nil)
+		bookings := store.NewMemoryBookingStore()
+		svc := service.NewBookingService(mockExtractor, mockRecommender,
+			service.WithIntentClassifier(classifiedAs(models.IntentHotelOnly, "")),
+			service.WithHotelRecommender(mockHotels),
+			service.WithBookingStore(bookings))
+
+		response, err := svc.ProcessBooking(context.Background(), request("We have flights, just need a hotel in Lisbon for 3 nights"))
+		require.NoError(t, err)
+		assert.Equal(t, models.IntentHotelOnly, response.Intent)
+		assert.Nil(t, response.FlightDetails)
+		require.NotNil(t, response.Hotels)
+		assert.Equal(t, 3, response.Hotels.Nights)
+		assert.Equal(t, 1, response.Hotels.Rooms)
+		assert.Equal(t, "Memmo Alfama", response.Hotels.Hotels[0].Name)
+		assert.Contains(t, response.Message, "Found hotels in Lisbon from ")
+		mockRecommender.AssertNotCalled(t, "ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+		record, err := bookings.Get(context.Background(), response.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Lisbon", record.Parameters.Destination)
+		assert.Equal(t, models.IntentHotelOnly, record.Booking.Intent)
+	})
+
+	t.Run("Hotel-only queries need where and when to stay", func(t *testing.T) {
+		mockExtractor := new(MockTravelParameterExtractor)
+		mockExtractor.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(&models.TravelParameters{Destination: "Lisbon", DepartureDate: &checkIn}, nil)
+		svc := service.NewBookingService(mockExtractor, new(MockFlightRecommender),
+			service.WithIntentClassifier(classifiedAs(models.IntentHotelOnly, "")),
+			service.WithHotelRecommender(new(MockHotelRecommender)))
+
+		_, err := svc.ProcessBooking(context.Background(), request("A hotel in Lisbon from Friday"))
+		assert.ErrorIs(t, err, service.ErrIncompleteStay)
+	})
+
+	t.Run("Status questions answer with the stored booking", func(t *testing.T) {
+		bookings := store.NewMemoryBookingStore()
+		stored := models.BookingRecord{Booking: models.BookingResponse{
+			ID:      "3f2b8c1e-9a4d-4e6f-8b7a-1c2d3e4f5a6b",
+			Status:  models.StatusProcessing,
+			Intent:  models.IntentFlightSearch,
+			Message: "Searching for one-way flights to Rome",
+		}}
+		require.NoError(t, bookings.Save(context.Background(), &stored))
+		mockExtractor := new(MockTravelParameterExtractor)
+
+		// The ID is picked out of the query when the classifier doesn't give it
+		svc := service.NewBookingService(mockExtractor, new(MockFlightRecommender),
+			service.WithIntentClassifier(classifiedAs(models.IntentBookingStatus, "")),
+			service.WithBookingStore(bookings))
+		response, err := svc.ProcessBooking(context.Background(), request("Any news on booking 3F2B8C1E-9A4D-4E6F-8B7A-1C2D3E4F5A6B?"))
+		require.NoError(t, err)
+		assert.Equal(t, stored.Booking.ID, response.ID)
+		assert.Equal(t, models.IntentBookingStatus, response.Intent)
+		assert.Equal(t, stored.Booking.Message, response.Message)
+		mockExtractor.AssertNotCalled(t, "ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+		record, err := bookings.Get(context.Background(), stored.Booking.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.IntentFlightSearch, record.Booking.Intent, "the stored booking is left as it was")
+
+		svc = service.NewBookingService(mockExtractor, new(MockFlightRecommender),
+			service.WithIntentClassifier(classifiedAs(models.IntentBookingStatus, "")),
+			service.WithBookingStore(bookings))
+		_, err = svc.ProcessBooking(context.Background(), request("Where is my booking?"))
+		assert.ErrorIs(t, err, service.ErrBookingNotFound)
+	})
+
+	t.Run("Unsure or failing classifiers leave the query to the flight search", func(t *testing.T) {
+		for _, result := range []struct {
+			classification *models.IntentClassification
+			err            error
+		}{
+			{classification: &models.IntentClassification{Intent: models.IntentOffTopic, Confidence: 0.4}},
+			{err: errors.New("classifier unavailable")},
+		} {
+			departure := time.Now().Add(72 * time.Hour)
+			mockExtractor := new(MockTravelParameterExtractor)
+			mockRecommender := new(MockFlightRecommender)
+			classifier := new(MockIntentClassifier)
+			classifier.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(result.classification, result.err)
+			mockExtractor.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(&models.TravelParameters{
+					TripType:      models.TripOneWay,
+					DepartureCity: "Paris",
+					Destination:   "Rome",
+					DepartureDate: &departure,
+				}, nil)
+			mockRecommender.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(&models.FlightRecommendation{
+					Recommendations: []models.Flight{{
+						Airline:        "ITA Airways",
+						FlightNumber:   "AZ317",
+						DepartureCity:  "Paris",
+						ArrivalCity:    "Rome",
+						DepartureTime:  departure,
+						ArrivalTime:    departure.Add(2 * time.Hour),
+						AvailableSeats: 9,
+						Price:          models.NewMoney(150, "USD"),
+					}},
+					Reasoning: "test",
+				}, nil)
+
+			svc := service.NewBookingService(mockExtractor, mockRecommender, service.WithIntentClassifier(classifier))
+			before := intentCount(models.IntentFlightSearch)
+			response, err := svc.ProcessBooking(context.Background(), request("Paris to Rome"))
+			require.NoError(t, err)
+			assert.Equal(t, models.IntentFlightSearch, response.Intent)
+			assert.Equal(t, "AZ317", response.FlightDetails.FlightNumber)
+			assert.Equal(t, before+1, intentCount(models.IntentFlightSearch))
+		}
+	})
+}
+
+func TestIntentClassificationDecoder(t *testing.T) {
+	classification, err := (&ai.IntentClassificationDecoder{}).DecodeResponse(`{"intent": "booking_status", "booking_id": " abc-123 ", "confidence": 0.9}`)
+	require.NoError(t, err)
+	assert.Equal(t, models.IntentBookingStatus, classification.Intent)
+	assert.Equal(t, "abc-123", classification.BookingID)
+
+	_, err = (&ai.IntentClassificationDecoder{}).DecodeResponse(`{"intent": "weather", "booking_id": "", "confidence": 0.9}`)
+	assert.ErrorContains(t, err, "unknown intent")
+	_, err = (&ai.IntentClassificationDecoder{}).DecodeResponse(`{"intent": "off_topic", "booking_id": "", "confidence": 1.5}`)
+	assert.Error(t, err)
+}
+
+func TestBookingHandler_IntentErrors(t *testing.T) {
+	tests := []struct {
+		err        error
+		wantStatus int
+	}{
+		{err: service.ErrBookingNotFound, wantStatus: http.StatusNotFound},
+		{err: service.ErrIncompleteStay, wantStatus: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			handler := handlers.NewBookingHandler(&MockBookingService{
+				processBookingFunc: func(ctx context.Context, req models.BookingRequest) (*models.BookingResponse, error) {
+					return nil, tt.err
+				},
+			})
+			body, err := json.Marshal(models.BookingRequest{Query: "Where is my booking?", Deadline: time.Now().Add(time.Hour)})
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			handler.CreateBooking(w, httptest.NewRequest(http.MethodPost, "/api/v1/bookings", bytes.NewReader(body)))
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
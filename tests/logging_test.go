@@ -0,0 +1,196 @@
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"travel-agent/internal/handlers"
+	"travel-agent/internal/logging"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// logLines decodes the JSON log lines written to buf
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var line map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// captureLogs sends the default logger's JSON lines at level and above to the returned
+// buffer until the test ends
+func captureLogs(t *testing.T, level string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, level, logging.FormatJSON)
+	require.NoError(t, err)
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+func TestLoggingNew(t *testing.T) {
+	_, err := logging.New(&bytes.Buffer{}, "verbose", logging.FormatText)
+	assert.ErrorContains(t, err, "unknown log level")
+	_, err = logging.New(&bytes.Buffer{}, "info", "xml")
+	assert.ErrorContains(t, err, "unknown log format")
+
+	t.Run("Text output", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger, err := logging.New(&buf, "INFO", "")
+		require.NoError(t, err)
+		logger.InfoContext(logging.WithRequestID(context.Background(), "req-1"), "hello")
+		assert.Contains(t, buf.String(), "msg=hello request_id=req-1")
+	})
+
+	t.Run("Levels below the configured one are dropped", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger, err := logging.New(&buf, "warn", logging.FormatJSON)
+		require.NoError(t, err)
+		logger.Info("dropped")
+		logger.Debug("dropped")
+		logger.Warn("kept")
+		lines := logLines(t, &buf)
+		require.Len(t, lines, 1)
+		assert.Equal(t, "kept", lines[0]["msg"])
+	})
+
+	t.Run("Context attributes are added and later stages replace earlier ones", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger, err := logging.New(&buf, "debug", logging.FormatJSON)
+		require.NoError(t, err)
+
+		ctx := logging.WithRequestID(context.Background(), "req-1")
+		ctx = logging.WithBookingID(ctx, "booking-1")
+		ctx = logging.WithStage(ctx, "extraction")
+		ctx = logging.WithStage(ctx, "flights")
+		logger.DebugContext(ctx, "step", "offers", 3)
+		assert.Equal(t, 1, strings.Count(buf.String(), `"stage"`))
+
+		lines := logLines(t, &buf)
+		require.Len(t, lines, 1)
+		assert.Equal(t, "req-1", lines[0]["request_id"])
+		assert.Equal(t, "booking-1", lines[0]["booking_id"])
+		assert.Equal(t, "flights", lines[0]["stage"])
+		assert.Equal(t, float64(3), lines[0]["offers"])
+		assert.Equal(t, "req-1", logging.RequestID(ctx))
+	})
+}
+
+func TestRequestLogging(t *testing.T) {
+	var seen string
+	handler := handlers.RequestLogging(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = logging.RequestID(r.Context())
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	tests := []struct {
+		name     string
+		incoming string
+		wantKept bool
+	}{
+		{name: "The caller's ID is propagated", incoming: "abc-123.4", wantKept: true},
+		{name: "A missing ID is generated"},
+		{name: "An ID that could forge log lines is replaced", incoming: "abc\nlevel=ERROR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := captureLogs(t, "info")
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/bookings/status", nil)
+			if tt.incoming != "" {
+				req.Header.Set(handlers.RequestIDHeader, tt.incoming)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			id := w.Header().Get(handlers.RequestIDHeader)
+			require.NotEmpty(t, id)
+			assert.Equal(t, id, seen)
+			if tt.wantKept {
+				assert.Equal(t, tt.incoming, id)
+			} else {
+				assert.NotEqual(t, tt.incoming, id)
+			}
+
+			lines := logLines(t, buf)
+			require.Len(t, lines, 1)
+			assert.Equal(t, "request handled", lines[0]["msg"])
+			assert.Equal(t, "WARN", lines[0]["level"])
+			assert.Equal(t, id, lines[0]["request_id"])
+			assert.Equal(t, float64(http.StatusNotFound), lines[0]["status"])
+		})
+	}
+}
+
+func TestBookingService_LogsCarryBookingIDAndStage(t *testing.T) {
+	buf := captureLogs(t, "debug")
+
+	departure := time.Now().Add(72 * time.Hour)
+	returning := departure.Add(96 * time.Hour)
+	mockExtractor := new(MockTravelParameterExtractor)
+	mockRecommender := new(MockFlightRecommender)
+	mockHotels := new(MockHotelRecommender)
+	mockExtractor.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(&models.TravelParameters{
+			TripType:      models.TripRoundTrip,
+			DepartureCity: "Paris",
+			Destination:   "Rome",
+			DepartureDate: &departure,
+			ReturnDate:    &returning,
+		}, nil)
+	mockRecommender.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(&models.FlightRecommendation{
+			Recommendations: []models.Flight{{
+				Airline:        "ITA Airways",
+				FlightNumber:   "AZ317",
+				DepartureCity:  "Paris",
+				ArrivalCity:    "Rome",
+				DepartureTime:  departure,
+				ArrivalTime:    departure.Add(2 * time.Hour),
+				AvailableSeats: 9,
+				Price:          models.NewMoney(150, "USD"),
+			}},
+			Reasoning: "test",
+		}, nil)
+	mockHotels.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil, assert.AnError)
+
+	svc := service.NewBookingService(mockExtractor, mockRecommender, service.WithHotelRecommender(mockHotels))
+	ctx := logging.WithRequestID(context.Background(), "req-42")
+	response, err := svc.ProcessBooking(ctx, models.BookingRequest{Query: "Paris to Rome", Deadline: time.Now().Add(24 * time.Hour)})
+	require.NoError(t, err)
+
+	byMessage := make(map[string]map[string]any)
+	for _, line := range logLines(t, buf) {
+		assert.Equal(t, "req-42", line["request_id"])
+		assert.Equal(t, response.ID, line["booking_id"], "the booking ID is known from the start")
+		byMessage[line["msg"].(string)] = line
+	}
+
+	require.Contains(t, byMessage, "query classified")
+	assert.Equal(t, string(models.IntentFlightSearch), byMessage["query classified"]["intent"])
+	require.Contains(t, byMessage, "hotel recommendations failed, booking goes ahead without them")
+	assert.Equal(t, "hotels", byMessage["hotel recommendations failed, booking goes ahead without them"]["stage"])
+	assert.Equal(t, "WARN", byMessage["hotel recommendations failed, booking goes ahead without them"]["level"])
+	require.Contains(t, byMessage, "booking processed")
+	assert.Equal(t, "INFO", byMessage["booking processed"]["level"])
+}
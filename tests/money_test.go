@@ -0,0 +1,150 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service/ai"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMoney_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    models.Money
+		wantErr bool
+	}{
+		{"Bare number from the model", `750.5`, models.Money{Minor: 75050}, false},
+		{"Integer", `800`, models.Money{Minor: 80000}, false},
+		{"Rounds half away from zero", `0.125`, models.Money{Minor: 13}, false},
+		{"Exponent", `7.5e2`, models.Money{Minor: 75000}, false},
+		{"Object", `{"amount": 19.99, "currency": "eur"}`, models.Money{Minor: 1999, Currency: "EUR"}, false},
+		{"Object with string amount", `{"amount": "15000", "currency": "JPY"}`, models.Money{Minor: 15000, Currency: "JPY"}, false},
+		{"String with currency", `"400.00 GBP"`, models.Money{Minor: 40000, Currency: "GBP"}, false},
+		{"Currency first", `"KWD 1.5"`, models.Money{Minor: 1500, Currency: "KWD"}, false},
+		{"Invalid currency is dropped", `{"amount": 10, "currency": "€"}`, models.Money{Minor: 1000}, false},
+		{"Not a number", `"a lot"`, models.Money{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m models.Money
+			err := json.Unmarshal([]byte(tt.input), &m)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, m)
+		})
+	}
+}
+
+func TestMoney_MarshalJSON(t *testing.T) {
+	data, err := json.Marshal(models.NewMoney(1234.5, "USD"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"amount": 1234.50, "currency": "USD"}`, string(data))
+
+	data, err = json.Marshal(models.NewMoney(-3, "JPY"))
+	require.NoError(t, err)
+	assert.Equal(t, `{"amount":-3,"currency":"JPY"}`, string(data))
+
+	var back models.Money
+	require.NoError(t, json.Unmarshal([]byte(`{"amount":1234.50,"currency":"USD"}`), &back))
+	assert.Equal(t, models.NewMoney(1234.5, "USD"), back)
+}
+
+func TestMoney_Arithmetic(t *testing.T) {
+	// Adding floats gives 0.30000000000000004; minor units stay exact
+	tenCents := models.NewMoney(0.1, "USD")
+	sum, err := tenCents.Add(tenCents)
+	require.NoError(t, err)
+	sum, err = sum.Add(tenCents)
+	require.NoError(t, err)
+	assert.Equal(t, "0.30 USD", sum.String())
+
+	fare := models.NewMoney(333.33, "USD")
+	assert.Equal(t, models.NewMoney(250, "USD"), fare.Scale(0.75))
+	assert.Equal(t, models.NewMoney(999.99, "USD"), fare.Mul(3))
+
+	// Amounts decoded without a currency take the other operand's
+	bare := models.Money{Minor: 500}
+	sum, err = fare.Add(bare)
+	require.NoError(t, err)
+	assert.Equal(t, models.NewMoney(338.33, "USD"), sum)
+	assert.Equal(t, models.NewMoney(15000, "JPY"), models.Money{Minor: 1500000}.Assume("JPY"))
+	assert.Equal(t, models.NewMoney(10, "EUR"), models.NewMoney(10, "EUR").Assume("USD"))
+
+	assert.Equal(t, 1, fare.Cmp(models.NewMoney(300, "USD")))
+	assert.Equal(t, models.NewMoney(50000, "JPY"), models.NewMoney(333.33, "USD").Exchange(150, "JPY"))
+
+	_, err = fare.Add(models.NewMoney(1, "EUR"))
+	assert.ErrorIs(t, err, models.ErrCurrencyMismatch)
+}
+
+func TestMoney_DifferentCurrenciesOrderByCode(t *testing.T) {
+	euros, dollars := models.NewMoney(500, "EUR"), models.NewMoney(1, "USD")
+	assert.Equal(t, -1, euros.Cmp(dollars))
+	assert.Equal(t, 1, dollars.Cmp(euros))
+	assert.Equal(t, 0, euros.Cmp(models.Money{Minor: 50000}))
+}
+
+func TestMoney_LessPutsThePreferredCurrencyFirst(t *testing.T) {
+	dollars, moreDollars := models.NewMoney(90, "USD"), models.NewMoney(200, "USD")
+	francs, yen := models.NewMoney(1, "CHF"), models.NewMoney(9000, "JPY")
+
+	assert.True(t, dollars.Less(moreDollars, "USD"))
+	assert.False(t, moreDollars.Less(dollars, "USD"))
+	assert.True(t, moreDollars.Less(francs, "USD"), "the preferred currency comes first whatever the amount")
+	assert.False(t, francs.Less(moreDollars, "USD"))
+	assert.True(t, francs.Less(yen, "USD"), "other currencies are grouped by code")
+	assert.False(t, yen.Less(francs, "USD"))
+}
+
+func TestMoney_OutOfRangeAmounts(t *testing.T) {
+	huge := models.NewMoney(1e300, "USD")
+	assert.Equal(t, models.Money{Minor: math.MaxInt64, Currency: "USD"}, huge)
+	assert.Equal(t, "92233720368547758.07 USD", huge.String())
+	assert.Equal(t, models.Money{Minor: -math.MaxInt64, Currency: "USD"}, models.NewMoney(-1e300, "USD"))
+	assert.Equal(t, models.Money{Minor: 0, Currency: "USD"}, models.NewMoney(math.NaN(), "USD"))
+
+	assert.Equal(t, huge, huge.Mul(3))
+	assert.Equal(t, huge, huge.Scale(2))
+	assert.Equal(t, models.Money{Minor: math.MaxInt64, Currency: "JPY"}, huge.Exchange(150, "JPY"))
+
+	_, err := huge.Add(models.NewMoney(1, "USD"))
+	assert.ErrorIs(t, err, models.ErrOverflow)
+	_, err = huge.Mul(-1).Add(models.NewMoney(-1, "USD"))
+	assert.ErrorIs(t, err, models.ErrOverflow)
+
+	// The most negative amount can't be negated as an int64
+	assert.Equal(t, "-92233720368547758.08 USD", models.Money{Minor: math.MinInt64, Currency: "USD"}.String())
+}
+
+func TestDecoders_AcceptNumericMoney(t *testing.T) {
+	departure := time.Now().Add(48 * time.Hour).UTC().Format(time.RFC3339)
+
+	extraction := &ai.ExtractionDecodingStrategy{}
+	params, err := extraction.DecodeResponse(fmt.Sprintf(`{"trip_type": "one_way", "departure_city": "Rome",
+		"destination": "Berlin", "departure_date": %q,
+		"preferences": {"budget_range": {"min": 200, "max": {"amount": 400.5, "currency": "eur"}}}}`, departure))
+	require.NoError(t, err)
+	require.NotNil(t, params.Preferences.BudgetRange.Min)
+	assert.Equal(t, models.Money{Minor: 20000}, *params.Preferences.BudgetRange.Min)
+	assert.Equal(t, models.NewMoney(400.5, "EUR"), *params.Preferences.BudgetRange.Max)
+
+	recommendations := &ai.FlightRecommendationDecoder{}
+	rec, err := recommendations.DecodeResponse(fmt.Sprintf(`{"recommendations": [
+		{"airline": "ANA", "flight_number": "NH10", "departure_time": %q, "price": 98000, "currency": "jpy"},
+		{"airline": "Lufthansa", "flight_number": "LH400", "departure_time": %q, "price": 612.35}
+	], "reasoning": "test"}`, departure, departure))
+	require.NoError(t, err)
+	assert.Equal(t, models.NewMoney(98000, "JPY"), rec.Recommendations[0].Price)
+	assert.Equal(t, models.Money{Minor: 61235}, rec.Recommendations[1].Price)
+}
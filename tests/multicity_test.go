@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service"
+	"travel-agent/internal/service/ai"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBookingService_MultiCity(t *testing.T) {
+	start := time.Now().Add(7 * 24 * time.Hour).UTC().Truncate(24 * time.Hour)
+	day := func(offset int) *time.Time {
+		d := start.AddDate(0, 0, offset).Add(12 * time.Hour)
+		return &d
+	}
+
+	legs := []models.TripLeg{
+		{Origin: "Bogotá", Destination: "Madrid", EarliestDate: day(0)},
+		{Origin: "Madrid", Destination: "Rome", EarliestDate: day(4)},
+		{Origin: "Rome", Destination: "Bogotá", EarliestDate: day(8)},
+	}
+
+	flightFor := func(leg models.TripLeg, departure time.Time, price float64) models.Flight {
+		return models.Flight{
+			Airline:        "Iberia",
+			FlightNumber:   fmt.Sprintf("IB%d", int(price)),
+			AvailableSeats: 9,
+			DepartureCity:  leg.Origin,
+			ArrivalCity:    leg.Destination,
+			DepartureTime:  departure,
+			ArrivalTime:    departure.Add(3 * time.Hour),
+			Price:          models.NewMoney(price, "USD"),
+		}
+	}
+
+	tests := []struct {
+		name         string
+		legFlights   [][]models.Flight
+		wantFeasible bool
+		wantTotal    float64
+	}{
+		{
+			name: "Every leg connects",
+			legFlights: [][]models.Flight{
+				{flightFor(legs[0], *day(0), 700)},
+				{flightFor(legs[1], *day(4), 120)},
+				{flightFor(legs[2], *day(8), 650)},
+			},
+			wantFeasible: true,
+			wantTotal:    1470,
+		},
+		{
+			name: "Falls back to an alternative inside the window",
+			legFlights: [][]models.Flight{
+				{flightFor(legs[0], *day(0), 700)},
+				{
+					func() models.Flight {
+						f := flightFor(legs[1], *day(6), 90)
+						f.RecommendationScore = 0.9
+						return f
+					}(),
+					flightFor(legs[1], *day(4), 120),
+				},
+				{flightFor(legs[2], *day(8), 650)},
+			},
+			wantFeasible: true,
+			wantTotal:    1470,
+		},
+		{
+			name: "Late-evening departure west of UTC stays on its local date",
+			legFlights: [][]models.Flight{
+				// 21:30 in Bogotá (UTC-5) is already the next day in UTC
+				{flightFor(legs[0], start.Add(26*time.Hour+30*time.Minute), 700)},
+				{flightFor(legs[1], *day(4), 120)},
+				{flightFor(legs[2], *day(8), 650)},
+			},
+			wantFeasible: true,
+			wantTotal:    1470,
+		},
+		{
+			name: "Leg outside its window is reported",
+			legFlights: [][]models.Flight{
+				{flightFor(legs[0], *day(0), 700)},
+				{flightFor(legs[1], *day(2), 120)},
+				{flightFor(legs[2], *day(8), 650)},
+			},
+			wantFeasible: false,
+			wantTotal:    1470,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockExtractor := new(MockTravelParameterExtractor)
+			mockRecommender := new(MockFlightRecommender)
+
+			mockExtractor.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(&models.TravelParameters{
+					TripType:      models.TripMultiCity,
+					DepartureCity: "Bogotá",
+					Destination:   "Madrid",
+					DepartureDate: legs[0].EarliestDate,
+					Legs:          legs,
+				}, nil)
+
+			for i, flights := range tt.legFlights {
+				legIndex := i
+				mockRecommender.On("ProcessRequest", mock.Anything, mock.Anything,
+					mock.MatchedBy(func(req models.FlightRecommendationRequest) bool {
+						return req.TripType == models.TripMultiCity && req.LegIndex == legIndex
+					}), mock.Anything).
+					Return(&models.FlightRecommendation{Recommendations: flights, Reasoning: "test"}, nil).Once()
+			}
+
+			svc := service.NewBookingService(mockExtractor, mockRecommender)
+			response, err := svc.ProcessBooking(context.Background(), models.BookingRequest{
+				Query:    "Bogotá → Madrid → Rome → back to Bogotá",
+				Deadline: time.Now().Add(24 * time.Hour),
+			})
+			require.NoError(t, err)
+
+			require.NotNil(t, response.Itinerary)
+			assert.Equal(t, models.TripMultiCity, response.TripType)
+			assert.Len(t, response.Itinerary.Legs, 3)
+			assert.Equal(t, tt.wantFeasible, response.Itinerary.Feasible)
+			assert.Equal(t, models.NewMoney(tt.wantTotal, "USD"), response.Itinerary.TotalPrice)
+			if !tt.wantFeasible {
+				assert.NotEmpty(t, response.Itinerary.Issues)
+			}
+
+			mockRecommender.AssertExpectations(t)
+		})
+	}
+}
+
+func TestExtractionDecodingStrategy_MultiCityLegs(t *testing.T) {
+	start := time.Now().Add(7 * 24 * time.Hour).UTC().Truncate(24 * time.Hour)
+	content := fmt.Sprintf(`{
+		"trip_type": "multi_city",
+		"legs": [
+			{"origin": "Bogotá", "destination": "Madrid", "earliest_date": %q, "stay_days": 4},
+			{"origin": "Madrid", "destination": "Rome", "earliest_date": null, "stay_days": 3},
+			{"origin": "Rome", "destination": "Bogotá", "earliest_date": null}
+		]
+	}`, start.Format(time.RFC3339))
+
+	params, err := (&ai.ExtractionDecodingStrategy{}).DecodeResponse(content)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Bogotá", params.DepartureCity)
+	assert.Equal(t, "Madrid", params.Destination)
+	assert.Nil(t, params.ReturnDate)
+	require.Len(t, params.Legs, 3)
+	// Leg dates are resolved like the top-level ones, to noon in the traveler's time zone
+	assert.True(t, params.Legs[1].EarliestDate.Equal(start.AddDate(0, 0, 4).Add(12*time.Hour)))
+	assert.True(t, params.Legs[2].EarliestDate.Equal(start.AddDate(0, 0, 7).Add(12*time.Hour)))
+}
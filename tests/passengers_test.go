@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service"
+	"travel-agent/internal/service/ai"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractionDecodingStrategy_Passengers(t *testing.T) {
+	departure := time.Now().Add(48 * time.Hour).UTC().Format(time.RFC3339)
+
+	tests := []struct {
+		name       string
+		passengers string
+		want       models.Passengers
+		wantErr    bool
+	}{
+		{
+			name:       "Defaults to a single adult",
+			passengers: `{}`,
+			want:       models.Passengers{Adults: 1},
+		},
+		{
+			name:       "Family with ages",
+			passengers: `{"adults": 2, "children": 2, "child_ages": [5, 8], "infants": 1}`,
+			want:       models.Passengers{Adults: 2, Children: 2, ChildAges: []int{5, 8}, Infants: 1},
+		},
+		{
+			name:       "Children without ages",
+			passengers: `{"adults": 2, "children": 2}`,
+			want:       models.Passengers{Adults: 2, Children: 2},
+		},
+		{
+			name:       "Ages move children to other passenger types",
+			passengers: `{"adults": 1, "children": 3, "child_ages": [1, 7, 14]}`,
+			want:       models.Passengers{Adults: 2, Children: 1, ChildAges: []int{7}, Infants: 1},
+		},
+		{
+			name:       "More infants than adults",
+			passengers: `{"adults": 1, "infants": 2}`,
+			wantErr:    true,
+		},
+		{
+			name:       "Party too large",
+			passengers: `{"adults": 6, "children": 4}`,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := fmt.Sprintf(`{"trip_type": "one_way", "departure_city": "Medellín", "destination": "Cancún",
+				"departure_date": %q, "return_date": null, "passengers": %s}`, departure, tt.passengers)
+
+			params, err := (&ai.ExtractionDecodingStrategy{}).DecodeResponse(content)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, params.Passengers)
+		})
+	}
+}
+
+func TestBookingService_PassengerPricing(t *testing.T) {
+	departure := time.Now().Add(48 * time.Hour)
+	family := models.Passengers{Adults: 2, Children: 2, ChildAges: []int{5, 8}, Infants: 1}
+
+	flight := func(number string, seats int) models.Flight {
+		return models.Flight{
+			Airline:        "Avianca",
+			FlightNumber:   number,
+			AvailableSeats: seats,
+			DepartureCity:  "Medellín",
+			ArrivalCity:    "Cancún",
+			DepartureTime:  departure,
+			ArrivalTime:    departure.Add(4 * time.Hour),
+			Price:          models.NewMoney(400, "USD"),
+		}
+	}
+
+	tests := []struct {
+		name       string
+		flights    []models.Flight
+		wantFlight string
+		wantErr    bool
+	}{
+		{
+			name:       "Flights without enough seats are rejected",
+			flights:    []models.Flight{flight("AV10", 3), flight("AV20", 4)},
+			wantFlight: "AV20",
+		},
+		{
+			name:    "No flight fits the party",
+			flights: []models.Flight{flight("AV10", 3)},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockExtractor := new(MockTravelParameterExtractor)
+			mockRecommender := new(MockFlightRecommender)
+
+			mockExtractor.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(&models.TravelParameters{
+					TripType:      models.TripOneWay,
+					DepartureCity: "Medellín",
+					Destination:   "Cancún",
+					DepartureDate: &departure,
+					Passengers:    family,
+				}, nil)
+			mockRecommender.On("ProcessRequest", mock.Anything, mock.Anything,
+				mock.MatchedBy(func(req models.FlightRecommendationRequest) bool {
+					return req.Passengers.Adults == 2 && req.Passengers.Children == 2 && req.Passengers.Infants == 1
+				}), mock.Anything).
+				Return(&models.FlightRecommendation{Recommendations: tt.flights, Reasoning: "test"}, nil)
+
+			svc := service.NewBookingService(mockExtractor, mockRecommender)
+			response, err := svc.ProcessBooking(context.Background(), models.BookingRequest{
+				Query:    "Me, my wife, our two kids (5 and 8) and the baby from Medellín to Cancún, one-way",
+				Deadline: time.Now().Add(24 * time.Hour),
+			})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantFlight, response.FlightDetails.FlightNumber)
+			require.NotNil(t, response.Passengers)
+			assert.Equal(t, family, *response.Passengers)
+
+			fare := response.FlightDetails.Fare
+			require.NotNil(t, fare)
+			assert.Equal(t, models.NewMoney(400, "USD"), fare.Adult)
+			require.NotNil(t, fare.Child)
+			assert.Equal(t, models.NewMoney(300, "USD"), *fare.Child)
+			require.NotNil(t, fare.Infant)
+			assert.Equal(t, models.NewMoney(40, "USD"), *fare.Infant)
+			assert.Equal(t, models.NewMoney(2*400+2*300+40, "USD"), fare.Total)
+			assert.Contains(t, response.Message, "for 5 passengers")
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+	"travel-agent/internal/handlers"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service"
+	"travel-agent/internal/service/ai"
+	"travel-agent/internal/store"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// planBooking stores a New York to Madrid round trip landing at 09:15 and leaving
+// at 16:40 three days later, Madrid time, with the flight times given in UTC
+func planBooking(t *testing.T) *store.MemoryBookingStore {
+	arrival := time.Date(2030, 6, 11, 7, 15, 0, 0, time.UTC)
+	departure := time.Date(2030, 6, 14, 14, 40, 0, 0, time.UTC)
+	outbound := models.Flight{FlightNumber: "IB6252", ArrivalCity: "Madrid", ArrivalTime: arrival}
+
+	params := models.TravelParameters{
+		TripType:      models.TripRoundTrip,
+		DepartureCity: "New York",
+		Destination:   "Madrid",
+		Passengers:    models.Passengers{Adults: 2},
+	}
+	params.Preferences.Activities = []string{"museums"}
+	params.Preferences.DietaryRestrictions = []string{"vegetarian"}
+
+	bookings := store.NewMemoryBookingStore()
+	require.NoError(t, bookings.Save(context.Background(), &models.BookingRecord{
+		Booking: models.BookingResponse{
+			ID:            "booking-1",
+			TripType:      models.TripRoundTrip,
+			FlightDetails: &outbound,
+			RoundTrip: &models.RoundTripItinerary{
+				Outbound: outbound,
+				Return:   models.Flight{FlightNumber: "IB6251", DepartureCity: "Madrid", DepartureTime: departure},
+			},
+			Hotels: &models.HotelStay{Hotels: []models.Hotel{{Name: "Only You Boutique", Neighborhood: "Chueca"}}},
+		},
+		Parameters: params,
+	}))
+	return bookings
+}
+
+func TestPlanService_CreatePlan(t *testing.T) {
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	require.NoError(t, err)
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2030, 6, day, hour, minute, 0, 0, madrid)
+	}
+
+	draft := &models.TripPlanDraft{
+		Days: []models.DraftDay{
+			{Day: 1, Blocks: []models.DraftBlock{
+				{Start: "09:00", End: "10:30", Title: "Breakfast"},
+				{Start: "14:00", End: "16:00", Title: "Prado Museum", Kind: "activity"},
+				{Start: "10:00", End: "12:00", Title: "Walk around Chueca", Kind: "activity"},
+				{Start: "13:00", End: "14:30", Title: "Lunch at Vega", Kind: "meal"},
+			}},
+			{Day: 2, Blocks: []models.DraftBlock{{Start: "21:00", End: "24:00", Title: "Flamenco show"}}},
+			{Day: 4, Blocks: []models.DraftBlock{
+				{Start: "10:00", End: "12:00", Title: "Retiro Park"},
+				{Start: "12:30", End: "14:30", Title: "Lunch at Rayén Vegano", Kind: "meal"},
+			}},
+			{Day: 7, Blocks: []models.DraftBlock{{Start: "10:00", End: "12:00", Title: "Toledo"}}},
+		},
+		Reasoning: "Museums early in the stay",
+	}
+
+	bookings := planBooking(t)
+	mockPlanner := new(MockTripPlanner)
+	mockPlanner.On("ProcessRequest", mock.Anything, mock.AnythingOfType("*ai.TripPlanStrategy"),
+		mock.Anything, mock.AnythingOfType("*ai.TripPlanDecoder")).Return(draft, nil)
+	svc := service.NewPlanService(bookings, mockPlanner)
+
+	_, err = svc.GetPlan(context.Background(), "booking-1")
+	assert.ErrorIs(t, err, service.ErrPlanNotFound)
+
+	plan, err := svc.CreatePlan(context.Background(), "booking-1")
+	require.NoError(t, err)
+	assert.Equal(t, "Europe/Madrid", plan.TimeZone)
+	assert.Equal(t, "Museums early in the stay", plan.Reasoning)
+	require.Len(t, plan.Days, 4)
+
+	// The arrival day starts 90 minutes after landing, in Madrid time
+	first := plan.Days[0]
+	assert.True(t, first.Start.Equal(at(11, 10, 45)), "got %s", first.Start)
+	assert.Contains(t, first.Note, "IB6252 lands at 09:15")
+	require.Len(t, first.Blocks, 2)
+	assert.Equal(t, "Walk around Chueca", first.Blocks[0].Title)
+	assert.True(t, first.Blocks[0].Start.Equal(at(11, 10, 45)))
+	assert.Equal(t, "Lunch at Vega", first.Blocks[1].Title)
+
+	assert.True(t, plan.Days[1].Blocks[0].End.Equal(at(13, 0, 0)))
+	assert.Empty(t, plan.Days[2].Blocks)
+
+	// The departure day ends three hours before the flight
+	last := plan.Days[3]
+	assert.True(t, last.End.Equal(at(14, 13, 40)), "got %s", last.End)
+	assert.Contains(t, last.Note, "IB6251 leaves at 16:40")
+	require.Len(t, last.Blocks, 2)
+	assert.True(t, last.Blocks[1].End.Equal(at(14, 13, 40)))
+
+	mockPlanner.AssertCalled(t, "ProcessRequest", mock.Anything, mock.Anything,
+		mock.MatchedBy(func(req models.TripPlanRequest) bool {
+			return req.City == "Madrid" && req.TimeZone == "Europe/Madrid" && len(req.Days) == 4 &&
+				req.Hotel == "Only You Boutique" && req.Neighborhood == "Chueca"
+		}), mock.Anything)
+
+	stored, err := svc.GetPlan(context.Background(), "booking-1")
+	require.NoError(t, err)
+	assert.Equal(t, plan, stored)
+}
+
+func TestPlanService_UpdatePlanDay(t *testing.T) {
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	require.NoError(t, err)
+	at := func(hour, minute int) time.Time {
+		return time.Date(2030, 6, 12, hour, minute, 0, 0, madrid)
+	}
+
+	bookings := planBooking(t)
+	mockPlanner := new(MockTripPlanner)
+	mockPlanner.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(&models.TripPlanDraft{Days: []models.DraftDay{{Day: 1}}}, nil)
+	svc := service.NewPlanService(bookings, mockPlanner)
+	_, err = svc.CreatePlan(context.Background(), "booking-1")
+	require.NoError(t, err)
+
+	t.Run("Blocks are stored in order in the destination's time zone", func(t *testing.T) {
+		plan, err := svc.UpdatePlanDay(context.Background(), "booking-1", 2, models.PlanDayUpdate{Blocks: []models.PlanBlock{
+			{Start: at(15, 0), End: at(17, 0), Title: "Reina Sofía"},
+			{Start: at(10, 0).UTC(), End: at(12, 0).UTC(), Title: "Royal Palace"},
+		}})
+		require.NoError(t, err)
+		blocks := plan.Days[1].Blocks
+		require.Len(t, blocks, 2)
+		assert.Equal(t, "Royal Palace", blocks[0].Title)
+		assert.Equal(t, madrid, blocks[0].Start.Location())
+
+		stored, err := svc.GetPlan(context.Background(), "booking-1")
+		require.NoError(t, err)
+		assert.Equal(t, blocks, stored.Days[1].Blocks)
+	})
+
+	invalid := []struct {
+		name   string
+		day    int
+		blocks []models.PlanBlock
+		err    error
+		detail string
+	}{
+		{
+			name:   "Overlapping blocks",
+			day:    2,
+			blocks: []models.PlanBlock{{Start: at(10, 0), End: at(12, 0), Title: "Palace"}, {Start: at(11, 0), End: at(13, 0), Title: "Lunch"}},
+			err:    service.ErrInvalidPlan,
+			detail: `"Lunch" overlaps "Palace"`,
+		},
+		{
+			name:   "Before the traveler reaches the hotel",
+			day:    1,
+			blocks: []models.PlanBlock{{Start: at(9, 0).AddDate(0, 0, -1), End: at(11, 0).AddDate(0, 0, -1), Title: "Prado"}},
+			err:    service.ErrInvalidPlan,
+			detail: "outside the free hours of day 1, 10:45 to 24:00",
+		},
+		{
+			name:   "Missing title",
+			day:    2,
+			blocks: []models.PlanBlock{{Start: at(10, 0), End: at(12, 0)}},
+			err:    service.ErrInvalidPlan,
+		},
+		{
+			name:   "Unknown day",
+			day:    9,
+			blocks: nil,
+			err:    service.ErrPlanDayNotFound,
+		},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.UpdatePlanDay(context.Background(), "booking-1", tt.day, models.PlanDayUpdate{Blocks: tt.blocks})
+			require.ErrorIs(t, err, tt.err)
+			assert.Contains(t, err.Error(), tt.detail)
+		})
+	}
+}
+
+// slowBookingStore widens the window between loading and saving a booking
+type slowBookingStore struct {
+	*store.MemoryBookingStore
+}
+
+func (s slowBookingStore) Get(ctx context.Context, id string) (*models.BookingRecord, error) {
+	record, err := s.MemoryBookingStore.Get(ctx, id)
+	time.Sleep(2 * time.Millisecond)
+	return record, err
+}
+
+func TestPlanService_ConcurrentDayEditsAreAllKept(t *testing.T) {
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	require.NoError(t, err)
+
+	mockPlanner := new(MockTripPlanner)
+	mockPlanner.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(&models.TripPlanDraft{Days: []models.DraftDay{{Day: 1}}}, nil)
+	svc := service.NewPlanService(slowBookingStore{planBooking(t)}, mockPlanner)
+	ctx := context.Background()
+	plan, err := svc.CreatePlan(ctx, "booking-1")
+	require.NoError(t, err)
+	require.Len(t, plan.Days, 4)
+
+	var wg sync.WaitGroup
+	for _, day := range plan.Days {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Date(2030, 6, 10+day.Day, 11, 0, 0, 0, madrid)
+			_, err := svc.UpdatePlanDay(ctx, "booking-1", day.Day, models.PlanDayUpdate{Blocks: []models.PlanBlock{
+				{Start: start, End: start.Add(time.Hour), Title: fmt.Sprintf("Walk %d", day.Day)},
+			}})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	stored, err := svc.GetPlan(ctx, "booking-1")
+	require.NoError(t, err)
+	for _, day := range stored.Days {
+		require.Len(t, day.Blocks, 1, "day %d", day.Day)
+		assert.Equal(t, fmt.Sprintf("Walk %d", day.Day), day.Blocks[0].Title)
+	}
+}
+
+func TestPlanHandler(t *testing.T) {
+	bookings := planBooking(t)
+	handler := handlers.NewPlanHandler(service.NewPlanService(bookings, new(MockTripPlanner)))
+
+	tests := []struct {
+		name   string
+		method string
+		id     string
+		day    string
+		body   string
+		status int
+	}{
+		{name: "Plan not created yet", method: http.MethodGet, id: "booking-1", status: http.StatusNotFound},
+		{name: "Unknown booking", method: http.MethodPost, id: "missing", status: http.StatusNotFound},
+		{name: "Invalid day", method: http.MethodPut, id: "booking-1", day: "first", body: `{"blocks": []}`, status: http.StatusBadRequest},
+		{name: "Update without a plan", method: http.MethodPut, id: "booking-1", day: "1", body: `{"blocks": []}`, status: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/v1/bookings/"+tt.id+"/plan", bytes.NewBufferString(tt.body))
+			req.SetPathValue("id", tt.id)
+			req.SetPathValue("day", tt.day)
+			w := httptest.NewRecorder()
+
+			switch tt.method {
+			case http.MethodGet:
+				handler.GetPlan(w, req)
+			case http.MethodPost:
+				handler.CreatePlan(w, req)
+			case http.MethodPut:
+				handler.UpdatePlanDay(w, req)
+			}
+
+			assert.Equal(t, tt.status, w.Code)
+			var body map[string]string
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+			assert.NotEmpty(t, body["error"])
+		})
+	}
+}
+
+func TestTripPlanDecoder(t *testing.T) {
+	decoder := &ai.TripPlanDecoder{}
+
+	draft, err := decoder.DecodeResponse(`{
+		"days": [{"day": 1, "blocks": [{"start": "21:00", "end": "24:00", "title": "Flamenco show", "kind": "activity"}]}],
+		"reasoning": "An easy first evening"
+	}`)
+	require.NoError(t, err)
+	assert.Equal(t, "24:00", draft.Days[0].Blocks[0].End)
+
+	_, err = decoder.DecodeResponse(`{"days": [{"day": 1, "blocks": [{"start": "9am", "end": "11:00", "title": "Prado"}]}]}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid time for block 1 of day 1")
+
+	_, err = decoder.DecodeResponse(`{"days": []}`)
+	assert.Error(t, err)
+}
@@ -0,0 +1,289 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBookingService_Preferences(t *testing.T) {
+	departure := time.Now().Add(48 * time.Hour)
+	moneyPtr := func(v float64) *models.Money {
+		m := models.NewMoney(v, "")
+		return &m
+	}
+	usd := func(v float64) models.Money { return models.NewMoney(v, "USD") }
+	intPtr := func(v int) *int { return &v }
+
+	flight := func(number, class string, price float64, layovers int) models.Flight {
+		return models.Flight{
+			Airline:        "Avianca",
+			FlightNumber:   number,
+			AvailableSeats: 9,
+			DepartureCity:  "Bogotá",
+			ArrivalCity:    "Madrid",
+			DepartureTime:  departure,
+			ArrivalTime:    departure.Add(10 * time.Hour),
+			Class:          class,
+			LayoverCount:   layovers,
+			Price:          models.NewMoney(price, "USD"),
+		}
+	}
+
+	tests := []struct {
+		name         string
+		preferences  models.Preferences
+		passengers   models.Passengers
+		profile      *models.TravelerProfile
+		flights      []models.Flight
+		matchReq     func(req models.FlightRecommendationRequest) bool
+		wantFlight   string
+		wantRejected []string
+		wantErr      bool
+	}{
+		{
+			name: "Defaults are sent but not enforced",
+			flights: []models.Flight{
+				flight("AV1", "business", 2500, 0),
+			},
+			matchReq: func(req models.FlightRecommendationRequest) bool {
+				return req.PreferredClass == "economy" && req.MaxBudget == usd(2000) && req.MinBudget.IsZero()
+			},
+			wantFlight: "AV1",
+		},
+		{
+			name: "Extracted class and budget are enforced",
+			preferences: func() models.Preferences {
+				var p models.Preferences
+				p.TravelClass = "Business"
+				p.BudgetRange.Min = moneyPtr(1000)
+				p.BudgetRange.Max = moneyPtr(3000)
+				return p
+			}(),
+			flights: []models.Flight{
+				flight("AV1", "economy", 900, 0),
+				flight("AV2", "business", 3200, 0),
+				flight("AV3", "Business", 2800, 1),
+			},
+			matchReq: func(req models.FlightRecommendationRequest) bool {
+				return req.PreferredClass == "business" && req.MinBudget == usd(1000) && req.MaxBudget == usd(3000)
+			},
+			wantFlight: "AV3",
+		},
+		{
+			name: "Profile fills what the query leaves out",
+			preferences: func() models.Preferences {
+				var p models.Preferences
+				p.DietaryRestrictions = []string{"vegetarian"}
+				return p
+			}(),
+			profile: &models.TravelerProfile{
+				TravelClass:         "premium economy",
+				MaxBudget:           moneyPtr(1500),
+				MaxLayovers:         intPtr(0),
+				DietaryRestrictions: []string{"gluten-free"},
+			},
+			flights: []models.Flight{
+				flight("AV1", "premium_economy", 1200, 1),
+				flight("AV2", "premium_economy", 1400, 0),
+			},
+			matchReq: func(req models.FlightRecommendationRequest) bool {
+				return req.PreferredClass == "premium_economy" && req.MaxBudget == usd(1500) &&
+					req.MaxLayovers != nil && *req.MaxLayovers == 0 &&
+					assert.ObjectsAreEqual([]string{"vegetarian", "gluten-free"}, req.DietaryRestrictions)
+			},
+			wantFlight: "AV2",
+		},
+		{
+			name: "Query overrides the profile",
+			preferences: func() models.Preferences {
+				var p models.Preferences
+				p.TravelClass = "first"
+				return p
+			}(),
+			profile: &models.TravelerProfile{TravelClass: "economy"},
+			flights: []models.Flight{
+				flight("AV1", "economy", 600, 0),
+				flight("AV2", "first", 6000, 0),
+			},
+			matchReq: func(req models.FlightRecommendationRequest) bool {
+				return req.PreferredClass == "first"
+			},
+			wantFlight: "AV2",
+		},
+		{
+			name: "A budget for the whole party is compared with the party's total",
+			preferences: func() models.Preferences {
+				var p models.Preferences
+				p.BudgetRange.Max = moneyPtr(3000)
+				p.BudgetRange.Scope = models.BudgetTotal
+				return p
+			}(),
+			passengers: models.Passengers{Adults: 4},
+			flights: []models.Flight{
+				flight("AV1", "economy", 700, 0),
+				flight("AV2", "economy", 800, 0),
+			},
+			matchReq: func(req models.FlightRecommendationRequest) bool {
+				return req.MaxBudget == usd(750)
+			},
+			wantFlight:   "AV1",
+			wantRejected: []string{"AV2"},
+		},
+		{
+			name: "A lap infant doesn't take a share of the party's budget",
+			preferences: func() models.Preferences {
+				var p models.Preferences
+				p.BudgetRange.Max = moneyPtr(3000)
+				p.BudgetRange.Scope = models.BudgetTotal
+				return p
+			}(),
+			passengers: models.Passengers{Adults: 2, Infants: 1},
+			flights: []models.Flight{
+				flight("AV1", "economy", 1300, 0),
+			},
+			matchReq: func(req models.FlightRecommendationRequest) bool {
+				return req.MaxBudget == usd(1500)
+			},
+			wantFlight: "AV1",
+		},
+		{
+			name: "A budget per person covers each traveler",
+			preferences: func() models.Preferences {
+				var p models.Preferences
+				p.BudgetRange.Max = moneyPtr(800)
+				return p
+			}(),
+			passengers: models.Passengers{Adults: 4},
+			flights: []models.Flight{
+				flight("AV1", "economy", 800, 0),
+				flight("AV2", "economy", 850, 0),
+			},
+			matchReq: func(req models.FlightRecommendationRequest) bool {
+				return req.MaxBudget == usd(800)
+			},
+			wantFlight:   "AV1",
+			wantRejected: []string{"AV2"},
+		},
+		{
+			name: "Bag fees count against the budget",
+			preferences: func() models.Preferences {
+				var p models.Preferences
+				p.BudgetRange.Max = moneyPtr(500)
+				p.Baggage.CheckedBags = 1
+				return p
+			}(),
+			flights: func() []models.Flight {
+				fee := usd(50)
+				basic := flight("AV1", "economy", 480, 0)
+				basic.Conditions = &models.FareConditions{CheckedBagFee: &fee}
+				included := flight("AV2", "economy", 495, 0)
+				included.Conditions = &models.FareConditions{CheckedBags: 1}
+				return []models.Flight{basic, included}
+			}(),
+			matchReq:     func(req models.FlightRecommendationRequest) bool { return true },
+			wantFlight:   "AV2",
+			wantRejected: []string{"AV1"},
+		},
+		{
+			name: "Every flight breaks a constraint",
+			preferences: func() models.Preferences {
+				var p models.Preferences
+				p.BudgetRange.Max = moneyPtr(500)
+				return p
+			}(),
+			flights: []models.Flight{
+				flight("AV1", "economy", 900, 0),
+			},
+			matchReq: func(req models.FlightRecommendationRequest) bool { return true },
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockExtractor := new(MockTravelParameterExtractor)
+			mockRecommender := new(MockFlightRecommender)
+
+			mockExtractor.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(&models.TravelParameters{
+					TripType:      models.TripOneWay,
+					DepartureCity: "Bogotá",
+					Destination:   "Madrid",
+					DepartureDate: &departure,
+					Passengers:    tt.passengers,
+					Preferences:   tt.preferences,
+				}, nil)
+			mockRecommender.On("ProcessRequest", mock.Anything, mock.Anything, mock.MatchedBy(tt.matchReq), mock.Anything).
+				Return(&models.FlightRecommendation{Recommendations: tt.flights, Reasoning: "test"}, nil)
+
+			svc := service.NewBookingService(mockExtractor, mockRecommender)
+			response, err := svc.ProcessBooking(context.Background(), models.BookingRequest{
+				Query:    "One-way from Bogotá to Madrid",
+				Deadline: time.Now().Add(24 * time.Hour),
+				Profile:  tt.profile,
+			})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantFlight, response.FlightDetails.FlightNumber)
+			if tt.wantRejected != nil {
+				var rejected []string
+				for _, rejection := range response.RejectedFlights {
+					rejected = append(rejected, rejection.FlightNumber)
+				}
+				assert.Equal(t, tt.wantRejected, rejected)
+			}
+			mockRecommender.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBookingService_RecommendationDefaults(t *testing.T) {
+	departure := time.Now().Add(48 * time.Hour)
+
+	mockExtractor := new(MockTravelParameterExtractor)
+	mockRecommender := new(MockFlightRecommender)
+
+	mockExtractor.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(&models.TravelParameters{
+			TripType:      models.TripOneWay,
+			DepartureCity: "Bogotá",
+			Destination:   "Madrid",
+			DepartureDate: &departure,
+		}, nil)
+	mockRecommender.On("ProcessRequest", mock.Anything, mock.Anything,
+		mock.MatchedBy(func(req models.FlightRecommendationRequest) bool {
+			return req.PreferredClass == "premium_economy" && req.MaxBudget == models.NewMoney(1200, "USD")
+		}), mock.Anything).
+		Return(&models.FlightRecommendation{
+			Recommendations: []models.Flight{{
+				Airline:        "Iberia",
+				FlightNumber:   "IB6584",
+				AvailableSeats: 9,
+				DepartureCity:  "Bogotá",
+				ArrivalCity:    "Madrid",
+				DepartureTime:  departure,
+				ArrivalTime:    departure.Add(10 * time.Hour),
+				Price:          models.NewMoney(1100, "USD"),
+			}},
+			Reasoning: "test",
+		}, nil)
+
+	svc := service.NewBookingService(mockExtractor, mockRecommender,
+		service.WithRecommendationDefaults(service.RecommendationDefaults{TravelClass: "Premium Economy", MaxBudget: 1200}))
+	_, err := svc.ProcessBooking(context.Background(), models.BookingRequest{
+		Query:    "One-way from Bogotá to Madrid",
+		Deadline: time.Now().Add(24 * time.Hour),
+	})
+	require.NoError(t, err)
+	mockRecommender.AssertExpectations(t)
+}
@@ -0,0 +1,194 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBookingService_Ranking(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	now := time.Now().In(newYork)
+	// Departures are local times in New York, sent in UTC as providers do
+	at := func(local time.Duration) time.Time {
+		return time.Date(now.Year(), now.Month(), now.Day()+3, 0, int(local.Minutes()), 0, 0, newYork).UTC()
+	}
+	departureDate := at(12 * time.Hour)
+
+	flight := func(number string, price float64, layovers int, departs, duration time.Duration) models.Flight {
+		return models.Flight{
+			Airline:             "Iberia",
+			FlightNumber:        number,
+			DepartureCity:       "New York",
+			ArrivalCity:         "Madrid",
+			DepartureTime:       at(departs),
+			ArrivalTime:         at(departs).Add(duration),
+			LayoverCount:        layovers,
+			AvailableSeats:      9,
+			Price:               models.NewMoney(price, "USD"),
+			RecommendationScore: 0.99, // Replaced by the engine
+		}
+	}
+	// Cheap overnight connection, mid-priced daytime direct flight, expensive fast flight
+	candidates := []models.Flight{
+		flight("IB1", 400, 1, 23*time.Hour+30*time.Minute, 11*time.Hour),
+		flight("IB2", 600, 0, 10*time.Hour, 8*time.Hour),
+		flight("IB3", 900, 0, 18*time.Hour, 7*time.Hour+30*time.Minute),
+	}
+
+	tests := []struct {
+		name        string
+		weights     *service.RankingWeights
+		departure   string
+		wantBest    string
+		wantScore   float64
+		wantDetails []string
+	}{
+		{
+			name:        "Default weights favor the daytime direct flight",
+			wantBest:    "IB2",
+			wantScore:   0.83,
+			wantDetails: []string{"600.00 USD, 50% above the cheapest", "direct", "8h00m, 0h30m longer than the fastest", "departs 10:00"},
+		},
+		{
+			name:      "Price only",
+			weights:   &service.RankingWeights{Price: 1},
+			wantBest:  "IB1",
+			wantScore: 1,
+		},
+		{
+			name:      "Duration and stops",
+			weights:   &service.RankingWeights{Duration: 2, Stops: 2},
+			wantBest:  "IB3",
+			wantScore: 1,
+		},
+		{
+			name:      "Requested departure time",
+			weights:   &service.RankingWeights{DepartureTime: 1},
+			departure: "Evenings",
+			wantBest:  "IB3",
+			wantScore: 1,
+			wantDetails: []string{
+				"900.00 USD, 125% above the cheapest", "direct", "7h30m", "departs 18:00, in the evening as requested",
+			},
+		},
+		{
+			name:      "Negative weights are ignored",
+			weights:   &service.RankingWeights{Price: -1, Stops: 1},
+			wantBest:  "IB2",
+			wantScore: 0.83,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockExtractor := new(MockTravelParameterExtractor)
+			mockRecommender := new(MockFlightRecommender)
+
+			params := &models.TravelParameters{
+				TripType:      models.TripOneWay,
+				DepartureCity: "New York",
+				Destination:   "Madrid",
+				DepartureDate: &departureDate,
+			}
+			params.Preferences.DepartureTime = tt.departure
+			mockExtractor.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(params, nil)
+			mockRecommender.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(&models.FlightRecommendation{
+					Recommendations: append([]models.Flight(nil), candidates...),
+					Reasoning:       "model reasoning",
+				}, nil)
+
+			var opts []service.BookingOption
+			if tt.weights != nil {
+				opts = append(opts, service.WithRankingWeights(*tt.weights))
+			}
+			svc := service.NewBookingService(mockExtractor, mockRecommender, opts...)
+			response, err := svc.ProcessBooking(context.Background(), models.BookingRequest{
+				Query:    "New York to Madrid",
+				Deadline: time.Now().Add(24 * time.Hour),
+			})
+			require.NoError(t, err)
+
+			best := response.FlightDetails
+			assert.Equal(t, tt.wantBest, best.FlightNumber)
+			assert.Equal(t, tt.wantScore, best.RecommendationScore)
+			// The model argued for its own order, so the ranking is explained instead
+			assert.Contains(t, response.Reasoning, "Iberia "+tt.wantBest+" scores")
+
+			require.Len(t, best.ScoreBreakdown, 4)
+			var weights, contributions float64
+			for i, factor := range best.ScoreBreakdown {
+				weights += factor.Weight
+				contributions += factor.Contribution
+				if tt.wantDetails != nil {
+					assert.Equal(t, tt.wantDetails[i], factor.Detail)
+				}
+			}
+			assert.InDelta(t, 1, weights, 0.011)
+			assert.InDelta(t, best.RecommendationScore, contributions, 1e-9)
+
+			if tt.departure != "" {
+				mockRecommender.AssertCalled(t, "ProcessRequest", mock.Anything, mock.Anything,
+					mock.MatchedBy(func(req models.FlightRecommendationRequest) bool {
+						return req.PreferredDepartureTime == "evening"
+					}), mock.Anything)
+			}
+		})
+	}
+}
+
+func TestBookingService_RankingFreeAwardFare(t *testing.T) {
+	departure := searchDate()
+	params := &models.TravelParameters{
+		TripType:      models.TripOneWay,
+		DepartureCity: "New York",
+		Destination:   "London",
+		DepartureDate: &departure,
+	}
+	offer := func(number string, price float64) models.Flight {
+		return models.Flight{
+			Airline:        "Test Air",
+			FlightNumber:   number,
+			DepartureCity:  "New York",
+			ArrivalCity:    "London",
+			DepartureTime:  departure,
+			ArrivalTime:    departure.Add(7 * time.Hour),
+			AvailableSeats: 9,
+			Price:          models.NewMoney(price, "USD"),
+		}
+	}
+
+	mockExtractor := new(MockTravelParameterExtractor)
+	mockExtractor.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(params, nil)
+	// The narrative request carries every ranked offer with its breakdown
+	var ranked []models.Flight
+	mockRecommender := new(MockFlightRecommender)
+	mockRecommender.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { ranked = args.Get(2).(models.FlightRecommendationRequest).Offers }).
+		Return(&models.FlightRecommendation{Reasoning: "The award seat is free."}, nil)
+
+	svc := service.NewBookingService(mockExtractor, mockRecommender,
+		service.WithFlightSearch(fixedOffers{offer("TA2", 300), offer("TA1", 0)}),
+		service.WithRankingWeights(service.RankingWeights{Price: 1}),
+		service.WithRankingNarrative(true),
+	)
+	response, err := svc.ProcessBooking(context.Background(), models.BookingRequest{
+		Query:    "New York to London on points",
+		Deadline: time.Now().Add(24 * time.Hour),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "TA1", response.FlightDetails.FlightNumber)
+
+	require.Len(t, ranked, 2)
+	assert.Equal(t, "0.00 USD, the cheapest", ranked[0].ScoreBreakdown[0].Detail)
+	assert.Equal(t, "300.00 USD, 300.00 USD above the cheapest", ranked[1].ScoreBreakdown[0].Detail)
+	assert.Equal(t, 0.0, ranked[1].RecommendationScore)
+}
@@ -0,0 +1,170 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"travel-agent/internal/config"
+	"travel-agent/internal/logging"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service/ai"
+	"travel-agent/pkg/redact"
+	"travel-agent/pkg/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactString(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "Trip details are kept",
+			text: "Bogotá to Madrid on 2025-03-10T08:00:00Z, flight IB6584, 2 adults, 1450.50 USD",
+			want: "Bogotá to Madrid on 2025-03-10T08:00:00Z, flight IB6584, 2 adults, 1450.50 USD",
+		},
+		{
+			name: "Emails",
+			text: "Send it to ana.gomez+trips@example.co and cc me",
+			want: "Send it to [email] and cc me",
+		},
+		{
+			name: "International and local phone numbers",
+			text: "Call +57 310 555 1234 or (212) 555-0199, or 415.555.0123",
+			want: "Call [phone] or [phone], or [phone]",
+		},
+		{
+			name: "Passport numbers",
+			text: "Passport no: X1234567, my wife's is AB9876543 and his is 123456789; passport holders only",
+			want: "Passport no: [passport], my wife's is [passport] and his is [passport]; passport holders only",
+		},
+		{
+			name: "Names introduced as such",
+			text: "Hi, my name is Ana María Gómez, traveling with Mr. Luis Pérez",
+			want: "Hi, my name is [name], traveling with Mr. [name]",
+		},
+		{
+			name: "Flight and order numbers outside a passport's clause",
+			text: "Flight UA123456 on order 987654321. Passport: X1234567",
+			want: "Flight UA123456 on order 987654321. Passport: [passport]",
+		},
+		{
+			name: "Introductions and companions",
+			text: "I'm Maria Lopez, flying with John",
+			want: "I'm [name], flying with [name]",
+		},
+		{
+			name: "People a trip is booked for",
+			text: "Book for Maria Lopez and John Smith, then Maria needs a hotel",
+			want: "Book for [name] and [name], then Maria needs a hotel",
+		},
+		{
+			name: "A city after \"for\" isn't masked elsewhere",
+			text: "Flights for Buenos Aires in May, then a hotel in Buenos Aires near Palermo",
+			want: "Flights for [name] in May, then a hotel in Buenos Aires near Palermo",
+		},
+		{
+			name: "Traveler labels",
+			text: "Traveler: María López; passengers: Ana Gómez y Luis Pérez",
+			want: "Traveler: [name]; passengers: [name] y [name]",
+		},
+		{
+			name: "Name fields of JSON, also when escaped inside a string",
+			text: `{"given_name": "Ana", "content": "{\"family_name\": \"Gómez\", \"destination\": \"Madrid\"}"}`,
+			want: `{"given_name": "[name]", "content": "{\"family_name\": \"[name]\", \"destination\": \"Madrid\"}"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, redact.String(tt.text))
+		})
+	}
+}
+
+func TestPayloadLogger_Sampled(t *testing.T) {
+	ctx := context.Background()
+	draw := func(v float64) func() float64 { return func() float64 { return v } }
+	debug := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	info := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	var nilLogger *utils.PayloadLogger
+	assert.False(t, nilLogger.Sampled(ctx))
+	assert.False(t, (&utils.PayloadLogger{SampleRate: 1, Logger: info, Sample: draw(0)}).Sampled(ctx), "payloads are only logged at debug")
+	assert.False(t, (&utils.PayloadLogger{Logger: debug, Sample: draw(0)}).Sampled(ctx), "a zero rate never logs")
+	assert.True(t, (&utils.PayloadLogger{SampleRate: 0.25, Logger: debug, Sample: draw(0.2)}).Sampled(ctx))
+	assert.False(t, (&utils.PayloadLogger{SampleRate: 0.25, Logger: debug, Sample: draw(0.3)}).Sampled(ctx))
+}
+
+func TestLoad_PayloadSampleRate(t *testing.T) {
+	load := func(logging string) (*config.Config, error) {
+		filename := filepath.Join(t.TempDir(), "config.json")
+		require.NoError(t, os.WriteFile(filename, []byte(`{"Logging": `+logging+`}`), 0o600))
+		return config.Load(filename)
+	}
+
+	cfg, err := load(`{}`)
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, *cfg.Logging.PayloadSampleRate, "every call is sampled by default")
+
+	cfg, err = load(`{"payload_sample_rate": 0}`)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, *cfg.Logging.PayloadSampleRate, "an explicit 0 turns payload logging off")
+
+	for _, rate := range []string{"-0.1", "1.5"} {
+		_, err = load(`{"payload_sample_rate": ` + rate + `}`)
+		assert.Error(t, err, rate)
+	}
+}
+
+func TestProcessRequest_PayloadLogging(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"choices": [{"message": {"role": "assistant", "content": "{\"email\": \"ana@example.com\"}"}}]}`)
+	}))
+	defer server.Close()
+
+	originalEndpoint := ai.AIProviderEndpoint
+	ai.AIProviderEndpoint = server.URL
+	defer func() { ai.AIProviderEndpoint = originalEndpoint }()
+
+	for _, tt := range []struct {
+		level     string
+		wantLines int
+	}{
+		{level: "debug", wantLines: 2},
+		{level: "info", wantLines: 0},
+	} {
+		t.Run(tt.level, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger, err := logging.New(&buf, tt.level, logging.FormatJSON)
+			require.NoError(t, err)
+			engine, err := ai.NewInferenceEngine[models.MockTravelResponse, models.MockTravelRequest]("test-key",
+				ai.WithPayloadLogging(&utils.PayloadLogger{SampleRate: 1, Logger: logger}))
+			require.NoError(t, err)
+
+			_, err = engine.ProcessRequest(context.Background(), MockPromptStrategy{}, models.MockTravelRequest{}, MockDecodingStrategy{})
+			require.NoError(t, err, "the logged response can still be decoded")
+
+			lines := logLines(t, &buf)
+			require.Len(t, lines, tt.wantLines)
+			if tt.wantLines > 0 {
+				assert.Equal(t, "provider request", lines[0]["msg"])
+				assert.Contains(t, lines[0]["body"], "user prompt")
+				assert.Equal(t, "provider response", lines[1]["msg"])
+				assert.Equal(t, "200 OK", lines[1]["status"])
+				assert.Contains(t, lines[1]["body"], redact.Email)
+				assert.NotContains(t, lines[1]["body"], "ana@example.com")
+			}
+		})
+	}
+}
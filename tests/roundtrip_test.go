@@ -0,0 +1,208 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service"
+	"travel-agent/internal/service/ai"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBookingService_RoundTripPairing(t *testing.T) {
+	departure := time.Now().Add(72 * time.Hour).UTC().Truncate(24 * time.Hour).Add(9 * time.Hour)
+	returning := departure.AddDate(0, 0, 7)
+
+	flight := func(number, from, to string, departs time.Time, price float64) models.Flight {
+		return models.Flight{
+			Airline:        "British Airways",
+			FlightNumber:   number,
+			AvailableSeats: 9,
+			DepartureCity:  from,
+			ArrivalCity:    to,
+			DepartureTime:  departs,
+			ArrivalTime:    departs.Add(7 * time.Hour),
+			Price:          models.NewMoney(price, "USD"),
+		}
+	}
+
+	setup := func(returns []models.Flight, preferences models.Preferences) (*MockTravelParameterExtractor, *MockFlightRecommender) {
+		mockExtractor := new(MockTravelParameterExtractor)
+		mockRecommender := new(MockFlightRecommender)
+		mockExtractor.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(&models.TravelParameters{
+				TripType:      models.TripRoundTrip,
+				DepartureCity: "New York",
+				Destination:   "London",
+				DepartureDate: &departure,
+				ReturnDate:    &returning,
+				Passengers:    models.Passengers{Adults: 2},
+				Preferences:   preferences,
+			}, nil)
+		// Both directions come from a single recommendation
+		mockRecommender.On("ProcessRequest", mock.Anything, mock.Anything,
+			mock.MatchedBy(func(req models.FlightRecommendationRequest) bool {
+				return req.ReturnDate != nil && req.ReturnDate.Equal(returning)
+			}), mock.Anything).
+			Return(&models.FlightRecommendation{
+				Recommendations: []models.Flight{
+					flight("BA178", "New York", "London", departure, 700),
+					flight("BA112", "New York", "London", departure.Add(4*time.Hour), 500),
+				},
+				ReturnRecommendations: returns,
+				Reasoning:             "test",
+			}, nil).Once()
+		return mockExtractor, mockRecommender
+	}
+
+	t.Run("Outbound and return flights are paired and priced together", func(t *testing.T) {
+		mockExtractor, mockRecommender := setup([]models.Flight{
+			flight("BA177", "London", "New York", returning, 600),
+			flight("BA117", "London", "New York", returning.Add(3*time.Hour), 450),
+			// Leaves from the wrong city
+			flight("BA999", "Paris", "New York", returning, 100),
+		}, models.Preferences{})
+
+		svc := service.NewBookingService(mockExtractor, mockRecommender,
+			service.WithRankingWeights(service.RankingWeights{Price: 1}))
+		response, err := svc.ProcessBooking(context.Background(), models.BookingRequest{
+			Query:    "Round trip from New York to London for two",
+			Deadline: time.Now().Add(24 * time.Hour),
+		})
+		require.NoError(t, err)
+		mockRecommender.AssertExpectations(t)
+
+		// The cheapest flights in each direction make the best pairing
+		trip := response.RoundTrip
+		require.NotNil(t, trip)
+		assert.Equal(t, "BA112", trip.Outbound.FlightNumber)
+		assert.Equal(t, "BA117", trip.Return.FlightNumber)
+		assert.Equal(t, models.NewMoney(1900, "USD"), trip.TotalPrice)
+		assert.Equal(t, 1.0, trip.RecommendationScore)
+		assert.Equal(t, models.NewMoney(900, "USD"), trip.Return.Fare.Total)
+		assert.Equal(t, "BA112", response.FlightDetails.FlightNumber)
+		assert.Contains(t, response.Message, "1900.00 USD in total")
+
+		require.Len(t, response.RoundTripOptions, 3)
+		for i, option := range response.RoundTripOptions {
+			total, err := option.Outbound.Fare.Total.Add(option.Return.Fare.Total)
+			require.NoError(t, err)
+			assert.Equal(t, total, option.TotalPrice)
+			assert.LessOrEqual(t, option.RecommendationScore, trip.RecommendationScore)
+			if i > 0 {
+				assert.LessOrEqual(t, option.RecommendationScore, response.RoundTripOptions[i-1].RecommendationScore)
+			}
+		}
+
+		require.Len(t, response.RejectedFlights, 1)
+		assert.Equal(t, "BA999", response.RejectedFlights[0].FlightNumber)
+		assert.Equal(t, "return", response.RejectedFlights[0].Direction)
+	})
+
+	t.Run("The outbound flight is kept without a way back", func(t *testing.T) {
+		mockExtractor, mockRecommender := setup(nil, models.Preferences{})
+
+		svc := service.NewBookingService(mockExtractor, mockRecommender)
+		response, err := svc.ProcessBooking(context.Background(), models.BookingRequest{
+			Query:    "Round trip from New York to London for two",
+			Deadline: time.Now().Add(24 * time.Hour),
+		})
+		require.NoError(t, err)
+
+		assert.Nil(t, response.RoundTrip)
+		assert.Empty(t, response.RoundTripOptions)
+		assert.NotNil(t, response.FlightDetails)
+		assert.Contains(t, response.Message, "no return flight found")
+	})
+
+	t.Run("Pairings over the budget are dropped though each leg is under it", func(t *testing.T) {
+		budget := models.NewMoney(2000, "USD")
+		var preferences models.Preferences
+		preferences.BudgetRange.Max = &budget
+		preferences.BudgetRange.Scope = models.BudgetTotal
+		mockExtractor, mockRecommender := setup([]models.Flight{
+			flight("BA177", "London", "New York", returning, 600),
+			flight("BA117", "London", "New York", returning.Add(3*time.Hour), 450),
+		}, preferences)
+
+		svc := service.NewBookingService(mockExtractor, mockRecommender)
+		response, err := svc.ProcessBooking(context.Background(), models.BookingRequest{
+			Query:    "Round trip from New York to London for two, 2000 dollars in total",
+			Deadline: time.Now().Add(24 * time.Hour),
+		})
+		require.NoError(t, err)
+
+		// Every flight fits the budget for two; only the cheapest pair does together
+		assert.Empty(t, response.RejectedFlights)
+		assert.Empty(t, response.RoundTripOptions)
+		trip := response.RoundTrip
+		require.NotNil(t, trip)
+		assert.Equal(t, "BA112", trip.Outbound.FlightNumber)
+		assert.Equal(t, "BA117", trip.Return.FlightNumber)
+		assert.Equal(t, models.NewMoney(1900, "USD"), trip.TotalPrice)
+	})
+}
+
+func TestBookingService_RoundTripSearch(t *testing.T) {
+	departure := searchDate()
+	returning := departure.AddDate(0, 0, 7)
+
+	mockExtractor := new(MockTravelParameterExtractor)
+	mockRecommender := new(MockFlightRecommender)
+	mockExtractor.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(&models.TravelParameters{
+			TripType:      models.TripRoundTrip,
+			DepartureCity: "NYC",
+			Destination:   "London",
+			DepartureDate: &departure,
+			ReturnDate:    &returning,
+		}, nil)
+
+	svc := service.NewBookingService(mockExtractor, mockRecommender, service.WithFlightSearch(newStubClient(t)))
+	response, err := svc.ProcessBooking(context.Background(), models.BookingRequest{
+		Query:    "Round trip from NYC to London",
+		Deadline: time.Now().Add(24 * time.Hour),
+	})
+	require.NoError(t, err)
+	mockRecommender.AssertNotCalled(t, "ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	trip := response.RoundTrip
+	require.NotNil(t, trip)
+	assert.Equal(t, "London", trip.Outbound.ArrivalCity)
+	assert.Equal(t, "London", trip.Return.DepartureCity)
+	assert.Equal(t, "New York", trip.Return.ArrivalCity)
+	assert.NotEmpty(t, trip.Return.OfferID)
+	assert.Equal(t, trip.Outbound.FlightNumber, response.FlightDetails.FlightNumber)
+
+	// Flights back from London are sold in pounds; the total is in the traveler's dollars
+	require.NotNil(t, trip.Return.Converted)
+	assert.Equal(t, "GBP", trip.Return.Price.Currency)
+	total, err := trip.Outbound.Fare.Total.Add(trip.Return.Converted.Total)
+	require.NoError(t, err)
+	assert.Equal(t, total, trip.TotalPrice)
+	assert.Equal(t, "USD", trip.TotalPrice.Currency)
+}
+
+func TestFlightRecommendationDecoder_ReturnRecommendations(t *testing.T) {
+	decoder := &ai.FlightRecommendationDecoder{}
+	recommendation, err := decoder.DecodeResponse(`{
+		"recommendations": [{"airline": "Iberia", "flight_number": "IB6250", "price": 620, "currency": "EUR"}],
+		"return_recommendations": [{"airline": "Iberia", "flight_number": "IB6251", "price": 580.5, "currency": "EUR"}],
+		"reasoning": "Iberia flies both ways"
+	}`)
+	require.NoError(t, err)
+	require.Len(t, recommendation.ReturnRecommendations, 1)
+	assert.Equal(t, models.NewMoney(580.50, "EUR"), recommendation.ReturnRecommendations[0].Price)
+
+	_, err = decoder.DecodeResponse(`{
+		"recommendations": [{"airline": "Iberia", "flight_number": "IB6250", "price": 620, "currency": "EUR"}],
+		"return_recommendations": [{"airline": "Iberia", "price": 580.5, "currency": "EUR"}],
+		"reasoning": "Iberia flies both ways"
+	}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing flight number for return recommendation 1")
+}
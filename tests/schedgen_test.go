@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service/airports"
+	"travel-agent/internal/service/flights"
+	"travel-agent/internal/service/schedgen"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func generateSchedules(t *testing.T, seed uint64, from time.Time, days int) []flights.Schedule {
+	t.Helper()
+	db, err := airports.Default()
+	require.NoError(t, err)
+	schedules, err := schedgen.Generate(db, schedgen.Options{
+		Seed: seed,
+		From: from,
+		To:   from.AddDate(0, 0, days-1),
+	})
+	require.NoError(t, err)
+	return schedules
+}
+
+func TestScheduleGenerator_Deterministic(t *testing.T) {
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	first := generateSchedules(t, 7, from, 30)
+	assert.Equal(t, first, generateSchedules(t, 7, from, 30))
+	assert.NotEqual(t, first, generateSchedules(t, 8, from, 30))
+
+	_, err := schedgen.Generate(nil, schedgen.Options{From: from, To: from.AddDate(0, 0, -1)})
+	assert.Error(t, err)
+}
+
+func TestScheduleGenerator_Realistic(t *testing.T) {
+	db, err := airports.Default()
+	require.NoError(t, err)
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	schedules := generateSchedules(t, 42, from, 30)
+
+	// Every schedule is valid stub inventory
+	_, err = flights.NewInventory(schedules, db)
+	require.NoError(t, err)
+
+	seen := make(map[string]bool)
+	for _, s := range schedules {
+		key := s.Carrier + s.FlightNumber + s.CabinClass
+		assert.False(t, seen[key], "duplicate flight %s", key)
+		seen[key] = true
+
+		assert.Equal(t, "2026-03-01", s.Effective)
+		assert.Equal(t, "2026-03-30", s.Discontinued)
+		assert.True(t, s.Seats >= 1 && s.Seats <= 9, "%s%s has %d seats", s.Carrier, s.FlightNumber, s.Seats)
+		assert.Len(t, s.FareCurve, 4)
+		for i := 1; i < len(s.FareCurve); i++ {
+			assert.Greater(t, s.FareCurve[i].Multiplier, s.FareCurve[i-1].Multiplier)
+		}
+
+		// Block times follow the great-circle distance of each leg
+		stops := append(append([]string{s.Origin}, s.Via...), s.Destination)
+		want := time.Duration(len(s.Via)) * time.Hour
+		for i := 0; i+1 < len(stops); i++ {
+			a, _, ok := db.Airport(stops[i])
+			require.True(t, ok, stops[i])
+			b, _, ok := db.Airport(stops[i+1])
+			require.True(t, ok, stops[i+1])
+			want += schedgen.BlockTime(airports.Distance(a, b))
+		}
+		duration, err := time.ParseDuration(s.Duration)
+		require.NoError(t, err)
+		assert.Equal(t, want, duration, "%s%s", s.Carrier, s.FlightNumber)
+	}
+
+	// New York to London is about 5,500 km
+	jfk, _, _ := db.Airport("JFK")
+	lhr, _, _ := db.Airport("LHR")
+	assert.InDelta(t, 5540, airports.Distance(jfk, lhr), 30)
+	assert.Equal(t, 7*time.Hour+15*time.Minute, schedgen.BlockTime(airports.Distance(jfk, lhr)))
+}
+
+func TestScheduleGenerator_FaresRiseNearDeparture(t *testing.T) {
+	db, err := airports.Default()
+	require.NoError(t, err)
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	// A daily economy flight, so every date searched is served
+	var daily flights.Schedule
+	for _, s := range generateSchedules(t, 1, today, 60) {
+		if len(s.Days) == 0 && len(s.Via) == 0 && s.CabinClass == "economy" {
+			daily = s
+			break
+		}
+	}
+	require.NotEmpty(t, daily.FlightNumber)
+
+	inventory, err := flights.NewInventory([]flights.Schedule{daily}, db)
+	require.NoError(t, err)
+	server := httptest.NewServer(flights.NewStubServer(inventory))
+	defer server.Close()
+	client := flights.NewClient(server.URL, "", 5*time.Second)
+
+	search := func(daysAhead int) []models.Flight {
+		offers, err := client.Search(context.Background(), models.FlightSearchRequest{
+			Origin:        daily.Origin,
+			Destination:   daily.Destination,
+			DepartureDate: today.AddDate(0, 0, daysAhead),
+			Passengers:    models.Passengers{Adults: 1},
+		})
+		require.NoError(t, err)
+		return offers
+	}
+
+	early, late := search(40), search(1)
+	require.Len(t, early, 1)
+	require.Len(t, late, 1)
+	base, err := models.ParseMoney(daily.Fare, daily.Currency)
+	require.NoError(t, err)
+	assert.Equal(t, base, early[0].Price)
+	assert.Equal(t, 1, late[0].Price.Cmp(early[0].Price))
+
+	// Nothing is offered after the schedule is discontinued
+	assert.Empty(t, search(70))
+}
@@ -0,0 +1,216 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service"
+	"travel-agent/internal/service/ai"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    time.Duration
+		wantErr bool
+	}{
+		{text: "7h 30m", want: 7*time.Hour + 30*time.Minute},
+		{text: "7h05m", want: 7*time.Hour + 5*time.Minute},
+		{text: "7 hours 30 minutes", want: 7*time.Hour + 30*time.Minute},
+		{text: "PT6H55M", want: 6*time.Hour + 55*time.Minute},
+		{text: "PT45M", want: 45 * time.Minute},
+		{text: "07:30", want: 7*time.Hour + 30*time.Minute},
+		{text: "1.5h", want: 90 * time.Minute},
+		{text: "soon", wantErr: true},
+		{text: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := models.ParseDuration(tt.text)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.Std())
+		})
+	}
+}
+
+func TestDuration_JSON(t *testing.T) {
+	encoded, err := json.Marshal(models.Duration(7*time.Hour + 5*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, `"7h05m"`, string(encoded))
+
+	var decoded struct {
+		Text    models.Duration `json:"text"`
+		Minutes models.Duration `json:"minutes"`
+		Garbage models.Duration `json:"garbage"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"text": "7h05m", "minutes": 425, "garbage": "a while"}`), &decoded))
+	assert.Equal(t, 7*time.Hour+5*time.Minute, decoded.Text.Std())
+	assert.Equal(t, 7*time.Hour+5*time.Minute, decoded.Minutes.Std())
+	assert.Zero(t, decoded.Garbage)
+}
+
+func TestBookingService_ConnectionChecks(t *testing.T) {
+	departure := time.Now().Add(72 * time.Hour).UTC().Truncate(time.Hour)
+
+	segment := func(number, origin, destination string, departs, flying time.Duration) models.Segment {
+		return models.Segment{
+			Airline:       "Aer Lingus",
+			Carrier:       "EI",
+			FlightNumber:  number,
+			Origin:        origin,
+			Destination:   destination,
+			DepartureTime: departure.Add(departs),
+			ArrivalTime:   departure.Add(departs + flying),
+		}
+	}
+	connecting := func(number string, segments ...models.Segment) models.Flight {
+		return models.Flight{
+			Airline:        "Aer Lingus",
+			FlightNumber:   number,
+			AvailableSeats: 9,
+			DepartureCity:  "New York",
+			ArrivalCity:    "London",
+			DepartureTime:  segments[0].DepartureTime,
+			ArrivalTime:    segments[len(segments)-1].ArrivalTime,
+			Segments:       segments,
+			Price:          models.NewMoney(500, "USD"),
+		}
+	}
+
+	tests := []struct {
+		name       string
+		flight     models.Flight
+		wantReason string
+		wantWarn   string
+	}{
+		{
+			name: "Comfortable connection",
+			flight: connecting("EI104",
+				segment("EI104", "JFK", "DUB", 0, 6*time.Hour+30*time.Minute),
+				segment("EI158", "DUB", "LHR", 7*time.Hour+30*time.Minute, time.Hour+15*time.Minute)),
+		},
+		{
+			name: "Connection shorter than the minimum",
+			flight: connecting("EI105",
+				segment("EI105", "JFK", "DUB", 0, 6*time.Hour+30*time.Minute),
+				segment("EI160", "DUB", "LHR", 7*time.Hour+10*time.Minute, time.Hour+15*time.Minute)),
+			wantWarn: "connection at DUB is only 0h40m, less than the 1h00m minimum",
+		},
+		{
+			name: "Changing airports",
+			flight: connecting("EI106",
+				segment("EI106", "JFK", "LGW", 0, 7*time.Hour),
+				segment("EI162", "LHR", "DUB", 9*time.Hour, time.Hour+15*time.Minute),
+				segment("EI164", "DUB", "LHR", 11*time.Hour+30*time.Minute, time.Hour+15*time.Minute)),
+			wantWarn: "connection from LGW to LHR is only 2h00m, less than the 3h00m needed to change airports",
+		},
+		{
+			name: "Segments don't connect",
+			flight: connecting("EI107",
+				segment("EI107", "JFK", "DUB", 0, 6*time.Hour+30*time.Minute),
+				segment("EI166", "ORD", "LHR", 8*time.Hour, time.Hour+15*time.Minute)),
+			wantReason: "segment EI166 departs from ORD, but EI107 lands at DUB",
+		},
+		{
+			name: "Segment departs before the previous one lands",
+			flight: connecting("EI108",
+				segment("EI108", "JFK", "DUB", 0, 6*time.Hour+30*time.Minute),
+				segment("EI168", "DUB", "LHR", 6*time.Hour, time.Hour+15*time.Minute)),
+			wantReason: "segment EI168 departs before EI108 lands",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockExtractor := new(MockTravelParameterExtractor)
+			mockRecommender := new(MockFlightRecommender)
+
+			mockExtractor.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(&models.TravelParameters{
+					TripType:      models.TripOneWay,
+					DepartureCity: "New York",
+					Destination:   "London",
+					DepartureDate: &departure,
+				}, nil)
+			// A direct alternative keeps the booking going so the rejection is reported
+			direct := connecting("EI200", segment("EI200", "JFK", "LHR", 0, 7*time.Hour))
+			direct.Price = models.NewMoney(2000, "USD")
+			mockRecommender.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(&models.FlightRecommendation{
+					Recommendations: []models.Flight{tt.flight, direct},
+					Reasoning:       "test",
+				}, nil)
+
+			svc := service.NewBookingService(mockExtractor, mockRecommender,
+				service.WithRankingWeights(service.RankingWeights{Price: 1}))
+			response, err := svc.ProcessBooking(context.Background(), models.BookingRequest{
+				Query:    "One-way from New York to London",
+				Deadline: time.Now().Add(24 * time.Hour),
+			})
+			require.NoError(t, err)
+
+			if tt.wantReason != "" {
+				require.Len(t, response.RejectedFlights, 1)
+				assert.Contains(t, response.RejectedFlights[0].Reasons, tt.wantReason)
+				assert.Equal(t, "EI200", response.FlightDetails.FlightNumber)
+				return
+			}
+
+			flight := response.FlightDetails
+			require.Equal(t, tt.flight.FlightNumber, flight.FlightNumber)
+			// Layovers, segment durations and the total duration are derived from the times
+			assert.Equal(t, len(tt.flight.Segments)-1, flight.LayoverCount)
+			assert.Equal(t, flight.ArrivalTime.Sub(flight.DepartureTime), flight.TotalDuration.Std())
+			require.Len(t, flight.Segments, len(tt.flight.Segments))
+			assert.Zero(t, flight.Segments[0].Layover)
+			for i, seg := range flight.Segments {
+				assert.Equal(t, seg.ArrivalTime.Sub(seg.DepartureTime), seg.Duration.Std())
+				if i > 0 {
+					assert.Equal(t, seg.DepartureTime.Sub(flight.Segments[i-1].ArrivalTime), seg.Layover.Std())
+				}
+			}
+
+			if tt.wantWarn == "" {
+				assert.Empty(t, flight.Warnings)
+			} else {
+				assert.Contains(t, flight.Warnings, tt.wantWarn)
+			}
+		})
+	}
+}
+
+func TestFlightRecommendationDecoder_TimesFromSegments(t *testing.T) {
+	// The flight's own times are in UTC, a day apart from the local arrival in Tokyo
+	decoder := &ai.FlightRecommendationDecoder{}
+	rec, err := decoder.DecodeResponse(`{"recommendations": [{
+		"airline": "Japan Airlines", "flight_number": "JL5", "price": 1450, "currency": "USD",
+		"departure_time": "2030-04-10T03:25:00Z", "arrival_time": "2030-04-10T17:30:00Z",
+		"segments": [{"carrier": "JL", "flight_number": "JL5", "origin": "JFK", "destination": "HND",
+			"departure_time": "2030-04-09T23:25:00-04:00", "arrival_time": "2030-04-11T02:30:00+09:00"}]
+	}], "reasoning": "test"}`)
+	require.NoError(t, err)
+
+	flight := rec.Recommendations[0]
+	assert.Equal(t, "2030-04-09T23:25:00-04:00", flight.DepartureTime.Format(time.RFC3339))
+	assert.Equal(t, "2030-04-11T02:30:00+09:00", flight.ArrivalTime.Format(time.RFC3339))
+	assert.Equal(t, 11, flight.ArrivalTime.Day(), "the hotel check-in day is the local arrival day")
+
+	// Without segments the flight's times are kept
+	rec, err = decoder.DecodeResponse(`{"recommendations": [{
+		"airline": "Japan Airlines", "flight_number": "JL5", "price": 1450, "currency": "USD",
+		"departure_time": "2030-04-09T23:25:00-04:00", "arrival_time": "2030-04-11T02:30:00+09:00"
+	}], "reasoning": "test"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "2030-04-09T23:25:00-04:00", rec.Recommendations[0].DepartureTime.Format(time.RFC3339))
+}
@@ -0,0 +1,276 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"travel-agent/internal/handlers"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service"
+	"travel-agent/internal/service/ai"
+	"travel-agent/internal/store"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuggestionService_Suggest(t *testing.T) {
+	departure := time.Now().Add(72 * time.Hour).UTC().Truncate(24 * time.Hour).Add(9 * time.Hour)
+	returning := departure.AddDate(0, 0, 3)
+
+	book := func(t *testing.T, bookings *store.MemoryBookingStore, returnDate *time.Time) *models.BookingResponse {
+		mockExtractor := new(MockTravelParameterExtractor)
+		mockRecommender := new(MockFlightRecommender)
+
+		tripType := models.TripOneWay
+		if returnDate != nil {
+			tripType = models.TripRoundTrip
+		}
+		params := &models.TravelParameters{
+			TripType:      tripType,
+			DepartureCity: "London",
+			Destination:   "Rome",
+			DepartureDate: &departure,
+			ReturnDate:    returnDate,
+			Passengers:    models.Passengers{Adults: 2},
+		}
+		params.Preferences.Activities = []string{"ancient history"}
+		params.Preferences.DietaryRestrictions = []string{"Vegetarian", "gluten free"}
+		mockExtractor.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(params, nil)
+		mockRecommender.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(&models.FlightRecommendation{
+				Recommendations: []models.Flight{{
+					Airline:        "ITA",
+					FlightNumber:   "AZ201",
+					AvailableSeats: 9,
+					DepartureCity:  "London",
+					ArrivalCity:    "Rome",
+					DepartureTime:  departure,
+					ArrivalTime:    departure.Add(150 * time.Minute),
+					Price:          models.NewMoney(180, "GBP"),
+				}},
+				Reasoning: "test",
+			}, nil)
+
+		svc := service.NewBookingService(mockExtractor, mockRecommender, service.WithBookingStore(bookings))
+		response, err := svc.ProcessBooking(context.Background(), models.BookingRequest{
+			Query:    "London to Rome",
+			Deadline: time.Now().Add(24 * time.Hour),
+		})
+		require.NoError(t, err)
+		return response
+	}
+
+	plan := &models.SuggestionPlan{
+		Days: []models.DaySuggestions{
+			{
+				Day:        2,
+				Activities: []models.Suggestion{{Name: "Palatine Hill", Interest: "ancient history"}},
+				Dining: []models.Suggestion{
+					{Name: "Trattoria da Enzo", DietaryOptions: []string{"vegetarian"}},
+					{Name: "Mama Eat", DietaryOptions: []string{"vegan", "Gluten-Free"}},
+				},
+			},
+			{
+				Day:        1,
+				Activities: []models.Suggestion{{Name: "Colosseum", Interest: "ancient history"}},
+				Dining:     []models.Suggestion{{Name: "Ristorante Pantheon", DietaryOptions: []string{"vegetarian", "gluten-free"}}},
+			},
+			{Day: 9, Activities: []models.Suggestion{{Name: "Ostia Antica"}}},
+		},
+		Reasoning: "Ancient sites near the center",
+	}
+
+	t.Run("Days cover the stay and dining respects every restriction", func(t *testing.T) {
+		bookings := store.NewMemoryBookingStore()
+		booking := book(t, bookings, &returning)
+
+		mockSuggestions := new(MockSuggestionRecommender)
+		mockSuggestions.On("ProcessRequest", mock.Anything, mock.AnythingOfType("*ai.SuggestionStrategy"),
+			mock.Anything, mock.AnythingOfType("*ai.SuggestionDecoder")).Return(plan, nil)
+
+		suggestions, err := service.NewSuggestionService(bookings, store.NewMemorySuggestionStore(), mockSuggestions).Suggest(context.Background(), booking.ID)
+		require.NoError(t, err)
+
+		start := departure.Truncate(24 * time.Hour)
+		assert.Equal(t, booking.ID, suggestions.BookingID)
+		assert.Equal(t, "Rome", suggestions.City)
+		assert.Equal(t, start, suggestions.StartDate)
+		assert.Equal(t, plan.Reasoning, suggestions.Reasoning)
+
+		// Day 9 is past the three-day stay
+		require.Len(t, suggestions.Days, 2)
+		assert.Equal(t, 1, suggestions.Days[0].Day)
+		assert.Equal(t, start, suggestions.Days[0].Date)
+		assert.Equal(t, start.AddDate(0, 0, 1), suggestions.Days[1].Date)
+		require.Len(t, suggestions.Days[1].Dining, 1)
+		assert.Equal(t, "Mama Eat", suggestions.Days[1].Dining[0].Name)
+		assert.Equal(t, []string{"Trattoria da Enzo"}, suggestions.Excluded)
+
+		mockSuggestions.AssertCalled(t, "ProcessRequest", mock.Anything, mock.Anything,
+			mock.MatchedBy(func(req models.SuggestionRequest) bool {
+				return req.City == "Rome" && req.Days == 3 && req.StartDate.Equal(start) &&
+					assert.ObjectsAreEqual([]string{"ancient history"}, req.Activities) &&
+					assert.ObjectsAreEqual([]string{"Vegetarian", "gluten free"}, req.DietaryRestrictions)
+			}), mock.Anything)
+	})
+
+	t.Run("One-way trips get a few days from arrival", func(t *testing.T) {
+		bookings := store.NewMemoryBookingStore()
+		booking := book(t, bookings, nil)
+
+		mockSuggestions := new(MockSuggestionRecommender)
+		mockSuggestions.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(plan, nil)
+
+		_, err := service.NewSuggestionService(bookings, store.NewMemorySuggestionStore(), mockSuggestions).Suggest(context.Background(), booking.ID)
+		require.NoError(t, err)
+		mockSuggestions.AssertCalled(t, "ProcessRequest", mock.Anything, mock.Anything,
+			mock.MatchedBy(func(req models.SuggestionRequest) bool { return req.Days == 3 }), mock.Anything)
+	})
+
+	t.Run("Stored suggestions are reused until the booking changes", func(t *testing.T) {
+		bookings := store.NewMemoryBookingStore()
+		booking := book(t, bookings, &returning)
+
+		mockSuggestions := new(MockSuggestionRecommender)
+		mockSuggestions.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(plan, nil)
+		svc := service.NewSuggestionService(bookings, store.NewMemorySuggestionStore(), mockSuggestions)
+		ctx := context.Background()
+
+		first, err := svc.Suggest(ctx, booking.ID)
+		require.NoError(t, err)
+		again, err := svc.Suggest(ctx, booking.ID)
+		require.NoError(t, err)
+		assert.Equal(t, first, again)
+		mockSuggestions.AssertNumberOfCalls(t, "ProcessRequest", 1)
+
+		record, err := bookings.Get(ctx, booking.ID)
+		require.NoError(t, err)
+		record.Parameters.Preferences.DietaryRestrictions = []string{"vegan"}
+		require.NoError(t, bookings.Save(ctx, record))
+
+		_, err = svc.Suggest(ctx, booking.ID)
+		require.NoError(t, err)
+		mockSuggestions.AssertNumberOfCalls(t, "ProcessRequest", 2)
+	})
+
+	t.Run("Unknown bookings are not found", func(t *testing.T) {
+		svc := service.NewSuggestionService(store.NewMemoryBookingStore(), store.NewMemorySuggestionStore(), new(MockSuggestionRecommender))
+		_, err := svc.Suggest(context.Background(), "missing")
+		assert.ErrorIs(t, err, service.ErrBookingNotFound)
+	})
+}
+
+func TestSuggestionHandler_GetSuggestions(t *testing.T) {
+	bookings := store.NewMemoryBookingStore()
+	require.NoError(t, bookings.Save(context.Background(), &models.BookingRecord{
+		Booking: models.BookingResponse{ID: "multi", TripType: models.TripMultiCity},
+		Parameters: models.TravelParameters{
+			TripType: models.TripMultiCity,
+		},
+	}))
+	handler := handlers.NewSuggestionHandler(service.NewSuggestionService(bookings, store.NewMemorySuggestionStore(), new(MockSuggestionRecommender)))
+
+	tests := []struct {
+		name   string
+		id     string
+		status int
+	}{
+		{name: "Unknown booking", id: "missing", status: http.StatusNotFound},
+		{name: "Multi-city booking", id: "multi", status: http.StatusUnprocessableEntity},
+		{name: "Missing ID", id: "", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/bookings/"+tt.id+"/suggestions", nil)
+			req.SetPathValue("id", tt.id)
+			w := httptest.NewRecorder()
+
+			handler.GetSuggestions(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			var body map[string]string
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+			assert.NotEmpty(t, body["error"])
+		})
+	}
+}
+
+func TestSuggestionDecoder(t *testing.T) {
+	decoder := &ai.SuggestionDecoder{}
+
+	plan, err := decoder.DecodeResponse(`{
+		"days": [{"day": 1, "activities": [{"name": "Colosseum", "time_of_day": "morning"}],
+			"dining": [{"name": "Mama Eat", "cuisine": "Roman", "dietary_options": ["gluten-free"]}]}],
+		"reasoning": "Start with the classics"
+	}`)
+	require.NoError(t, err)
+	require.Len(t, plan.Days, 1)
+	assert.Equal(t, []string{"gluten-free"}, plan.Days[0].Dining[0].DietaryOptions)
+
+	_, err = decoder.DecodeResponse(`{"days": [{"day": 0, "activities": []}]}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid day number for entry 1")
+
+	_, err = decoder.DecodeResponse(`{"days": [{"day": 1, "dining": [{"name": ""}]}]}`)
+	assert.Error(t, err)
+}
+
+func TestSuggestionsAndPlan_ArrivalAfterMidnight(t *testing.T) {
+	// Lands at 01:30 on June 11 and leaves at 10:00 on June 14, Tokyo time, with the
+	// flight times given in UTC
+	arrival := time.Date(2030, 6, 10, 16, 30, 0, 0, time.UTC)
+	departure := time.Date(2030, 6, 14, 1, 0, 0, 0, time.UTC)
+	outbound := models.Flight{FlightNumber: "NH105", ArrivalCity: "Tokyo", ArrivalTime: arrival}
+
+	bookings := store.NewMemoryBookingStore()
+	require.NoError(t, bookings.Save(context.Background(), &models.BookingRecord{
+		Booking: models.BookingResponse{
+			ID:            "booking-1",
+			TripType:      models.TripRoundTrip,
+			FlightDetails: &outbound,
+			RoundTrip: &models.RoundTripItinerary{
+				Outbound: outbound,
+				Return:   models.Flight{FlightNumber: "NH106", DepartureCity: "Tokyo", DepartureTime: departure},
+			},
+		},
+		Parameters: models.TravelParameters{
+			TripType:      models.TripRoundTrip,
+			DepartureCity: "Los Angeles",
+			Destination:   "Tokyo",
+			Passengers:    models.Passengers{Adults: 1},
+		},
+	}))
+
+	mockSuggestions := new(MockSuggestionRecommender)
+	mockSuggestions.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(&models.SuggestionPlan{Days: []models.DaySuggestions{{Day: 1}}, Reasoning: "test"}, nil)
+	suggestions, err := service.NewSuggestionService(bookings, store.NewMemorySuggestionStore(), mockSuggestions).
+		Suggest(context.Background(), "booking-1")
+	require.NoError(t, err)
+
+	// The stay starts on the day the traveler lands in Tokyo, not the UTC date
+	start := time.Date(2030, 6, 11, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, start, suggestions.StartDate)
+	mockSuggestions.AssertCalled(t, "ProcessRequest", mock.Anything, mock.Anything,
+		mock.MatchedBy(func(req models.SuggestionRequest) bool {
+			return req.StartDate.Equal(start) && req.Days == 3
+		}), mock.Anything)
+
+	mockPlanner := new(MockTripPlanner)
+	mockPlanner.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(&models.TripPlanDraft{Reasoning: "test"}, nil)
+	plan, err := service.NewPlanService(bookings, mockPlanner).CreatePlan(context.Background(), "booking-1")
+	require.NoError(t, err)
+
+	// The plan covers the same days, through the departure day
+	require.Len(t, plan.Days, 4)
+	assert.Equal(t, "Asia/Tokyo", plan.TimeZone)
+	assert.Equal(t, "2030-06-11", plan.Days[0].Date.Format("2006-01-02"))
+	assert.Equal(t, "2030-06-14", plan.Days[3].Date.Format("2006-01-02"))
+}
@@ -0,0 +1,210 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"travel-agent/internal/models"
+	"travel-agent/internal/service"
+	"travel-agent/internal/service/airports"
+	"travel-agent/internal/service/transport"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransportFixture_Options(t *testing.T) {
+	db, err := airports.Default()
+	require.NoError(t, err)
+	fixture, err := transport.DefaultFixture(db)
+	require.NoError(t, err)
+
+	byProvider := func(options []models.TransportOption) map[string]models.TransportOption {
+		found := make(map[string]models.TransportOption)
+		for _, option := range options {
+			found[option.Provider] = option
+		}
+		return found
+	}
+
+	t.Run("Options leave once the traveler is out of the terminal", func(t *testing.T) {
+		madrid, err := time.LoadLocation("Europe/Madrid")
+		require.NoError(t, err)
+		returning := time.Date(2030, 6, 14, 16, 40, 0, 0, madrid)
+
+		// Lands at 09:15 Madrid time
+		options, err := fixture.Options(context.Background(), models.GroundTransportRequest{
+			Airport:     "MAD",
+			ArrivalTime: time.Date(2030, 6, 11, 7, 15, 0, 0, time.UTC),
+			Destination: "Only You Boutique, Chueca",
+			Passengers:  models.Passengers{Adults: 2, Children: 1, Infants: 1},
+			ReturnTime:  &returning,
+		})
+		require.NoError(t, err)
+		found := byProvider(options)
+
+		train := found["Renfe Cercanías C1"]
+		assert.Equal(t, time.Date(2030, 6, 11, 10, 0, 0, 0, madrid), train.Departs)
+		assert.Equal(t, time.Date(2030, 6, 11, 10, 25, 0, 0, madrid), train.Arrives)
+		assert.Equal(t, "Madrid Atocha", train.To)
+		assert.Equal(t, models.NewMoney(7.80, "EUR"), train.Price, "the lap infant rides free")
+
+		taxi := found["Taxi"]
+		assert.Equal(t, "Only You Boutique, Chueca", taxi.To)
+		assert.Equal(t, 1, taxi.Vehicles)
+		assert.Equal(t, models.NewMoney(33, "EUR"), taxi.Price)
+
+		rental := found["Sixt"]
+		assert.Equal(t, time.Date(2030, 6, 11, 10, 30, 0, 0, madrid), rental.Departs)
+		assert.Equal(t, 4, rental.RentalDays)
+		assert.Equal(t, models.NewMoney(192, "EUR"), rental.Price)
+	})
+
+	t.Run("Services not running again soon are left out", func(t *testing.T) {
+		london, err := time.LoadLocation("Europe/London")
+		require.NoError(t, err)
+
+		options, err := fixture.Options(context.Background(), models.GroundTransportRequest{
+			Airport:     "LHR",
+			ArrivalTime: time.Date(2030, 6, 11, 22, 50, 0, 0, london),
+			Passengers:  models.Passengers{Adults: 6},
+		})
+		require.NoError(t, err)
+		found := byProvider(options)
+
+		assert.NotContains(t, found, "Heathrow Express")
+		assert.Equal(t, time.Date(2030, 6, 11, 23, 40, 0, 0, london), found["Elizabeth line"].Departs)
+		assert.Equal(t, 2, found["Black cab"].Vehicles)
+		assert.Equal(t, models.NewMoney(190, "GBP"), found["Black cab"].Price)
+	})
+
+	t.Run("Services running past midnight are still caught", func(t *testing.T) {
+		barcelona, err := time.LoadLocation("Europe/Madrid")
+		require.NoError(t, err)
+
+		options, err := fixture.Options(context.Background(), models.GroundTransportRequest{
+			Airport:     "BCN",
+			ArrivalTime: time.Date(2030, 6, 11, 23, 30, 0, 0, barcelona),
+			Passengers:  models.Passengers{Adults: 1},
+		})
+		require.NoError(t, err)
+		found := byProvider(options)
+
+		assert.NotContains(t, found, "Rodalies R2 Nord")
+		assert.Equal(t, time.Date(2030, 6, 12, 0, 20, 0, 0, barcelona), found["Aerobús"].Departs)
+	})
+
+	t.Run("Unknown airports have no options", func(t *testing.T) {
+		options, err := fixture.Options(context.Background(), models.GroundTransportRequest{
+			Airport:     "SYD",
+			ArrivalTime: time.Now(),
+		})
+		require.NoError(t, err)
+		assert.Empty(t, options)
+	})
+
+	t.Run("Invalid routes are rejected", func(t *testing.T) {
+		_, err := transport.ParseFixture([]byte(`[{"airport": "MAD", "mode": "taxi", "provider": "Taxi", "duration": "30m", "price": "33", "currency": "EUR", "per": "vehicle"}]`), db)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "capacity is required")
+	})
+}
+
+func TestBookingService_GroundTransport(t *testing.T) {
+	departure := time.Now().Add(72 * time.Hour).UTC().Truncate(24 * time.Hour).Add(9 * time.Hour)
+
+	book := func(t *testing.T, provider service.GroundTransportProvider) *models.BookingResponse {
+		mockExtractor := new(MockTravelParameterExtractor)
+		mockRecommender := new(MockFlightRecommender)
+		mockExtractor.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(&models.TravelParameters{
+				TripType:      models.TripOneWay,
+				DepartureCity: "London",
+				Destination:   "Madrid",
+				DepartureDate: &departure,
+				Passengers:    models.Passengers{Adults: 2},
+			}, nil)
+		mockRecommender.On("ProcessRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(&models.FlightRecommendation{
+				Recommendations: []models.Flight{{
+					Airline:        "Iberia",
+					FlightNumber:   "IB3163",
+					AvailableSeats: 9,
+					DepartureCity:  "London",
+					ArrivalCity:    "Madrid",
+					DepartureTime:  departure,
+					ArrivalTime:    departure.Add(150 * time.Minute),
+					Price:          models.NewMoney(120, "GBP"),
+				}},
+				Reasoning: "test",
+			}, nil)
+
+		svc := service.NewBookingService(mockExtractor, mockRecommender, service.WithGroundTransport(provider))
+		response, err := svc.ProcessBooking(context.Background(), models.BookingRequest{
+			Query:    "London to Madrid",
+			Deadline: time.Now().Add(24 * time.Hour),
+		})
+		require.NoError(t, err)
+		return response
+	}
+
+	t.Run("Options go from the arrival airport to the city center, earliest first", func(t *testing.T) {
+		db, err := airports.Default()
+		require.NoError(t, err)
+		fixture, err := transport.DefaultFixture(db)
+		require.NoError(t, err)
+
+		response := book(t, fixture)
+		ground := response.GroundTransport
+		require.NotNil(t, ground)
+		assert.Equal(t, "MAD", ground.Airport)
+		assert.Equal(t, "Madrid city center", ground.Destination)
+		require.NotEmpty(t, ground.Options)
+		for i := 1; i < len(ground.Options); i++ {
+			assert.False(t, ground.Options[i].Arrives.Before(ground.Options[i-1].Arrives))
+		}
+		for _, option := range ground.Options {
+			require.NotNil(t, option.Converted, "%s is priced in EUR", option.Provider)
+			assert.Equal(t, response.Currency, option.Converted.Total.Currency)
+		}
+	})
+
+	t.Run("Options the traveler's currency can't price come after the ones it can", func(t *testing.T) {
+		arrives := departure.Add(4 * time.Hour)
+		option := func(provider string, price float64, code string) models.TransportOption {
+			return models.TransportOption{Mode: "transfer", Provider: provider, From: "MAD", Arrives: arrives, Price: models.NewMoney(price, code)}
+		}
+		provider := new(MockGroundTransportProvider)
+		provider.On("Options", mock.Anything, mock.Anything).Return([]models.TransportOption{
+			option("Test Shuttle", 5, "XTT"),
+			option("Premium Transfer", 80, "USD"),
+			option("Other Test Shuttle", 1, "XTS"),
+			option("Shared Transfer", 40, "USD"),
+			option("Euro Transfer", 60, "EUR"),
+		}, nil)
+
+		response := book(t, provider)
+		require.Equal(t, "USD", response.Currency)
+		ground := response.GroundTransport
+		require.NotNil(t, ground)
+		providers := make([]string, len(ground.Options))
+		for i, o := range ground.Options {
+			providers[i] = o.Provider
+		}
+		assert.Equal(t, []string{"Shared Transfer", "Euro Transfer", "Premium Transfer", "Other Test Shuttle", "Test Shuttle"}, providers)
+	})
+
+	t.Run("The booking goes ahead when the provider fails", func(t *testing.T) {
+		provider := new(MockGroundTransportProvider)
+		provider.On("Options", mock.Anything, mock.MatchedBy(func(req models.GroundTransportRequest) bool {
+			return req.Airport == "MAD" && req.Destination == "Madrid city center"
+		})).Return(nil, errors.New("provider unavailable"))
+
+		response := book(t, provider)
+		assert.Nil(t, response.GroundTransport)
+		assert.NotNil(t, response.FlightDetails)
+		provider.AssertExpectations(t)
+	})
+}